@@ -29,6 +29,11 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
+type contextLimitsFlags struct {
+	json               bool
+	logLevelDefaultOff command.LogLevelDefaultOff
+}
+
 type topFlags struct {
 	path               string
 	nmetrics           int
@@ -78,6 +83,26 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 		},
 	})
 
+	contextLimitsFlags := contextLimitsFlags{}
+
+	contextLimitsCmd := &cobra.Command{
+		Use:   "context-limits",
+		Short: "Display the metrics and origins which reached their aggregator context limit",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(contextLimits,
+				fx.Supply(&contextLimitsFlags),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, contextLimitsFlags.logLevelDefaultOff.Value(), true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	contextLimitsFlags.logLevelDefaultOff.Register(contextLimitsCmd)
+	contextLimitsCmd.Flags().BoolVarP(&contextLimitsFlags.json, "json", "j", false, "print out raw json")
+
+	c.AddCommand(contextLimitsCmd)
+
 	return []*cobra.Command{c}
 }
 
@@ -120,6 +145,59 @@ func dumpContexts(config cconfig.Component, _ log.Component) error {
 	return nil
 }
 
+func contextLimits(config cconfig.Component, flags *contextLimitsFlags, _ log.Component) error {
+	c := util.GetClient()
+	addr, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://%v:%v/agent/aggregator-context-limits", addr, config.GetInt("cmd_port"))
+
+	if err = util.SetAuthToken(config); err != nil {
+		return err
+	}
+
+	body, err := util.DoGet(c, url, util.LeaveConnectionOpen)
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		fmt.Println(string(body))
+		return nil
+	}
+
+	var overflows []aggregator.ContextLimitOverflow
+	if err = json.Unmarshal(body, &overflows); err != nil {
+		return err
+	}
+
+	printContextLimits(os.Stdout, overflows)
+	return nil
+}
+
+func printContextLimits(w io.Writer, overflows []aggregator.ContextLimitOverflow) {
+	if len(overflows) == 0 {
+		fmt.Fprintln(w, "No metric or origin reached its context limit.")
+		return
+	}
+
+	fmt.Fprintf(w, " % 10s\t% 10s\t% 10s\t%s\t%s\n", "Contexts", "Dropped", "Folded", "Limit", "Metric name or origin")
+	for _, o := range overflows {
+		what := o.Metric
+		if o.Limit == "origin" {
+			what = strings.Join(o.OriginTags, ",")
+			if o.Check != "" {
+				what = strings.TrimPrefix(what+" (check "+o.Check+")", " ")
+			} else if what == "" {
+				what = "(no origin)"
+			}
+		}
+		fmt.Fprintf(w, " % 10d\t% 10d\t% 10d\t%s\t%s\n", o.Contexts, o.Dropped, o.Folded, o.Limit, what)
+	}
+}
+
 type metric struct {
 	count uint
 	tags  map[string]struct{}
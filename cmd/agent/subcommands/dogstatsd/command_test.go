@@ -6,11 +6,13 @@
 package dogstatsd
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -32,3 +34,32 @@ func TestCommand(t *testing.T) {
 			assert.Equal(t, 2, f.ntags)
 		})
 }
+
+func TestContextLimitsCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "context-limits", "--json"},
+		contextLimits,
+		func(f *contextLimitsFlags) {
+			assert.True(t, f.json)
+		})
+}
+
+func TestPrintContextLimits(t *testing.T) {
+	var b bytes.Buffer
+	printContextLimits(&b, nil)
+	assert.Equal(t, "No metric or origin reached its context limit.\n", b.String())
+
+	b.Reset()
+	printContextLimits(&b, []aggregator.ContextLimitOverflow{
+		{Limit: "origin", OriginTags: []string{"pod_name:web", "kube_namespace:default"}, Contexts: 100, Dropped: 42},
+		{Limit: "metric", Metric: "http.requests", Contexts: 11, Folded: 7},
+		{Limit: "origin", Contexts: 100, Dropped: 1},
+		{Limit: "origin", Check: "disk:1234", Contexts: 100, Dropped: 1},
+	})
+	assert.Equal(t, "   Contexts\t   Dropped\t    Folded\tLimit\tMetric name or origin\n"+
+		"        100\t        42\t         0\torigin\tpod_name:web,kube_namespace:default\n"+
+		"         11\t         0\t         7\tmetric\thttp.requests\n"+
+		"        100\t         1\t         0\torigin\t(no origin)\n"+
+		"        100\t         1\t         0\torigin\t(check disk:1234)\n", b.String())
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"go.uber.org/fx"
 	"google.golang.org/grpc"
@@ -47,6 +48,31 @@ type cliParams struct {
 	dsdVerboseReplay    bool
 	dsdMmapReplay       bool
 	dsdReplayIterations int
+	dsdReplaySpeed      float64
+	dsdReplayMaxGap     time.Duration
+
+	// filter of the replayed or exported packets
+	dsdFilterNames []string
+	dsdFilterTags  []string
+	dsdFilterPids  []int32
+
+	// export and import
+	dsdOutputPath     string
+	dsdExportFormat   string
+	dsdImportCompress bool
+}
+
+// filter returns the filter of the replayed or exported packets, nil when no filter is set.
+func (c *cliParams) filter() *replay.TrafficFilter {
+	filter := &replay.TrafficFilter{
+		Names: c.dsdFilterNames,
+		Tags:  c.dsdFilterTags,
+		Pids:  c.dsdFilterPids,
+	}
+	if filter.IsEmpty() {
+		return nil
+	}
+	return filter
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -71,10 +97,92 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdVerboseReplay, "verbose", "v", false, "Verbose replay.")
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdMmapReplay, "mmap", "m", true, "Mmap file for replay. Set to false to load the entire file into memory instead")
 	dogstatsdReplayCmd.Flags().IntVarP(&cliParams.dsdReplayIterations, "loops", "l", defaultIterations, "Number of iterations to replay.")
+	dogstatsdReplayCmd.Flags().Float64VarP(&cliParams.dsdReplaySpeed, "speed", "s", 1, "Replay speed factor, 2 replays twice as fast as captured. 0 replays without delay.")
+	dogstatsdReplayCmd.Flags().DurationVar(&cliParams.dsdReplayMaxGap, "max-gap", 0, "Maximum delay between two packets, to compress the idle periods of the capture.")
+	dogstatsdReplayCmd.PersistentFlags().StringSliceVar(&cliParams.dsdFilterNames, "name", nil, "Only keep the metrics and service checks whose name matches one of these patterns (\"*\" matches any characters).")
+	dogstatsdReplayCmd.PersistentFlags().StringSliceVar(&cliParams.dsdFilterTags, "tag", nil, "Only keep the messages with all these tags, a tag without value matches any value.")
+	dogstatsdReplayCmd.PersistentFlags().Int32SliceVar(&cliParams.dsdFilterPids, "pid", nil, "Only keep the packets sent by these processes.")
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a dogstatsd capture as text or JSON",
+		Long:  `Export a capture file in a human-readable form. The JSON form keeps the tagger state of the capture and can be imported back into a capture file.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(exportCapture,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	exportCmd.Flags().StringVarP(&cliParams.dsdReplayFilePath, "file", "f", "", "Input file with traffic captured with dogstatsd-capture.")
+	exportCmd.Flags().StringVarP(&cliParams.dsdOutputPath, "output", "o", "", "Output file, the standard output by default.")
+	exportCmd.Flags().StringVar(&cliParams.dsdExportFormat, "format", replay.ExportFormatJSON, "Output format: json or text.")
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import a dogstatsd capture exported as JSON",
+		Long:  ``,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(importCapture,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+	importCmd.Flags().StringVarP(&cliParams.dsdReplayFilePath, "file", "f", "", "Input file with a capture exported as JSON.")
+	importCmd.Flags().StringVarP(&cliParams.dsdOutputPath, "output", "o", "", "Output capture file.")
+	importCmd.Flags().BoolVarP(&cliParams.dsdImportCompress, "compress", "z", false, "Compress the output capture file with zstd.")
+
+	dogstatsdReplayCmd.AddCommand(exportCmd, importCmd)
 
 	return []*cobra.Command{dogstatsdReplayCmd}
 }
 
+func exportCapture(_ log.Component, cliParams *cliParams) error {
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdReplayFilePath, 1, false)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", cliParams.dsdReplayFilePath, err)
+	}
+	defer reader.Close()
+
+	out := os.Stdout
+	if cliParams.dsdOutputPath != "" {
+		out, err = os.Create(cliParams.dsdOutputPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	return replay.ExportCapture(reader, out, cliParams.dsdExportFormat, cliParams.filter())
+}
+
+func importCapture(_ log.Component, cliParams *cliParams) error {
+	if cliParams.dsdOutputPath == "" {
+		return fmt.Errorf("an output capture file is required")
+	}
+
+	in, err := os.Open(cliParams.dsdReplayFilePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(cliParams.dsdOutputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := replay.ImportCapture(in, out, cliParams.dsdImportCompress); err != nil {
+		return err
+	}
+	fmt.Printf("Capture written to %s\n", cliParams.dsdOutputPath)
+	return nil
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliParams) error {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -132,6 +240,8 @@ func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliPar
 		fmt.Printf("could not open: %s\n", cliParams.dsdReplayFilePath)
 		return err
 	}
+	reader.SetFilter(cliParams.filter())
+	reader.SetTimeScale(cliParams.dsdReplaySpeed, cliParams.dsdReplayMaxGap)
 
 	s := pkgconfigsetup.Datadog().GetString("dogstatsd_socket")
 	if s == "" {
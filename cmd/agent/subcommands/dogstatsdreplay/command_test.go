@@ -7,12 +7,14 @@ package dogstatsdreplay
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
 	"github.com/DataDog/datadog-agent/comp/core"
 	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -26,3 +28,44 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestReplayFilterCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-replay", "-f", "capture.dog", "--name", "app.*", "--tag", "env:prod", "--pid", "12,34", "--speed", "10", "--max-gap", "1s"},
+		dogstatsdReplay,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, 10.0, cliParams.dsdReplaySpeed)
+			require.Equal(t, time.Second, cliParams.dsdReplayMaxGap)
+			require.Equal(t, &replay.TrafficFilter{
+				Names: []string{"app.*"},
+				Tags:  []string{"env:prod"},
+				Pids:  []int32{12, 34},
+			}, cliParams.filter())
+		})
+}
+
+func TestExportCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-replay", "export", "-f", "capture.dog", "--format", "text", "--name", "app.*"},
+		exportCapture,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, "capture.dog", cliParams.dsdReplayFilePath)
+			require.Equal(t, replay.ExportFormatText, cliParams.dsdExportFormat)
+			require.Equal(t, []string{"app.*"}, cliParams.dsdFilterNames)
+		})
+}
+
+func TestImportCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-replay", "import", "-f", "capture.json", "-o", "capture.dog", "-z"},
+		importCapture,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, "capture.json", cliParams.dsdReplayFilePath)
+			require.Equal(t, "capture.dog", cliParams.dsdOutputPath)
+			require.True(t, cliParams.dsdImportCompress)
+			require.Nil(t, cliParams.filter())
+		})
+}
@@ -37,6 +37,7 @@ type cliParams struct {
 	dsdStatsFilePath string
 	jsonStatus       bool
 	prettyPrintJSON  bool
+	clientStats      bool
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -61,6 +62,7 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdStatsCmd.Flags().BoolVarP(&cliParams.jsonStatus, "json", "j", false, "print out raw json")
 	dogstatsdStatsCmd.Flags().BoolVarP(&cliParams.prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
 	dogstatsdStatsCmd.Flags().StringVarP(&cliParams.dsdStatsFilePath, "file", "o", "", "Output the dogstatsd-stats command to a file")
+	dogstatsdStatsCmd.Flags().BoolVarP(&cliParams.clientStats, "clients", "c", false, "print the packets received and dropped per client of the stream socket")
 
 	return []*cobra.Command{dogstatsdStatsCmd}
 }
@@ -75,7 +77,13 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 	if err != nil {
 		return err
 	}
-	urlstr := fmt.Sprintf("https://%v:%v/agent/dogstatsd-stats", ipcAddress, pkgconfigsetup.Datadog().GetInt("cmd_port"))
+	endpoint := "dogstatsd-stats"
+	formatStats := serverdebugimpl.FormatDebugStats
+	if cliParams.clientStats {
+		endpoint = "dogstatsd-client-stats"
+		formatStats = serverdebugimpl.FormatClientStats
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/%s", ipcAddress, pkgconfigsetup.Datadog().GetInt("cmd_port"), endpoint)
 
 	// Set session token
 	e = util.SetAuthToken(config)
@@ -110,7 +118,7 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 	} else if cliParams.jsonStatus {
 		s = string(r)
 	} else {
-		s, e = serverdebugimpl.FormatDebugStats(r)
+		s, e = formatStats(r)
 		if e != nil {
 			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
 			return nil
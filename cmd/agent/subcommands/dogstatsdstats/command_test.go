@@ -26,3 +26,14 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestClientsCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-stats", "--clients"},
+		requestDogstatsdStats,
+		func(cliParams *cliParams, _ core.BundleParams, _ secrets.Params) {
+			require.True(t, cliParams.clientStats)
+			require.False(t, cliParams.jsonStatus)
+		})
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	logsAgent "github.com/DataDog/datadog-agent/comp/logs/agent"
+	logsconfig "github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// LogsProcessingRulesRuntimeSetting wraps operations to replace the logs processing rules at runtime.
+type LogsProcessingRulesRuntimeSetting struct {
+	logsAgent option.Option[logsAgent.Component]
+
+	mu     sync.Mutex
+	update *logsconfig.ProcessingRulesUpdate
+}
+
+// NewLogsProcessingRulesRuntimeSetting returns a new LogsProcessingRulesRuntimeSetting
+func NewLogsProcessingRulesRuntimeSetting(agent option.Option[logsAgent.Component]) *LogsProcessingRulesRuntimeSetting {
+	return &LogsProcessingRulesRuntimeSetting{
+		logsAgent: agent,
+	}
+}
+
+// Description returns the runtime setting's description
+func (s *LogsProcessingRulesRuntimeSetting) Description() string {
+	return `Replace the logs processing rules without restarting the tailers. Possible values: a JSON object {"global": [rules], "sources": {"source name": [rules]}}, or an empty string to remove the rules set at runtime. The rules received through Remote Configuration are applied first`
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *LogsProcessingRulesRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *LogsProcessingRulesRuntimeSetting) Name() string {
+	return "logs_processing_rules"
+}
+
+// Get returns the processing rules set at runtime, nil when the configured rules are used
+func (s *LogsProcessingRulesRuntimeSetting) Get(_ config.Component) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update, nil
+}
+
+// Set changes the value of the runtime setting
+func (s *LogsProcessingRulesRuntimeSetting) Set(_ config.Component, v interface{}, _ model.Source) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%s.Set: Invalid data type", s.Name())
+	}
+
+	logs, ok := s.logsAgent.Get()
+	if !ok || logs.GetPipelineProvider() == nil {
+		return errors.New("the logs agent is not running")
+	}
+
+	var update *logsconfig.ProcessingRulesUpdate
+	if str != "" {
+		var err error
+		if update, err = logsconfig.ParseProcessingRulesUpdate([]byte(str)); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", s.Name(), err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logs.GetPipelineProvider().ReconfigureProcessingRules(logsconfig.RuntimeSettingProcessingRules, update)
+	s.update = update
+	return nil
+}
@@ -36,6 +36,7 @@ import (
 	cmdstreamep "github.com/DataDog/datadog-agent/cmd/agent/subcommands/streamep"
 	cmdstreamlogs "github.com/DataDog/datadog-agent/cmd/agent/subcommands/streamlogs"
 	cmdtaggerlist "github.com/DataDog/datadog-agent/cmd/agent/subcommands/taggerlist"
+	cmdtaplogs "github.com/DataDog/datadog-agent/cmd/agent/subcommands/taplogs"
 	cmdversion "github.com/DataDog/datadog-agent/cmd/agent/subcommands/version"
 	cmdworkloadlist "github.com/DataDog/datadog-agent/cmd/agent/subcommands/workloadlist"
 )
@@ -64,6 +65,7 @@ func AgentSubcommands() []command.SubcommandFactory {
 		cmdsnmp.Commands,
 		cmdstatus.Commands,
 		cmdstreamlogs.Commands,
+		cmdtaplogs.Commands,
 		cmdstreamep.Commands,
 		cmdtaggerlist.Commands,
 		cmdversion.Commands,
@@ -0,0 +1,222 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package taplogs implements 'agent tap-logs'.
+package taplogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/comp/core/autodiscovery/providers/names"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/logs/agent/agentimpl"
+	logsconfig "github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/schedulers/ad"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+// dryRunSourceName is the name of the source tailing the sample file in dry-run mode.
+const dryRunSourceName = "tap-logs"
+
+// CliParams are the command-line arguments for this subcommand
+type CliParams struct {
+	*command.GlobalParams
+
+	filters diagnostic.Filters
+
+	// Duration represents the duration of the tap, 0 to tap until interrupted.
+	Duration time.Duration
+
+	// DryRun runs the pipeline in-process on SampleFilePath rather than tapping the running agent.
+	DryRun bool
+
+	// SampleFilePath is the path of the file read in dry-run mode.
+	SampleFilePath string
+
+	// LogsConfigPath is the path of the logs configuration whose source processing
+	// rules and parsing options are used in dry-run mode.
+	LogsConfigPath string
+
+	// inactivityTimeout is the time the dry-run waits for new logs before exiting
+	inactivityTimeout time.Duration
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &CliParams{
+		GlobalParams: globalParams,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "tap-logs [sample file]",
+		Short: "Show the content of the logs at each stage of the logs pipeline",
+		Long: `Show, for each log of the tapped sources, the raw frames read by the tailer, the output of the decoder,
+the content after the processing rules and the encoded log.
+
+By default the logs processed by the running agent are tapped, use --name to select a source.
+With --dry-run, the given sample file is processed in-process with the global processing rules and, when
+--logs-config is set, the processing rules and parsing options of its first source. Nothing is sent.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if !cliParams.DryRun {
+				return fxutil.OneShot(tapLogs,
+					fx.Supply(cliParams),
+					fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+					core.Bundle(),
+				)
+			}
+
+			if len(args) < 1 {
+				return fmt.Errorf("a sample file path is required with --dry-run")
+			}
+			cliParams.SampleFilePath = args[0]
+			return fxutil.OneShot(dryRun,
+				fx.Supply(cliParams),
+				fx.Supply(core.BundleParams{
+					ConfigParams: config.NewAgentParams(globalParams.ConfFilePath, config.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					SecretParams: secrets.NewEnabledParams(),
+					LogParams:    log.ForOneShot("", "off", true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	cmd.Flags().StringVar(&cliParams.filters.Name, "name", "", "Name of the tapped source, all the sources are tapped when empty")
+	cmd.Flags().StringVar(&cliParams.filters.Type, "type", "", "Filter by type")
+	cmd.Flags().StringVar(&cliParams.filters.Source, "source", "", "Filter by source")
+	cmd.Flags().StringVar(&cliParams.filters.Service, "service", "", "Filter by service")
+	cmd.Flags().DurationVarP(&cliParams.Duration, "duration", "d", 0, "Duration of the tap (default: 0, infinite)")
+	cmd.Flags().BoolVar(&cliParams.DryRun, "dry-run", false, "Process the sample file in-process instead of tapping the running agent, nothing is sent")
+	cmd.Flags().StringVarP(&cliParams.LogsConfigPath, "logs-config", "c", "", "Logs configuration file whose first source is used to process the sample file (optional)")
+	cmd.Flags().DurationVarP(&cliParams.inactivityTimeout, "inactivity-timeout", "t", time.Second, "Time the dry-run waits for new logs before exiting")
+	cmd.PreRunE = func(_ *cobra.Command, _ []string) error {
+		if cliParams.Duration < 0 {
+			return fmt.Errorf("duration must be a positive value")
+		}
+		return nil
+	}
+
+	return []*cobra.Command{cmd}
+}
+
+// tapLogs streams the traces of the logs processed by the running agent.
+func tapLogs(config config.Component, cliParams *CliParams) error {
+	ipcAddress, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&cliParams.filters)
+	if err != nil {
+		return err
+	}
+
+	c := util.GetClient()
+	if cliParams.Duration != 0 {
+		c.Timeout = cliParams.Duration
+	}
+	if err = util.SetAuthToken(pkgconfigsetup.Datadog()); err != nil {
+		return err
+	}
+
+	urlstr := fmt.Sprintf("https://%v:%v/agent/tap-logs", ipcAddress, config.GetInt("cmd_port"))
+	err = util.DoPostChunked(c, urlstr, "application/json", bytes.NewBuffer(body), func(chunk []byte) {
+		fmt.Print(string(chunk))
+	})
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("Could not reach agent: %v \nMake sure the agent is running before tapping the logs and contact support if you continue having issues. \n", err)
+	}
+	return err
+}
+
+// dryRun processes the sample file in-process and prints the traces of its logs.
+func dryRun(config config.Component, cliParams *CliParams) error {
+	source, err := dryRunSource(cliParams)
+	if err != nil {
+		return err
+	}
+	configSource := sources.NewConfigSources()
+	configSource.AddSource(source)
+
+	// the tap must be enabled before the tailer starts to trace the first logs
+	receiver := diagnostic.DefaultTapReceiver
+	receiver.SetEnabled(true)
+	defer receiver.SetEnabled(false)
+	done := make(chan struct{})
+	defer close(done)
+	traces := receiver.Filter(&diagnostic.Filters{Name: source.Name}, done)
+
+	outputChan, launchers, pipelineProvider, err := agentimpl.SetUpLaunchers(config, configSource)
+	if err != nil {
+		return err
+	}
+
+	idleTimer := time.NewTimer(cliParams.inactivityTimeout)
+	for {
+		select {
+		case <-outputChan:
+			// the encoded logs are part of the traces, nothing is sent
+		case trace := <-traces:
+			fmt.Print(trace)
+			if !idleTimer.Stop() {
+				<-idleTimer.C
+			}
+			idleTimer.Reset(cliParams.inactivityTimeout)
+		case <-idleTimer.C:
+			launchers.Stop()
+			pipelineProvider.Stop()
+			return nil
+		}
+	}
+}
+
+// dryRunSource returns the source reading the sample file from its beginning, with
+// the configuration of the first source of the logs configuration, if any.
+func dryRunSource(cliParams *CliParams) (*sources.LogSource, error) {
+	source := sources.NewLogSource(dryRunSourceName, &logsconfig.LogsConfig{})
+	if cliParams.LogsConfigPath != "" {
+		data, err := os.ReadFile(cliParams.LogsConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		configured, err := ad.CreateSources(integration.Config{
+			Provider:   names.File,
+			LogsConfig: data,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(configured) == 0 {
+			return nil, fmt.Errorf("no logs configuration found in %s", cliParams.LogsConfigPath)
+		}
+		source = configured[0]
+	}
+
+	source.Config.Type = logsconfig.FileType
+	source.Config.Path = cliParams.SampleFilePath
+	source.Config.TailingMode = "beginning"
+	if err := source.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid logs configuration: %v", err)
+	}
+	return source, nil
+}
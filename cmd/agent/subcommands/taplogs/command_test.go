@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package taplogs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	logsconfig "github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"tap-logs", "--name", "foo", "--duration", "10s"},
+		tapLogs,
+		func(cliParams *CliParams, _ core.BundleParams) {
+			require.Equal(t, "foo", cliParams.filters.Name)
+			require.Equal(t, 10*time.Second, cliParams.Duration)
+			require.False(t, cliParams.DryRun)
+		})
+}
+
+func TestDryRunCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"tap-logs", "--dry-run", "-c", "conf.yaml", "-t", "5s", "sample.log"},
+		dryRun,
+		func(cliParams *CliParams, _ core.BundleParams) {
+			require.True(t, cliParams.DryRun)
+			require.Equal(t, "sample.log", cliParams.SampleFilePath)
+			require.Equal(t, "conf.yaml", cliParams.LogsConfigPath)
+			require.Equal(t, 5*time.Second, cliParams.inactivityTimeout)
+		})
+}
+
+func TestDryRunSource(t *testing.T) {
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "conf.yaml")
+	require.NoError(t, os.WriteFile(confPath, []byte(`
+logs:
+  - type: tcp
+    port: 10514
+    service: app
+    source: python
+    log_processing_rules:
+      - type: exclude_at_match
+        name: exclude_healthchecks
+        pattern: healthcheck
+`), 0644))
+
+	// the sample file replaces the input of the first source, its processing rules are kept
+	source, err := dryRunSource(&CliParams{SampleFilePath: "sample.log", LogsConfigPath: confPath})
+	require.NoError(t, err)
+	assert.Equal(t, logsconfig.FileType, source.Config.Type)
+	assert.Equal(t, "sample.log", source.Config.Path)
+	assert.Equal(t, "beginning", source.Config.TailingMode)
+	assert.Equal(t, "app", source.Config.Service)
+	require.Len(t, source.Config.ProcessingRules, 1)
+	assert.NotNil(t, source.Config.ProcessingRules[0].Regex)
+
+	// without logs configuration, only the global processing rules apply
+	source, err = dryRunSource(&CliParams{SampleFilePath: "sample.log"})
+	require.NoError(t, err)
+	assert.Equal(t, dryRunSourceName, source.Name)
+	assert.Empty(t, source.Config.ProcessingRules)
+
+	_, err = dryRunSource(&CliParams{SampleFilePath: "sample.log", LogsConfigPath: filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/controlsvc"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/info"
 	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/run"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands/traces"
 	"github.com/DataDog/datadog-agent/pkg/cli/subcommands/version"
 )
 
@@ -48,6 +49,7 @@ func makeCommands(globalParams *subcommands.GlobalParams) *cobra.Command {
 		info.MakeCommand(globalConfGetter),
 		version.MakeCommand("trace-agent"),
 		config.MakeCommand(globalConfGetter),
+		traces.MakeCommand(globalConfGetter),
 	}
 
 	commands = append(commands, controlsvc.Commands(globalConfGetter)...)
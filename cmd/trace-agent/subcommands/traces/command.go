@@ -0,0 +1,205 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package traces contains the 'traces' subcommand for the 'trace-agent' command.
+package traces
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/comp/api/authtoken/fetchonlyimpl"
+	coreconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	logfx "github.com/DataDog/datadog-agent/comp/core/log/fx"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/secrets/secretsimpl"
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/fx-noop"
+	"github.com/DataDog/datadog-agent/comp/trace/config"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/agent"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	traceID     string
+	service     string
+	resource    string
+	errorFilter string
+	limit       int
+}
+
+// MakeCommand returns the traces subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	cliParams := &cliParams{}
+	tracesCmd := &cobra.Command{
+		Use:   "traces [trace ID]",
+		Short: "Print the traces kept by the trace store of a running trace-agent.",
+		Long: `Use this to inspect the last sampled traces of a running trace-agent, when apm_config.trace_store.enabled is set.
+Without argument, the traces are listed, the most recent first. With a trace ID, the spans of the trace are printed as a tree.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				cliParams.traceID = args[0]
+			}
+			params := globalParamsGetter()
+			return fxutil.OneShot(printTraces,
+				fx.Supply(cliParams),
+				config.Module(),
+				fx.Supply(coreconfig.NewAgentParams(params.ConfPath, coreconfig.WithFleetPoliciesDirPath(params.FleetPoliciesDirPath))),
+				fx.Supply(log.ForOneShot(params.LoggerName, "off", true)),
+				fx.Supply(option.None[secrets.Component]()),
+				fx.Supply(secrets.NewEnabledParams()),
+				coreconfig.Module(),
+				secretsimpl.Module(),
+				nooptagger.Module(),
+				fetchonlyimpl.Module(),
+				logfx.Module(),
+			)
+		},
+		SilenceUsage: true,
+	}
+	tracesCmd.Flags().StringVar(&cliParams.service, "service", "", "List the traces with a span of this service.")
+	tracesCmd.Flags().StringVar(&cliParams.resource, "resource", "", "List the traces with a span of this resource.")
+	tracesCmd.Flags().StringVar(&cliParams.errorFilter, "error", "", "List the traces with an error when true, and without error when false.")
+	tracesCmd.Flags().IntVarP(&cliParams.limit, "limit", "n", 20, "Maximum number of traces listed.")
+	return tracesCmd
+}
+
+func printTraces(config config.Component, cliParams *cliParams) error {
+	tracecfg := config.Object()
+	if tracecfg == nil {
+		return fmt.Errorf("Unable to successfully parse config")
+	}
+	u := fmt.Sprintf("https://127.0.0.1:%d%s", tracecfg.DebugServerPort, agent.TraceStoreRoute)
+	if cliParams.traceID != "" {
+		if _, err := strconv.ParseUint(cliParams.traceID, 10, 64); err != nil {
+			return fmt.Errorf("invalid trace ID %q: the trace IDs are decimal numbers", cliParams.traceID)
+		}
+		var trace agent.StoredTrace
+		if err := getJSON(u+"/"+cliParams.traceID, &trace); err != nil {
+			return err
+		}
+		printTraceTree(os.Stdout, &trace)
+		return nil
+	}
+	q := url.Values{}
+	for k, v := range map[string]string{"service": cliParams.service, "resource": cliParams.resource, "error": cliParams.errorFilter} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	q.Set("limit", strconv.Itoa(cliParams.limit))
+	var summaries []agent.TraceSummary
+	if err := getJSON(u+"?"+q.Encode(), &summaries); err != nil {
+		return err
+	}
+	printTraceList(os.Stdout, summaries)
+	return nil
+}
+
+// getJSON decodes the JSON response of the debug server to the given URL into v.
+func getJSON(u string, v interface{}) error {
+	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := http.Client{Timeout: 3 * time.Second, Transport: tr}
+	resp, err := client.Get(u)
+	if err != nil {
+		return fmt.Errorf("cannot reach the trace-agent, is it running with the debug server enabled? %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		msg := strings.TrimSpace(string(body))
+		if resp.StatusCode == http.StatusNotFound && msg == "404 page not found" {
+			// the route is not registered
+			return fmt.Errorf("the trace store is disabled, set apm_config.trace_store.enabled to enable it")
+		}
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func printTraceList(w io.Writer, summaries []agent.TraceSummary) {
+	if len(summaries) == 0 {
+		fmt.Fprintln(w, "No trace found.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TRACE ID\tSERVICE\tNAME\tRESOURCE\tDURATION\tSPANS\tERROR")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%d\t%t\n", s.TraceID, s.Service, s.Name, s.Resource, time.Duration(s.Duration), s.Spans, s.Error)
+	}
+	tw.Flush()
+}
+
+// printTraceTree prints the spans of a trace as a tree, the children of each span sorted by start time.
+func printTraceTree(w io.Writer, trace *agent.StoredTrace) {
+	var spans []*pb.Span
+	for _, tp := range trace.Payloads {
+		for _, chunk := range tp.Chunks {
+			spans = append(spans, chunk.Spans...)
+		}
+	}
+	fmt.Fprintf(w, "Trace %d (env: %s, spans: %d)\n", trace.TraceID, trace.Env, len(spans))
+
+	ids := make(map[uint64]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanID] = true
+	}
+	var roots []*pb.Span
+	children := make(map[uint64][]*pb.Span)
+	for _, s := range spans {
+		if s.ParentID == 0 || s.ParentID == s.SpanID || !ids[s.ParentID] {
+			// the parent of the span may not have been received
+			roots = append(roots, s)
+		} else {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		}
+	}
+	var printSpan func(s *pb.Span, prefix, branch, indent string)
+	printSpan = func(s *pb.Span, prefix, branch, indent string) {
+		line := fmt.Sprintf("%s%s%s %s %s %s", prefix, branch, s.Service, s.Name, s.Resource, time.Duration(s.Duration))
+		if s.Error != 0 {
+			line += " [error"
+			if msg := s.Meta["error.msg"]; msg != "" {
+				line += ": " + msg
+			}
+			line += "]"
+		}
+		fmt.Fprintln(w, line)
+		sortByStart(children[s.SpanID])
+		for i, c := range children[s.SpanID] {
+			if i == len(children[s.SpanID])-1 {
+				printSpan(c, prefix+indent, "└─ ", "   ")
+			} else {
+				printSpan(c, prefix+indent, "├─ ", "│  ")
+			}
+		}
+	}
+	sortByStart(roots)
+	for _, r := range roots {
+		printSpan(r, "", "", "")
+	}
+}
+
+func sortByStart(spans []*pb.Span) {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package traces
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/agent"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestTracesCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		[]*cobra.Command{MakeCommand(func() *subcommands.GlobalParams {
+			return &subcommands.GlobalParams{}
+		})},
+		[]string{"traces", "--service", "web", "--error", "true", "-n", "5"},
+		printTraces,
+		func(cliParams *cliParams) {
+			require.Equal(t, "web", cliParams.service)
+			require.Equal(t, "true", cliParams.errorFilter)
+			require.Equal(t, 5, cliParams.limit)
+		})
+}
+
+func TestPrintTraceList(t *testing.T) {
+	var buf bytes.Buffer
+	printTraceList(&buf, nil)
+	assert.Equal(t, "No trace found.\n", buf.String())
+
+	buf.Reset()
+	printTraceList(&buf, []agent.TraceSummary{
+		{TraceID: 42, Service: "web", Name: "http.request", Resource: "GET /users", Duration: int64(3 * time.Millisecond), Spans: 3, Error: true},
+	})
+	assert.Equal(t, ""+
+		"TRACE ID  SERVICE  NAME          RESOURCE    DURATION  SPANS  ERROR\n"+
+		"42        web      http.request  GET /users  3ms       3      true\n", buf.String())
+}
+
+func TestPrintTraceTree(t *testing.T) {
+	span := func(spanID, parentID uint64, service, name, resource string, start int64) *pb.Span {
+		return &pb.Span{TraceID: 42, SpanID: spanID, ParentID: parentID, Service: service, Name: name, Resource: resource, Start: start, Duration: int64(time.Millisecond)}
+	}
+	query := span(3, 2, "db", "postgres.query", "SELECT", 30)
+	query.Error = 1
+	query.Meta = map[string]string{"error.msg": "timeout"}
+	trace := &agent.StoredTrace{
+		TraceSummary: agent.TraceSummary{TraceID: 42, Env: "dev"},
+		Payloads: []*pb.TracerPayload{
+			{Chunks: []*pb.TraceChunk{{Spans: []*pb.Span{
+				span(1, 0, "web", "http.request", "GET /users", 0),
+				span(4, 1, "web", "template.render", "users.html", 40),
+				span(2, 1, "web", "db.session", "session", 10),
+			}}}},
+			// a chunk of the trace received in another payload
+			{Chunks: []*pb.TraceChunk{{Spans: []*pb.Span{query}}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	printTraceTree(&buf, trace)
+	assert.Equal(t, ""+
+		"Trace 42 (env: dev, spans: 4)\n"+
+		"web http.request GET /users 1ms\n"+
+		"├─ web db.session session 1ms\n"+
+		"│  └─ db postgres.query SELECT 1ms [error: timeout]\n"+
+		"└─ web template.render users.html 1ms\n", buf.String())
+}
@@ -3,7 +3,7 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2023-present Datadog, Inc.
 
-// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump API endpoint that can register via Fx value groups.
+// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump and /aggregator-context-limits API endpoints that can register via Fx value groups.
 package demultiplexerendpointimpl
 
 import (
@@ -37,7 +37,8 @@ type demultiplexerEndpoint struct {
 
 // Provides defines the output of the demultiplexerendpoint component
 type Provides struct {
-	Endpoint api.AgentEndpointProvider
+	Endpoint              api.AgentEndpointProvider
+	ContextLimitsEndpoint api.AgentEndpointProvider
 }
 
 // NewComponent creates a new demultiplexerendpoint component
@@ -49,7 +50,8 @@ func NewComponent(reqs Requires) Provides {
 	}
 
 	return Provides{
-		Endpoint: api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		Endpoint:              api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		ContextLimitsEndpoint: api.NewAgentEndpointProvider(endpoint.contextLimits, "/aggregator-context-limits", "GET"),
 	}
 }
 
@@ -70,6 +72,17 @@ func (demuxendpoint demultiplexerEndpoint) dumpDogstatsdContexts(w http.Response
 	w.Write(resp)
 }
 
+func (demuxendpoint demultiplexerEndpoint) contextLimits(w http.ResponseWriter, _ *http.Request) {
+	resp, err := json.Marshal(demuxendpoint.demux.ContextLimitOverflows())
+	if err != nil {
+		httputils.SetJSONError(w, demuxendpoint.log.Errorf("Failed to serialize response: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
 func (demuxendpoint demultiplexerEndpoint) writeDogstatsdContexts() (string, error) {
 	path := path.Join(demuxendpoint.config.GetString("run_path"), "dogstatsd_contexts.json.zstd")
 
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Acknowledgements written back on the stream socket for each packet received, when
+// the flow control is enabled. Cooperating clients read them to slow down before
+// the server drops their packets.
+const (
+	// AckOK acknowledges a packet which has been queued for processing.
+	AckOK byte = 0
+	// AckBusy acknowledges a packet which has been queued for processing, while the
+	// processing queue is filling up: the client should slow down.
+	AckBusy byte = 1
+	// AckDropped acknowledges a packet which has been dropped because the processing
+	// queue is full: the client may send it again later.
+	AckDropped byte = 2
+)
+
+// clientStatsFlushInterval is how often the stats of a client are added to the server debug
+const clientStatsFlushInterval = time.Second
+
+// flowControl tells the clients of the stream socket whether the server is saturated,
+// based on the length of the queue of packets waiting to be processed.
+type flowControl struct {
+	packetOut     chan packets.Packets
+	highWatermark int
+	ackTimeout    time.Duration
+	debug         serverdebug.Component
+}
+
+// newFlowControl returns the flow control of the stream socket, or nil when it is disabled.
+func newFlowControl(cfg model.Reader, packetOut chan packets.Packets, debug serverdebug.Component) *flowControl {
+	if !cfg.GetBool("dogstatsd_stream_flow_control.enabled") {
+		return nil
+	}
+
+	highWatermark := int(cfg.GetFloat64("dogstatsd_stream_flow_control.high_watermark") * float64(cap(packetOut)))
+	if highWatermark <= 0 || highWatermark > cap(packetOut) {
+		highWatermark = cap(packetOut)
+	}
+	return &flowControl{
+		packetOut:     packetOut,
+		highWatermark: highWatermark,
+		ackTimeout:    cfg.GetDuration("dogstatsd_stream_flow_control.ack_timeout"),
+		debug:         debug,
+	}
+}
+
+// status returns the acknowledgement of a packet received now.
+func (f *flowControl) status() byte {
+	queued := len(f.packetOut)
+	switch {
+	case queued >= cap(f.packetOut):
+		return AckDropped
+	case queued >= f.highWatermark:
+		return AckBusy
+	}
+	return AckOK
+}
+
+// connFlowControl is the flow control of a connection, which accounts the packets
+// received from its client.
+type connFlowControl struct {
+	*flowControl
+	conn        netUnixConn
+	client      string
+	acksEnabled bool
+	received    uint64
+	dropped     uint64
+	lastFlush   time.Time
+}
+
+func (f *flowControl) forConnection(conn netUnixConn, listenerID string) *connFlowControl {
+	return &connFlowControl{
+		flowControl: f,
+		conn:        conn,
+		client:      listenerID,
+		acksEnabled: true,
+		lastFlush:   time.Now(),
+	}
+}
+
+// setClient identifies the client of the connection by the process which sent its
+// packets, when origin detection is enabled.
+func (c *connFlowControl) setClient(pid int, container string) {
+	if pid == 0 {
+		return
+	}
+	client := "pid:" + strconv.Itoa(pid)
+	if container != "" {
+		client += " container:" + container
+	}
+	if client != c.client {
+		c.flush()
+		c.client = client
+	}
+}
+
+// ack accounts a received packet and writes its acknowledgement back to the client.
+// The acknowledgements are disabled for the connection when the client doesn't read
+// them, to avoid blocking the reads of its packets.
+func (c *connFlowControl) ack(status byte, now time.Time) {
+	c.received++
+	if status == AckDropped {
+		c.dropped++
+	}
+	if now.Sub(c.lastFlush) >= clientStatsFlushInterval {
+		c.flush()
+	}
+
+	if !c.acksEnabled {
+		return
+	}
+	_ = c.conn.SetWriteDeadline(now.Add(c.ackTimeout))
+	if _, err := c.conn.Write([]byte{status}); err != nil {
+		log.Debugf("dogstatsd-uds: client %s doesn't read the acknowledgements, disabling them: %v", c.client, err)
+		c.acksEnabled = false
+	}
+}
+
+// flush adds the packets accounted since the last flush to the stats of the client.
+func (c *connFlowControl) flush() {
+	c.lastFlush = time.Now()
+	if c.received == 0 || c.debug == nil {
+		return
+	}
+	c.debug.StoreClientStats(c.client, c.received, c.dropped)
+	c.received, c.dropped = 0, 0
+}
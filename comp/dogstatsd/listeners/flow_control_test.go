@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+// UDS won't work in windows
+
+package listeners
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// clientStatsRecorder records the clients stats stored in the server debug
+type clientStatsRecorder struct {
+	serverdebug.Component
+	sync.Mutex
+	received map[string]uint64
+	dropped  map[string]uint64
+}
+
+func (r *clientStatsRecorder) StoreClientStats(client string, received uint64, dropped uint64) {
+	r.Lock()
+	defer r.Unlock()
+	r.received[client] += received
+	r.dropped[client] += dropped
+}
+
+func (r *clientStatsRecorder) totals() (uint64, uint64) {
+	r.Lock()
+	defer r.Unlock()
+	var received, dropped uint64
+	for client := range r.received {
+		received += r.received[client]
+		dropped += r.dropped[client]
+	}
+	return received, dropped
+}
+
+func TestFlowControlStatus(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_stream_flow_control.enabled":        true,
+		"dogstatsd_stream_flow_control.high_watermark": 0.5,
+	})
+	packetOut := make(chan packets.Packets, 4)
+	f := newFlowControl(deps.Config, packetOut, nil)
+	require.NotNil(t, f)
+
+	assert.Equal(t, AckOK, f.status())
+	packetOut <- packets.Packets{}
+	assert.Equal(t, AckOK, f.status())
+	packetOut <- packets.Packets{}
+	assert.Equal(t, AckBusy, f.status())
+	packetOut <- packets.Packets{}
+	packetOut <- packets.Packets{}
+	assert.Equal(t, AckDropped, f.status())
+}
+
+func TestFlowControlDisabled(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_stream_flow_control.enabled": false,
+	})
+	assert.Nil(t, newFlowControl(deps.Config, make(chan packets.Packets, 4), nil))
+}
+
+func TestUDSStreamFlowControl(t *testing.T) {
+	socketPath := testSocketPath(t)
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_stream_socket":               socketPath,
+		"dogstatsd_origin_detection":            false,
+		"dogstatsd_stream_flow_control.enabled": true,
+	})
+
+	// the processing queue is full
+	packetsChannel := make(chan packets.Packets, 1)
+	packetsChannel <- packets.Packets{}
+
+	debug := &clientStatsRecorder{received: map[string]uint64{}, dropped: map[string]uint64{}}
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	s, err := NewUDSStreamListener(packetsChannel, newPacketPoolManagerUDS(deps.Config, packetsTelemetryStore), nil, deps.Config, nil, option.None[workloadmeta.Component](), deps.PidMap, debug, telemetryStore, packetsTelemetryStore, deps.Telemetry)
+	require.NoError(t, err)
+	s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+
+	contents := []byte("daemon:666|g|#sometag1:somevalue1")
+	send := func() byte {
+		require.NoError(t, binary.Write(conn, binary.LittleEndian, int32(len(contents))))
+		_, err := conn.Write(contents)
+		require.NoError(t, err)
+
+		ack := make([]byte, 1)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, err = conn.Read(ack)
+		require.NoError(t, err)
+		return ack[0]
+	}
+
+	assert.Equal(t, AckDropped, send())
+
+	<-packetsChannel
+	assert.Equal(t, AckOK, send())
+
+	select {
+	case pkts := <-packetsChannel:
+		require.Len(t, pkts, 1)
+		assert.Equal(t, contents, pkts[0].Contents)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		received, dropped := debug.totals()
+		return received == 2 && dropped == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
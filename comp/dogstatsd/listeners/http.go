@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// HTTPPath is the path of the endpoint accepting batches of dogstatsd messages
+const HTTPPath = "/dogstatsd/v1/batch"
+
+// HTTPListener implements the StatsdListener interface for HTTP. It accepts POST
+// requests whose body is a batch of newline separated messages, and sends back
+// packets ready to be processed.
+// Origin detection is not implemented for HTTP.
+type HTTPListener struct {
+	listener        net.Listener
+	httpServer      *http.Server
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	bufferSize      int
+	maxRequestSize  int64
+	trafficCapture  replay.Component // Currently ignored
+	listenWg        sync.WaitGroup
+	telemetryStore  *TelemetryStore
+}
+
+// NewHTTPListener returns an idle HTTP Statsd listener
+func NewHTTPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*HTTPListener, error) {
+	addr := listenAddress(cfg, cfg.GetString("dogstatsd_http_port"))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "http", packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.HTTP)
+
+	l := &HTTPListener{
+		listener:        listener,
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packetAssembler,
+		bufferSize:      cfg.GetInt("dogstatsd_buffer_size"),
+		maxRequestSize:  cfg.GetInt64("dogstatsd_http_max_request_size"),
+		trafficCapture:  capture,
+		telemetryStore:  telemetryStore,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HTTPPath, l.handle)
+	l.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Debugf("dogstatsd-http: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *HTTPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *HTTPListener) Listen() {
+	l.listenWg.Add(1)
+
+	go func() {
+		defer l.listenWg.Done()
+		log.Infof("dogstatsd-http: starting to listen on %s%s", l.listener.Addr(), HTTPPath)
+		if err := l.httpServer.Serve(l.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd-http: error serving requests: %v", err)
+		}
+	}()
+}
+
+func (l *HTTPListener) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	t1 := time.Now()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, l.maxRequestSize))
+	if err != nil {
+		l.telemetryStore.tlmHTTPRequests.Inc("error")
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "could not read the request body", http.StatusBadRequest)
+		return
+	}
+
+	l.telemetryStore.tlmHTTPRequests.Inc("ok")
+	l.telemetryStore.tlmHTTPRequestsBytes.Add(float64(len(body)))
+
+	dropped := l.addMessages(body)
+	l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), "http", "http", "http")
+
+	if dropped > 0 {
+		log.Debugf("dogstatsd-http: dropped %d messages bigger than the buffer from %s", dropped, r.RemoteAddr)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addMessages sends the newline separated messages of a batch to the packet assembler,
+// grouped in chunks fitting in a packet. It returns the number of messages dropped
+// because they don't fit in a packet.
+func (l *HTTPListener) addMessages(batch []byte) int {
+	dropped := 0
+	for len(batch) > 0 {
+		chunk := batch
+		if len(chunk) > l.bufferSize {
+			end := bytes.LastIndexByte(chunk[:l.bufferSize], '\n')
+			if end < 0 {
+				// the first message doesn't fit in a packet, skip it
+				dropped++
+				if next := bytes.IndexByte(batch, '\n'); next >= 0 {
+					batch = batch[next+1:]
+				} else {
+					batch = nil
+				}
+				continue
+			}
+			chunk = chunk[:end+1]
+		}
+		batch = batch[len(chunk):]
+
+		chunk = bytes.Trim(chunk, "\n")
+		if len(chunk) > 0 {
+			// packetAssembler merges multiple packets together and sends them when its buffer is full
+			l.packetAssembler.AddMessage(chunk)
+		}
+	}
+	return dropped
+}
+
+// Stop stops the HTTP server and stops listening
+func (l *HTTPListener) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.httpServer.Shutdown(ctx); err != nil {
+		log.Warnf("dogstatsd-http: could not stop the server: %v", err)
+	}
+	l.listenWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestHTTPListener(t *testing.T, packetChannel chan packets.Packets, overrides map[string]interface{}) *HTTPListener {
+	port, err := getAvailableTCPPort()
+	require.NoError(t, err)
+
+	cfg := map[string]interface{}{
+		"dogstatsd_http_port":         port,
+		"dogstatsd_non_local_traffic": false,
+	}
+	for k, v := range overrides {
+		cfg[k] = v
+	}
+	deps := fulfillDepsWithConfig(t, cfg)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	s, err := NewHTTPListener(packetChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	return s
+}
+
+func TestHTTPReceive(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	s := newTestHTTPListener(t, packetChannel, nil)
+	s.Listen()
+	defer s.Stop()
+
+	url := "http://" + s.LocalAddr() + HTTPPath
+	body := "daemon:666|g|#sometag1:somevalue1\ndaemon:999|c\n"
+	resp, err := http.Post(url, "text/plain", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	select {
+	case pkts := <-packetChannel:
+		require.Len(t, pkts, 1)
+		assert.Equal(t, "daemon:666|g|#sometag1:somevalue1\ndaemon:999|c", string(pkts[0].Contents))
+		assert.Equal(t, packets.HTTP, pkts[0].Source)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHTTPRequestTooLarge(t *testing.T) {
+	s := newTestHTTPListener(t, nil, map[string]interface{}{"dogstatsd_http_max_request_size": 16})
+	s.Listen()
+	defer s.Stop()
+
+	resp, err := http.Post("http://"+s.LocalAddr()+HTTPPath, "text/plain", strings.NewReader("daemon:666|g|#sometag1:somevalue1\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestHTTPAddMessages(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 10)
+	s := newTestHTTPListener(t, packetChannel, map[string]interface{}{"dogstatsd_buffer_size": 16})
+	defer s.Stop()
+
+	dropped := s.addMessages([]byte("a:1|c\nb:2|c\nthis.message.is.too.long:1|c\nc:3|c"))
+	assert.Equal(t, 1, dropped)
+
+	var received [][]byte
+	timeout := time.After(2 * time.Second)
+	for len(bytes.Join(received, []byte("\n"))) < len("a:1|c\nb:2|c\nc:3|c") {
+		select {
+		case pkts := <-packetChannel:
+			for _, packet := range pkts {
+				received = append(received, packet.Contents)
+			}
+		case <-timeout:
+			require.FailNow(t, "Timeout on receive channel")
+		}
+	}
+	assert.Equal(t, "a:1|c\nb:2|c\nc:3|c", string(bytes.Join(received, []byte("\n"))))
+}
@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// TCPListener implements the StatsdListener interface for TCP streams.
+// It accepts connections on a given TCP address, where the messages are
+// separated by newlines, and sends back packets ready to be processed.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	conn            *net.TCPListener
+	connTracker     *ConnectionTracker
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	bufferSize      int
+	trafficCapture  replay.Component // Currently ignored
+	listenWg        sync.WaitGroup
+	telemetryStore  *TelemetryStore
+}
+
+// listenAddress returns the address to listen to for the given port, on all
+// network interfaces when dogstatsd_non_local_traffic is enabled.
+func listenAddress(cfg model.Reader, port string) string {
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		return ":" + port
+	}
+	return net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	addr := listenAddress(cfg, cfg.GetString("dogstatsd_tcp_port"))
+	tcpListener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	conn, ok := tcpListener.(*net.TCPListener)
+	if !ok {
+		return nil, fmt.Errorf("unexpected return type from Listen, expected TCPListener: %#v", tcpListener)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "tcp", packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.TCP)
+
+	listener := &TCPListener{
+		conn:            conn,
+		connTracker:     NewConnectionTracker("tcp", 1*time.Second),
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packetAssembler,
+		bufferSize:      cfg.GetInt("dogstatsd_buffer_size"),
+		trafficCapture:  capture,
+		telemetryStore:  telemetryStore,
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized", conn.Addr())
+	return listener, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.conn.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.conn.Addr())
+	for {
+		conn, err := l.conn.AcceptTCP()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), " use of closed network connection") {
+				log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			}
+			return
+		}
+		go func() {
+			l.connTracker.Track(conn)
+			l.handleConnection(conn)
+			l.connTracker.Close(conn)
+		}()
+	}
+}
+
+// handleConnection reads the messages of a connection until it is closed. Only the
+// messages terminated by a newline are forwarded, a partial message is kept until
+// the rest of it is read.
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	l.telemetryStore.tlmTCPConnections.Inc()
+	defer l.telemetryStore.tlmTCPConnections.Dec()
+
+	log.Debugf("dogstatsd-tcp: starting to handle %s", conn.RemoteAddr())
+	buffer := make([]byte, l.bufferSize)
+	startWriteIndex := 0
+	// discarding is true while dropping the end of a message bigger than the buffer
+	discarding := false
+	t1 := time.Now()
+	var t2 time.Time
+	for {
+		t2 = time.Now()
+		l.telemetryStore.tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), "tcp", "tcp", "tcp")
+
+		bytesRead, err := conn.Read(buffer[startWriteIndex:])
+		t1 = time.Now()
+
+		if bytesRead > 0 {
+			endIndex := startWriteIndex + bytesRead
+
+			if discarding {
+				if newline := bytes.IndexByte(buffer[:endIndex], '\n'); newline >= 0 {
+					copy(buffer, buffer[newline+1:endIndex])
+					endIndex -= newline + 1
+					discarding = false
+				} else {
+					endIndex = 0
+				}
+			}
+
+			// When there is no '\n', the message is partial and messageSize is 0.
+			messageSize := bytes.LastIndexByte(buffer[:endIndex], '\n') + 1
+			if messageSize > 0 {
+				l.telemetryStore.tlmTCPPackets.Inc("ok")
+				l.telemetryStore.tlmTCPPacketsBytes.Add(float64(messageSize))
+
+				// packetAssembler merges multiple packets together and sends them when its buffer is full
+				l.packetAssembler.AddMessage(buffer[:messageSize-1])
+			}
+
+			startWriteIndex = endIndex - messageSize
+
+			// If the message is bigger than the buffer size, drop it up to its '\n'
+			// to continue reading the next messages.
+			if startWriteIndex >= len(buffer) {
+				log.Debugf("dogstatsd-tcp: message from %s bigger than the buffer, dropping it", conn.RemoteAddr())
+				l.telemetryStore.tlmTCPPackets.Inc("error")
+				startWriteIndex = 0
+				discarding = true
+			} else {
+				copy(buffer, buffer[messageSize:endIndex])
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				log.Debugf("dogstatsd-tcp: connection %s closed", conn.RemoteAddr())
+				return
+			}
+			log.Errorf("dogstatsd-tcp: error reading packet: %v", err)
+			l.telemetryStore.tlmTCPPackets.Inc("error")
+			return
+		}
+	}
+}
+
+// Stop closes the TCP connections and stops listening
+func (l *TCPListener) Stop() {
+	_ = l.conn.Close()
+	l.connTracker.Stop()
+	l.listenWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, packetChannel chan packets.Packets) (*TCPListener, listenerDeps) {
+	port, err := getAvailableTCPPort()
+	require.NoError(t, err)
+
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_tcp_port":          port,
+		"dogstatsd_non_local_traffic": false,
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	s, err := NewTCPListener(packetChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	return s, deps
+}
+
+func TestStartStopTCPListener(t *testing.T) {
+	s, _ := newTestTCPListener(t, nil)
+	s.Listen()
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s.Stop()
+
+	// the listener doesn't accept connections anymore
+	_, err = net.Dial("tcp", s.LocalAddr())
+	assert.Error(t, err)
+}
+
+func TestTCPReceive(t *testing.T) {
+	packetChannel := make(chan packets.Packets)
+	s, deps := newTestTCPListener(t, packetChannel)
+	s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon:999|c\ndaemon:"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("1|g\n"))
+	require.NoError(t, err)
+
+	var contents []byte
+	timeout := time.After(2 * time.Second)
+	for string(contents) != "daemon:666|g|#sometag1:somevalue1\ndaemon:999|c\ndaemon:1|g" {
+		select {
+		case pkts := <-packetChannel:
+			for _, packet := range pkts {
+				assert.Equal(t, packets.TCP, packet.Source)
+				if len(contents) > 0 {
+					contents = append(contents, '\n')
+				}
+				contents = append(contents, packet.Contents...)
+			}
+		case <-timeout:
+			require.FailNow(t, "Timeout on receive channel", "received %q", contents)
+		}
+	}
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	bytesCountMetrics, err := telemetryMock.GetCountMetric("dogstatsd", "tcp_packets_bytes")
+	require.NoError(t, err)
+	require.Len(t, bytesCountMetrics, 1)
+	assert.Equal(t, float64(len(contents)+1), bytesCountMetrics[0].Value())
+}
+
+func TestTCPReceiveMessageBiggerThanBuffer(t *testing.T) {
+	packetChannel := make(chan packets.Packets)
+	s, _ := newTestTCPListener(t, packetChannel)
+	s.bufferSize = 32
+	s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("big:1|g|#" + strings.Repeat("a", 100) + "\ndaemon:1|g\n"))
+	require.NoError(t, err)
+
+	// the end of the oversized message is dropped with its beginning
+	select {
+	case pkts := <-packetChannel:
+		require.Len(t, pkts, 1)
+		assert.Equal(t, "daemon:1|g", string(pkts[0].Contents))
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+}
+
+// getAvailableTCPPort requests a random port number and makes sure it is available
+func getAvailableTCPPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return -1, fmt.Errorf("can't find an available tcp port: %s", err)
+	}
+	defer listener.Close()
+
+	_, portString, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return -1, fmt.Errorf("can't find an available tcp port: %s", err)
+	}
+	return strconv.Atoi(portString)
+}
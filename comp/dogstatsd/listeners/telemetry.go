@@ -23,6 +23,13 @@ type TelemetryStore struct {
 	tlmUDSOriginDetectionError telemetry.Counter
 	tlmUDSPacketsBytes         telemetry.Counter
 	tlmUDSConnections          telemetry.Gauge
+	// TCP
+	tlmTCPPackets      telemetry.Counter
+	tlmTCPPacketsBytes telemetry.Counter
+	tlmTCPConnections  telemetry.Gauge
+	// HTTP
+	tlmHTTPRequests      telemetry.Counter
+	tlmHTTPRequestsBytes telemetry.Counter
 
 	tlmListener telemetry.Histogram
 }
@@ -46,6 +53,16 @@ func NewTelemetryStore(buckets []float64, telemetrycomp telemetry.Component) *Te
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS packets bytes"),
 		tlmUDSConnections: telemetrycomp.NewGauge("dogstatsd", "uds_connections",
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count"),
+		tlmTCPPackets: telemetrycomp.NewCounter("dogstatsd", "tcp_packets",
+			[]string{"state"}, "Dogstatsd TCP packets count"),
+		tlmTCPPacketsBytes: telemetrycomp.NewCounter("dogstatsd", "tcp_packets_bytes",
+			nil, "Dogstatsd TCP packets bytes count"),
+		tlmTCPConnections: telemetrycomp.NewGauge("dogstatsd", "tcp_connections",
+			nil, "Dogstatsd TCP connections count"),
+		tlmHTTPRequests: telemetrycomp.NewCounter("dogstatsd", "http_requests",
+			[]string{"state"}, "Dogstatsd HTTP requests count"),
+		tlmHTTPRequestsBytes: telemetrycomp.NewCounter("dogstatsd", "http_requests_bytes",
+			nil, "Dogstatsd HTTP requests bytes count"),
 		tlmListener: telemetrycomp.NewHistogram(
 			"dogstatsd",
 			"listener_read_latency",
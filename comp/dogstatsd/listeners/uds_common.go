@@ -71,6 +71,9 @@ type UDSListener struct {
 
 	listenWg *sync.WaitGroup
 
+	// flowControl acknowledges the packets of the stream connections, nil when disabled
+	flowControl *flowControl
+
 	// telemetry
 	telemetry             telemetry.Component
 	telemetryStore        *TelemetryStore
@@ -205,6 +208,12 @@ func (l *UDSListener) handleConnection(conn netUnixConn, closeFunc CloseFunction
 		l.telemetryStore.tlmUDSConnections.Dec(tlmListenerID, l.transport)
 	}()
 
+	var flow *connFlowControl
+	if l.flowControl != nil {
+		flow = l.flowControl.forConnection(conn, listenerID)
+		defer flow.flush()
+	}
+
 	t1 := time.Now()
 	var t2 time.Time
 	log.Debugf("dogstatsd-uds: starting to handle %s", conn.LocalAddr())
@@ -367,6 +376,18 @@ func (l *UDSListener) handleConnection(conn netUnixConn, closeFunc CloseFunction
 		packet.Source = packets.UDS
 		packet.ListenerID = listenerID
 
+		if flow != nil {
+			flow.setClient(int(packet.ProcessID), packet.Origin)
+			status := flow.status()
+			flow.ack(status, t1)
+			if status == AckDropped {
+				// the processing queue is full, the packet is dropped instead of blocking
+				// the reads, the client has been told it can send it again later.
+				l.sharedPacketPoolManager.Put(packet)
+				continue
+			}
+		}
+
 		// packetsBuffer handles the forwarding of the packets to the dogstatsd server intake channel
 		packetsBuffer.Append(packet)
 	}
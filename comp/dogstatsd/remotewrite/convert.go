@@ -0,0 +1,305 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	taggertypes "github.com/DataDog/datadog-agent/pkg/tagger/types"
+)
+
+const (
+	metricNameLabel  = "__name__"
+	bucketBoundLabel = "le"
+	// containerIDLabel and podUIDLabel are used for origin detection, like the
+	// container ID field and the entity ID tag of the dogstatsd protocol
+	containerIDLabel = "container_id"
+	podUIDLabel      = "pod_uid"
+)
+
+// seriesKind is how the samples of a series are converted
+type seriesKind int
+
+const (
+	kindGauge seriesKind = iota
+	kindCounter
+	kindBucket
+	kindSkipped
+)
+
+// Converter converts the remote-write time series into metric samples:
+//   - the counters are sent as counts of their increase since the previous request,
+//   - the buckets of the histograms are sent as distributions of their increase,
+//   - the other series are sent as gauges.
+//
+// The type of a metric family comes from the metadata of the requests, or from the
+// suffix of its name when its metadata hasn't been received yet. The first value of
+// a counter or histogram is only used as a reference. The metric samples keep the
+// timestamp of the remote-write samples. Converter is safe for concurrent use.
+type Converter struct {
+	mu sync.Mutex
+	// types are the metric types by family name
+	types map[string]MetricType
+	// cumulative are the last values of the counters and histogram buckets by series key
+	cumulative map[string]cumulativeValue
+
+	stateExpiry time.Duration
+	lastSweep   time.Time
+	now         func() time.Time
+}
+
+type cumulativeValue struct {
+	value float64
+	seen  time.Time
+}
+
+// histogram collects the buckets of a histogram series at a timestamp during a conversion
+type histogram struct {
+	name       string
+	tags       []string
+	origin     taggertypes.OriginInfo
+	timestamp  int64
+	buckets    []bucket
+	incomplete bool
+}
+
+// histogramKey identifies the buckets of a histogram series at a timestamp
+type histogramKey struct {
+	series    string
+	timestamp int64
+}
+
+type bucket struct {
+	upperBound float64
+	increase   float64
+}
+
+// NewConverter returns a Converter forgetting the last value of the counters and
+// histogram buckets which haven't been updated for stateExpiry.
+func NewConverter(stateExpiry time.Duration) *Converter {
+	return &Converter{
+		types:       make(map[string]MetricType),
+		cumulative:  make(map[string]cumulativeValue),
+		stateExpiry: stateExpiry,
+		now:         time.Now,
+	}
+}
+
+// Convert appends the metric samples of the request to dest, and returns the number of
+// samples which couldn't be converted.
+func (c *Converter) Convert(req *WriteRequest, dest []metrics.MetricSample) ([]metrics.MetricSample, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.sweep(now)
+
+	for _, md := range req.Metadata {
+		if md.MetricFamilyName != "" {
+			c.types[md.MetricFamilyName] = md.Type
+		}
+	}
+
+	dropped := 0
+	histograms := make(map[histogramKey]*histogram)
+	for _, ts := range req.Timeseries {
+		dropped += ts.NativeHistograms
+
+		name, tags, origin, upperBound, hasBound := parseLabels(ts.Labels)
+		if name == "" {
+			dropped += len(ts.Samples)
+			continue
+		}
+
+		switch c.kind(name, hasBound) {
+		case kindGauge:
+			for _, s := range ts.Samples {
+				dest = append(dest, newSample(name, metrics.GaugeType, s.Value, 1, tags, origin, s.Timestamp))
+			}
+		case kindCounter:
+			key := seriesKey(ts.Labels, "")
+			for _, s := range ts.Samples {
+				if increase, ok := c.increase(key, s.Value, now); ok {
+					dest = append(dest, newSample(name, metrics.CountType, increase, 1, tags, origin, s.Timestamp))
+				}
+			}
+		case kindBucket:
+			key := seriesKey(ts.Labels, "")
+			// the buckets of a histogram only differ by their upper bound
+			groupKey := seriesKey(ts.Labels, bucketBoundLabel)
+			tags = slices.DeleteFunc(tags, func(tag string) bool { return strings.HasPrefix(tag, bucketBoundLabel+":") })
+			for _, s := range ts.Samples {
+				h, found := histograms[histogramKey{groupKey, s.Timestamp}]
+				if !found {
+					h = &histogram{name: strings.TrimSuffix(name, "_bucket"), tags: tags, origin: origin, timestamp: s.Timestamp}
+					histograms[histogramKey{groupKey, s.Timestamp}] = h
+				}
+				increase, ok := c.increase(key, s.Value, now)
+				if !ok {
+					h.incomplete = true
+				}
+				h.buckets = append(h.buckets, bucket{upperBound: upperBound, increase: increase})
+			}
+		case kindSkipped:
+		}
+	}
+
+	for _, h := range histograms {
+		if !h.incomplete {
+			dest = h.appendSamples(dest)
+		}
+	}
+	return dest, dropped
+}
+
+// kind returns how the samples of a metric are converted.
+func (c *Converter) kind(name string, hasBound bool) seriesKind {
+	if typ, found := c.types[name]; found {
+		if typ == MetricTypeCounter {
+			return kindCounter
+		}
+		return kindGauge
+	}
+
+	for _, suffix := range []string{"_total", "_bucket", "_sum", "_count"} {
+		family, found := strings.CutSuffix(name, suffix)
+		if !found {
+			continue
+		}
+		typ, known := c.types[family]
+		switch {
+		case suffix == "_total" && (!known || typ == MetricTypeCounter):
+			return kindCounter
+		case suffix == "_bucket" && hasBound && (!known || typ == MetricTypeHistogram):
+			return kindBucket
+		case (suffix == "_sum" || suffix == "_count") && typ == MetricTypeHistogram:
+			// the count and sum of the histograms are part of their distribution
+			return kindSkipped
+		case (suffix == "_sum" || suffix == "_count") && (!known || typ == MetricTypeSummary):
+			return kindCounter
+		}
+	}
+	return kindGauge
+}
+
+// increase returns the increase of a cumulative value since the previous request, the
+// cumulative value was reset when it decreased. It returns false for the first value.
+func (c *Converter) increase(key string, value float64, now time.Time) (float64, bool) {
+	previous, found := c.cumulative[key]
+	c.cumulative[key] = cumulativeValue{value: value, seen: now}
+	if !found {
+		return 0, false
+	}
+	if value < previous.value {
+		return value, true
+	}
+	return value - previous.value, true
+}
+
+// sweep forgets the cumulative values which haven't been updated for stateExpiry.
+func (c *Converter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.stateExpiry {
+		return
+	}
+	c.lastSweep = now
+	for key, v := range c.cumulative {
+		if now.Sub(v.seen) > c.stateExpiry {
+			delete(c.cumulative, key)
+		}
+	}
+}
+
+// appendSamples appends a distribution sample per bucket whose count increased, with
+// the middle of the bucket as value.
+func (h *histogram) appendSamples(dest []metrics.MetricSample) []metrics.MetricSample {
+	slices.SortFunc(h.buckets, func(a, b bucket) int {
+		switch {
+		case a.upperBound < b.upperBound:
+			return -1
+		case a.upperBound > b.upperBound:
+			return 1
+		}
+		return 0
+	})
+
+	lowerBound, previous := 0.0, 0.0
+	for i, b := range h.buckets {
+		count := math.Round(b.increase - previous)
+		if count >= 1 {
+			value := (lowerBound + b.upperBound) / 2
+			switch {
+			case math.IsInf(b.upperBound, 1):
+				value = lowerBound
+			case i == 0 && b.upperBound <= 0:
+				value = b.upperBound
+			}
+			dest = append(dest, newSample(h.name, metrics.DistributionType, value, 1/count, h.tags, h.origin, h.timestamp))
+		}
+		lowerBound, previous = b.upperBound, b.increase
+	}
+	return dest
+}
+
+// parseLabels returns the metric name, the tags, the origin and the bucket upper bound of a series.
+func parseLabels(labels []Label) (name string, tags []string, origin taggertypes.OriginInfo, upperBound float64, hasBound bool) {
+	origin.ProductOrigin = origindetection.ProductOriginDogStatsD
+	tags = make([]string, 0, len(labels))
+	for _, l := range labels {
+		switch l.Name {
+		case metricNameLabel:
+			name = l.Value
+		case containerIDLabel:
+			origin.LocalData.ContainerID = l.Value
+		case podUIDLabel:
+			origin.LocalData.PodUID = l.Value
+		case bucketBoundLabel:
+			if bound, err := strconv.ParseFloat(l.Value, 64); err == nil {
+				upperBound, hasBound = bound, true
+			}
+			tags = append(tags, l.Name+":"+l.Value)
+		default:
+			tags = append(tags, l.Name+":"+l.Value)
+		}
+	}
+	return name, tags, origin, upperBound, hasBound
+}
+
+// seriesKey identifies a series by its labels, which are sorted by name in the requests,
+// except the ignored one.
+func seriesKey(labels []Label, ignored string) string {
+	var b strings.Builder
+	for _, l := range labels {
+		if l.Name == ignored {
+			continue
+		}
+		b.WriteString(l.Name)
+		b.WriteByte('=')
+		b.WriteString(l.Value)
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// newSample returns a metric sample, timestamp is in milliseconds.
+func newSample(name string, mtype metrics.MetricType, value float64, sampleRate float64, tags []string, origin taggertypes.OriginInfo, timestamp int64) metrics.MetricSample {
+	return metrics.MetricSample{
+		Name:       name,
+		Mtype:      mtype,
+		Value:      value,
+		SampleRate: sampleRate,
+		Tags:       tags,
+		OriginInfo: origin,
+		Timestamp:  float64(timestamp) / 1000,
+		Source:     metrics.MetricSourceDogstatsd,
+	}
+}
@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func series(name string, value float64, labels ...string) TimeSeries {
+	ts := TimeSeries{
+		Labels:  []Label{{Name: metricNameLabel, Value: name}},
+		Samples: []Sample{{Value: value}},
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.Labels = append(ts.Labels, Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return ts
+}
+
+func TestConvertGauge(t *testing.T) {
+	c := NewConverter(time.Minute)
+	samples, dropped := c.Convert(&WriteRequest{Timeseries: []TimeSeries{
+		series("temperature", 21.5, "room", "kitchen", "container_id", "abc", "pod_uid", "123"),
+		{Labels: []Label{{Name: "job", Value: "no_name"}}, Samples: []Sample{{Value: 1}}},
+	}}, nil)
+
+	assert.Equal(t, 1, dropped)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "temperature", samples[0].Name)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, 21.5, samples[0].Value)
+	assert.Equal(t, []string{"room:kitchen"}, samples[0].Tags)
+	assert.Equal(t, "abc", samples[0].OriginInfo.LocalData.ContainerID)
+	assert.Equal(t, "123", samples[0].OriginInfo.LocalData.PodUID)
+	assert.Equal(t, origindetection.ProductOriginDogStatsD, samples[0].OriginInfo.ProductOrigin)
+	assert.Equal(t, metrics.MetricSourceDogstatsd, samples[0].Source)
+	assert.Zero(t, samples[0].Timestamp)
+
+	samples, _ = c.Convert(&WriteRequest{Timeseries: []TimeSeries{
+		{Labels: []Label{{Name: metricNameLabel, Value: "temperature"}}, Samples: []Sample{{Value: 20, Timestamp: 1700000000500}}},
+	}}, nil)
+	require.Len(t, samples, 1)
+	assert.Equal(t, 1700000000.5, samples[0].Timestamp)
+}
+
+func TestConvertCounter(t *testing.T) {
+	c := NewConverter(time.Minute)
+	convert := func(value float64) []metrics.MetricSample {
+		samples, _ := c.Convert(&WriteRequest{Timeseries: []TimeSeries{series("requests_total", value, "code", "200")}}, nil)
+		return samples
+	}
+
+	// the first value is only a reference
+	assert.Empty(t, convert(10))
+
+	samples := convert(15)
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.CountType, samples[0].Mtype)
+	assert.Equal(t, 5.0, samples[0].Value)
+	assert.Equal(t, []string{"code:200"}, samples[0].Tags)
+
+	// the counter was reset
+	samples = convert(3)
+	require.Len(t, samples, 1)
+	assert.Equal(t, 3.0, samples[0].Value)
+}
+
+func TestConvertMetadataTypes(t *testing.T) {
+	c := NewConverter(time.Minute)
+	req := &WriteRequest{
+		Timeseries: []TimeSeries{
+			series("processed", 1),
+			series("queue_total", 1),
+			series("latency_sum", 1),
+		},
+		Metadata: []MetricMetadata{
+			{Type: MetricTypeCounter, MetricFamilyName: "processed"},
+			{Type: MetricTypeGauge, MetricFamilyName: "queue_total"},
+			{Type: MetricTypeHistogram, MetricFamilyName: "latency"},
+		},
+	}
+	samples, _ := c.Convert(req, nil)
+	// the counter is only a reference, the histogram sum is skipped
+	require.Len(t, samples, 1)
+	assert.Equal(t, "queue_total", samples[0].Name)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+
+	// the metadata is remembered
+	samples, _ = c.Convert(&WriteRequest{Timeseries: []TimeSeries{series("processed", 4)}}, nil)
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.CountType, samples[0].Mtype)
+	assert.Equal(t, 3.0, samples[0].Value)
+}
+
+func TestConvertHistogram(t *testing.T) {
+	c := NewConverter(time.Minute)
+	histogram := func(le1, le5, inf float64) *WriteRequest {
+		return &WriteRequest{Timeseries: []TimeSeries{
+			series("latency_bucket", inf, "le", "+Inf", "path", "/"),
+			series("latency_bucket", le1, "le", "1", "path", "/"),
+			series("latency_bucket", le5, "le", "5", "path", "/"),
+			series("latency_count", inf, "path", "/"),
+			series("latency_sum", 10, "path", "/"),
+		}}
+	}
+
+	samples, _ := c.Convert(histogram(1, 2, 2), nil)
+	for _, s := range samples {
+		assert.NotEqual(t, metrics.DistributionType, s.Mtype)
+	}
+
+	samples, _ = c.Convert(histogram(3, 7, 8), nil)
+	var distributions []metrics.MetricSample
+	for _, s := range samples {
+		if s.Mtype == metrics.DistributionType {
+			distributions = append(distributions, s)
+		}
+	}
+	sort.Slice(distributions, func(i, j int) bool { return distributions[i].Value < distributions[j].Value })
+
+	// 2 observations in ]0, 1], 3 in ]1, 5] and 1 above 5
+	require.Len(t, distributions, 3)
+	for i, expected := range []struct {
+		value float64
+		count float64
+	}{{0.5, 2}, {3, 3}, {5, 1}} {
+		assert.Equal(t, "latency", distributions[i].Name)
+		assert.Equal(t, expected.value, distributions[i].Value)
+		assert.InDelta(t, expected.count, 1/distributions[i].SampleRate, 1e-9)
+		assert.Equal(t, []string{"path:/"}, distributions[i].Tags)
+	}
+}
+
+func TestConvertHistogramTimestamps(t *testing.T) {
+	c := NewConverter(time.Minute)
+	bucket := func(le string, values ...float64) TimeSeries {
+		ts := series("latency_bucket", 0, "le", le)
+		ts.Samples = nil
+		for i, v := range values {
+			ts.Samples = append(ts.Samples, Sample{Value: v, Timestamp: int64(1000+i) * 1000})
+		}
+		return ts
+	}
+
+	// the first samples are only a reference
+	samples, _ := c.Convert(&WriteRequest{Timeseries: []TimeSeries{
+		bucket("1", 1, 2, 4),
+		bucket("+Inf", 1, 3, 6),
+	}}, nil)
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Timestamp != samples[j].Timestamp {
+			return samples[i].Timestamp < samples[j].Timestamp
+		}
+		return samples[i].Value < samples[j].Value
+	})
+
+	require.Len(t, samples, 4)
+	for i, expected := range []struct {
+		timestamp float64
+		value     float64
+		count     float64
+	}{{1001, 0.5, 1}, {1001, 1, 1}, {1002, 0.5, 2}, {1002, 1, 1}} {
+		assert.Equal(t, metrics.DistributionType, samples[i].Mtype)
+		assert.Equal(t, expected.timestamp, samples[i].Timestamp)
+		assert.Equal(t, expected.value, samples[i].Value)
+		assert.InDelta(t, expected.count, 1/samples[i].SampleRate, 1e-9)
+	}
+}
+
+func TestConvertStateExpiry(t *testing.T) {
+	now := time.Now()
+	c := NewConverter(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Convert(&WriteRequest{Timeseries: []TimeSeries{series("requests_total", 10)}}, nil)
+	assert.Len(t, c.cumulative, 1)
+
+	now = now.Add(2 * time.Minute)
+	samples, _ := c.Convert(&WriteRequest{Timeseries: []TimeSeries{series("requests_total", 20)}}, nil)
+	// the previous value expired, the new one is a reference again
+	assert.Empty(t, samples)
+	assert.Len(t, c.cumulative, 1)
+}
+
+func TestHistogramAppendSamplesInfiniteBucket(t *testing.T) {
+	h := &histogram{name: "foo", buckets: []bucket{
+		{upperBound: math.Inf(1), increase: 4},
+		{upperBound: -1, increase: 1},
+	}}
+	samples := h.appendSamples(nil)
+	require.Len(t, samples, 2)
+	assert.Equal(t, -1.0, samples[0].Value)
+	assert.Equal(t, -1.0, samples[1].Value)
+	assert.InDelta(t, 3.0, 1/samples[1].SampleRate, 1e-9)
+}
@@ -0,0 +1,259 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite decodes Prometheus remote-write requests and converts
+// their samples into metric samples for the aggregator.
+//
+// The requests are decoded with protowire rather than with the prompb package:
+// prompb would make github.com/prometheus/prometheus, only an indirect requirement
+// of the agent, a direct dependency of dogstatsd for three messages, and its
+// generated code decodes the native histograms which are only counted here.
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/klauspost/compress/s2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MetricType is the type of a metric family, as sent in the remote-write metadata.
+type MetricType int32
+
+// Metric types of the remote-write protocol
+const (
+	MetricTypeUnknown MetricType = iota
+	MetricTypeCounter
+	MetricTypeGauge
+	MetricTypeHistogram
+	MetricTypeGaugeHistogram
+	MetricTypeSummary
+	MetricTypeInfo
+	MetricTypeStateset
+)
+
+// Label is a label of a time series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a time series.
+type Sample struct {
+	Value float64
+	// Timestamp is in milliseconds
+	Timestamp int64
+}
+
+// TimeSeries is a series of samples identified by its labels, the metric name is the
+// __name__ label.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+	// NativeHistograms is the number of native histogram samples, which aren't decoded
+	NativeHistograms int
+}
+
+// MetricMetadata describes a metric family.
+type MetricMetadata struct {
+	Type             MetricType
+	MetricFamilyName string
+}
+
+// WriteRequest is a remote-write (version 1) request.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+	Metadata   []MetricMetadata
+}
+
+// ErrDecodedTooLarge is returned when the decompressed size of a request is over the limit.
+var ErrDecodedTooLarge = errors.New("decompressed request too large")
+
+// DecodeWriteRequest decodes a snappy compressed remote-write request, whose decompressed
+// size must not be over maxDecodedSize.
+func DecodeWriteRequest(compressed []byte, maxDecodedSize int) (*WriteRequest, error) {
+	size, err := s2.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %v", err)
+	}
+	if size > maxDecodedSize {
+		return nil, ErrDecodedTooLarge
+	}
+	data, err := s2.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %v", err)
+	}
+	return UnmarshalWriteRequest(data)
+}
+
+// EncodeWriteRequest encodes a remote-write request and compresses it with snappy, as
+// sent by the Prometheus servers.
+func EncodeWriteRequest(req *WriteRequest) []byte {
+	return s2.EncodeSnappy(nil, MarshalWriteRequest(req))
+}
+
+// MarshalWriteRequest returns the protobuf encoding of a remote-write request.
+func MarshalWriteRequest(req *WriteRequest) []byte {
+	var b []byte
+	for _, ts := range req.Timeseries {
+		var series []byte
+		for _, l := range ts.Labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.Name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.Value)
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+		for _, s := range ts.Samples {
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(s.Timestamp))
+			series = protowire.AppendTag(series, 2, protowire.BytesType)
+			series = protowire.AppendBytes(series, sample)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, series)
+	}
+	for _, md := range req.Metadata {
+		var metadata []byte
+		metadata = protowire.AppendTag(metadata, 1, protowire.VarintType)
+		metadata = protowire.AppendVarint(metadata, uint64(md.Type))
+		metadata = protowire.AppendTag(metadata, 2, protowire.BytesType)
+		metadata = protowire.AppendString(metadata, md.MetricFamilyName)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, metadata)
+	}
+	return b
+}
+
+// UnmarshalWriteRequest decodes the protobuf encoding of a remote-write request, the
+// unknown fields are skipped.
+func UnmarshalWriteRequest(data []byte) (*WriteRequest, error) {
+	req := &WriteRequest{}
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			ts, err := unmarshalTimeSeries(value)
+			if err != nil {
+				return err
+			}
+			req.Timeseries = append(req.Timeseries, ts)
+		case num == 3 && typ == protowire.BytesType:
+			md, err := unmarshalMetadata(value)
+			if err != nil {
+				return err
+			}
+			req.Metadata = append(req.Metadata, md)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func unmarshalTimeSeries(data []byte) (TimeSeries, error) {
+	var ts TimeSeries
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var label Label
+			err := forEachField(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if typ != protowire.BytesType {
+					return nil
+				}
+				switch num {
+				case 1:
+					label.Name = string(value)
+				case 2:
+					label.Value = string(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.Labels = append(ts.Labels, label)
+		case num == 2 && typ == protowire.BytesType:
+			var sample Sample
+			err := forEachField(value, func(num protowire.Number, typ protowire.Type, _ []byte, scalar uint64) error {
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					sample.Value = math.Float64frombits(scalar)
+				case num == 2 && typ == protowire.VarintType:
+					sample.Timestamp = int64(scalar)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.Samples = append(ts.Samples, sample)
+		case num == 4 && typ == protowire.BytesType:
+			ts.NativeHistograms++
+		}
+		return nil
+	})
+	return ts, err
+}
+
+func unmarshalMetadata(data []byte) (MetricMetadata, error) {
+	var md MetricMetadata
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			md.Type = MetricType(scalar)
+		case num == 2 && typ == protowire.BytesType:
+			md.MetricFamilyName = string(value)
+		}
+		return nil
+	})
+	return md, err
+}
+
+// forEachField calls fn with each field of a protobuf message: the content of the
+// length-delimited fields, or the value of the scalar fields.
+func forEachField(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		var scalar uint64
+		switch typ {
+		case protowire.VarintType:
+			scalar, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			scalar, n = protowire.ConsumeFixed64(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			scalar = uint64(v)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, value, scalar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
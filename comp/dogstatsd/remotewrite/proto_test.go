@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package remotewrite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecodeWriteRequest(t *testing.T) {
+	expected := &WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels:  []Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "code", Value: "200"}},
+				Samples: []Sample{{Value: 12, Timestamp: 1700000000000}, {Value: 15.5, Timestamp: 1700000015000}},
+			},
+			{
+				Labels:  []Label{{Name: "__name__", Value: "temperature"}},
+				Samples: []Sample{{Value: -3.25, Timestamp: 1700000000000}},
+			},
+		},
+		Metadata: []MetricMetadata{{Type: MetricTypeCounter, MetricFamilyName: "http_requests_total"}},
+	}
+
+	req, err := DecodeWriteRequest(EncodeWriteRequest(expected), 1024)
+	require.NoError(t, err)
+	assert.Equal(t, expected, req)
+}
+
+func TestDecodeWriteRequestInvalid(t *testing.T) {
+	_, err := DecodeWriteRequest([]byte("not snappy"), 1024)
+	assert.Error(t, err)
+
+	// the decompressed size is checked before decoding
+	compressed := EncodeWriteRequest(&WriteRequest{Timeseries: []TimeSeries{{Labels: []Label{{Name: "__name__", Value: "foo"}}}}})
+	_, err = DecodeWriteRequest(compressed, 8)
+	assert.ErrorIs(t, err, ErrDecodedTooLarge)
+
+	// a truncated protobuf message
+	data := MarshalWriteRequest(&WriteRequest{Timeseries: []TimeSeries{{Labels: []Label{{Name: "__name__", Value: "foo"}}}}})
+	_, err = UnmarshalWriteRequest(data[:len(data)-2])
+	assert.Error(t, err)
+}
+
+func TestUnmarshalWriteRequestSkipsUnknownFields(t *testing.T) {
+	data := MarshalWriteRequest(&WriteRequest{Timeseries: []TimeSeries{{
+		Labels:  []Label{{Name: "__name__", Value: "foo"}},
+		Samples: []Sample{{Value: 1}},
+	}}})
+
+	// a native histogram in the time series, and an unknown field in the request
+	series := protowire.AppendTag(nil, 4, protowire.BytesType)
+	series = protowire.AppendBytes(series, []byte{0x08, 0x01})
+	data = protowire.AppendTag(data, 1, protowire.BytesType)
+	data = protowire.AppendBytes(data, series)
+	data = protowire.AppendTag(data, 15, protowire.VarintType)
+	data = protowire.AppendVarint(data, 42)
+
+	req, err := UnmarshalWriteRequest(data)
+	require.NoError(t, err)
+	require.Len(t, req.Timeseries, 2)
+	assert.Equal(t, []Sample{{Value: 1}}, req.Timeseries[0].Samples)
+	assert.Equal(t, 1, req.Timeseries[1].NativeHistograms)
+}
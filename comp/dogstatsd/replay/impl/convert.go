@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+const (
+	// ExportFormatJSON exports a capture as JSON lines, which can be imported back.
+	ExportFormatJSON = "json"
+	// ExportFormatText exports a capture as the dogstatsd messages of each packet.
+	ExportFormatText = "text"
+
+	jsonCaptureFormat = "dogstatsd-capture"
+)
+
+// CapturePacket is the JSON representation of a captured packet.
+type CapturePacket struct {
+	// Timestamp is in nanoseconds
+	Timestamp int64  `json:"timestamp"`
+	Pid       int32  `json:"pid,omitempty"`
+	Payload   string `json:"payload"`
+	Ancillary []byte `json:"ancillary,omitempty"`
+}
+
+// captureJSONHeader is the first line of a capture exported as JSON lines.
+type captureJSONHeader struct {
+	Format string `json:"format"`
+	// State is the tagger state of the capture, in the JSON encoding of pb.TaggerState
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// ExportCapture writes the packets of a capture kept by the filter in the given format.
+// The tagger state of the capture is only exported in the JSON format.
+func ExportCapture(tc *TrafficCaptureReader, w io.Writer, format string, filter *TrafficFilter) error {
+	bw := bufio.NewWriter(w)
+
+	switch format {
+	case ExportFormatJSON:
+		header := captureJSONHeader{Format: jsonCaptureFormat}
+		pidMap, state, err := tc.ReadState()
+		if err == nil && (len(pidMap) > 0 || len(state) > 0) {
+			header.State, err = protojson.Marshal(&pb.TaggerState{State: state, PidMap: pidMap})
+			if err != nil {
+				return fmt.Errorf("could not encode the tagger state: %v", err)
+			}
+		}
+		if err := writeJSONLine(bw, header); err != nil {
+			return err
+		}
+	case ExportFormatText:
+		fmt.Fprintf(bw, "# dogstatsd capture, file version %d\n", tc.Version)
+	default:
+		return fmt.Errorf("unknown export format %q, must be %q or %q", format, ExportFormatJSON, ExportFormatText)
+	}
+
+	tsResolution := tc.timestampResolution()
+	tc.Seek(0)
+	for {
+		msg, err := tc.ReadNext()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !filter.Apply(msg) {
+			continue
+		}
+
+		timestamp := msg.Timestamp * int64(tsResolution)
+		payload := msg.Payload[:msg.PayloadSize]
+		if format == ExportFormatJSON {
+			err = writeJSONLine(bw, CapturePacket{
+				Timestamp: timestamp,
+				Pid:       msg.Pid,
+				Payload:   string(payload),
+				Ancillary: msg.Ancillary,
+			})
+		} else {
+			fmt.Fprintf(bw, "# %s pid=%d\n", time.Unix(0, timestamp).UTC().Format(time.RFC3339Nano), msg.Pid)
+			_, err = bw.Write(append(bytes.TrimRight(payload, "\n"), '\n'))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeJSONLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// ImportCapture converts a capture exported as JSON lines back into a capture file,
+// which can be replayed.
+func ImportCapture(r io.Reader, w io.Writer, compressed bool) error {
+	var zWriter *zstd.Writer
+	if compressed {
+		zWriter = zstd.NewWriter(w)
+		w = zWriter
+	}
+	bw := bufio.NewWriter(w)
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	var header captureJSONHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Format != jsonCaptureFormat {
+		return fmt.Errorf("the input is not a capture exported as JSON")
+	}
+	state := &pb.TaggerState{}
+	if len(header.State) > 0 {
+		if err := protojson.Unmarshal(header.State, state); err != nil {
+			return fmt.Errorf("could not decode the tagger state: %v", err)
+		}
+	}
+
+	if err := WriteHeader(bw); err != nil {
+		return err
+	}
+
+	for lineNumber := 2; ; lineNumber++ {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var packet CapturePacket
+			if err := json.Unmarshal(line, &packet); err != nil {
+				return fmt.Errorf("invalid packet on line %d: %v", lineNumber, err)
+			}
+			record, err := proto.Marshal(&pb.UnixDogstatsdMsg{
+				Timestamp:     packet.Timestamp,
+				PayloadSize:   int32(len(packet.Payload)),
+				Payload:       []byte(packet.Payload),
+				Pid:           packet.Pid,
+				AncillarySize: int32(len(packet.Ancillary)),
+				Ancillary:     packet.Ancillary,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := writeRecord(bw, record); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	s, err := proto.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if _, err := writeStateRecord(bw, s); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if zWriter != nil {
+		return zWriter.Close()
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+func readAllPackets(t *testing.T, tc *TrafficCaptureReader) []*pb.UnixDogstatsdMsg {
+	var msgs []*pb.UnixDogstatsdMsg
+	tc.Seek(0)
+	for {
+		msg, err := tc.ReadNext()
+		if err == io.EOF {
+			return msgs
+		}
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestExportImportCapture(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		original, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+		require.NoError(t, err)
+		defer original.Close()
+
+		var exported bytes.Buffer
+		require.NoError(t, ExportCapture(original, &exported, ExportFormatJSON, nil))
+
+		path := filepath.Join(t.TempDir(), "capture.dog")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		require.NoError(t, ImportCapture(&exported, f, compressed))
+		require.NoError(t, f.Close())
+
+		imported, err := NewTrafficCaptureReader(path, 1, false)
+		require.NoError(t, err)
+		defer imported.Close()
+
+		originalPackets := readAllPackets(t, original)
+		importedPackets := readAllPackets(t, imported)
+		require.Len(t, importedPackets, len(originalPackets))
+		resolution := original.timestampResolution()
+		for i := range originalPackets {
+			assert.Equal(t, originalPackets[i].Timestamp*int64(resolution), importedPackets[i].Timestamp)
+			assert.Equal(t, originalPackets[i].Pid, importedPackets[i].Pid)
+			assert.Equal(t, originalPackets[i].Payload[:originalPackets[i].PayloadSize], importedPackets[i].Payload[:importedPackets[i].PayloadSize])
+		}
+
+		originalPidMap, originalState, err := original.ReadState()
+		require.NoError(t, err)
+		importedPidMap, importedState, err := imported.ReadState()
+		require.NoError(t, err)
+		assert.Equal(t, originalPidMap, importedPidMap)
+		assert.Equal(t, len(originalState), len(importedState))
+	}
+}
+
+func TestExportCaptureText(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	var exported bytes.Buffer
+	require.NoError(t, ExportCapture(tc, &exported, ExportFormatText, nil))
+
+	lines := strings.Split(strings.TrimSuffix(exported.String(), "\n"), "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "# dogstatsd capture, file version "))
+	assert.True(t, strings.HasPrefix(lines[1], "# "))
+	assert.Contains(t, lines[1], " pid=")
+
+	assert.Error(t, ExportCapture(tc, &exported, "xml", nil))
+}
+
+func TestImportCaptureInvalid(t *testing.T) {
+	assert.Error(t, ImportCapture(strings.NewReader("foo:1|c\n"), io.Discard, false))
+	assert.Error(t, ImportCapture(strings.NewReader("{\"format\":\"dogstatsd-capture\"}\nnot json\n"), io.Discard, false))
+}
@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+var (
+	eventPrefix        = []byte("_e{")
+	serviceCheckPrefix = []byte("_sc|")
+	fieldSeparator     = []byte("|")
+	tagsFieldPrefix    = byte('#')
+	tagSeparator       = []byte(",")
+)
+
+// TrafficFilter selects the messages of a capture: the packets sent by other processes
+// are skipped, and the messages of the kept packets are filtered by name and by tags.
+type TrafficFilter struct {
+	// Names are the patterns of the metric and service check names to keep, "*" matches
+	// any sequence of characters. The events are skipped when Names are set.
+	Names []string
+	// Tags are the tags the messages must all have, a tag without value matches any
+	// value of its key.
+	Tags []string
+	// Pids are the processes whose packets are kept.
+	Pids []int32
+}
+
+// IsEmpty returns whether the filter keeps every message.
+func (f *TrafficFilter) IsEmpty() bool {
+	return f == nil || (len(f.Names) == 0 && len(f.Tags) == 0 && len(f.Pids) == 0)
+}
+
+// Apply returns the packet with the messages kept by the filter, or false when none
+// of its messages is kept. The payload of the packet is rewritten in place.
+func (f *TrafficFilter) Apply(msg *pb.UnixDogstatsdMsg) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	if len(f.Pids) > 0 && !containsPid(f.Pids, msg.Pid) {
+		return false
+	}
+	if len(f.Names) == 0 && len(f.Tags) == 0 {
+		return true
+	}
+
+	payload := msg.Payload[:msg.PayloadSize]
+	kept := payload[:0]
+	for len(payload) > 0 {
+		var message []byte
+		if i := bytes.IndexByte(payload, '\n'); i >= 0 {
+			message, payload = payload[:i], payload[i+1:]
+		} else {
+			message, payload = payload, nil
+		}
+		if len(message) == 0 || !f.keeps(message) {
+			continue
+		}
+		if len(kept) > 0 {
+			kept = append(kept, '\n')
+		}
+		kept = append(kept, message...)
+	}
+
+	if len(kept) == 0 {
+		return false
+	}
+	msg.Payload = kept
+	msg.PayloadSize = int32(len(kept))
+	return true
+}
+
+// keeps returns whether a dogstatsd message is kept by the name and tags filters.
+func (f *TrafficFilter) keeps(message []byte) bool {
+	if len(f.Names) > 0 {
+		name, ok := messageName(message)
+		if !ok || !matchesAny(f.Names, string(name)) {
+			return false
+		}
+	}
+
+	for _, tag := range f.Tags {
+		if !hasTag(message, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// messageName returns the name of a metric or service check message.
+func messageName(message []byte) ([]byte, bool) {
+	switch {
+	case bytes.HasPrefix(message, eventPrefix):
+		return nil, false
+	case bytes.HasPrefix(message, serviceCheckPrefix):
+		name, _, _ := bytes.Cut(message[len(serviceCheckPrefix):], fieldSeparator)
+		return name, true
+	}
+	name, _, found := bytes.Cut(message, []byte(":"))
+	return name, found
+}
+
+// hasTag returns whether the tags field of the message contains the tag, or a tag of
+// the same key when the tag has no value.
+func hasTag(message []byte, tag string) bool {
+	withValue := strings.Contains(tag, ":")
+	for _, field := range bytes.Split(message, fieldSeparator) {
+		if len(field) == 0 || field[0] != tagsFieldPrefix {
+			continue
+		}
+		for _, t := range bytes.Split(field[1:], tagSeparator) {
+			if string(t) == tag {
+				return true
+			}
+			if !withValue {
+				if key, _, found := bytes.Cut(t, []byte(":")); found && string(key) == tag {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPid(pids []int32, pid int32) bool {
+	for _, p := range pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
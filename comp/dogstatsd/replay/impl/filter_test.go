@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+func newTestMsg(pid int32, payload string) *pb.UnixDogstatsdMsg {
+	return &pb.UnixDogstatsdMsg{
+		Pid:         pid,
+		Payload:     []byte(payload),
+		PayloadSize: int32(len(payload)),
+	}
+}
+
+func TestTrafficFilterEmpty(t *testing.T) {
+	var filter *TrafficFilter
+	assert.True(t, filter.IsEmpty())
+	assert.True(t, filter.Apply(newTestMsg(1, "foo:1|c")))
+	assert.True(t, (&TrafficFilter{}).IsEmpty())
+}
+
+func TestTrafficFilterPids(t *testing.T) {
+	filter := &TrafficFilter{Pids: []int32{12}}
+	assert.True(t, filter.Apply(newTestMsg(12, "foo:1|c")))
+	assert.False(t, filter.Apply(newTestMsg(13, "foo:1|c")))
+}
+
+func TestTrafficFilterMessages(t *testing.T) {
+	payload := "app.requests:1|c|#env:prod,service:web\n" +
+		"app.latency:10|d|#env:staging\n" +
+		"other.metric:2|g|#env:prod\n" +
+		"_sc|app.check|0|#env:prod|m:ok\n" +
+		"_e{5,4}:title|text|#env:prod\n"
+
+	for _, tc := range []struct {
+		name     string
+		filter   *TrafficFilter
+		expected string
+	}{
+		{
+			name:     "names",
+			filter:   &TrafficFilter{Names: []string{"app.*"}},
+			expected: "app.requests:1|c|#env:prod,service:web\napp.latency:10|d|#env:staging\n_sc|app.check|0|#env:prod|m:ok",
+		},
+		{
+			name:     "tags",
+			filter:   &TrafficFilter{Tags: []string{"env:prod", "service"}},
+			expected: "app.requests:1|c|#env:prod,service:web",
+		},
+		{
+			name:     "names and tags",
+			filter:   &TrafficFilter{Names: []string{"*.metric", "app.check"}, Tags: []string{"env:prod"}},
+			expected: "other.metric:2|g|#env:prod\n_sc|app.check|0|#env:prod|m:ok",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := newTestMsg(1, payload)
+			assert.True(t, tc.filter.Apply(msg))
+			assert.Equal(t, tc.expected, string(msg.Payload[:msg.PayloadSize]))
+		})
+	}
+
+	msg := newTestMsg(1, payload)
+	assert.False(t, (&TrafficFilter{Names: []string{"unknown"}}).Apply(msg))
+}
+
+func TestScaleDelay(t *testing.T) {
+	assert.Equal(t, time.Second, scaleDelay(time.Second, 1, 0))
+	assert.Equal(t, 100*time.Millisecond, scaleDelay(time.Second, 10, 0))
+	assert.Equal(t, 2*time.Second, scaleDelay(time.Second, 0.5, 0))
+	assert.Equal(t, 200*time.Millisecond, scaleDelay(time.Minute, 1, 200*time.Millisecond))
+	assert.Equal(t, time.Duration(0), scaleDelay(time.Minute, 0, 0))
+}
@@ -29,9 +29,33 @@ type TrafficCaptureReader struct {
 	offset      uint32
 	mmap        bool
 
+	// filter selects the replayed packets, nil replays all of them
+	filter *TrafficFilter
+	// speed is the factor applied to the cadence of the capture
+	speed float64
+	// maxGap caps the delay between two packets, 0 keeps the captured delays
+	maxGap time.Duration
+
 	sync.Mutex
 }
 
+// SetFilter sets the filter selecting the packets sent by Read.
+func (tc *TrafficCaptureReader) SetFilter(filter *TrafficFilter) {
+	tc.Lock()
+	defer tc.Unlock()
+	tc.filter = filter
+}
+
+// SetTimeScale sets the cadence of Read: the delays between the packets are divided by
+// speed, then capped to maxGap when it is positive. A speed of 0 sends the packets
+// without delay.
+func (tc *TrafficCaptureReader) SetTimeScale(speed float64, maxGap time.Duration) {
+	tc.Lock()
+	defer tc.Unlock()
+	tc.speed = speed
+	tc.maxGap = maxGap
+}
+
 // Read reads the contents of the traffic capture and writes each packet to a channel
 func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	tc.Lock()
@@ -44,15 +68,13 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	// skip header
 	tc.offset = uint32(len(datadogHeader))
 
-	var tsResolution time.Duration
-	if tc.Version < minNanoVersion {
-		tsResolution = time.Second
-	} else {
-		tsResolution = time.Nanosecond
-	}
+	tsResolution := tc.timestampResolution()
+	filter, speed, maxGap := tc.filter, tc.speed, tc.maxGap
 	tc.Unlock()
 
-	first := int64(0)
+	previous := int64(0)
+	// elapsed is the scaled time elapsed since the first packet
+	var elapsed time.Duration
 
 	// we are all ready to go - let the caller know
 	ready <- struct{}{}
@@ -71,12 +93,17 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 			break
 		}
 
-		if first == 0 {
-			first = msg.Timestamp
+		if previous == 0 {
+			previous = msg.Timestamp
 		}
+		elapsed += scaleDelay(time.Duration(msg.Timestamp-previous)*tsResolution, speed, maxGap)
+		previous = msg.Timestamp
 
-		t := time.Duration(msg.Timestamp-first) * tsResolution
-		time.Sleep(t - time.Since(start))
+		if !filter.Apply(msg) {
+			continue
+		}
+
+		time.Sleep(elapsed - time.Since(start))
 
 		tc.Traffic <- msg
 
@@ -89,6 +116,30 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	}
 }
 
+// scaleDelay returns the delay between two packets when replaying at the given speed,
+// capped to maxGap when it is positive.
+func scaleDelay(delay time.Duration, speed float64, maxGap time.Duration) time.Duration {
+	if speed <= 0 {
+		return 0
+	}
+	if speed != 1 {
+		delay = time.Duration(float64(delay) / speed)
+	}
+	if maxGap > 0 && delay > maxGap {
+		delay = maxGap
+	}
+	return delay
+}
+
+// timestampResolution returns the resolution of the timestamps of the packets, which
+// depends on the version of the capture file.
+func (tc *TrafficCaptureReader) timestampResolution() time.Duration {
+	if tc.Version < minNanoVersion {
+		return time.Second
+	}
+	return time.Nanosecond
+}
+
 // Close cleans up any resources used by the TrafficCaptureReader, should not normally
 // be called directly.
 func (tc *TrafficCaptureReader) Close() error {
@@ -353,19 +353,24 @@ func (tc *TrafficCaptureWriter) writeState() (int, error) {
 		return 0, err
 	}
 
+	return writeStateRecord(tc.writer, s)
+}
+
+// writeStateRecord writes the serialized tagger state after the packets of a capture file.
+func writeStateRecord(w io.Writer, s []byte) (int, error) {
 	// Record State Separator
-	if n, err := tc.writer.Write([]byte{0, 0, 0, 0}); err != nil {
+	if n, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
 		return n, err
 	}
 
 	// Record State
-	n, err := tc.writer.Write(s)
+	n, err := w.Write(s)
 
 	// Record size
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, uint32(len(s)))
 
-	if n, err := tc.writer.Write(buf); err != nil {
+	if n, err := w.Write(buf); err != nil {
 		return n, err
 	}
 
@@ -396,16 +401,21 @@ func (tc *TrafficCaptureWriter) writeNext(msg *replay.CaptureBuffer) error {
 
 // Write writes the byte slice argument to file.
 func (tc *TrafficCaptureWriter) Write(p []byte) (int, error) {
+	return writeRecord(tc.writer, p)
+}
+
+// writeRecord writes a record of a capture file: its size followed by its content.
+func writeRecord(w io.Writer, p []byte) (int, error) {
 	buf := make([]byte, 4)
 	binary.LittleEndian.PutUint32(buf, uint32(len(p)))
 
 	// Record size
-	if n, err := tc.writer.Write(buf); err != nil {
+	if n, err := w.Write(buf); err != nil {
 		return n, err
 	}
 
 	// Record
-	n, err := tc.writer.Write(p)
+	n, err := w.Write(p)
 
 	return n + 4, err
 }
@@ -6,6 +6,7 @@
 package server
 
 import (
+	"path"
 	"strings"
 	"time"
 
@@ -34,6 +35,8 @@ type enrichConfig struct {
 	defaultHostname           string
 	entityIDPrecedenceEnabled bool
 	serverlessMode            bool
+	// histogramToDistribution lists the glob patterns of the histograms sent as distributions
+	histogramToDistribution []string
 }
 
 // extractTagsMetadata returns tags (client tags + host tag) and information needed to query tagger (origins, cardinality).
@@ -94,6 +97,16 @@ func enrichMetricType(dogstatsdMetricType metricType) metrics.MetricType {
 	return metrics.GaugeType
 }
 
+// isHistogramToDistribution returns true when the histogram must be sent as a distribution.
+func isHistogramToDistribution(metricName string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, metricName); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func isExcluded(metricName, namespace string, excludedNamespaces []string) bool {
 	if namespace != "" {
 		for _, prefix := range excludedNamespaces {
@@ -131,6 +144,10 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 	}
 
 	mtype := enrichMetricType(ddSample.metricType)
+	// timestamped samples skip the aggregation and can't be converted to distributions
+	if mtype == metrics.HistogramType && ddSample.ts.IsZero() && isHistogramToDistribution(metricName, conf.histogramToDistribution) {
+		mtype = metrics.DistributionType
+	}
 
 	// if 'ddSample.values' contains values we're enriching a multi-value
 	// dogstatsd message and will create a MetricSample per value. If not
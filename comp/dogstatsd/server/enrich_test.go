@@ -140,6 +140,29 @@ func TestConvertParseSingle(t *testing.T) {
 	}
 }
 
+func TestConvertHistogramToDistribution(t *testing.T) {
+	conf := enrichConfig{
+		defaultHostname:         "default-hostname",
+		histogramToDistribution: []string{"http.request.*", "queue.*.latency"},
+	}
+
+	for message, expected := range map[string]metrics.MetricType{
+		"http.request.duration:666|h":    metrics.DistributionType,
+		"http.request.duration:666|ms":   metrics.DistributionType,
+		"http.request.count:666|c":       metrics.CounterType,
+		"queue.jobs.latency:666|h":       metrics.DistributionType,
+		"queue.jobs.size:666|h":          metrics.HistogramType,
+		"http.response.size:666|h":       metrics.HistogramType,
+		"http.request.duration:666|h|T1": metrics.HistogramType,
+	} {
+		parsed, err := parseAndEnrichMultipleMetricMessage(t, []byte(message), conf)
+
+		assert.NoError(t, err)
+		require.Len(t, parsed, 1)
+		assert.Equal(t, expected, parsed[0].Mtype, message)
+	}
+}
+
 func TestConvertParseSingleWithTags(t *testing.T) {
 	conf := enrichConfig{
 		defaultHostname: "default-hostname",
@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/remotewrite"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const remoteWritePath = "/api/v1/write"
+
+// remoteWriteReceiver receives the Prometheus remote-write requests on an HTTP listener
+// and sends their samples to the aggregator, like the metrics received by the dogstatsd
+// listeners.
+type remoteWriteReceiver struct {
+	log        log.Component
+	converter  *remotewrite.Converter
+	conf       enrichConfig
+	extraTags  []string
+	maxReqSize int64
+	// maxDecodedSize is the maximum size of a request once decompressed
+	maxDecodedSize int
+
+	// batcher is shared by the requests, mu must be held when using it
+	mu      sync.Mutex
+	batcher *batcher
+
+	listener   net.Listener
+	httpServer *http.Server
+
+	tlmSamples telemetry.Counter
+	tlmErrors  telemetry.Counter
+}
+
+func newRemoteWriteReceiver(cfg model.Reader, log log.Component, demux aggregator.DemultiplexerWithAggregator, conf enrichConfig, extraTags []string, tlmChannel telemetry.Histogram, telemetrycomp telemetry.Component) (*remoteWriteReceiver, error) {
+	port := cfg.GetString("dogstatsd_remote_write.port")
+	var addr string
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		addr = ":" + port
+	} else {
+		addr = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s: %v", addr, err)
+	}
+
+	r := &remoteWriteReceiver{
+		log:            log,
+		converter:      remotewrite.NewConverter(cfg.GetDuration("dogstatsd_remote_write.state_expiry")),
+		conf:           conf,
+		extraTags:      extraTags,
+		maxReqSize:     cfg.GetInt64("dogstatsd_remote_write.max_request_size"),
+		maxDecodedSize: cfg.GetInt("dogstatsd_remote_write.max_decoded_request_size"),
+		batcher:        newBatcher(demux, tlmChannel),
+		listener:       listener,
+		tlmSamples: telemetrycomp.NewCounter("dogstatsd", "remote_write_samples",
+			[]string{"state"}, "Count of the samples received by the dogstatsd remote-write listener"),
+		tlmErrors: telemetrycomp.NewCounter("dogstatsd", "remote_write_errors",
+			[]string{"reason"}, "Count of the remote-write requests rejected by dogstatsd"),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(remoteWritePath, r.handle)
+	r.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return r, nil
+}
+
+// listen serves the remote-write requests until stop is called.
+func (r *remoteWriteReceiver) listen() {
+	r.log.Infof("dogstatsd-remote-write: listening on %s", r.listener.Addr())
+	go func() {
+		if err := r.httpServer.Serve(r.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.log.Errorf("dogstatsd-remote-write: listener stopped: %v", err)
+		}
+	}()
+}
+
+func (r *remoteWriteReceiver) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.httpServer.Shutdown(ctx); err != nil {
+		r.log.Warnf("dogstatsd-remote-write: could not stop the listener: %v", err)
+	}
+}
+
+func (r *remoteWriteReceiver) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.tlmErrors.Inc("method")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, r.maxReqSize+1))
+	if err != nil {
+		r.tlmErrors.Inc("read")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > r.maxReqSize {
+		r.tlmErrors.Inc("too_large")
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	writeRequest, err := remotewrite.DecodeWriteRequest(body, r.maxDecodedSize)
+	if errors.Is(err, remotewrite.ErrDecodedTooLarge) {
+		r.tlmErrors.Inc("too_large")
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		r.tlmErrors.Inc("decode")
+		r.log.Debugf("dogstatsd-remote-write: invalid request: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	samples, dropped := r.converter.Convert(writeRequest, nil)
+	r.submit(samples)
+	if dropped > 0 {
+		r.tlmSamples.Add(float64(dropped), "dropped")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// submit enriches the samples like the dogstatsd metrics and sends them to the aggregator.
+func (r *remoteWriteReceiver) submit(samples []metrics.MetricSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	submitted := 0
+	for _, sample := range samples {
+		if !isExcluded(sample.Name, r.conf.metricPrefix, r.conf.metricPrefixBlacklist) {
+			sample.Name = r.conf.metricPrefix + sample.Name
+		}
+		if r.conf.metricBlocklist.test(sample.Name) {
+			continue
+		}
+		sample.Host = r.conf.defaultHostname
+		// the samples of a series share their tags slice
+		sample.Tags = append(sample.Tags[:len(sample.Tags):len(sample.Tags)], r.extraTags...)
+		r.batcher.appendSample(sample)
+		submitted++
+	}
+	r.batcher.flush()
+
+	r.tlmSamples.Add(float64(submitted), "ok")
+	// a remote-write request is counted as a metric packet, its samples are counted by tlmSamples
+	dogstatsdMetricPackets.Add(1)
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/remotewrite"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestRemoteWriteReceiver(t *testing.T) {
+	cfg := map[string]interface{}{
+		"dogstatsd_remote_write.port": 0,
+		"statsd_metric_namespace":     "prom",
+		"statsd_metric_blocklist":     []string{"prom.blocked"},
+	}
+	deps, s := fulfillDepsWithInactiveServer(t, cfg)
+
+	r, err := newRemoteWriteReceiver(deps.Config, deps.Log, deps.Demultiplexer, s.enrichConfig, []string{"extra:tag"}, s.tlmChannel, deps.Telemetry)
+	require.NoError(t, err)
+	defer r.listener.Close()
+
+	body := remotewrite.EncodeWriteRequest(&remotewrite.WriteRequest{Timeseries: []remotewrite.TimeSeries{
+		{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "temperature"}, {Name: "room", Value: "kitchen"}},
+			Samples: []remotewrite.Sample{{Value: 21.5}},
+		},
+		{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "blocked"}},
+			Samples: []remotewrite.Sample{{Value: 1}},
+		},
+	}})
+	recorder := httptest.NewRecorder()
+	r.handle(recorder, httptest.NewRequest(http.MethodPost, remoteWritePath, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	samples, timedSamples := deps.Demultiplexer.WaitForSamples(time.Second * 2)
+	assert.Empty(t, timedSamples)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "prom.temperature", samples[0].Name)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, 21.5, samples[0].Value)
+	assert.Equal(t, []string{"room:kitchen", "extra:tag"}, samples[0].Tags)
+	assert.Equal(t, s.enrichConfig.defaultHostname, samples[0].Host)
+}
+
+func TestRemoteWriteReceiverInvalidRequests(t *testing.T) {
+	cfg := map[string]interface{}{
+		"dogstatsd_remote_write.port":                     0,
+		"dogstatsd_remote_write.max_request_size":         16,
+		"dogstatsd_remote_write.max_decoded_request_size": 8,
+	}
+	deps, s := fulfillDepsWithInactiveServer(t, cfg)
+
+	r, err := newRemoteWriteReceiver(deps.Config, deps.Log, deps.Demultiplexer, s.enrichConfig, nil, s.tlmChannel, deps.Telemetry)
+	require.NoError(t, err)
+	defer r.listener.Close()
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		body     []byte
+		expected int
+	}{
+		{"method", http.MethodGet, nil, http.StatusMethodNotAllowed},
+		{"not snappy", http.MethodPost, []byte{0x03, 0xff}, http.StatusBadRequest},
+		{"too large", http.MethodPost, bytes.Repeat([]byte("a"), 17), http.StatusRequestEntityTooLarge},
+		{"decoded too large", http.MethodPost, remotewrite.EncodeWriteRequest(&remotewrite.WriteRequest{
+			Metadata: []remotewrite.MetricMetadata{{MetricFamilyName: "abc"}},
+		}), http.StatusRequestEntityTooLarge},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			r.handle(recorder, httptest.NewRequest(tc.method, remoteWritePath, bytes.NewReader(tc.body)))
+			assert.Equal(t, tc.expected, recorder.Code)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"expvar"
 	"fmt"
 	"net"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -84,8 +85,9 @@ type dependencies struct {
 type provides struct {
 	fx.Out
 
-	Comp          Component
-	StatsEndpoint api.AgentEndpointProvider
+	Comp                Component
+	StatsEndpoint       api.AgentEndpointProvider
+	ClientStatsEndpoint api.AgentEndpointProvider
 }
 
 // When the internal telemetry is enabled, used to tag the origin
@@ -104,6 +106,8 @@ type server struct {
 	config model.Reader
 	// listeners are the instantiated socket listener (UDS or UDP or both)
 	listeners []listeners.StatsdListener
+	// remoteWrite receives the Prometheus remote-write requests, when enabled
+	remoteWrite *remoteWriteReceiver
 
 	// demultiplexer will receive the metrics processed by the DogStatsD server,
 	// will take care of processing them concurrently if possible, and will
@@ -193,8 +197,9 @@ func newServer(deps dependencies) provides {
 	}
 
 	return provides{
-		Comp:          s,
-		StatsEndpoint: api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		Comp:                s,
+		StatsEndpoint:       api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		ClientStatsEndpoint: api.NewAgentEndpointProvider(s.writeClientStats, "/dogstatsd-client-stats", "GET"),
 	}
 }
 
@@ -231,6 +236,14 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 
 	histToDist := cfg.GetBool("histogram_copy_to_distribution")
 	histToDistPrefix := cfg.GetString("histogram_copy_to_distribution_prefix")
+	histogramToDistribution := []string{}
+	for _, pattern := range cfg.GetStringSlice("dogstatsd_histogram_to_distribution") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Errorf("Invalid dogstatsd_histogram_to_distribution pattern '%s': %s", pattern, err)
+			continue
+		}
+		histogramToDistribution = append(histogramToDistribution, pattern)
+	}
 
 	extraTags := cfg.GetStringSlice("dogstatsd_tags")
 
@@ -299,6 +312,7 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 			entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
 			defaultHostname:           defaultHostname,
 			serverlessMode:            serverless,
+			histogramToDistribution:   histogramToDistribution,
 		},
 		wmeta:                   wmeta,
 		telemetry:               telemetrycomp,
@@ -379,7 +393,7 @@ func (s *server) start(context.Context) error {
 
 	if len(socketStreamPath) > 0 {
 		s.log.Warnf("dogstatsd_stream_socket is not yet supported, run it at your own risk")
-		unixListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPoolManager, sharedUDSOobPoolManager, s.config, s.tCapture, s.wmeta, s.pidMap, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
+		unixListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPoolManager, sharedUDSOobPoolManager, s.config, s.tCapture, s.wmeta, s.pidMap, s.Debug, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
 		if err != nil {
 			s.log.Errorf("Can't init listener: %s", err.Error())
 		} else {
@@ -397,6 +411,24 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init TCP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
+	if s.config.GetInt("dogstatsd_http_port") > 0 {
+		httpListener, err := listeners.NewHTTPListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init HTTP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, httpListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -417,6 +449,19 @@ func (s *server) start(context.Context) error {
 	s.sharedPacketPoolManager = sharedPacketPoolManager
 	s.listeners = tmpListeners
 
+	// Prometheus remote-write
+	// ----------------------
+
+	if s.config.GetBool("dogstatsd_remote_write.enabled") && !s.ServerlessMode {
+		demux := s.demultiplexer.(aggregator.DemultiplexerWithAggregator)
+		remoteWrite, err := newRemoteWriteReceiver(s.config, s.log, demux, s.enrichConfig, s.extraTags, s.tlmChannel, s.telemetry)
+		if err != nil {
+			s.log.Errorf("Can't init remote-write listener: %s", err.Error())
+		} else {
+			s.remoteWrite = remoteWrite
+		}
+	}
+
 	// packets forwarding
 	// ----------------------
 
@@ -476,6 +521,9 @@ func (s *server) stop(context.Context) error {
 	for _, l := range s.listeners {
 		l.Stop()
 	}
+	if s.remoteWrite != nil {
+		s.remoteWrite.stop()
+	}
 	if s.Statistics != nil {
 		s.Statistics.Stop()
 	}
@@ -506,6 +554,9 @@ func (s *server) handleMessages() {
 	for _, l := range s.listeners {
 		l.Listen()
 	}
+	if s.remoteWrite != nil {
+		s.remoteWrite.listen()
+	}
 
 	workersCount, _ := aggregator.GetDogStatsDWorkerAndPipelineCount()
 
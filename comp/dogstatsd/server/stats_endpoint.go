@@ -54,3 +54,37 @@ func (s *server) writeStats(w http.ResponseWriter, _ *http.Request) {
 
 	w.Write(jsonStats)
 }
+
+func (s *server) writeClientStats(w http.ResponseWriter, _ *http.Request) {
+	s.log.Info("Got a request for the Dogstatsd clients stats.")
+
+	if !s.config.GetBool("use_dogstatsd") {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := json.Marshal(map[string]string{
+			"error":      "Dogstatsd not enabled in the Agent configuration",
+			"error_type": "no server",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	if !s.config.GetBool("dogstatsd_stream_flow_control.enabled") {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := json.Marshal(map[string]string{
+			"error":      "Dogstatsd stream flow control not enabled in the Agent configuration",
+			"error_type": "not enabled",
+		})
+		w.WriteHeader(400)
+		w.Write(body)
+		return
+	}
+
+	jsonStats, err := s.Debug.GetJSONClientStats()
+	if err != nil {
+		httputils.SetJSONError(w, s.log.Errorf("Error getting marshalled Dogstatsd clients stats: %s", err), 500)
+		return
+	}
+
+	w.Write(jsonStats)
+}
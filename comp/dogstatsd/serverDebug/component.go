@@ -25,4 +25,10 @@ type Component interface {
 
 	// GetJSONDebugStats returns a json representation of debug stats
 	GetJSONDebugStats() ([]byte, error)
+
+	// StoreClientStats adds the packets received from a client, and the ones dropped
+	// because the server was saturated, to the stats of the client.
+	StoreClientStats(client string, received uint64, dropped uint64)
+	// GetJSONClientStats returns a json representation of the clients stats
+	GetJSONClientStats() ([]byte, error)
 }
@@ -56,11 +56,26 @@ type metricStat struct {
 	Tags     string    `json:"tags"`
 }
 
+// maxClientStats is the maximum number of clients whose stats are kept, the least
+// recently seen client is forgotten when a new one is seen.
+const maxClientStats = 1024
+
+// clientStat holds how many packets have been received from a client, how
+// many of them have been dropped and when was the last time.
+type clientStat struct {
+	Client   string    `json:"client"`
+	Received uint64    `json:"received"`
+	Dropped  uint64    `json:"dropped"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
 type serverDebugImpl struct {
 	sync.Mutex
 	log     log.Component
 	enabled *atomic.Bool
 	Stats   map[ckey.ContextKey]metricStat `json:"stats"`
+	// clientStats are the stats of the packets received from each client
+	clientStats map[string]clientStat
 	// counting number of metrics processed last X seconds
 	metricsCounts metricsCountBuckets
 	// keyGen is used to generate hashes of the metrics received by dogstatsd
@@ -86,9 +101,10 @@ func newServerDebug(deps dependencies) serverdebug.Component {
 
 func newServerDebugCompat(l log.Component, cfg model.Reader) serverdebug.Component {
 	sd := &serverDebugImpl{
-		log:     l,
-		enabled: atomic.NewBool(false),
-		Stats:   make(map[ckey.ContextKey]metricStat),
+		log:         l,
+		enabled:     atomic.NewBool(false),
+		Stats:       make(map[ckey.ContextKey]metricStat),
+		clientStats: make(map[string]clientStat),
 		metricsCounts: metricsCountBuckets{
 			counts:     [5]uint64{0, 0, 0, 0, 0},
 			metricChan: make(chan struct{}),
@@ -150,6 +166,46 @@ func FormatDebugStats(stats []byte) (string, error) {
 	return buf.String(), nil
 }
 
+// FormatClientStats returns a printable version of clients stats.
+func FormatClientStats(stats []byte) (string, error) {
+	var clientStats map[string]clientStat
+	if err := json.Unmarshal(stats, &clientStats); err != nil {
+		return "", err
+	}
+
+	// put clients in order: first is the one with the most drops
+	order := make([]string, 0, len(clientStats))
+	for client := range clientStats {
+		order = append(order, client)
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		ci, cj := clientStats[order[i]], clientStats[order[j]]
+		if ci.Dropped != cj.Dropped {
+			return ci.Dropped > cj.Dropped
+		}
+		return ci.Client < cj.Client
+	})
+
+	// write the response
+	buf := bytes.NewBuffer(nil)
+
+	header := fmt.Sprintf("%-40s | %-10s | %-10s | %-20s\n", "Client", "Received", "Dropped", "Last Seen")
+	buf.Write([]byte(header))
+	buf.Write([]byte(strings.Repeat("-", 40) + "-|-" + strings.Repeat("-", 10) + "-|-" + strings.Repeat("-", 10) + "-|-" + strings.Repeat("-", 20) + "\n"))
+
+	for _, client := range order {
+		stats := clientStats[client]
+		buf.Write([]byte(fmt.Sprintf("%-40s | %-10d | %-10d | %-20v\n", stats.Client, stats.Received, stats.Dropped, stats.LastSeen)))
+	}
+
+	if len(clientStats) == 0 {
+		buf.Write([]byte("No client stats recorded yet."))
+	}
+
+	return buf.String(), nil
+}
+
 // storeMetricStats stores stats on the given metric sample.
 //
 // It can help troubleshooting clients with bad behaviors.
@@ -262,6 +318,42 @@ func (d *serverDebugImpl) GetJSONDebugStats() ([]byte, error) {
 	return json.Marshal(d.Stats)
 }
 
+// StoreClientStats adds the packets received from a client, and the ones dropped
+// because the server was saturated, to the stats of the client.
+func (d *serverDebugImpl) StoreClientStats(client string, received uint64, dropped uint64) {
+	now := d.clock.Now()
+	d.Lock()
+	defer d.Unlock()
+
+	cs, found := d.clientStats[client]
+	if !found && len(d.clientStats) >= maxClientStats {
+		d.forgetLeastRecentClient()
+	}
+	cs.Client = client
+	cs.Received += received
+	cs.Dropped += dropped
+	cs.LastSeen = now
+	d.clientStats[client] = cs
+}
+
+func (d *serverDebugImpl) forgetLeastRecentClient() {
+	var oldest string
+	var oldestSeen time.Time
+	for client, cs := range d.clientStats {
+		if oldest == "" || cs.LastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = client, cs.LastSeen
+		}
+	}
+	delete(d.clientStats, oldest)
+}
+
+// GetJSONClientStats returns jsonified clients statistics.
+func (d *serverDebugImpl) GetJSONClientStats() ([]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+	return json.Marshal(d.clientStats)
+}
+
 func (d *serverDebugImpl) IsDebugEnabled() bool {
 	return d.enabled.Load()
 }
@@ -7,6 +7,7 @@ package serverdebugimpl
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -220,3 +221,60 @@ test.metric1                             | env:prod service:api | 10         | 2
 	_, err = FormatDebugStats([]byte("invalid json"))
 	assert.Error(t, err)
 }
+
+func TestClientStats(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_logging_enabled"] = false
+	debug := fulfillDeps(t, cfg)
+	d := debug.(*serverDebugImpl)
+
+	clk := clock.NewMock()
+	d.clock = clk
+
+	d.StoreClientStats("pid:1", 10, 0)
+	clk.Add(time.Second)
+	d.StoreClientStats("pid:2", 5, 3)
+	d.StoreClientStats("pid:1", 10, 2)
+
+	statsJSON, err := d.GetJSONClientStats()
+	require.NoError(t, err)
+
+	var stats map[string]clientStat
+	require.NoError(t, json.Unmarshal(statsJSON, &stats))
+	require.Len(t, stats, 2)
+	assert.Equal(t, uint64(20), stats["pid:1"].Received)
+	assert.Equal(t, uint64(2), stats["pid:1"].Dropped)
+	assert.Equal(t, uint64(5), stats["pid:2"].Received)
+	assert.Equal(t, uint64(3), stats["pid:2"].Dropped)
+
+	result, err := FormatClientStats(statsJSON)
+	require.NoError(t, err)
+	// clients should be sorted by drops in descending order
+	assert.True(t, strings.Index(result, "pid:2") < strings.Index(result, "pid:1"))
+
+	emptyResult, err := FormatClientStats([]byte("{}"))
+	require.NoError(t, err)
+	assert.Contains(t, emptyResult, "No client stats recorded yet.")
+}
+
+func TestClientStatsEviction(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_logging_enabled"] = false
+	debug := fulfillDeps(t, cfg)
+	d := debug.(*serverDebugImpl)
+
+	clk := clock.NewMock()
+	d.clock = clk
+
+	for i := 0; i < maxClientStats; i++ {
+		d.StoreClientStats(strconv.Itoa(i), 1, 0)
+		clk.Add(time.Millisecond)
+	}
+	d.StoreClientStats("0", 1, 0)
+	d.StoreClientStats("new", 1, 0)
+
+	assert.Len(t, d.clientStats, maxClientStats)
+	assert.Contains(t, d.clientStats, "0")
+	assert.Contains(t, d.clientStats, "new")
+	assert.NotContains(t, d.clientStats, "1")
+}
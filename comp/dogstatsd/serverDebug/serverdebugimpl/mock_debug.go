@@ -44,6 +44,13 @@ func (d *mockServerDebug) GetJSONDebugStats() ([]byte, error) {
 	return []byte{}, nil
 }
 
+func (d *mockServerDebug) StoreClientStats(_ string, _ uint64, _ uint64) {
+}
+
+func (d *mockServerDebug) GetJSONClientStats() ([]byte, error) {
+	return []byte{}, nil
+}
+
 func (d *mockServerDebug) IsDebugEnabled() bool {
 	return d.enabled.Load()
 }
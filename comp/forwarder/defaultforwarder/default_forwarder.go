@@ -300,6 +300,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	}
 
 	flushToDiskMemRatio := config.GetFloat64("forwarder_flush_to_disk_mem_ratio")
+	priorityClassBudgets := getRetryQueuePriorityClassBudgets(config)
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 
@@ -342,7 +343,8 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 				diskUsageLimit,
 				transactionContainerSort,
 				resolver,
-				pointCountTelemetry)
+				pointCountTelemetry,
+				priorityClassBudgets)
 			f.domainResolvers[domain] = resolver
 			numberOfWorkers := options.NumberOfWorkers
 			if isLocal {
@@ -397,6 +399,24 @@ func getAgentName(options *Options) string {
 	return ""
 }
 
+// getRetryQueuePriorityClassBudgets returns the budgets of the priority classes of the retry queue,
+// or nil when the transactions are not split into priority classes.
+func getRetryQueuePriorityClassBudgets(config config.Component) []retry.PriorityClassBudget {
+	if !config.GetBool("forwarder_retry_queue_priority_classes.enabled") {
+		return nil
+	}
+	var budgets []retry.PriorityClassBudget
+	for _, class := range []retry.PriorityClass{retry.PriorityClassCritical, retry.PriorityClassStandard, retry.PriorityClassBulk} {
+		prefix := "forwarder_retry_queue_priority_classes." + string(class)
+		budgets = append(budgets, retry.PriorityClassBudget{
+			Class:        class,
+			MaxMemRatio:  config.GetFloat64(prefix + ".max_mem_ratio"),
+			MaxDiskRatio: config.GetFloat64(prefix + ".max_disk_ratio"),
+		})
+	}
+	return budgets
+}
+
 // Start initialize and runs the forwarder.
 func (f *DefaultForwarder) Start() error {
 	// Lock so we can't stop a Forwarder while is starting
@@ -52,3 +52,8 @@ func (s *DiskUsageLimit) computeAvailableSpace(currentSize int64) (int64, error)
 func (s *DiskUsageLimit) getMaxSizeInBytes() int64 {
 	return s.maxSizeInBytes
 }
+
+// withMaxSizeRatio returns a DiskUsageLimit using only a ratio of the maximum size of `s`.
+func (s *DiskUsageLimit) withMaxSizeRatio(ratio float64) *DiskUsageLimit {
+	return NewDiskUsageLimit(s.diskPath, s.disk, int64(float64(s.maxSizeInBytes)*max(ratio, 0)), s.maxDiskRatio)
+}
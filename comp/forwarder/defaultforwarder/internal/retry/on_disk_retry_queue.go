@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
@@ -31,6 +32,12 @@ type onDiskRetryQueue struct {
 	currentSizeInBytes  int64
 	telemetry           onDiskRetryQueueTelemetry
 	pointCountTelemetry *PointCountTelemetry
+
+	// class is the priority class of the transactions stored, or empty when the
+	// transactions are not split into priority classes.
+	class PriorityClass
+	// loadUnclassifiedFiles reloads the files stored without a priority class.
+	loadUnclassifiedFiles bool
 }
 
 func newOnDiskRetryQueue(
@@ -40,18 +47,34 @@ func newOnDiskRetryQueue(
 	diskUsageLimit *DiskUsageLimit,
 	telemetry onDiskRetryQueueTelemetry,
 	pointCountTelemetry *PointCountTelemetry) (*onDiskRetryQueue, error) {
+	return newPriorityClassOnDiskRetryQueue(log, serializer, storagePath, diskUsageLimit, telemetry, pointCountTelemetry, "", true)
+}
+
+// newPriorityClassOnDiskRetryQueue creates an onDiskRetryQueue storing the transactions of a priority class.
+// The priority class is part of the file names so that several classes can share the same folder.
+func newPriorityClassOnDiskRetryQueue(
+	log log.Component,
+	serializer *HTTPTransactionsSerializer,
+	storagePath string,
+	diskUsageLimit *DiskUsageLimit,
+	telemetry onDiskRetryQueueTelemetry,
+	pointCountTelemetry *PointCountTelemetry,
+	class PriorityClass,
+	loadUnclassifiedFiles bool) (*onDiskRetryQueue, error) {
 
 	if err := os.MkdirAll(storagePath, 0700); err != nil {
 		return nil, err
 	}
 
 	storage := &onDiskRetryQueue{
-		log:                 log,
-		serializer:          serializer,
-		storagePath:         storagePath,
-		diskUsageLimit:      diskUsageLimit,
-		telemetry:           telemetry,
-		pointCountTelemetry: pointCountTelemetry,
+		log:                   log,
+		serializer:            serializer,
+		storagePath:           storagePath,
+		diskUsageLimit:        diskUsageLimit,
+		telemetry:             telemetry,
+		pointCountTelemetry:   pointCountTelemetry,
+		class:                 class,
+		loadUnclassifiedFiles: loadUnclassifiedFiles,
 	}
 
 	if err := storage.reloadExistingRetryFiles(); err != nil {
@@ -90,7 +113,7 @@ func (s *onDiskRetryQueue) Store(transactions []transaction.Transaction) error {
 	}
 
 	filename := time.Now().UTC().Format(retryFileFormat)
-	file, err := os.CreateTemp(s.storagePath, filename+"*"+retryTransactionsExtension)
+	file, err := os.CreateTemp(s.storagePath, filename+"*"+s.fileSuffix())
 	if err != nil {
 		return err
 	}
@@ -176,6 +199,7 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 				pointDroppedCount += tr.GetPointCount()
 			}
 			s.onPointDropped(pointDroppedCount)
+			s.telemetry.addTransactionsDroppedCount(dropReasonDiskFull, len(transactions))
 		} else {
 			s.log.Errorf("Cannot deserialize the content of file %v: %v", filename, errDeserialize)
 		}
@@ -248,10 +272,31 @@ func (s *onDiskRetryQueue) getExistingRetryFiles() ([]os.FileInfo, int64, error)
 			continue
 		}
 
-		if info.Mode().IsRegular() && filepath.Ext(entry.Name()) == retryTransactionsExtension {
+		if info.Mode().IsRegular() && s.ownsFile(entry.Name()) {
 			currentSizeInBytes += info.Size()
 			files = append(files, info)
 		}
 	}
 	return files, currentSizeInBytes, nil
 }
+
+// fileSuffix returns the suffix of the files storing the transactions, for instance
+// `.critical.retry` for the transactions of the priority class `critical`.
+func (s *onDiskRetryQueue) fileSuffix() string {
+	if s.class == "" {
+		return retryTransactionsExtension
+	}
+	return "." + string(s.class) + retryTransactionsExtension
+}
+
+// ownsFile returns whether the file stores transactions of this queue.
+func (s *onDiskRetryQueue) ownsFile(filename string) bool {
+	if filepath.Ext(filename) != retryTransactionsExtension {
+		return false
+	}
+	if s.class == "" {
+		return true
+	}
+	class := strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(filename, retryTransactionsExtension)), ".")
+	return class == string(s.class) || (class == "" && s.loadUnclassifiedFiles)
+}
@@ -102,6 +102,49 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestOnDiskRetryQueuePriorityClassFiles(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	unclassified := newTestOnDiskRetryQueue(t, a, path, 1000)
+	a.NoError(unclassified.Store(createHTTPTransactionCollectionTests("endpoint1")))
+	critical := newTestPriorityClassOnDiskRetryQueue(t, a, path, PriorityClassCritical, false)
+	a.NoError(critical.Store(createHTTPTransactionCollectionTests("endpoint2")))
+
+	// Each class only reloads its own files, the lowest priority class also reloads the unclassified files
+	critical = newTestPriorityClassOnDiskRetryQueue(t, a, path, PriorityClassCritical, false)
+	a.Equal(1, critical.getFilesCount())
+	bulk := newTestPriorityClassOnDiskRetryQueue(t, a, path, PriorityClassBulk, true)
+	a.Equal(1, bulk.getFilesCount())
+	standard := newTestPriorityClassOnDiskRetryQueue(t, a, path, PriorityClassStandard, false)
+	a.Equal(0, standard.getFilesCount())
+
+	// Without priority classes, all the files are reloaded
+	a.Equal(2, newTestOnDiskRetryQueue(t, a, path, 1000).getFilesCount())
+
+	transactions, err := critical.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint2"}, getEndpointsFromTransactions(transactions))
+	transactions, err = bulk.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskRetryQueueDroppedByClassTelemetry(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	dropped := classTransactionsDroppedCountTelemetry.expvar.Value()
+	q := newTestOnDiskRetryQueue(t, a, path, 1000)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+	// The disk budget only allows a single file
+	q.diskUsageLimit.maxSizeInBytes = q.GetDiskSpaceUsed()
+
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint3", "endpoint4")))
+	a.Equal(1, q.getFilesCount())
+	a.Equal(dropped+2, classTransactionsDroppedCountTelemetry.expvar.Value())
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
@@ -138,3 +181,24 @@ func newTestOnDiskRetryQueue(t *testing.T, a *assert.Assertions, path string, ma
 	a.NoError(err)
 	return storage
 }
+
+func newTestPriorityClassOnDiskRetryQueue(t *testing.T, a *assert.Assertions, path string, class PriorityClass, loadUnclassifiedFiles bool) *onDiskRetryQueue {
+	disk := diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}
+	diskUsageLimit := NewDiskUsageLimit("", disk, 1000, 1)
+	log := logmock.New(t)
+	storage, err := newPriorityClassOnDiskRetryQueue(
+		log,
+		NewHTTPTransactionsSerializer(log, resolver.NewSingleDomainResolver(domainName, nil)),
+		path,
+		diskUsageLimit,
+		newOnDiskRetryQueueTelemetry(domainName).withClass(class),
+		NewPointCountTelemetryMock(),
+		class,
+		loadUnclassifiedFiles)
+	a.NoError(err)
+	return storage
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"slices"
+
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+)
+
+// PriorityClass is a class of transactions sharing the same memory and disk budgets in
+// the retry queue. The transactions of a class are never evicted to make room for the
+// transactions of another class.
+type PriorityClass string
+
+const (
+	// PriorityClassCritical is the class of the small and high-value payloads: service checks,
+	// check runs, events and metadata.
+	PriorityClassCritical PriorityClass = "critical"
+	// PriorityClassStandard is the class of the payloads which are neither critical nor bulk.
+	PriorityClassStandard PriorityClass = "standard"
+	// PriorityClassBulk is the class of the large payloads: series and sketches.
+	PriorityClassBulk PriorityClass = "bulk"
+
+	// allPriorityClasses is the class of a retry queue which doesn't split its transactions.
+	allPriorityClasses PriorityClass = "all"
+)
+
+// priorityClasses lists the priority classes, from the highest priority to the lowest one.
+var priorityClasses = []PriorityClass{PriorityClassCritical, PriorityClassStandard, PriorityClassBulk}
+
+// endpointPriorityClasses maps the endpoint names to their priority class. The endpoint
+// name is used rather than the transaction kind as it is kept when a transaction is
+// serialized on disk. Unknown endpoints belong to `PriorityClassStandard`.
+var endpointPriorityClasses = map[string]PriorityClass{
+	endpoints.V1CheckRunsEndpoint.Name:    PriorityClassCritical,
+	endpoints.V1IntakeEndpoint.Name:       PriorityClassCritical,
+	endpoints.V1ValidateEndpoint.Name:     PriorityClassCritical,
+	endpoints.V1MetadataEndpoint.Name:     PriorityClassCritical,
+	endpoints.EventsEndpoint.Name:         PriorityClassCritical,
+	endpoints.ServiceChecksEndpoint.Name:  PriorityClassCritical,
+	endpoints.HostMetadataEndpoint.Name:   PriorityClassCritical,
+	endpoints.V1SeriesEndpoint.Name:       PriorityClassBulk,
+	endpoints.V1SketchSeriesEndpoint.Name: PriorityClassBulk,
+	endpoints.SeriesEndpoint.Name:         PriorityClassBulk,
+	endpoints.SketchSeriesEndpoint.Name:   PriorityClassBulk,
+}
+
+// PriorityClassOf returns the priority class of a transaction.
+func PriorityClassOf(t transaction.Transaction) PriorityClass {
+	if class, ok := endpointPriorityClasses[t.GetEndpointName()]; ok {
+		return class
+	}
+	return PriorityClassStandard
+}
+
+// PriorityClassBudget defines the share of the retry queue budgets used by a priority class.
+type PriorityClassBudget struct {
+	Class PriorityClass
+	// MaxMemRatio is the ratio of `forwarder_retry_queue_payloads_max_size` the class can use.
+	// With a ratio of 0, the transactions of the class are not kept in memory: they are stored
+	// on disk right away, or dropped when the class has no storage on disk.
+	MaxMemRatio float64
+	// MaxDiskRatio is the ratio of `forwarder_storage_max_size_in_bytes` the class can use.
+	// With a ratio of 0, the class has no storage on disk.
+	MaxDiskRatio float64
+}
+
+// sortPriorityClassBudgets sorts the budgets from the highest priority class to the lowest one.
+func sortPriorityClassBudgets(budgets []PriorityClassBudget) []PriorityClassBudget {
+	budgets = slices.Clone(budgets)
+	slices.SortStableFunc(budgets, func(a, b PriorityClassBudget) int {
+		return priorityClassRank(a.Class) - priorityClassRank(b.Class)
+	})
+	return budgets
+}
+
+func priorityClassRank(class PriorityClass) int {
+	if rank := slices.Index(priorityClasses, class); rank >= 0 {
+		return rank
+	}
+	return len(priorityClasses)
+}
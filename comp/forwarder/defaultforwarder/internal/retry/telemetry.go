@@ -64,6 +64,9 @@ var (
 	transactionsDroppedCountTelemetry *counterExpvar
 	errorsCountTelemetry              *counterExpvar
 
+	classCurrentMemSizeInBytesTelemetry    *gaugeExpvar
+	classTransactionsDroppedCountTelemetry *counterExpvar
+
 	transactionContainerPointDroppedCountTelemetry *counterExpvar
 
 	fileStorageExpvar                       = expvar.Map{}
@@ -138,6 +141,18 @@ func init() {
 		domainTag,
 		"The number of points dropped",
 		&transactionContainerExpvar)
+	classCurrentMemSizeInBytesTelemetry = newGaugeExpvar(
+		"transaction_container",
+		"class_current_mem_size_in_bytes",
+		[]string{"domain", "class"},
+		"The retry queue size of a priority class",
+		&transactionContainerExpvar)
+	classTransactionsDroppedCountTelemetry = newCounterExpvar(
+		"transaction_container",
+		"class_transactions_dropped_count",
+		[]string{"domain", "class", "reason"},
+		"The number of transactions of a priority class dropped from the retry queue, in memory or on disk, and why",
+		&transactionContainerExpvar)
 
 	transaction.ForwarderExpvars.Set("FileStorage", &fileStorageExpvar)
 	serializeCountTelemetry = newCounterExpvar(
@@ -254,16 +269,31 @@ func (t TransactionRetryQueueTelemetry) addPointDroppedCount(count int) {
 	transactionContainerPointDroppedCountTelemetry.add(float64(count), t.domainName)
 }
 
+func (t TransactionRetryQueueTelemetry) setClassCurrentMemSizeInBytes(class PriorityClass, count int) {
+	classCurrentMemSizeInBytesTelemetry.set(float64(count), t.domainName, string(class))
+}
+
+func (t TransactionRetryQueueTelemetry) addClassTransactionsDroppedCount(class PriorityClass, reason string, count int) {
+	classTransactionsDroppedCountTelemetry.add(float64(count), t.domainName, string(class), reason)
+}
+
 type onDiskRetryQueueTelemetry struct {
 	domainName string
+	class      PriorityClass
 }
 
 func newOnDiskRetryQueueTelemetry(domainName string) onDiskRetryQueueTelemetry {
 	return onDiskRetryQueueTelemetry{
 		domainName: domainName,
+		class:      allPriorityClasses,
 	}
 }
 
+func (t onDiskRetryQueueTelemetry) withClass(class PriorityClass) onDiskRetryQueueTelemetry {
+	t.class = class
+	return t
+}
+
 func (t onDiskRetryQueueTelemetry) addSerializeCount() {
 	serializeCountTelemetry.add(1, t.domainName)
 }
@@ -296,6 +326,10 @@ func (t onDiskRetryQueueTelemetry) addPointDroppedCount(count int) {
 	fileStoragePointDroppedCountTelemetry.add(float64(count), t.domainName)
 }
 
+func (t onDiskRetryQueueTelemetry) addTransactionsDroppedCount(reason string, count int) {
+	classTransactionsDroppedCountTelemetry.add(float64(count), t.domainName, string(t.class), reason)
+}
+
 func (t onDiskRetryQueueTelemetry) addDeserializeErrorsCount(count int) {
 	deserializeErrorsCountTelemetry.add(float64(count), t.domainName)
 }
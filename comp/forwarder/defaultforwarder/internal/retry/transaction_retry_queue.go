@@ -30,19 +30,45 @@ type TransactionPrioritySorter interface {
 
 // TransactionRetryQueue stores transactions in memory and flush them to disk when the memory
 // limit is exceeded.
+// The transactions can be split into priority classes (see `PriorityClass`). Each class has its
+// own memory and disk budgets so that the transactions of a class are only evicted to make room
+// for the transactions of the same class.
 type TransactionRetryQueue struct {
-	transactions          []transaction.Transaction
+	classes               []*retryQueueClass
 	currentMemSizeInBytes int
 	maxMemSizeInBytes     int
 	flushToStorageRatio   float64
 	dropPrioritySorter    TransactionPrioritySorter
-	optionalStorage       TransactionDiskStorage
 	telemetry             TransactionRetryQueueTelemetry
 	pointCountTelemetry   *PointCountTelemetry
 	mutex                 sync.RWMutex
 }
 
+// retryQueueClass holds the transactions of a priority class.
+type retryQueueClass struct {
+	class                 PriorityClass
+	transactions          []transaction.Transaction
+	currentMemSizeInBytes int
+	maxMemSizeInBytes     int
+	optionalStorage       TransactionDiskStorage
+	// noMemStorage is set when the memory budget ratio of the class is 0, its transactions
+	// are not kept in memory.
+	noMemStorage bool
+}
+
+// Reasons why transactions are dropped from the retry queue
+const (
+	// dropReasonMemoryFull is used when the memory budget of the class is exceeded and the
+	// transactions cannot be stored on disk.
+	dropReasonMemoryFull = "memory_full"
+	// dropReasonDiskError is used when the transactions cannot be written on disk.
+	dropReasonDiskError = "disk_error"
+	// dropReasonDiskFull is used when the disk budget of the class is exceeded.
+	dropReasonDiskFull = "disk_full"
+)
+
 // BuildTransactionRetryQueue builds a new instance of TransactionRetryQueue
+// When `priorityClassBudgets` is empty, the transactions are not split into priority classes.
 func BuildTransactionRetryQueue(
 	log log.Component,
 	maxMemSizeInBytes int,
@@ -51,11 +77,51 @@ func BuildTransactionRetryQueue(
 	optionalDiskUsageLimit *DiskUsageLimit,
 	dropPrioritySorter TransactionPrioritySorter,
 	resolver resolver.DomainResolver,
-	pointCountTelemetry *PointCountTelemetry) *TransactionRetryQueue {
-	var storage TransactionDiskStorage
-	var err error
+	pointCountTelemetry *PointCountTelemetry,
+	priorityClassBudgets []PriorityClassBudget) *TransactionRetryQueue {
 	domain := resolver.GetBaseDomain()
 
+	if len(priorityClassBudgets) > 0 {
+		priorityClassBudgets = sortPriorityClassBudgets(priorityClassBudgets)
+		storages := make(map[PriorityClass]TransactionDiskStorage)
+		if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
+			for i, budget := range priorityClassBudgets {
+				if budget.MaxDiskRatio <= 0 {
+					continue
+				}
+				// The lowest priority class reloads the files stored before the priority classes were enabled.
+				loadUnclassifiedFiles := i == len(priorityClassBudgets)-1
+				serializer := NewHTTPTransactionsSerializer(log, resolver)
+				storage, err := newPriorityClassOnDiskRetryQueue(
+					log,
+					serializer,
+					optionalDomainFolderPath,
+					optionalDiskUsageLimit.withMaxSizeRatio(budget.MaxDiskRatio),
+					newOnDiskRetryQueueTelemetry(domain).withClass(budget.Class),
+					pointCountTelemetry,
+					budget.Class,
+					loadUnclassifiedFiles)
+				if err != nil {
+					log.Errorf("Error when creating the file storage of the priority class %s: %v", budget.Class, err)
+				}
+				if storage != nil {
+					storages[budget.Class] = storage
+				}
+			}
+		}
+
+		return NewPriorityClassTransactionRetryQueue(
+			dropPrioritySorter,
+			priorityClassBudgets,
+			storages,
+			maxMemSizeInBytes,
+			flushToStorageRatio,
+			NewTransactionRetryQueueTelemetry(domain),
+			pointCountTelemetry)
+	}
+
+	var storage TransactionDiskStorage
+	var err error
 	if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
 		serializer := NewHTTPTransactionsSerializer(log, resolver)
 		storage, err = newOnDiskRetryQueue(log, serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()), pointCountTelemetry)
@@ -85,10 +151,49 @@ func NewTransactionRetryQueue(
 	telemetry TransactionRetryQueueTelemetry,
 	pointCountTelemetry *PointCountTelemetry) *TransactionRetryQueue {
 	return &TransactionRetryQueue{
+		classes: []*retryQueueClass{{
+			class:             allPriorityClasses,
+			maxMemSizeInBytes: maxMemSizeInBytes,
+			optionalStorage:   optionalTransactionStorage,
+		}},
+		maxMemSizeInBytes:   maxMemSizeInBytes,
+		flushToStorageRatio: flushToStorageRatio,
+		dropPrioritySorter:  dropPrioritySorter,
+		telemetry:           telemetry,
+		pointCountTelemetry: pointCountTelemetry,
+	}
+}
+
+// NewPriorityClassTransactionRetryQueue creates a new instance of TransactionRetryQueue which splits
+// its transactions into priority classes. The memory budget of each class is a ratio of `maxMemSizeInBytes`
+// and `optionalStorages` holds the storage on disk of each class.
+// The transactions whose class has no budget are added to the lowest priority class.
+func NewPriorityClassTransactionRetryQueue(
+	dropPrioritySorter TransactionPrioritySorter,
+	budgets []PriorityClassBudget,
+	optionalStorages map[PriorityClass]TransactionDiskStorage,
+	maxMemSizeInBytes int,
+	flushToStorageRatio float64,
+	telemetry TransactionRetryQueueTelemetry,
+	pointCountTelemetry *PointCountTelemetry) *TransactionRetryQueue {
+	var classes []*retryQueueClass
+	for _, budget := range sortPriorityClassBudgets(budgets) {
+		classes = append(classes, &retryQueueClass{
+			class:             budget.Class,
+			maxMemSizeInBytes: int(float64(maxMemSizeInBytes) * max(budget.MaxMemRatio, 0)),
+			optionalStorage:   optionalStorages[budget.Class],
+			noMemStorage:      budget.MaxMemRatio <= 0,
+		})
+	}
+	if len(classes) == 0 {
+		return NewTransactionRetryQueue(dropPrioritySorter, nil, maxMemSizeInBytes, flushToStorageRatio, telemetry, pointCountTelemetry)
+	}
+
+	return &TransactionRetryQueue{
+		classes:             classes,
 		maxMemSizeInBytes:   maxMemSizeInBytes,
 		flushToStorageRatio: flushToStorageRatio,
 		dropPrioritySorter:  dropPrioritySorter,
-		optionalStorage:     optionalTransactionStorage,
 		telemetry:           telemetry,
 		pointCountTelemetry: pointCountTelemetry,
 	}
@@ -104,23 +209,26 @@ func NewTransactionRetryQueue(
 // The first 3 transactions are flushed to the disk as 10 + 20 + 30 >= 60
 // If disk serialization failed or is not enabled, remove old transactions such as
 // `currentMemSizeInBytes` <= `maxMemSizeInBytes`
+// When the transactions are split into priority classes, these rules apply to the priority
+// class of the transaction, using the budgets of this class.
 func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
 
+	c := tc.classOf(t)
+	if c.noMemStorage {
+		return tc.addWithoutMemStorage(c, t)
+	}
+
 	var diskErr error
 	payloadSize := t.GetPayloadSize()
-	if tc.optionalStorage != nil {
-		payloadsGroupToFlush := tc.extractTransactionsForDisk(payloadSize)
+	if c.optionalStorage != nil {
+		payloadsGroupToFlush := tc.extractTransactionsForDisk(c, payloadSize)
 		for _, payloads := range payloadsGroupToFlush {
-			if err := tc.optionalStorage.Store(payloads); err != nil {
+			if err := c.optionalStorage.Store(payloads); err != nil {
 				diskErr = multierror.Append(diskErr, err)
 				// Assuming all payloads failed during serialization
-				pointCountDroppped := 0
-				for _, payload := range payloads {
-					pointCountDroppped += payload.GetPointCount()
-				}
-				tc.onDropPoints(pointCountDroppped)
+				tc.onDropTransactions(c, payloads, dropReasonDiskError)
 			}
 		}
 		if diskErr != nil {
@@ -130,55 +238,112 @@ func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
 	}
 
 	// If disk serialization failed or is not enabled, make sure `currentMemSizeInBytes` <= `maxMemSizeInBytes`
-	payloadSizeInBytesToDrop := (tc.currentMemSizeInBytes + payloadSize) - tc.maxMemSizeInBytes
+	payloadSizeInBytesToDrop := (c.currentMemSizeInBytes + payloadSize) - c.maxMemSizeInBytes
 	inMemTransactionDroppedCount := 0
 	if payloadSizeInBytesToDrop > 0 {
-		transactions := tc.extractTransactionsFromMemory(payloadSizeInBytesToDrop)
-		pointCountDroppped := 0
-		for _, tr := range transactions {
-			pointCountDroppped += tr.GetPointCount()
-		}
-		tc.onDropPoints(pointCountDroppped)
+		transactions := tc.extractTransactionsFromMemory(c, payloadSizeInBytesToDrop)
+		tc.onDropTransactions(c, transactions, dropReasonMemoryFull)
 		inMemTransactionDroppedCount = len(transactions)
 		tc.telemetry.addTransactionsDroppedCount(inMemTransactionDroppedCount)
 	}
 
-	tc.transactions = append(tc.transactions, t)
+	c.transactions = append(c.transactions, t)
+	c.currentMemSizeInBytes += payloadSize
 	tc.currentMemSizeInBytes += payloadSize
-	tc.telemetry.setCurrentMemSizeInBytes(tc.currentMemSizeInBytes)
-	tc.telemetry.setTransactionsCount(len(tc.transactions))
+	tc.updateMemTelemetry()
 
 	return inMemTransactionDroppedCount, diskErr
 }
 
+// addWithoutMemStorage stores the transaction of a class which doesn't keep its transactions
+// in memory on disk, or drops it when the class has no storage on disk.
+func (tc *TransactionRetryQueue) addWithoutMemStorage(c *retryQueueClass, t transaction.Transaction) (int, error) {
+	transactions := []transaction.Transaction{t}
+	if c.optionalStorage == nil {
+		tc.onDropTransactions(c, transactions, dropReasonMemoryFull)
+		tc.telemetry.addTransactionsDroppedCount(len(transactions))
+		return len(transactions), nil
+	}
+
+	if err := c.optionalStorage.Store(transactions); err != nil {
+		tc.onDropTransactions(c, transactions, dropReasonDiskError)
+		tc.telemetry.incErrorsCount()
+		return 0, fmt.Errorf("Cannot store transactions on disk: %v", err)
+	}
+	return 0, nil
+}
+
+// classOf returns the priority class of a transaction.
+func (tc *TransactionRetryQueue) classOf(t transaction.Transaction) *retryQueueClass {
+	if len(tc.classes) > 1 {
+		class := PriorityClassOf(t)
+		for _, c := range tc.classes {
+			if c.class == class {
+				return c
+			}
+		}
+	}
+	return tc.classes[len(tc.classes)-1]
+}
+
+func (tc *TransactionRetryQueue) onDropTransactions(c *retryQueueClass, transactions []transaction.Transaction, reason string) {
+	if len(transactions) == 0 {
+		return
+	}
+	pointCountDroppped := 0
+	for _, tr := range transactions {
+		pointCountDroppped += tr.GetPointCount()
+	}
+	tc.onDropPoints(pointCountDroppped)
+	tc.telemetry.addClassTransactionsDroppedCount(c.class, reason, len(transactions))
+}
+
 func (tc *TransactionRetryQueue) onDropPoints(count int) {
 	tc.telemetry.addPointDroppedCount(count)
 	tc.pointCountTelemetry.OnPointDropped(count)
 }
 
+func (tc *TransactionRetryQueue) updateMemTelemetry() {
+	tc.telemetry.setCurrentMemSizeInBytes(tc.currentMemSizeInBytes)
+	tc.telemetry.setTransactionsCount(tc.getTransactionCount())
+	for _, c := range tc.classes {
+		tc.telemetry.setClassCurrentMemSizeInBytes(c.class, c.currentMemSizeInBytes)
+	}
+}
+
 // ExtractTransactions extracts transactions from the container.
 // If some transactions exist in memory extract them otherwise extract transactions
-// from the disk.
+// from the disk, starting with the highest priority class having transactions on disk.
 // No transactions are in memory after calling this method.
 func (tc *TransactionRetryQueue) ExtractTransactions() ([]transaction.Transaction, error) {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
 
 	var transactions []transaction.Transaction
-	var err error
-	if len(tc.transactions) > 0 {
-		transactions = tc.transactions
-		tc.transactions = nil
-	} else if tc.optionalStorage != nil {
-		transactions, err = tc.optionalStorage.ExtractLast()
-		if err != nil {
-			tc.telemetry.incErrorsCount()
-			return nil, err
+	for _, c := range tc.classes {
+		transactions = append(transactions, c.transactions...)
+		c.transactions = nil
+		c.currentMemSizeInBytes = 0
+	}
+
+	if len(transactions) == 0 {
+		for _, c := range tc.classes {
+			if c.optionalStorage == nil {
+				continue
+			}
+			var err error
+			transactions, err = c.optionalStorage.ExtractLast()
+			if err != nil {
+				tc.telemetry.incErrorsCount()
+				return nil, err
+			}
+			if len(transactions) > 0 {
+				break
+			}
 		}
 	}
 	tc.currentMemSizeInBytes = 0
-	tc.telemetry.setCurrentMemSizeInBytes(tc.currentMemSizeInBytes)
-	tc.telemetry.setTransactionsCount(len(tc.transactions))
+	tc.updateMemTelemetry()
 	return transactions, nil
 }
 
@@ -187,7 +352,15 @@ func (tc *TransactionRetryQueue) GetTransactionCount() int {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
 
-	return len(tc.transactions)
+	return tc.getTransactionCount()
+}
+
+func (tc *TransactionRetryQueue) getTransactionCount() int {
+	count := 0
+	for _, c := range tc.classes {
+		count += len(c.transactions)
+	}
+	return count
 }
 
 // GetMaxMemSizeInBytes gets the maximum memory usage for storing transactions
@@ -202,32 +375,42 @@ func (tc *TransactionRetryQueue) GetMaxMemSizeInBytes() int {
 func (tc *TransactionRetryQueue) GetDiskSpaceUsed() int64 {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
-	if tc.optionalStorage != nil {
-		return tc.optionalStorage.GetDiskSpaceUsed()
+
+	var diskSpaceUsed int64
+	for _, c := range tc.classes {
+		if c.optionalStorage != nil {
+			diskSpaceUsed += c.optionalStorage.GetDiskSpaceUsed()
+		}
 	}
-	return 0
+	return diskSpaceUsed
 }
 
 // FlushToDisk is called on shutdown and persists all transactions to disk. The normal limits
 // on capacity still apply, and the same rules are followed as during normal operation in terms
 // of which transactions are dropped and which are persisted.
 func (tc *TransactionRetryQueue) FlushToDisk() error {
-	if tc.optionalStorage == nil {
-		return nil
-	}
-	tc.mutex.RLock()
-	defer tc.mutex.RUnlock()
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
 
-	transactions := tc.extractTransactionsFromMemory(tc.GetMaxMemSizeInBytes())
-	return tc.optionalStorage.Store(transactions)
+	var err error
+	for _, c := range tc.classes {
+		if c.optionalStorage == nil {
+			continue
+		}
+		transactions := tc.extractTransactionsFromMemory(c, c.maxMemSizeInBytes)
+		if storeErr := c.optionalStorage.Store(transactions); storeErr != nil {
+			err = multierror.Append(err, storeErr)
+		}
+	}
+	return err
 }
 
-func (tc *TransactionRetryQueue) extractTransactionsForDisk(payloadSize int) [][]transaction.Transaction {
-	sizeInBytesToFlush := int(float64(tc.maxMemSizeInBytes) * tc.flushToStorageRatio)
+func (tc *TransactionRetryQueue) extractTransactionsForDisk(c *retryQueueClass, payloadSize int) [][]transaction.Transaction {
+	sizeInBytesToFlush := int(float64(c.maxMemSizeInBytes) * tc.flushToStorageRatio)
 	var payloadsGroupToFlush [][]transaction.Transaction
-	for tc.currentMemSizeInBytes+payloadSize > tc.maxMemSizeInBytes && len(tc.transactions) > 0 {
+	for c.currentMemSizeInBytes+payloadSize > c.maxMemSizeInBytes && len(c.transactions) > 0 {
 		// Flush the N first transactions whose payload size sum is greater than `sizeInBytesToFlush`
-		transactions := tc.extractTransactionsFromMemory(sizeInBytesToFlush)
+		transactions := tc.extractTransactionsFromMemory(c, sizeInBytesToFlush)
 
 		if len(transactions) == 0 {
 			// Happens when `sizeInBytesToFlush == 0`
@@ -240,19 +423,20 @@ func (tc *TransactionRetryQueue) extractTransactionsForDisk(payloadSize int) [][
 	return payloadsGroupToFlush
 }
 
-func (tc *TransactionRetryQueue) extractTransactionsFromMemory(payloadSizeInBytesToExtract int) []transaction.Transaction {
+func (tc *TransactionRetryQueue) extractTransactionsFromMemory(c *retryQueueClass, payloadSizeInBytesToExtract int) []transaction.Transaction {
 	i := 0
 	sizeInBytesExtracted := 0
 	var transactionsExtracted []transaction.Transaction
 
-	tc.dropPrioritySorter.Sort(tc.transactions)
-	for ; i < len(tc.transactions) && sizeInBytesExtracted < payloadSizeInBytesToExtract; i++ {
-		transaction := tc.transactions[i]
+	tc.dropPrioritySorter.Sort(c.transactions)
+	for ; i < len(c.transactions) && sizeInBytesExtracted < payloadSizeInBytesToExtract; i++ {
+		transaction := c.transactions[i]
 		sizeInBytesExtracted += transaction.GetPayloadSize()
 		transactionsExtracted = append(transactionsExtracted, transaction)
 	}
 
-	tc.transactions = tc.transactions[i:]
+	c.transactions = c.transactions[i:]
+	c.currentMemSizeInBytes -= sizeInBytesExtracted
 	tc.currentMemSizeInBytes -= sizeInBytesExtracted
 	return transactionsExtracted
 }
@@ -144,6 +144,120 @@ func TestTransactionRetryQueueZeroMaxMemSizeInBytes(t *testing.T) {
 	a.Equal(pointDropped+1, transactionContainerPointDroppedCountTelemetry.expvar.Value())
 }
 
+func TestTransactionRetryQueuePriorityClassesBudgets(t *testing.T) {
+	a := assert.New(t)
+	dropped := classTransactionsDroppedCountTelemetry.expvar.Value()
+	container := NewPriorityClassTransactionRetryQueue(createDropPrioritySorter(), createPriorityClassBudgets(), nil, 100, 0.1, NewTransactionRetryQueueTelemetry("domain"), NewPointCountTelemetryMock())
+
+	for _, tr := range []*transaction.HTTPTransaction{
+		createTransactionWithEndpoint("events_v2", 10),
+		createTransactionWithEndpoint("services_checks_v2", 10),
+		createTransactionWithEndpoint("series_v2", 30),
+	} {
+		dropCount, err := container.Add(tr)
+		a.Equal(0, dropCount)
+		a.NoError(err)
+	}
+
+	// The bulk class is full: its oldest transaction is dropped, the critical ones are kept
+	dropCount, err := container.Add(createTransactionWithEndpoint("sketches_v2", 40))
+	a.Equal(1, dropCount)
+	a.NoError(err)
+	a.Equal(dropped+1, classTransactionsDroppedCountTelemetry.expvar.Value())
+	a.Equal(10+10+40, container.getCurrentMemSizeInBytes())
+	a.Equal(3, container.GetTransactionCount())
+
+	// Unknown endpoints use the standard class
+	dropCount, err = container.Add(createTransactionWithEndpoint("unknown", 30))
+	a.Equal(0, dropCount)
+	a.NoError(err)
+
+	assertPayloadSizeFromExtractTransactions(a, container, []int{10, 10, 30, 40})
+}
+
+func TestTransactionRetryQueuePriorityClassesStorage(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+	storages := map[PriorityClass]TransactionDiskStorage{
+		PriorityClassCritical: newTestPriorityClassOnDiskRetryQueue(t, a, path, PriorityClassCritical, false),
+		PriorityClassBulk:     newTestPriorityClassOnDiskRetryQueue(t, a, path, PriorityClassBulk, true),
+	}
+	container := NewPriorityClassTransactionRetryQueue(createDropPrioritySorter(), createPriorityClassBudgets(), storages, 100, 1, NewTransactionRetryQueueTelemetry("domain"), NewPointCountTelemetryMock())
+
+	// Flush to disk when adding the second transaction of each class
+	for _, tr := range []*transaction.HTTPTransaction{
+		createTransactionWithEndpoint("series_v2", 40),
+		createTransactionWithEndpoint("events_v2", 15),
+		createTransactionWithEndpoint("series_v2", 41),
+		createTransactionWithEndpoint("events_v2", 16),
+	} {
+		dropCount, err := container.Add(tr)
+		a.Equal(0, dropCount)
+		a.NoError(err)
+	}
+	a.Equal(16+41, container.getCurrentMemSizeInBytes())
+	a.Greater(container.GetDiskSpaceUsed(), int64(0))
+
+	// The transactions in memory first, then the files of the highest priority class
+	assertPayloadSizeFromExtractTransactions(a, container, []int{16, 41})
+	assertPayloadSizeFromExtractTransactions(a, container, []int{15})
+	assertPayloadSizeFromExtractTransactions(a, container, []int{40})
+	assertPayloadSizeFromExtractTransactions(a, container, nil)
+	a.Equal(int64(0), container.GetDiskSpaceUsed())
+}
+
+func TestTransactionRetryQueuePriorityClassesZeroMemRatio(t *testing.T) {
+	a := assert.New(t)
+	dropped := classTransactionsDroppedCountTelemetry.expvar.Value()
+	budgets := []PriorityClassBudget{
+		{Class: PriorityClassCritical, MaxMemRatio: 0, MaxDiskRatio: 0.5},
+		{Class: PriorityClassStandard, MaxMemRatio: 0.5, MaxDiskRatio: 0.5},
+		{Class: PriorityClassBulk, MaxMemRatio: 0, MaxDiskRatio: 0},
+	}
+	storages := map[PriorityClass]TransactionDiskStorage{
+		PriorityClassCritical: newTestPriorityClassOnDiskRetryQueue(t, a, t.TempDir(), PriorityClassCritical, false),
+	}
+	container := NewPriorityClassTransactionRetryQueue(createDropPrioritySorter(), budgets, storages, 100, 1, NewTransactionRetryQueueTelemetry("domain"), NewPointCountTelemetryMock())
+
+	// The critical transactions are stored on disk right away
+	dropCount, err := container.Add(createTransactionWithEndpoint("events_v2", 10))
+	a.Equal(0, dropCount)
+	a.NoError(err)
+	a.Equal(0, container.getCurrentMemSizeInBytes())
+	a.Greater(container.GetDiskSpaceUsed(), int64(0))
+
+	// The bulk transactions are dropped as the class has no storage on disk
+	dropCount, err = container.Add(createTransactionWithEndpoint("series_v2", 10))
+	a.Equal(1, dropCount)
+	a.NoError(err)
+	a.Equal(dropped+1, classTransactionsDroppedCountTelemetry.expvar.Value())
+	a.Equal(0, container.getCurrentMemSizeInBytes())
+
+	dropCount, err = container.Add(createTransactionWithEndpoint("unknown", 20))
+	a.Equal(0, dropCount)
+	a.NoError(err)
+	a.Equal(20, container.getCurrentMemSizeInBytes())
+
+	assertPayloadSizeFromExtractTransactions(a, container, []int{20})
+	assertPayloadSizeFromExtractTransactions(a, container, []int{10})
+	assertPayloadSizeFromExtractTransactions(a, container, nil)
+}
+
+func createPriorityClassBudgets() []PriorityClassBudget {
+	return []PriorityClassBudget{
+		{Class: PriorityClassBulk, MaxMemRatio: 0.5, MaxDiskRatio: 0.5},
+		{Class: PriorityClassCritical, MaxMemRatio: 0.2, MaxDiskRatio: 0.2},
+		{Class: PriorityClassStandard, MaxMemRatio: 0.3, MaxDiskRatio: 0.3},
+	}
+}
+
+func createTransactionWithEndpoint(endpointName string, payloadSize int) *transaction.HTTPTransaction {
+	tr := createTransactionWithPayloadSize(payloadSize)
+	tr.Domain = domainName
+	tr.Endpoint.Name = endpointName
+	return tr
+}
+
 func createTransactionWithPayloadSize(payloadSize int) *transaction.HTTPTransaction {
 	tr := transaction.NewHTTPTransaction()
 	payload := make([]byte, payloadSize)
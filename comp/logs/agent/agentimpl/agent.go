@@ -10,7 +10,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	"go.uber.org/atomic"
 	"go.uber.org/fx"
 
+	"github.com/DataDog/datadog-agent/comp/aggregator/demultiplexer"
 	api "github.com/DataDog/datadog-agent/comp/api/api/def"
 	apiutils "github.com/DataDog/datadog-agent/comp/api/api/utils/stream"
 	configComponent "github.com/DataDog/datadog-agent/comp/core/config"
@@ -87,6 +90,7 @@ type dependencies struct {
 	SchedulerProviders []schedulers.Scheduler `group:"log-agent-scheduler"`
 	Tagger             tagger.Component
 	Compression        logscompression.Component
+	Demultiplexer      demultiplexer.Component `optional:"true"`
 }
 
 type provides struct {
@@ -98,6 +102,7 @@ type provides struct {
 	RCListener     rctypes.ListenerProvider
 	LogsReciever   option.Option[integrations.Component]
 	APIStreamLogs  api.AgentEndpointProvider
+	APITapLogs     api.AgentEndpointProvider
 }
 
 // logAgent represents the data pipeline that collects, decodes,
@@ -126,6 +131,8 @@ type logAgent struct {
 	schedulerProviders        []schedulers.Scheduler
 	integrationsLogs          integrations.Component
 	compression               logscompression.Component
+	demultiplexer             demultiplexer.Component
+	logMetricsSender          *logMetricsSender
 
 	// make sure this is done only once, when we're ready
 	prepareSchedulers sync.Once
@@ -158,6 +165,7 @@ func newLogsAgent(deps dependencies) provides {
 			integrationsLogs:   integrationsLogs,
 			tagger:             deps.Tagger,
 			compression:        deps.Compression,
+			demultiplexer:      deps.Demultiplexer,
 		}
 		deps.Lc.Append(fx.Hook{
 			OnStart: logsAgent.start,
@@ -171,6 +179,12 @@ func newLogsAgent(deps dependencies) provides {
 				state.ProductSDSRules:       logsAgent.onUpdateSDSRules,
 			}
 		}
+		if deps.Config.GetBool("logs_config.remote_processing_rules.enabled") {
+			if rcListener.ListenerProvider == nil {
+				rcListener.ListenerProvider = rctypes.RCListener{}
+			}
+			rcListener.ListenerProvider[state.ProductLogsProcessingRules] = logsAgent.onUpdateProcessingRules
+		}
 
 		return provides{
 			Comp:           option.New[agent.Component](logsAgent),
@@ -182,6 +196,10 @@ func newLogsAgent(deps dependencies) provides {
 				"/stream-logs",
 				"POST",
 			),
+			APITapLogs: api.NewAgentEndpointProvider(tapLogsEvents(),
+				"/tap-logs",
+				"POST",
+			),
 		}
 	}
 
@@ -266,6 +284,9 @@ func (a *logAgent) startPipeline() {
 		a.diagnosticMessageReceiver,
 		a.launchers,
 	)
+	if a.logMetricsSender != nil {
+		starter.Add(a.logMetricsSender)
+	}
 	starter.Start()
 
 	if !sds.ShouldBlockCollectionUntilSDSConfiguration(a.config) {
@@ -302,6 +323,9 @@ func (a *logAgent) stop(context.Context) error {
 		a.destinationsCtx,
 		a.diagnosticMessageReceiver,
 	)
+	if a.logMetricsSender != nil {
+		stopper.Add(a.logMetricsSender)
+	}
 
 	// This will try to stop everything in order, including the potentially blocking
 	// parts like the sender. After StopTimeout it will just stop the last part of the
@@ -422,3 +446,45 @@ func streamLogsEvents(logsAgent agent.Component) func(w http.ResponseWriter, r *
 		return logsAgent.GetMessageReceiver()
 	}, "logs", "logs agent")
 }
+
+func tapLogsEvents() func(w http.ResponseWriter, r *http.Request) {
+	return apiutils.GetStreamFunc(func() apiutils.MessageReceiver {
+		return diagnostic.DefaultTapReceiver
+	}, "log traces", "logs agent")
+}
+
+// onUpdateProcessingRules replaces the processing rules of the pipelines with the
+// ones received through Remote Configuration, the tailers are left untouched.
+// The updates are merged together in the order of their path, an empty list of
+// updates removes the Remote Configuration rules. The rules set with the
+// logs_processing_rules runtime setting are kept.
+func (a *logAgent) onUpdateProcessingRules(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) { //nolint:revive
+	var merged *config.ProcessingRulesUpdate
+	errs := make(map[string]error)
+
+	for _, cfgPath := range slices.Sorted(maps.Keys(updates)) {
+		update, err := config.ParseProcessingRulesUpdate(updates[cfgPath].Config)
+		if err != nil {
+			a.log.Errorf("Can't update the processing rules with %s: %v", cfgPath, err)
+			errs[cfgPath] = err
+			continue
+		}
+		if merged == nil {
+			merged = &config.ProcessingRulesUpdate{}
+		}
+		merged.Merge(update)
+	}
+
+	a.pipelineProvider.ReconfigureProcessingRules(config.RemoteConfigProcessingRules, merged)
+
+	for cfgPath := range updates {
+		if err, found := errs[cfgPath]; found {
+			applyStateCallback(cfgPath, state.ApplyStatus{
+				State: state.ApplyStateError,
+				Error: err.Error(),
+			})
+		} else {
+			applyStateCallback(cfgPath, state.ApplyStatus{State: state.ApplyStateAcknowledged})
+		}
+	}
+}
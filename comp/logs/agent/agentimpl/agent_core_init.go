@@ -28,6 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/listener"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers/windowsevent"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/option"
@@ -45,12 +46,24 @@ func (a *logAgent) SetupPipeline(processingRules []*config.ProcessingRule, wmeta
 	destinationsCtx := client.NewDestinationsContext()
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver(nil, a.hostname)
 
+	// setup the sender of the metrics generated by the generate_metric processing rules
+	var metricSender processor.MetricSender
+	if a.demultiplexer != nil {
+		if defaultSender, err := a.demultiplexer.GetDefaultSender(); err != nil {
+			a.log.Warnf("Metrics generated from logs will not be submitted, can't get the default sender: %v", err)
+		} else {
+			a.logMetricsSender = newLogMetricsSender(defaultSender, logMetricsCommitInterval)
+			metricSender = a.logMetricsSender
+		}
+	}
+
 	// setup the pipeline provider that provides pairs of processor and sender
 	pipelineProvider := pipeline.NewProvider(
 		a.config.GetInt("logs_config.pipelines"),
 		auditor,
 		diagnosticMessageReceiver,
 		processingRules,
+		metricSender,
 		a.endpoints,
 		destinationsCtx,
 		NewStatusProvider(),
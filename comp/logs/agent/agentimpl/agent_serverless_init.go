@@ -53,7 +53,9 @@ func (a *logAgent) SetupPipeline(
 		a.config.GetInt("logs_config.pipelines"),
 		a.auditor,
 		diagnosticMessageReceiver,
-		processingRules, a.endpoints,
+		processingRules,
+		nil, // metricSender
+		a.endpoints,
 		destinationsCtx,
 		NewStatusProvider(),
 		a.hostname,
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentimpl
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+)
+
+// logMetricsCommitInterval is the interval at which the metrics generated
+// from logs are committed to the aggregator.
+const logMetricsCommitInterval = 10 * time.Second
+
+// logMetricsSender submits the metrics generated by the generate_metric
+// processing rules through an aggregator sender, it periodically commits
+// them so they are flushed by the aggregator.
+type logMetricsSender struct {
+	sender   sender.Sender
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newLogMetricsSender(s sender.Sender, interval time.Duration) *logMetricsSender {
+	return &logMetricsSender{
+		sender:   s,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Count submits a count metric.
+func (s *logMetricsSender) Count(metric string, value float64, hostname string, tags []string) {
+	s.sender.Count(metric, value, hostname, tags)
+}
+
+// Gauge submits a gauge metric.
+func (s *logMetricsSender) Gauge(metric string, value float64, hostname string, tags []string) {
+	s.sender.Gauge(metric, value, hostname, tags)
+}
+
+// Distribution submits a distribution metric.
+func (s *logMetricsSender) Distribution(metric string, value float64, hostname string, tags []string) {
+	s.sender.Distribution(metric, value, hostname, tags)
+}
+
+// Start starts committing the metrics periodically.
+func (s *logMetricsSender) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sender.Commit()
+			case <-s.stop:
+				s.sender.Commit()
+				return
+			}
+		}
+	}()
+}
+
+// Stop commits the pending metrics and stops the commit loop.
+func (s *logMetricsSender) Stop() {
+	close(s.stop)
+	<-s.done
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentimpl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+)
+
+func TestLogMetricsSender(t *testing.T) {
+	s := mocksender.NewMockSender("")
+	s.SetupAcceptAll()
+
+	metricsSender := newLogMetricsSender(s, time.Hour)
+	metricsSender.Start()
+	metricsSender.Count("app.errors", 1, "", []string{"env:prod"})
+	metricsSender.Distribution("app.latency", 0.5, "", nil)
+	metricsSender.Stop()
+
+	s.AssertMetric(t, "Count", "app.errors", 1, "", []string{"env:prod"})
+	s.AssertMetric(t, "Distribution", "app.latency", 0.5, "", nil)
+	s.AssertCalled(t, "Commit")
+	s.AssertNotCalled(t, "Gauge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
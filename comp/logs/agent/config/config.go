@@ -172,7 +172,9 @@ func buildTCPEndpoints(coreConfig pkgconfigmodel.Reader, logsConfig *LogsConfigK
 	}
 
 	additionals := loadTCPAdditionalEndpoints(main, logsConfig)
-	return NewEndpoints(main, additionals, useProto, false), nil
+	endpoints := NewEndpoints(main, additionals, useProto, false)
+	endpoints.DiskSpoolPath, endpoints.DiskSpoolMaxSizeBytes = logsConfig.diskSpool()
+	return endpoints, nil
 }
 
 // BuildHTTPEndpoints returns the HTTP endpoints to send logs to.
@@ -230,6 +232,14 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 	}
 
 	additionals := loadHTTPAdditionalEndpoints(main, logsConfig, intakeTrackType, intakeProtocol, intakeOrigin)
+	for _, e := range additionals {
+		if err := validateEndpointType(e); err != nil {
+			return nil, fmt.Errorf("invalid additional endpoint %s: %v", e.Host, err)
+		}
+		if intakeOrigin == ServerlessIntakeOrigin && e.Type != DatadogEndpointType {
+			return nil, fmt.Errorf("invalid additional endpoint %s: %s endpoints are not supported in serverless", e.Host, e.Type)
+		}
+	}
 
 	// Add in the MRF endpoint if MRF is enabled.
 	if coreConfig.GetBool("multi_region_failover.enabled") {
@@ -266,7 +276,9 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 	batchMaxContentSize := logsConfig.batchMaxContentSize()
 	inputChanSize := logsConfig.inputChanSize()
 
-	return NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize), nil
+	endpoints := NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize)
+	endpoints.DiskSpoolPath, endpoints.DiskSpoolMaxSizeBytes = logsConfig.diskSpool()
+	return endpoints, nil
 }
 
 type defaultParseAddressFunc func(string) (host string, port int, err error)
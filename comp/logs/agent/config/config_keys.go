@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -303,6 +304,25 @@ func (l *LogsConfigKeys) senderRecoveryReset() bool {
 	return l.getConfig().GetBool(l.getConfigKey("sender_recovery_reset"))
 }
 
+// diskSpool returns the directory and the maximum size of the disk spool of the
+// senders, an empty directory means the disk spool is disabled.
+func (l *LogsConfigKeys) diskSpool() (string, int64) {
+	if !l.getConfig().GetBool(l.getConfigKey("disk_spool.enabled")) {
+		return "", 0
+	}
+	maxSizeKey := l.getConfigKey("disk_spool.max_size_bytes")
+	maxSize := l.getConfig().GetInt64(maxSizeKey)
+	if maxSize <= 0 {
+		log.Warnf("Invalid %s: %v should be > 0, the disk spool is disabled", maxSizeKey, maxSize)
+		return "", 0
+	}
+	path := l.getConfig().GetString(l.getConfigKey("disk_spool.path"))
+	if path == "" {
+		path = filepath.Join(l.getConfig().GetString("logs_config.run_path"), "spool", strings.TrimSuffix(l.prefix, "."))
+	}
+	return path, maxSize
+}
+
 // AggregationTimeout is used when performing aggregation operations
 func (l *LogsConfigKeys) aggregationTimeout() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("aggregation_timeout")) * time.Millisecond
@@ -255,6 +255,32 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 	suite.compareEndpoints(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestInvalidHTTPAdditionalEndpoints() {
+	suite.config.SetWithoutSource("api_key", "123")
+	for name, endpoint := range map[string]string{
+		"unknown type":            `{"api_key": "456", "host": "additional.endpoint", "type": "syslog"}`,
+		"invalid body template":   `{"api_key": "456", "host": "additional.endpoint", "type": "http_json", "body_template": "{{ .Logs"}`,
+		"undefined template func": `{"api_key": "456", "host": "additional.endpoint", "type": "http_json", "body_template": "{{ xml .Logs }}"}`,
+	} {
+		suite.Run(name, func() {
+			suite.config.SetWithoutSource("logs_config.additional_endpoints", "["+endpoint+"]")
+			_, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+			suite.Error(err)
+		})
+	}
+
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[{"api_key": "456", "host": "additional.endpoint", "type": "http_json"}]`)
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Require().NoError(err)
+	suite.Require().Len(endpoints.Endpoints, 2)
+	suite.Equal(HTTPJSONEndpointType, endpoints.Endpoints[1].Type)
+
+	// only the Datadog endpoints are supported in serverless
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[{"api_key": "456", "host": "additional.endpoint", "type": "otlp"}]`)
+	_, err = BuildServerlessEndpoints(suite.config, "test-track", "test-proto")
+	suite.Error(err)
+}
+
 func (suite *ConfigTestSuite) TestMultipleTCPEndpointsEnvVar() {
 	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[{"api_key": "456      \n", "host": "additional.endpoint", "port": 1234}]`)
 
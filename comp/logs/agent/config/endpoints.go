@@ -6,7 +6,9 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"text/template"
 	"time"
 
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
@@ -28,6 +30,23 @@ type IntakeProtocol string
 // IntakeOrigin indicates the log source to use for an endpoint intake.
 type IntakeOrigin string
 
+// EndpointType indicates the kind of intake an endpoint sends the logs to.
+type EndpointType string
+
+const (
+	// DatadogEndpointType is the type of the endpoints sending logs to a Datadog intake, it is the default
+	DatadogEndpointType EndpointType = ""
+	// OTLPEndpointType is the type of the endpoints sending logs to an OTLP/gRPC logs endpoint
+	OTLPEndpointType EndpointType = "otlp"
+	// HTTPJSONEndpointType is the type of the endpoints posting logs to an arbitrary HTTP endpoint with a
+	// JSON body built from a template
+	HTTPJSONEndpointType EndpointType = "http_json"
+)
+
+// DefaultBodyTemplate is the body template of the http_json endpoints: the logs are posted
+// as a JSON array of objects.
+const DefaultBodyTemplate = "{{ json .Logs }}"
+
 const (
 	_ EPIntakeVersion = iota
 	// EPIntakeVersion1 is version 1 of the envets platform intake API
@@ -71,6 +90,15 @@ type Endpoint struct {
 	TrackType IntakeTrackType
 	Protocol  IntakeProtocol
 	Origin    IntakeOrigin
+
+	// Type is the kind of intake of the endpoint, only additional HTTP endpoints can use a non Datadog type
+	Type EndpointType `mapstructure:"type" json:"type"`
+	// Path is the URL path the logs are posted to by the http_json endpoints
+	Path string `mapstructure:"path" json:"path"`
+	// Headers are sent along with the logs by the otlp and http_json endpoints
+	Headers map[string]string `mapstructure:"headers" json:"headers"`
+	// BodyTemplate is the text/template used by the http_json endpoints to build the request body
+	BodyTemplate string `mapstructure:"body_template" json:"body_template"`
 }
 
 // unmarshalEndpoint is used to load additional endpoints from the configuration which stored as JSON/mapstructure.
@@ -214,6 +242,11 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 			newE.Origin = intakeOrigin
 		}
 
+		newE.Type = e.Type
+		newE.Path = e.Path
+		newE.Headers = e.Headers
+		newE.BodyTemplate = e.BodyTemplate
+
 		newEndpoints = append(newEndpoints, newE)
 		newE.onConfigUpdate(l)
 	}
@@ -241,7 +274,11 @@ func (e *Endpoint) GetStatus(prefix string, useHTTP bool) string {
 	port := e.Port
 
 	var protocol string
-	if useHTTP {
+	if e.Type == OTLPEndpointType {
+		protocol = "OTLP/gRPC"
+	} else if e.Type == HTTPJSONEndpointType {
+		protocol = "HTTP JSON"
+	} else if useHTTP {
 		if e.UseSSL() {
 			protocol = "HTTPS"
 			if port == 0 {
@@ -329,6 +366,11 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+
+	// DiskSpoolPath is the directory where the payloads are spooled while
+	// the reliable endpoints are unavailable, empty when the spool is disabled
+	DiskSpoolPath         string
+	DiskSpoolMaxSizeBytes int64
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -395,3 +437,35 @@ func (e *Endpoints) GetUnReliableEndpoints() []Endpoint {
 	}
 	return endpoints
 }
+
+// ParseBodyTemplate parses the body template of a http_json endpoint, the template can
+// use the json function to encode any value.
+func ParseBodyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultBodyTemplate
+	}
+	tmpl, err := template.New("body").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body_template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// validateEndpointType returns an error when no destination can be created for the type
+// of the endpoint, or for its settings.
+func validateEndpointType(e Endpoint) error {
+	switch e.Type {
+	case DatadogEndpointType, OTLPEndpointType:
+		return nil
+	case HTTPJSONEndpointType:
+		_, err := ParseBodyTemplate(e.BodyTemplate)
+		return err
+	default:
+		return fmt.Errorf("unknown type %q", e.Type)
+	}
+}
@@ -26,6 +26,7 @@ const (
 	IntegrationType   = "integration"
 	WindowsEventType  = "windows_event"
 	StringChannelType = "string_channel"
+	SyslogType        = "syslog"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -46,6 +47,10 @@ type LogsConfig struct {
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout"` // Network
 	Path        string // File, Journald
 
+	Protocol    string `mapstructure:"protocol" json:"protocol" yaml:"protocol"`                // Syslog
+	TLSCertFile string `mapstructure:"tls_cert_file" json:"tls_cert_file" yaml:"tls_cert_file"` // Syslog
+	TLSKeyFile  string `mapstructure:"tls_key_file" json:"tls_key_file" yaml:"tls_key_file"`    // Syslog
+
 	Encoding     string           `mapstructure:"encoding" json:"encoding" yaml:"encoding"`                   // File
 	ExcludePaths StringSliceField `mapstructure:"exclude_paths" json:"exclude_paths" yaml:"exclude_paths"`    // File
 	TailingMode  string           `mapstructure:"start_position" json:"start_position" yaml:"start_position"` // File
@@ -93,6 +98,9 @@ type LogsConfig struct {
 	AutoMultiLine               *bool   `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection" yaml:"auto_multi_line_detection"`
 	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size" yaml:"auto_multi_line_sample_size"`
 	AutoMultiLineMatchThreshold float64 `mapstructure:"auto_multi_line_match_threshold" json:"auto_multi_line_match_threshold" yaml:"auto_multi_line_match_threshold"`
+
+	// SquashRepeatedLogs overrides logs_config.squash_repeated_logs.enabled for this source.
+	SquashRepeatedLogs *bool `mapstructure:"squash_repeated_logs" json:"squash_repeated_logs" yaml:"squash_repeated_logs"`
 }
 
 // StringSliceField is a custom type for unmarshalling comma-separated string values or typical yaml fields into a slice of strings.
@@ -145,6 +153,12 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("TLSCertFile: %#v,"), c.TLSCertFile)
+		fmt.Fprintf(&b, ws("TLSKeyFile: %#v,"), c.TLSKeyFile)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -288,6 +302,11 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		err := c.validateSyslog()
+		if err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -296,6 +315,25 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+func (c *LogsConfig) validateSyslog() error {
+	if c.Port == 0 {
+		return fmt.Errorf("syslog source must have a port")
+	}
+	switch c.Protocol {
+	case "", UDPType:
+		if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+			return fmt.Errorf("syslog source on port %d can only use TLS with the tcp protocol", c.Port)
+		}
+	case TCPType:
+		if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+			return fmt.Errorf("syslog source on port %d must have both a tls_cert_file and a tls_key_file", c.Port)
+		}
+	default:
+		return fmt.Errorf("invalid protocol '%v' for syslog source on port %d, must be tcp or udp", c.Protocol, c.Port)
+	}
+	return nil
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -348,6 +386,15 @@ func (c *LogsConfig) AutoMultiLineEnabled(coreConfig pkgconfigmodel.Reader) bool
 	return coreConfig.GetBool("logs_config.auto_multi_line_detection")
 }
 
+// SquashRepeatedLogsEnabled determines whether the consecutive repetitions of a log
+// should be squashed into a single log for this config.
+func (c *LogsConfig) SquashRepeatedLogsEnabled(coreConfig pkgconfigmodel.Reader) bool {
+	if c.SquashRepeatedLogs != nil {
+		return *c.SquashRepeatedLogs
+	}
+	return coreConfig.GetBool("logs_config.squash_repeated_logs.enabled")
+}
+
 // ShouldProcessRawMessage returns if the raw message should be processed instead
 // of only the message content.
 // This is tightly linked to how messages are transmitted through the pipeline.
@@ -6,6 +6,8 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 )
@@ -16,6 +18,45 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+
+	// Field extraction and remapping rule types, the parse_* rules turn the
+	// log line into attributes while remap_fields only operates on attributes
+	// extracted by a previous rule.
+	ParseJSONRule = "parse_json"
+	ParseLogfmt   = "parse_logfmt"
+	ParseKeyValue = "parse_key_value"
+	ParseRegex    = "parse_regex"
+	RemapFields   = "remap_fields"
+
+	// GenerateMetric submits a metric for every matching log, the log
+	// itself is left untouched.
+	GenerateMetric = "generate_metric"
+
+	// Sample keeps a fraction of the matching logs and RateLimit caps the
+	// number of matching logs per second, the other logs are left untouched.
+	Sample    = "sample"
+	RateLimit = "rate_limit"
+)
+
+// Supported values for the ProcessingRule.LimitBy field
+const (
+	LimitBySource  = "source"
+	LimitByPattern = "pattern"
+)
+
+// Supported values for the LogMetric.Type field
+const (
+	LogMetricCount        = "count"
+	LogMetricGauge        = "gauge"
+	LogMetricDistribution = "distribution"
+)
+
+// Supported values for the ProcessingRule.Coerce map
+const (
+	CoerceString = "string"
+	CoerceInt    = "int"
+	CoerceFloat  = "float"
+	CoerceBool   = "bool"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,9 +66,61 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder" yaml:"replace_placeholder"`
 	Pattern            string
+
+	// Options of the field extraction and remapping rules
+	KeyValueSeparator string            `mapstructure:"key_value_separator" json:"key_value_separator" yaml:"key_value_separator"`
+	FieldSeparator    string            `mapstructure:"field_separator" json:"field_separator" yaml:"field_separator"`
+	Rename            map[string]string `mapstructure:"rename" json:"rename" yaml:"rename"`
+	Drop              []string          `mapstructure:"drop" json:"drop" yaml:"drop"`
+	Coerce            map[string]string `mapstructure:"coerce" json:"coerce" yaml:"coerce"`
+	Promote           FieldPromotion    `mapstructure:"promote" json:"promote" yaml:"promote"`
+
+	// Options of the generate_metric rules
+	Metric LogMetric `mapstructure:"metric" json:"metric" yaml:"metric"`
+
+	// Options of the sample and rate_limit rules
+	SampleRate   float64 `mapstructure:"sample_rate" json:"sample_rate" yaml:"sample_rate"`
+	MaxPerSecond float64 `mapstructure:"max_per_second" json:"max_per_second" yaml:"max_per_second"`
+	Burst        int     `mapstructure:"burst" json:"burst" yaml:"burst"`
+	LimitBy      string  `mapstructure:"limit_by" json:"limit_by" yaml:"limit_by"`
+
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	Limiter     *RateLimiter
+}
+
+// FieldPromotion lists the extracted attributes which should be promoted
+// to the reserved fields of a log.
+type FieldPromotion struct {
+	Status          string   `mapstructure:"status" json:"status" yaml:"status"`
+	Service         string   `mapstructure:"service" json:"service" yaml:"service"`
+	Timestamp       string   `mapstructure:"timestamp" json:"timestamp" yaml:"timestamp"`
+	TimestampLayout string   `mapstructure:"timestamp_layout" json:"timestamp_layout" yaml:"timestamp_layout"`
+	Tags            []string `mapstructure:"tags" json:"tags" yaml:"tags"`
+}
+
+// LogMetric describes the metric submitted by a generate_metric rule.
+// Without a value field, the metric is incremented by one for every matching log.
+// The value field is looked up in the named capture groups of the rule pattern
+// first, then in the attributes extracted by a previous rule.
+// Tags lists the tag keys of the log whose values are added to the metric,
+// "service" and "source" being resolved from the log origin.
+type LogMetric struct {
+	Name       string   `mapstructure:"name" json:"name" yaml:"name"`
+	Type       string   `mapstructure:"type" json:"type" yaml:"type"`
+	ValueField string   `mapstructure:"value_field" json:"value_field" yaml:"value_field"`
+	Tags       []string `mapstructure:"tags" json:"tags" yaml:"tags"`
+}
+
+// IsFieldRule returns true if the rule extracts or remaps attributes
+// instead of operating on the raw content of the log.
+func (r *ProcessingRule) IsFieldRule() bool {
+	switch r.Type {
+	case ParseJSONRule, ParseLogfmt, ParseKeyValue, ParseRegex, RemapFields:
+		return true
+	}
+	return false
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
@@ -35,6 +128,8 @@ type ProcessingRule struct {
 // - a valid name
 // - a valid type
 // - a valid pattern that compiles
+// The pattern is optional for the parse_json, parse_logfmt, parse_key_value
+// and remap_fields rules, when set it restricts the rule to the matching logs.
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,8 +137,23 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, ParseRegex:
 			break
+		case GenerateMetric:
+			if err := validateLogMetric(rule); err != nil {
+				return err
+			}
+		case Sample, RateLimit:
+			if err := validateSamplingRule(rule); err != nil {
+				return err
+			}
+		case ParseJSONRule, ParseLogfmt, ParseKeyValue, RemapFields:
+			if err := validateFieldRule(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				continue
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,24 +163,98 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
-		_, err := regexp.Compile(rule.Pattern)
+		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
 		}
+		if rule.Type == ParseRegex {
+			if !hasNamedGroup(re) {
+				return fmt.Errorf("pattern %s for processing rule %s must contain at least one named capture group", rule.Pattern, rule.Name)
+			}
+			if err := validateFieldRule(rule); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+func validateFieldRule(rule *ProcessingRule) error {
+	for field, kind := range rule.Coerce {
+		switch kind {
+		case CoerceString, CoerceInt, CoerceFloat, CoerceBool:
+		default:
+			return fmt.Errorf("invalid coercion %s for field %s in processing rule: %s", kind, field, rule.Name)
+		}
+	}
+	if rule.Type == ParseKeyValue && rule.KeyValueSeparator != "" && rule.KeyValueSeparator == rule.FieldSeparator {
+		return fmt.Errorf("key_value_separator and field_separator must differ for processing rule: %s", rule.Name)
+	}
+	return nil
+}
+
+func validateLogMetric(rule *ProcessingRule) error {
+	if rule.Metric.Name == "" {
+		return fmt.Errorf("no metric name provided for processing rule: %s", rule.Name)
+	}
+	switch rule.Metric.Type {
+	case "", LogMetricCount:
+	case LogMetricGauge, LogMetricDistribution:
+		if rule.Metric.ValueField == "" {
+			return fmt.Errorf("metric of type %s requires a value_field for processing rule: %s", rule.Metric.Type, rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric type %s is not supported for processing rule: %s", rule.Metric.Type, rule.Name)
+	}
+	return nil
+}
+
+func validateSamplingRule(rule *ProcessingRule) error {
+	if rule.Type == Sample {
+		if rule.SampleRate <= 0 || rule.SampleRate > 1 {
+			return fmt.Errorf("sample_rate must be in ]0, 1] for processing rule: %s", rule.Name)
+		}
+		return nil
+	}
+	if rule.MaxPerSecond <= 0 {
+		return fmt.Errorf("max_per_second must be > 0 for processing rule: %s", rule.Name)
+	}
+	if rule.Burst < 0 {
+		return fmt.Errorf("burst must be >= 0 for processing rule: %s", rule.Name)
+	}
+	switch rule.LimitBy {
+	case "", LimitBySource, LimitByPattern:
+	default:
+		return fmt.Errorf("limit_by %s is not supported for processing rule: %s", rule.LimitBy, rule.Name)
+	}
+	return nil
+}
+
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.IsFieldRule() && rule.Pattern == "" {
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, ParseJSONRule, ParseLogfmt, ParseKeyValue, ParseRegex, RemapFields, GenerateMetric, Sample:
+			rule.Regex = re
+		case RateLimit:
 			rule.Regex = re
+			rule.Limiter = NewRateLimiter(rule.MaxPerSecond, rule.Burst)
 		case MaskSequences:
 			rule.Regex = re
 			rule.Placeholder = []byte(rule.ReplacePlaceholder)
@@ -83,3 +267,97 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 	}
 	return nil
 }
+
+// ProcessingRulesUpdate holds the processing rules replacing the configured
+// ones at runtime. Global replaces the global processing rules, a nil Global
+// keeps the configured ones. Sources replaces the processing rules of the
+// sources with the given names, the other sources keep their configured rules.
+type ProcessingRulesUpdate struct {
+	Global  []*ProcessingRule            `json:"global" yaml:"global"`
+	Sources map[string][]*ProcessingRule `json:"sources" yaml:"sources"`
+}
+
+// ParseProcessingRulesUpdate parses, validates and compiles a JSON encoded
+// processing rules update.
+// The multi_line rules are applied when decoding the logs, they can't be
+// replaced without restarting the tailers and are rejected.
+func ParseProcessingRulesUpdate(data []byte) (*ProcessingRulesUpdate, error) {
+	var update ProcessingRulesUpdate
+	if err := json.Unmarshal(data, &update); err != nil {
+		return nil, err
+	}
+	if err := compileUpdatedRules(update.Global); err != nil {
+		return nil, fmt.Errorf("invalid global processing rules: %v", err)
+	}
+	for name, rules := range update.Sources {
+		if err := compileUpdatedRules(rules); err != nil {
+			return nil, fmt.Errorf("invalid processing rules for source %s: %v", name, err)
+		}
+	}
+	return &update, nil
+}
+
+func compileUpdatedRules(rules []*ProcessingRule) error {
+	if HasMultiLineRule(rules) {
+		return errors.New("multi_line processing rules can't be updated at runtime")
+	}
+	if err := ValidateProcessingRules(rules); err != nil {
+		return err
+	}
+	return CompileProcessingRules(rules)
+}
+
+// Merge adds the rules of another update to this one, the global rules are
+// appended and the rules of the sources defined by both updates are appended.
+func (u *ProcessingRulesUpdate) Merge(other *ProcessingRulesUpdate) {
+	if other.Global != nil {
+		u.Global = append(u.Global, other.Global...)
+		if u.Global == nil {
+			u.Global = []*ProcessingRule{}
+		}
+	}
+	for name, rules := range other.Sources {
+		if u.Sources == nil {
+			u.Sources = make(map[string][]*ProcessingRule)
+		}
+		u.Sources[name] = append(u.Sources[name], rules...)
+	}
+}
+
+// ProcessingRulesSource identifies where a processing rules update comes from.
+type ProcessingRulesSource int
+
+const (
+	// RemoteConfigProcessingRules is the update received through Remote Configuration
+	RemoteConfigProcessingRules ProcessingRulesSource = iota
+	// RuntimeSettingProcessingRules is the update set with the logs_processing_rules runtime setting
+	RuntimeSettingProcessingRules
+
+	numProcessingRulesSources
+)
+
+// ProcessingRulesUpdates holds the last processing rules update of each source,
+// so that a source doesn't replace the rules of the other ones.
+type ProcessingRulesUpdates struct {
+	updates [numProcessingRulesSources]*ProcessingRulesUpdate
+}
+
+// Set replaces the update of a source, a nil update removes it, and returns the
+// updates of all the sources merged in the order of the sources: the rules of the
+// runtime setting are applied after the ones of Remote Configuration. It returns nil
+// when no source has an update, to restore the configured processing rules.
+func (u *ProcessingRulesUpdates) Set(source ProcessingRulesSource, update *ProcessingRulesUpdate) *ProcessingRulesUpdate {
+	u.updates[source] = update
+
+	var merged *ProcessingRulesUpdate
+	for _, update := range u.updates {
+		if update == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &ProcessingRulesUpdate{}
+		}
+		merged.Merge(update)
+	}
+	return merged
+}
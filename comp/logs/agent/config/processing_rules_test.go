@@ -9,6 +9,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,3 +34,176 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateFieldRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "json", Type: ParseJSONRule},
+		{Name: "logfmt", Type: ParseLogfmt, Pattern: "level="},
+		{Name: "kv", Type: ParseKeyValue, KeyValueSeparator: ":", FieldSeparator: ";"},
+		{Name: "regex", Type: ParseRegex, Pattern: `(?P<level>\w+):`},
+		{Name: "remap", Type: RemapFields, Coerce: map[string]string{"code": CoerceInt}},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.NotNil(t, validRules[1].Regex)
+	assert.NotNil(t, validRules[3].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "regex", Type: ParseRegex},
+		{Name: "regex", Type: ParseRegex, Pattern: `(\w+):`},
+		{Name: "remap", Type: RemapFields, Coerce: map[string]string{"code": "integer"}},
+		{Name: "kv", Type: ParseKeyValue, KeyValueSeparator: ":", FieldSeparator: ":"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count", Type: GenerateMetric, Pattern: "ERROR", Metric: LogMetric{Name: "app.errors"}},
+		{Name: "gauge", Type: GenerateMetric, Pattern: `size=(?P<size>\d+)`, Metric: LogMetric{Name: "app.size", Type: LogMetricGauge, ValueField: "size"}},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.NotNil(t, validRules[0].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_pattern", Type: GenerateMetric, Metric: LogMetric{Name: "app.errors"}},
+		{Name: "no_name", Type: GenerateMetric, Pattern: "ERROR"},
+		{Name: "no_value", Type: GenerateMetric, Pattern: "ERROR", Metric: LogMetric{Name: "app.size", Type: LogMetricDistribution}},
+		{Name: "bad_type", Type: GenerateMetric, Pattern: "ERROR", Metric: LogMetric{Name: "app.size", Type: "histogram"}},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateSamplingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "sample", Type: Sample, Pattern: "DEBUG", SampleRate: 0.1},
+		{Name: "rate_limit", Type: RateLimit, Pattern: "Exception", MaxPerSecond: 5},
+		{Name: "rate_limit_pattern", Type: RateLimit, Pattern: "Exception", MaxPerSecond: 0.5, Burst: 2, LimitBy: LimitByPattern},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.NotNil(t, validRules[0].Regex)
+	assert.Nil(t, validRules[0].Limiter)
+	assert.NotNil(t, validRules[1].Limiter)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_pattern", Type: Sample, SampleRate: 0.1},
+		{Name: "no_rate", Type: Sample, Pattern: "DEBUG"},
+		{Name: "rate_too_high", Type: Sample, Pattern: "DEBUG", SampleRate: 2},
+		{Name: "no_limit", Type: RateLimit, Pattern: "Exception"},
+		{Name: "bad_burst", Type: RateLimit, Pattern: "Exception", MaxPerSecond: 5, Burst: -1},
+		{Name: "bad_limit_by", Type: RateLimit, Pattern: "Exception", MaxPerSecond: 5, LimitBy: "host"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestRateLimiterBudgetPerKey(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 2)
+
+	assert.True(t, limiter.Allow("a"))
+	assert.True(t, limiter.Allow("a"))
+	assert.False(t, limiter.Allow("a"))
+
+	// every key has its own budget
+	assert.True(t, limiter.Allow("b"))
+}
+
+func TestRateLimiterEvictsIdleKeys(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(0.01, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow("b"))
+	now = now.Add(30 * time.Second)
+	assert.True(t, limiter.Allow("a"))
+	assert.True(t, limiter.Allow("a"))
+	assert.Len(t, limiter.limiters, 2)
+
+	// the bucket of "b" is full again, "a" is still limited
+	now = now.Add(170 * time.Second)
+	assert.True(t, limiter.Allow("a"))
+	assert.False(t, limiter.Allow("a"))
+	assert.Len(t, limiter.limiters, 1)
+	assert.Contains(t, limiter.limiters, "a")
+}
+
+func TestParseProcessingRulesUpdate(t *testing.T) {
+	update, err := ParseProcessingRulesUpdate([]byte(`{
+		"global": [{"type": "exclude_at_match", "name": "no_debug", "pattern": "DEBUG"}],
+		"sources": {"app": [{"type": "mask_sequences", "name": "mask", "pattern": "secret", "replace_placeholder": "***"}]}
+	}`))
+	assert.NoError(t, err)
+	assert.Len(t, update.Global, 1)
+	assert.NotNil(t, update.Global[0].Regex)
+	assert.Len(t, update.Sources["app"], 1)
+	assert.Equal(t, []byte("***"), update.Sources["app"][0].Placeholder)
+
+	// the global rules are kept when not defined
+	update, err = ParseProcessingRulesUpdate([]byte(`{"sources": {}}`))
+	assert.NoError(t, err)
+	assert.Nil(t, update.Global)
+
+	// and removed when empty
+	update, err = ParseProcessingRulesUpdate([]byte(`{"global": []}`))
+	assert.NoError(t, err)
+	assert.NotNil(t, update.Global)
+	assert.Empty(t, update.Global)
+
+	for _, invalid := range []string{
+		`not json`,
+		`{"global": [{"type": "exclude_at_match", "pattern": "DEBUG"}]}`,
+		`{"sources": {"app": [{"type": "include_at_match", "name": "invalid", "pattern": "(?=abf)"}]}}`,
+		`{"global": [{"type": "multi_line", "name": "multi", "pattern": "\\d{4}"}]}`,
+	} {
+		_, err = ParseProcessingRulesUpdate([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMergeProcessingRulesUpdates(t *testing.T) {
+	global := &ProcessingRule{Name: "global"}
+	app := &ProcessingRule{Name: "app"}
+	other := &ProcessingRule{Name: "other"}
+
+	merged := &ProcessingRulesUpdate{}
+	merged.Merge(&ProcessingRulesUpdate{Sources: map[string][]*ProcessingRule{"app": {app}}})
+	assert.Nil(t, merged.Global)
+
+	merged.Merge(&ProcessingRulesUpdate{Global: []*ProcessingRule{}})
+	assert.NotNil(t, merged.Global)
+	assert.Empty(t, merged.Global)
+
+	merged.Merge(&ProcessingRulesUpdate{
+		Global:  []*ProcessingRule{global},
+		Sources: map[string][]*ProcessingRule{"app": {other}, "other": {other}},
+	})
+	assert.Equal(t, []*ProcessingRule{global}, merged.Global)
+	assert.Equal(t, []*ProcessingRule{app, other}, merged.Sources["app"])
+	assert.Equal(t, []*ProcessingRule{other}, merged.Sources["other"])
+}
+
+func TestProcessingRulesUpdatesBySource(t *testing.T) {
+	remote := &ProcessingRule{Name: "remote"}
+	runtime := &ProcessingRule{Name: "runtime"}
+	var updates ProcessingRulesUpdates
+
+	merged := updates.Set(RuntimeSettingProcessingRules, &ProcessingRulesUpdate{Global: []*ProcessingRule{runtime}})
+	assert.Equal(t, []*ProcessingRule{runtime}, merged.Global)
+
+	// the runtime setting rules are kept, after the Remote Configuration ones
+	merged = updates.Set(RemoteConfigProcessingRules, &ProcessingRulesUpdate{Global: []*ProcessingRule{remote}})
+	assert.Equal(t, []*ProcessingRule{remote, runtime}, merged.Global)
+
+	merged = updates.Set(RuntimeSettingProcessingRules, nil)
+	assert.Equal(t, []*ProcessingRule{remote}, merged.Global)
+
+	assert.Nil(t, updates.Set(RemoteConfigProcessingRules, nil))
+}
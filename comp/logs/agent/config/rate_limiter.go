@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterSweepInterval is how often the idle keys of a RateLimiter are evicted
+const rateLimiterSweepInterval = time.Minute
+
+// RateLimiter holds the budgets of a rate_limit processing rule, one token
+// bucket per key. It is shared by all the pipelines applying the rule.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	limiters  map[string]*rate.Limiter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter returns a rate limiter allowing maxPerSecond events per second
+// and per key, with bursts of up to burst events. A zero burst defaults to
+// one second worth of events.
+func NewRateLimiter(maxPerSecond float64, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(maxPerSecond)))
+	}
+	return &RateLimiter{
+		limit:    rate.Limit(maxPerSecond),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+		now:      time.Now,
+	}
+}
+
+// Allow returns true if an event can happen now for the given key.
+func (r *RateLimiter) Allow(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastSweep) >= rateLimiterSweepInterval {
+		r.sweep(now)
+	}
+	limiter, exists := r.limiters[key]
+	if !exists {
+		limiter = rate.NewLimiter(r.limit, r.burst)
+		r.limiters[key] = limiter
+	}
+	return limiter.AllowN(now, 1)
+}
+
+// sweep evicts the keys whose bucket is full again, they are idle and a new
+// bucket would have the same budget.
+func (r *RateLimiter) sweep(now time.Time) {
+	r.lastSweep = now
+	for key, limiter := range r.limiters {
+		if limiter.TokensAt(now) >= float64(r.burst) {
+			delete(r.limiters, key)
+		}
+	}
+}
@@ -70,6 +70,32 @@ func TestParseRepaceRules(t *testing.T) {
 	}
 }
 
+// TestCompileSpanProcessorRules tests the compileSpanProcessorRules helper function.
+func TestCompileSpanProcessorRules(t *testing.T) {
+	rules := []*traceconfig.SpanProcessorRule{
+		{Service: "^web$", Action: "add", Key: "team", Value: "frontend"},
+		{Resource: "GET /users", Action: "hash", KeyPattern: "^user\\."},
+		{Action: "rename", Key: "customer", NewKey: "tenant"},
+	}
+	require.NoError(t, compileSpanProcessorRules(rules))
+	assert.Equal(t, "^web$", rules[0].ServiceRe.String())
+	assert.Nil(t, rules[0].OperationRe)
+	assert.Equal(t, "GET /users", rules[1].ResourceRe.String())
+	assert.Equal(t, "^user\\.", rules[1].KeyRe.String())
+
+	for _, r := range []*traceconfig.SpanProcessorRule{
+		{Action: "drop", Key: "team"},
+		{Action: "add"},
+		{Action: "rename", Key: "customer"},
+		{Action: "delete"},
+		{Action: "delete", Key: "team", KeyPattern: "team"},
+		{Action: "hash", KeyPattern: "("},
+		{Action: "to_meta", Key: "team", Operation: "("},
+	} {
+		assert.Error(t, compileSpanProcessorRules([]*traceconfig.SpanProcessorRule{r}))
+	}
+}
+
 // TestSplitTag tests various split-tagging scenarios
 func TestSplitTag(t *testing.T) {
 	for _, tt := range []struct {
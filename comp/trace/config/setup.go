@@ -250,6 +250,12 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	if core.IsSet("apm_config.receiver_socket") {
 		c.ReceiverSocket = core.GetString("apm_config.receiver_socket")
 	}
+	if core.IsSet("apm_config.zipkin_receiver.enabled") {
+		c.ZipkinReceiverEnabled = core.GetBool("apm_config.zipkin_receiver.enabled")
+	}
+	if core.IsSet("apm_config.jaeger_receiver.enabled") {
+		c.JaegerReceiverEnabled = core.GetBool("apm_config.jaeger_receiver.enabled")
+	}
 	if core.IsSet("apm_config.connection_limit") {
 		c.ConnectionLimit = core.GetInt("apm_config.connection_limit")
 	}
@@ -273,6 +279,16 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	if core.IsSet("apm_config.peer_tags") {
 		c.PeerTags = core.GetStringSlice("apm_config.peer_tags")
 	}
+	if core.IsSet("apm_config.stats_extra_dimensions") {
+		c.StatsExtraDimensions = core.GetStringSlice("apm_config.stats_extra_dimensions")
+		if len(c.StatsExtraDimensions) > config.MaxStatsExtraDimensions {
+			log.Warnf("apm_config.stats_extra_dimensions contains %d tags, only the first %d are used", len(c.StatsExtraDimensions), config.MaxStatsExtraDimensions)
+			c.StatsExtraDimensions = c.StatsExtraDimensions[:config.MaxStatsExtraDimensions]
+		}
+	}
+	if core.IsSet("apm_config.stats_extra_dimensions_max_cardinality") {
+		c.StatsExtraDimensionsMaxCardinality = core.GetInt("apm_config.stats_extra_dimensions_max_cardinality")
+	}
 
 	if core.IsSet("apm_config.extra_sample_rate") {
 		c.ExtraSampleRate = core.GetFloat64("apm_config.extra_sample_rate")
@@ -313,10 +329,60 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
 
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSamplingEnabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSamplingDecisionWait = core.GetDuration("apm_config.tail_sampling.decision_wait")
+	}
+	if core.IsSet("apm_config.tail_sampling.max_buffer_size") {
+		c.TailSamplingMaxBufferBytes = core.GetInt("apm_config.tail_sampling.max_buffer_size")
+	}
+	if core.IsSet("apm_config.tail_sampling.policies.errors") {
+		c.TailSamplingErrors = core.GetBool("apm_config.tail_sampling.policies.errors")
+	}
+	if core.IsSet("apm_config.tail_sampling.policies.latency_threshold") {
+		c.TailSamplingLatencyThreshold = core.GetDuration("apm_config.tail_sampling.policies.latency_threshold")
+	}
+	if core.IsSet("apm_config.tail_sampling.policies.span_tags") {
+		for _, tag := range core.GetStringSlice("apm_config.tail_sampling.policies.span_tags") {
+			if splitTag := splitTagRegex(tag); splitTag != nil {
+				c.TailSamplingSpanTags = append(c.TailSamplingSpanTags, splitTag)
+			}
+		}
+	}
+	if core.IsSet("apm_config.tail_sampling.policies.rare_resources.enabled") {
+		c.TailSamplingRareResources = core.GetBool("apm_config.tail_sampling.policies.rare_resources.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.policies.rare_resources.cooldown") {
+		c.TailSamplingRareResourcesCooldown = core.GetDuration("apm_config.tail_sampling.policies.rare_resources.cooldown")
+	}
+	if core.IsSet("apm_config.tail_sampling.policies.rare_resources.cardinality") {
+		c.TailSamplingRareResourcesCardinality = core.GetInt("apm_config.tail_sampling.policies.rare_resources.cardinality")
+	}
+
 	if core.IsSet("apm_config.error_tracking_standalone.enabled") {
 		c.ErrorTrackingStandalone = core.GetBool("apm_config.error_tracking_standalone.enabled")
 	}
 
+	if core.IsSet("apm_config.trace_store.enabled") {
+		c.TraceStoreEnabled = core.GetBool("apm_config.trace_store.enabled")
+	}
+	if core.IsSet("apm_config.trace_store.retention") {
+		if v := core.GetDuration("apm_config.trace_store.retention"); v > 0 {
+			c.TraceStoreRetention = v
+		} else {
+			log.Warnf("apm_config.trace_store.retention must be positive, using the default of %s", c.TraceStoreRetention)
+		}
+	}
+	if core.IsSet("apm_config.trace_store.max_traces") {
+		if v := core.GetInt("apm_config.trace_store.max_traces"); v > 0 {
+			c.TraceStoreMaxTraces = v
+		} else {
+			log.Warnf("apm_config.trace_store.max_traces must be positive, using the default of %d", c.TraceStoreMaxTraces)
+		}
+	}
+
 	if core.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = core.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -367,6 +433,18 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	if k := "apm_config.span_processors"; core.IsSet(k) {
+		rules := make([]*config.SpanProcessorRule, 0)
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"action\": \"add\",\"key\":\"tag_name\",\"value\":\"tag_value\"}]', error: %v", k, err)
+		} else {
+			if err := compileSpanProcessorRules(rules); err != nil {
+				return fmt.Errorf("span_processors: %s", err)
+			}
+			c.SpanProcessors = rules
+		}
+	}
+
 	if core.IsSet("bind_host") || core.IsSet("apm_config.apm_non_local_traffic") {
 		if core.IsSet("bind_host") {
 			host := core.GetString("bind_host")
@@ -723,6 +801,49 @@ func compileReplaceRules(rules []*config.ReplaceRule) error {
 	return nil
 }
 
+// compileSpanProcessorRules validates the span processor rules and compiles their regular expressions.
+// If it fails it returns the first error.
+func compileSpanProcessorRules(rules []*config.SpanProcessorRule) error {
+	compile := func(pattern string) (*regexp.Regexp, error) {
+		if pattern == "" {
+			return nil, nil
+		}
+		return regexp.Compile(pattern)
+	}
+	for i, r := range rules {
+		switch r.Action {
+		case config.SpanProcessorAdd:
+			if r.Key == "" {
+				return fmt.Errorf("rule %d: the %q action requires a \"key\"", i, r.Action)
+			}
+		case config.SpanProcessorRename:
+			if r.Key == "" || r.NewKey == "" {
+				return fmt.Errorf("rule %d: the %q action requires a \"key\" and a \"new_key\"", i, r.Action)
+			}
+		case config.SpanProcessorDelete, config.SpanProcessorHash, config.SpanProcessorToMetrics, config.SpanProcessorToMeta:
+			if (r.Key == "") == (r.KeyPattern == "") {
+				return fmt.Errorf("rule %d: the %q action requires either a \"key\" or a \"key_pattern\"", i, r.Action)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown action %q", i, r.Action)
+		}
+		var err error
+		if r.ServiceRe, err = compile(r.Service); err != nil {
+			return fmt.Errorf("rule %d: service: %s", i, err)
+		}
+		if r.OperationRe, err = compile(r.Operation); err != nil {
+			return fmt.Errorf("rule %d: operation: %s", i, err)
+		}
+		if r.ResourceRe, err = compile(r.Resource); err != nil {
+			return fmt.Errorf("rule %d: resource: %s", i, err)
+		}
+		if r.KeyRe, err = compile(r.KeyPattern); err != nil {
+			return fmt.Errorf("rule %d: key_pattern: %s", i, err)
+		}
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
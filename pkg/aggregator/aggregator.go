@@ -167,6 +167,8 @@ var (
 		[]string{"shard", "metric_type"}, "Count the number of checks contexts in the check aggregator, by metric type")
 	tlmChecksContextsBytesByMtype = telemetry.NewGauge("aggregator", "checks_contexts_bytes_by_mtype",
 		[]string{"shard", "metric_type", tags.BytesKindTelemetryKey}, "Estimated count of bytes taken by contexts in the check aggregator, by metric type")
+	tlmContextLimitOverflow = telemetry.NewCounter("aggregator", "context_limit_overflow",
+		[]string{"limit", "action"}, "Count the number of new contexts dropped or folded because a context limit was reached")
 
 	// Hold series to be added to aggregated series on each flush
 	recurrentSeries     metrics.Series
@@ -243,7 +245,9 @@ type BufferedAggregator struct {
 	// Used by the Dogstatsd Batcher.
 	MetricSamplePool *metrics.MetricSamplePool
 
-	tagsStore              *tags.Store
+	tagsStore *tags.Store
+	// contextLimiter is shared by the check samplers and the dogstatsd time samplers
+	contextLimiter         *contextLimiter
 	checkSamplers          map[checkid.ID]*CheckSampler
 	serviceChecks          servicecheck.ServiceChecks
 	events                 event.Events
@@ -328,6 +332,7 @@ func NewBufferedAggregator(s serializer.MetricSerializer, eventPlatformForwarder
 		eventPlatformIn:        make(chan senderEventPlatformEvent, bufferSize),
 
 		tagsStore:                   tagsStore,
+		contextLimiter:              newContextLimiter(pkgconfigsetup.Datadog()),
 		checkSamplers:               make(map[checkid.ID]*CheckSampler),
 		flushInterval:               flushInterval,
 		serializer:                  s,
@@ -993,5 +998,6 @@ func (agg *BufferedAggregator) handleRegisterSampler(id checkid.ID) {
 		agg.tagsStore,
 		id,
 		agg.tagger,
+		agg.contextLimiter,
 	)
 }
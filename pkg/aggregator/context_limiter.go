@@ -0,0 +1,319 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// limitKindMetric is the limit of active contexts per metric name
+	limitKindMetric = "metric"
+	// limitKindOrigin is the limit of active contexts per origin
+	limitKindOrigin = "origin"
+
+	overflowModeDrop = "drop"
+	overflowModeFold = "fold"
+
+	// overflowTagValue replaces the value of the folded tag of the contexts over a limit
+	overflowTagValue = "overflow"
+)
+
+// ContextLimitOverflow describes a metric name or an origin which reached its context limit.
+type ContextLimitOverflow struct {
+	// Limit is the reached limit, either "metric" or "origin"
+	Limit string
+	// Metric is the metric name, for the metric limit
+	Metric string `json:",omitempty"`
+	// OriginTags are the tags identifying the origin, for the origin limit
+	OriginTags []string `json:",omitempty"`
+	// Check is the id of the check of the origin, for the origin limit of the check contexts
+	Check string `json:",omitempty"`
+	// Contexts is the number of active contexts of the metric name or origin
+	Contexts int
+	// Dropped is the number of new contexts dropped since the limit was reached
+	Dropped uint64
+	// Folded is the number of new contexts folded since the limit was reached
+	Folded uint64
+}
+
+type limitOverflow struct {
+	originTags []string
+	dropped    uint64
+	folded     uint64
+}
+
+// limitOrigin identifies an origin: its tagger tags (container, pod, ...) and, for the contexts
+// of a check sampler, the check. The checks don't share their origins, so all the contexts of a
+// check without origin tags share the same origin: the check.
+type limitOrigin struct {
+	check string
+	tags  ckey.TagsKey
+}
+
+// tagCardinality counts the contexts by tag, for each tag key.
+type tagCardinality map[string]map[string]int
+
+func (c tagCardinality) add(tags []string) {
+	for _, tag := range tags {
+		if key, _, found := strings.Cut(tag, ":"); found {
+			if c[key] == nil {
+				c[key] = make(map[string]int)
+			}
+			c[key][tag]++
+		}
+	}
+}
+
+func (c tagCardinality) remove(tags []string) {
+	for _, tag := range tags {
+		key, _, found := strings.Cut(tag, ":")
+		if values := c[key]; found && values != nil {
+			if values[tag]--; values[tag] <= 0 {
+				delete(values, tag)
+			}
+			if len(values) == 0 {
+				delete(c, key)
+			}
+		}
+	}
+}
+
+// highest returns the key, among the keys of the tags, with the most values.
+func (c tagCardinality) highest(tags []string) string {
+	highest := ""
+	for _, tag := range tags {
+		key, _, found := strings.Cut(tag, ":")
+		if !found {
+			continue
+		}
+		if highest == "" || len(c[key]) > len(c[highest]) || (len(c[key]) == len(c[highest]) && key < highest) {
+			highest = key
+		}
+	}
+	return highest
+}
+
+// contextLimiter limits the number of active contexts per metric name and per origin. It is
+// shared by the dogstatsd and check samplers, so the limits apply across the agent.
+type contextLimiter struct {
+	metricLimit int
+	originLimit int
+	fold        bool
+
+	mu sync.Mutex
+
+	contextsByMetric map[string]int
+	contextsByOrigin map[limitOrigin]int
+
+	// the tags of the contexts, only counted when the contexts are folded
+	tagsByMetric map[string]tagCardinality
+	tagsByOrigin map[limitOrigin]tagCardinality
+
+	overflowByMetric map[string]*limitOverflow
+	overflowByOrigin map[limitOrigin]*limitOverflow
+}
+
+// newContextLimiter returns a contextLimiter configured with the aggregator_context_limits
+// settings, or nil when no limit is configured.
+func newContextLimiter(cfg model.Reader) *contextLimiter {
+	metricLimit := cfg.GetInt("aggregator_context_limits.per_metric")
+	originLimit := cfg.GetInt("aggregator_context_limits.per_origin")
+	if metricLimit <= 0 && originLimit <= 0 {
+		return nil
+	}
+
+	fold := false
+	switch mode := cfg.GetString("aggregator_context_limits.overflow"); mode {
+	case overflowModeFold:
+		fold = true
+	case overflowModeDrop:
+	default:
+		log.Warnf("Unknown aggregator_context_limits.overflow value %q, the contexts over the limits are dropped", mode)
+	}
+
+	return &contextLimiter{
+		metricLimit:      metricLimit,
+		originLimit:      originLimit,
+		fold:             fold,
+		contextsByMetric: make(map[string]int),
+		contextsByOrigin: make(map[limitOrigin]int),
+		tagsByMetric:     make(map[string]tagCardinality),
+		tagsByOrigin:     make(map[limitOrigin]tagCardinality),
+		overflowByMetric: make(map[string]*limitOverflow),
+		overflowByOrigin: make(map[limitOrigin]*limitOverflow),
+	}
+}
+
+// admit tracks a new context of the metric and origin and returns an empty string, or records
+// an overflow and returns the reached limit when the context is over a limit.
+func (l *contextLimiter) admit(name string, origin limitOrigin, originTags *tagset.HashingTagsAccumulator, metricTags []string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := ""
+	if l.metricLimit > 0 && l.contextsByMetric[name] >= l.metricLimit {
+		limit = limitKindMetric
+	} else if l.originLimit > 0 && l.contextsByOrigin[origin] >= l.originLimit {
+		limit = limitKindOrigin
+	}
+	if limit == "" {
+		l.trackLocked(name, origin, metricTags)
+		return ""
+	}
+
+	l.recordOverflowLocked(limit, name, origin, originTags)
+	return limit
+}
+
+// track tracks a new context of the metric and origin, even over the limits.
+func (l *contextLimiter) track(name string, origin limitOrigin, metricTags []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.trackLocked(name, origin, metricTags)
+}
+
+func (l *contextLimiter) trackLocked(name string, origin limitOrigin, metricTags []string) {
+	l.contextsByMetric[name]++
+	l.contextsByOrigin[origin]++
+	if !l.fold {
+		return
+	}
+	if l.tagsByMetric[name] == nil {
+		l.tagsByMetric[name] = make(tagCardinality)
+	}
+	l.tagsByMetric[name].add(metricTags)
+	if l.tagsByOrigin[origin] == nil {
+		l.tagsByOrigin[origin] = make(tagCardinality)
+	}
+	l.tagsByOrigin[origin].add(metricTags)
+}
+
+// untrack forgets an expired context of the metric and origin, the overflows are forgotten
+// with their last context.
+func (l *contextLimiter) untrack(name string, origin limitOrigin, metricTags []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fold {
+		l.tagsByMetric[name].remove(metricTags)
+		l.tagsByOrigin[origin].remove(metricTags)
+	}
+	if l.contextsByMetric[name]--; l.contextsByMetric[name] <= 0 {
+		delete(l.contextsByMetric, name)
+		delete(l.tagsByMetric, name)
+		delete(l.overflowByMetric, name)
+	}
+	if l.contextsByOrigin[origin]--; l.contextsByOrigin[origin] <= 0 {
+		delete(l.contextsByOrigin, origin)
+		delete(l.tagsByOrigin, origin)
+		delete(l.overflowByOrigin, origin)
+	}
+}
+
+func (l *contextLimiter) recordOverflowLocked(limit string, name string, origin limitOrigin, originTags *tagset.HashingTagsAccumulator) {
+	var overflow *limitOverflow
+	if limit == limitKindMetric {
+		if overflow = l.overflowByMetric[name]; overflow == nil {
+			overflow = &limitOverflow{}
+			l.overflowByMetric[name] = overflow
+			log.Warnf("The metric %s reached its limit of %d contexts", name, l.metricLimit)
+		}
+	} else {
+		if overflow = l.overflowByOrigin[origin]; overflow == nil {
+			overflow = &limitOverflow{originTags: originTags.Copy()}
+			l.overflowByOrigin[origin] = overflow
+			log.Warnf("The origin %v reached its limit of %d contexts", overflow.originTags, l.originLimit)
+		}
+	}
+
+	action := overflowModeDrop
+	if l.fold {
+		overflow.folded++
+		action = overflowModeFold
+	} else {
+		overflow.dropped++
+	}
+	tlmContextLimitOverflow.Inc(limit, action)
+}
+
+// foldTags replaces by overflowTagValue the value of the offending tag of a context over the
+// limit: the tag whose key has the most values among the contexts of the metric, or of the
+// origin, which reached the limit. The contexts over the limit which only differ by the value
+// of that tag share the same context.
+func (l *contextLimiter) foldTags(limit string, name string, origin limitOrigin, buffer *tagset.HashingTagsAccumulator) {
+	l.mu.Lock()
+	cardinality := l.tagsByMetric[name]
+	if limit == limitKindOrigin {
+		cardinality = l.tagsByOrigin[origin]
+	}
+	key := cardinality.highest(buffer.Get())
+	l.mu.Unlock()
+
+	if key != "" {
+		foldTag(buffer, key)
+	}
+}
+
+// report returns the metric names and origins which reached their limit.
+func (l *contextLimiter) report() []ContextLimitOverflow {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var overflows []ContextLimitOverflow
+	for name, overflow := range l.overflowByMetric {
+		overflows = append(overflows, ContextLimitOverflow{
+			Limit:    limitKindMetric,
+			Metric:   name,
+			Contexts: l.contextsByMetric[name],
+			Dropped:  overflow.dropped,
+			Folded:   overflow.folded,
+		})
+	}
+	for origin, overflow := range l.overflowByOrigin {
+		overflows = append(overflows, ContextLimitOverflow{
+			Limit:      limitKindOrigin,
+			OriginTags: overflow.originTags,
+			Check:      origin.check,
+			Contexts:   l.contextsByOrigin[origin],
+			Dropped:    overflow.dropped,
+			Folded:     overflow.folded,
+		})
+	}
+	return overflows
+}
+
+// foldTag replaces the value of the tags of the buffer with the key by overflowTagValue.
+func foldTag(buffer *tagset.HashingTagsAccumulator, key string) {
+	folded := make([]string, 0, len(buffer.Get()))
+	for _, tag := range buffer.Get() {
+		if k, _, found := strings.Cut(tag, ":"); found && k == key {
+			tag = key + ":" + overflowTagValue
+		}
+		folded = append(folded, tag)
+	}
+	buffer.Reset()
+	buffer.Append(folded...)
+}
+
+// sortContextLimitOverflows sorts the overflows by number of dropped and folded contexts, the
+// worst offenders first.
+func sortContextLimitOverflows(overflows []ContextLimitOverflow) {
+	sort.SliceStable(overflows, func(i, j int) bool {
+		return overflows[i].Dropped+overflows[i].Folded > overflows[j].Dropped+overflows[j].Folded
+	})
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestContextLimiterDisabled(t *testing.T) {
+	l := newContextLimiter(configmock.New(t))
+	assert.Nil(t, l)
+	assert.Nil(t, l.report())
+}
+
+func testContextLimitPerOrigin(t *testing.T, store *tags.Store) {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("aggregator_context_limits.per_origin", 2)
+	limiter := newContextLimiter(mockConfig)
+	r := newContextResolver(nooptagger.NewComponent(), store, "test", limiter)
+
+	_, ok := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"request:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"request:2"}}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"request:3"}}, 0)
+	assert.False(t, ok)
+	_, ok = r.trackContext(&mockSample{"bar", []string{"pod:a"}, []string{}}, 0)
+	assert.False(t, ok)
+
+	// the known contexts and the other origins are still tracked
+	_, ok = r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"request:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"foo", []string{"pod:b"}, []string{"request:3"}}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 3, r.length())
+
+	assert.Equal(t, []ContextLimitOverflow{{
+		Limit:      limitKindOrigin,
+		OriginTags: []string{"pod:a"},
+		Contexts:   2,
+		Dropped:    2,
+	}}, limiter.report())
+
+	// the overflow is forgotten with the last context of the origin
+	for key, entry := range r.contextsByKey {
+		if entry.context.taggerTags.Tags()[0] == "pod:a" {
+			r.remove(key)
+		}
+	}
+	assert.Empty(t, limiter.report())
+	_, ok = r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"request:3"}}, 0)
+	assert.True(t, ok)
+}
+
+func TestContextLimitPerOrigin(t *testing.T) {
+	testWithTagsStore(t, testContextLimitPerOrigin)
+}
+
+func testContextLimitPerMetricFold(t *testing.T, store *tags.Store) {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("aggregator_context_limits.per_metric", 2)
+	mockConfig.SetWithoutSource("aggregator_context_limits.overflow", "fold")
+	limiter := newContextLimiter(mockConfig)
+	r := newContextResolver(nooptagger.NewComponent(), store, "test", limiter)
+
+	key1, ok := r.trackContext(&mockSample{"foo", nil, []string{"env:prod", "request:1"}}, 0)
+	require.True(t, ok)
+	key2, ok := r.trackContext(&mockSample{"foo", nil, []string{"env:prod", "request:2"}}, 0)
+	require.True(t, ok)
+	key3, ok := r.trackContext(&mockSample{"foo", nil, []string{"env:prod", "request:3"}}, 0)
+	require.True(t, ok)
+	key4, ok := r.trackContext(&mockSample{"foo", nil, []string{"env:prod", "request:4"}}, 0)
+	require.True(t, ok)
+	key5, ok := r.trackContext(&mockSample{"foo", nil, []string{"env:staging", "request:5"}}, 0)
+	require.True(t, ok)
+
+	assert.NotEqual(t, key1, key2)
+	assert.NotEqual(t, key2, key3)
+	assert.Equal(t, key3, key4)
+	assert.NotEqual(t, key4, key5)
+
+	// only the request tag, with the most values, is folded
+	context, found := r.get(key3)
+	require.True(t, found)
+	assertContext(t, context, "foo", []string{"env:prod", "request:overflow"}, "noop")
+	context, found = r.get(key5)
+	require.True(t, found)
+	assertContext(t, context, "foo", []string{"env:staging", "request:overflow"}, "noop")
+
+	assert.Equal(t, []ContextLimitOverflow{{
+		Limit:    limitKindMetric,
+		Metric:   "foo",
+		Contexts: 4,
+		Folded:   3,
+	}}, limiter.report())
+}
+
+func TestContextLimitPerMetricFold(t *testing.T) {
+	testWithTagsStore(t, testContextLimitPerMetricFold)
+}
+
+func testContextLimitShared(t *testing.T, store *tags.Store) {
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("aggregator_context_limits.per_metric", 2)
+	mockConfig.SetWithoutSource("aggregator_context_limits.per_origin", 1)
+	limiter := newContextLimiter(mockConfig)
+	shard0 := newContextResolver(nooptagger.NewComponent(), store, "0", limiter)
+	shard1 := newContextResolver(nooptagger.NewComponent(), store, "1", limiter)
+	check1 := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "check:1", limiter)
+	check2 := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "check:2", limiter)
+
+	// the dogstatsd shards share their origins
+	_, ok := shard0.trackContext(&mockSample{"foo", nil, []string{"request:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = shard1.trackContext(&mockSample{"bar", nil, []string{"request:2"}}, 0)
+	assert.False(t, ok)
+
+	// the checks don't, but the metric limit applies to all the resolvers
+	_, ok = check1.trackContext(&mockSample{"foo", nil, []string{"request:3"}})
+	assert.True(t, ok)
+	_, ok = check2.trackContext(&mockSample{"foo", nil, []string{"request:4"}})
+	assert.False(t, ok)
+	_, ok = check2.trackContext(&mockSample{"bar", nil, []string{"request:4"}})
+	assert.True(t, ok)
+
+	assert.ElementsMatch(t, []ContextLimitOverflow{
+		{Limit: limitKindOrigin, OriginTags: []string{}, Contexts: 1, Dropped: 1},
+		{Limit: limitKindMetric, Metric: "foo", Contexts: 2, Dropped: 1},
+	}, limiter.report())
+
+	// the contexts of a released resolver are forgotten
+	check1.release()
+	_, ok = check2.trackContext(&mockSample{"foo", nil, []string{"request:4"}})
+	assert.False(t, ok, "check:2 reached its origin limit")
+	assert.Equal(t, 1, limiter.contextsByMetric["foo"])
+	assert.NotContains(t, limiter.contextsByOrigin, limitOrigin{check: "check:1"})
+}
+
+func TestContextLimitShared(t *testing.T) {
+	testWithTagsStore(t, testContextLimitShared)
+}
+
+func TestFoldTag(t *testing.T) {
+	buffer := tagset.NewHashingTagsAccumulatorWithTags([]string{"env:prod", "request:1", "standalone"})
+	foldTag(buffer, "request")
+	assert.Equal(t, []string{"env:prod", "request:overflow", "standalone"}, buffer.Get())
+	assert.Equal(t, tagset.NewHashingTagsAccumulatorWithTags(buffer.Get()).Hashes(), buffer.Hashes())
+}
+
+func TestTagCardinality(t *testing.T) {
+	c := tagCardinality{}
+	c.add([]string{"env:prod", "request:1", "standalone"})
+	c.add([]string{"env:prod", "request:2"})
+	assert.Equal(t, "request", c.highest([]string{"env:prod", "request:3"}))
+	assert.Equal(t, "env", c.highest([]string{"env:prod", "other:1"}))
+	assert.Equal(t, "", c.highest([]string{"standalone"}))
+
+	c.remove([]string{"env:prod", "request:1", "standalone"})
+	c.remove([]string{"env:prod", "request:2"})
+	assert.Empty(t, c)
+}
+
+func TestSortContextLimitOverflows(t *testing.T) {
+	overflows := []ContextLimitOverflow{
+		{Metric: "a", Dropped: 1},
+		{Metric: "b", Folded: 5},
+		{Metric: "c", Dropped: 3},
+	}
+	sortContextLimitOverflows(overflows)
+	assert.Equal(t, "b", overflows[0].Metric)
+	assert.Equal(t, "c", overflows[1].Metric)
+	assert.Equal(t, "a", overflows[2].Metric)
+}
@@ -27,6 +27,7 @@ type Context struct {
 	metricTags *tags.Entry
 	noIndex    bool
 	source     metrics.MetricSource
+	originKey  ckey.TagsKey
 }
 
 type resolverEntry struct {
@@ -76,6 +77,11 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	limiter          *contextLimiter
+	// originCheck is the check id of the check resolvers, the checks don't share their
+	// origins in the limiter
+	originCheck string
+	tagRules    *tagRules
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -83,7 +89,7 @@ func (cr *contextResolver) generateContextKey(metricSampleContext metrics.Metric
 	return cr.keyGenerator.GenerateWithTags2(metricSampleContext.GetName(), metricSampleContext.GetHost(), cr.taggerBuffer, cr.metricBuffer)
 }
 
-func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *contextResolver {
+func newContextResolver(tagger tagger.Component, cache *tags.Store, id string, limiter *contextLimiter) *contextResolver {
 	return &contextResolver{
 		id:               id,
 		contextsByKey:    make(map[ckey.ContextKey]resolverEntry),
@@ -96,46 +102,78 @@ func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *
 		keyGenerator:     ckey.NewKeyGenerator(),
 		taggerBuffer:     tagset.NewHashingTagsAccumulator(),
 		metricBuffer:     tagset.NewHashingTagsAccumulator(),
+		limiter:          limiter,
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) ckey.ContextKey {
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false when the context is over the context limits and the sample must be dropped.
+func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, cr.tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
+	if cr.tagRules != nil {
+		cr.tagRules.apply(metricSampleContext.GetName(), cr.taggerBuffer, cr.metricBuffer)
+	}
 
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
+	if cr.refresh(contextKey, timestamp) {
+		return contextKey, true
+	}
 
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		context := &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
-			noIndex:    metricSampleContext.IsNoIndex(),
-			source:     metricSampleContext.GetSource(),
-		}
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  context,
+	if cr.limiter != nil {
+		origin := limitOrigin{check: cr.originCheck, tags: taggerKey}
+		if limit := cr.limiter.admit(metricSampleContext.GetName(), origin, cr.taggerBuffer, cr.metricBuffer.Get()); limit != "" {
+			if !cr.limiter.fold {
+				return contextKey, false
+			}
+
+			// the folded contexts are tracked even over the limits
+			cr.limiter.foldTags(limit, metricSampleContext.GetName(), origin, cr.metricBuffer)
+			contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+			if cr.refresh(contextKey, timestamp) {
+				return contextKey, true
+			}
+			cr.limiter.track(metricSampleContext.GetName(), origin, cr.metricBuffer.Get())
 		}
+	}
 
-		cr.seendByMtype[mtype] = true
-		cr.countsByMtype[mtype]++
-		cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
-		cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
-	} else {
-		// We can't assign to a field of a struct contained in map
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  entry.context,
-		}
+	mtype := metricSampleContext.GetMetricType()
+	context := &Context{
+		Name:       metricSampleContext.GetName(),
+		taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		noIndex:    metricSampleContext.IsNoIndex(),
+		source:     metricSampleContext.GetSource(),
+		originKey:  taggerKey,
 	}
+	cr.contextsByKey[contextKey] = resolverEntry{
+		lastSeen: timestamp,
+		context:  context,
+	}
+
+	cr.seendByMtype[mtype] = true
+	cr.countsByMtype[mtype]++
+	cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
+	cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
 
-	return contextKey
+	return contextKey, true
+}
+
+// refresh updates the last time a context was seen, it returns false if the context isn't tracked.
+func (cr *contextResolver) refresh(contextKey ckey.ContextKey, timestamp int64) bool {
+	entry, ok := cr.contextsByKey[contextKey]
+	if !ok {
+		return false
+	}
+	// We can't assign to a field of a struct contained in map
+	cr.contextsByKey[contextKey] = resolverEntry{
+		lastSeen: timestamp,
+		context:  entry.context,
+	}
+	return true
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -155,6 +193,7 @@ func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
+		cr.untrackLimit(context)
 		context.release()
 	}
 }
@@ -176,8 +215,16 @@ func (cr *contextResolver) updateMetrics(countsByMTypeGauge telemetry.Gauge, byt
 	}
 }
 
+// untrackLimit forgets a removed context in the limiter.
+func (cr *contextResolver) untrackLimit(context *Context) {
+	if cr.limiter != nil {
+		cr.limiter.untrack(context.Name, limitOrigin{check: cr.originCheck, tags: context.originKey}, context.metricTags.Tags())
+	}
+}
+
 func (cr *contextResolver) release() {
 	for _, c := range cr.contextsByKey {
+		cr.untrackLimit(c.context)
 		c.context.release()
 	}
 }
@@ -221,9 +268,9 @@ type timestampContextResolver struct {
 	counterExpireTime int64
 }
 
-func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, limiter *contextLimiter, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
 	return &timestampContextResolver{
-		resolver: newContextResolver(tagger, cache, id),
+		resolver: newContextResolver(tagger, cache, id, limiter),
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
@@ -231,9 +278,8 @@ func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id
 }
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, currentTimestamp)
-	return contextKey
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, currentTimestamp)
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -281,9 +327,11 @@ type countBasedContextResolver struct {
 	expireCountInterval int64
 }
 
-func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string) *countBasedContextResolver {
+func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string, limiter *contextLimiter) *countBasedContextResolver {
+	resolver := newContextResolver(tagger, cache, id, limiter)
+	resolver.originCheck = id
 	return &countBasedContextResolver{
-		resolver:            newContextResolver(tagger, cache, id),
+		resolver:            resolver,
 		expireCount:         0,
 		expireCountInterval: int64(expireCountInterval),
 	}
@@ -299,9 +347,8 @@ func (cr *countBasedContextResolver) updateMetrics(countsByMTypeGauge telemetry.
 }
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, cr.expireCount)
-	return contextKey
+func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, cr.expireCount)
 }
 
 func (cr *countBasedContextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -42,6 +42,7 @@ type DemultiplexerWithAggregator interface {
 	GetEventPlatformForwarder() (eventplatform.Forwarder, error)
 	GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck)
 	DumpDogstatsdContexts(io.Writer) error
+	ContextLimitOverflows() []ContextLimitOverflow
 }
 
 // AgentDemultiplexer is the demultiplexer implementation for the main Agent.
@@ -67,6 +68,9 @@ type AgentDemultiplexer struct {
 
 	hostTagProvider *HostTagProvider
 
+	// openMetrics exposes the metrics of the last flush, nil when disabled
+	openMetrics *openMetricsExporter
+
 	// sharded statsd time samplers
 	statsd
 }
@@ -115,6 +119,8 @@ type dataOutputs struct {
 	forwarders       forwarders
 	sharedSerializer serializer.MetricSerializer
 	noAggSerializer  serializer.MetricSerializer
+	// sinks written by both serializers
+	serializerSinks *serializer.Sinks
 }
 
 // InitAndStartAgentDemultiplexer creates a new Demultiplexer and runs what's necessary
@@ -154,7 +160,8 @@ func initAgentDemultiplexer(log log.Component,
 	// prepare the serializer
 	// ----------------------
 
-	sharedSerializer := serializer.NewSerializer(sharedForwarder, orchestratorForwarder, compressor, pkgconfigsetup.Datadog(), log, hostname)
+	serializerSinks := serializer.NewSinks(pkgconfigsetup.Datadog(), log)
+	sharedSerializer := serializer.NewSerializer(sharedForwarder, orchestratorForwarder, compressor, pkgconfigsetup.Datadog(), log, hostname, serializerSinks)
 
 	// prepare the embedded aggregator
 	// --
@@ -175,7 +182,7 @@ func initAgentDemultiplexer(log log.Component,
 		// the sampler
 		tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname)
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, agg.contextLimiter, tagger, agg.hostname)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -186,7 +193,7 @@ func initAgentDemultiplexer(log log.Component,
 	var noAggWorker *noAggregationStreamWorker
 	var noAggSerializer serializer.MetricSerializer
 	if options.EnableNoAggregationPipeline {
-		noAggSerializer = serializer.NewSerializer(sharedForwarder, orchestratorForwarder, compressor, pkgconfigsetup.Datadog(), log, hostname)
+		noAggSerializer = serializer.NewSerializer(sharedForwarder, orchestratorForwarder, compressor, pkgconfigsetup.Datadog(), log, hostname, serializerSinks)
 		noAggWorker = newNoAggregationStreamWorker(
 			pkgconfigsetup.Datadog().GetInt("dogstatsd_no_aggregation_pipeline_batch_size"),
 			metricSamplePool,
@@ -196,6 +203,11 @@ func initAgentDemultiplexer(log log.Component,
 		)
 	}
 
+	openMetrics, err := newOpenMetricsExporter(pkgconfigsetup.Datadog(), log)
+	if err != nil {
+		log.Errorf("Could not start the OpenMetrics endpoint: %v", err)
+	}
+
 	// --
 	demux := &AgentDemultiplexer{
 		log:       log,
@@ -212,10 +224,12 @@ func initAgentDemultiplexer(log log.Component,
 
 			sharedSerializer: sharedSerializer,
 			noAggSerializer:  noAggSerializer,
+			serializerSinks:  serializerSinks,
 		},
 
 		hostTagProvider: NewHostTagProvider(),
 		senders:         newSenders(agg),
+		openMetrics:     openMetrics,
 
 		// statsd time samplers
 		statsd: statsd{
@@ -287,6 +301,10 @@ func (d *AgentDemultiplexer) run() {
 		go d.noAggStreamWorker.run()
 	}
 
+	if d.openMetrics != nil {
+		go d.openMetrics.run()
+	}
+
 	d.flushLoop() // this is the blocking call
 }
 
@@ -357,6 +375,17 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 	}
 	d.aggregator = nil
 
+	if d.openMetrics != nil {
+		d.openMetrics.stop()
+	}
+
+	// serializers: their sinks are closed once nothing is flushed anymore
+
+	if d.dataOutputs.serializerSinks != nil {
+		d.dataOutputs.serializerSinks.Close()
+		d.dataOutputs.serializerSinks = nil
+	}
+
 	// forwarders
 
 	if !d.options.DontStartForwarders {
@@ -408,10 +437,22 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 
 	logPayloads := pkgconfigsetup.Datadog().GetBool("log_payloads")
 	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false, d.hostTagProvider)
+
+	// record the flushed metrics for the OpenMetrics endpoint
+	var omFlush *openMetricsFlush
+	if d.openMetrics != nil {
+		omFlush = &openMetricsFlush{}
+	}
+
 	metrics.Serialize(
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			if omFlush != nil {
+				seriesSink = openMetricsSerieSink{flush: omFlush, sink: seriesSink}
+				sketchesSink = openMetricsSketchesSink{flush: omFlush, sink: sketchesSink}
+			}
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -461,6 +502,10 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 			}
 		})
 
+	if omFlush != nil {
+		d.openMetrics.update(omFlush)
+	}
+
 	addFlushTime("MainFlushTime", int64(time.Since(start)))
 	aggregatorNumberOfFlush.Add(1)
 }
@@ -563,6 +608,21 @@ func (d *AgentDemultiplexer) DumpDogstatsdContexts(dest io.Writer) error {
 	return nil
 }
 
+// ContextLimitOverflows returns the metric names and origins which reached their context limit,
+// in the dogstatsd and check samplers, the worst offenders first.
+func (d *AgentDemultiplexer) ContextLimitOverflows() []ContextLimitOverflow {
+	d.m.RLock()
+	defer d.m.RUnlock()
+
+	if d.aggregator == nil {
+		return nil
+	}
+
+	overflows := d.aggregator.contextLimiter.report()
+	sortContextLimitOverflows(overflows)
+	return overflows
+}
+
 // GetSender returns a sender.Sender with passed ID, properly registered with the aggregator
 // If no error is returned here, DestroySender must be called with the same ID
 // once the sender is not used anymore
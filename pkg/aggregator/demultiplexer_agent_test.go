@@ -127,6 +127,20 @@ func TestDemuxNoAggOptionIsDisabledByDefault(t *testing.T) {
 	demux.Stop(false)
 }
 
+func TestDemuxContextLimitOverflowsAfterStop(t *testing.T) {
+	opts := demuxTestOptions()
+	deps := fxutil.Test[TestDeps](t,
+		defaultforwarder.MockModule(),
+		core.MockBundle(),
+		haagentmock.Module(),
+		logscompression.MockModule(),
+		metricscompression.MockModule())
+	demux := InitAndStartAgentDemultiplexerForTest(deps, opts, "")
+	demux.Stop(false)
+
+	require.Nil(t, demux.ContextLimitOverflows())
+}
+
 func TestMetricSampleTypeConversion(t *testing.T) {
 	require := require.New(t)
 
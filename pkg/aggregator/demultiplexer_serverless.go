@@ -29,10 +29,11 @@ type ServerlessDemultiplexer struct {
 	// shared metric sample pool between the dogstatsd server & the time sampler
 	metricSamplePool *metrics.MetricSamplePool
 
-	serializer    *serializer.Serializer
-	forwarder     *forwarder.SyncForwarder
-	statsdSampler *TimeSampler
-	statsdWorker  *timeSamplerWorker
+	serializer      *serializer.Serializer
+	serializerSinks *serializer.Sinks
+	forwarder       *forwarder.SyncForwarder
+	statsdSampler   *TimeSampler
+	statsdWorker    *timeSamplerWorker
 
 	flushLock *sync.Mutex
 
@@ -51,11 +52,12 @@ func InitAndStartServerlessDemultiplexer(keysPerDomain map[string][]utils.APIKey
 	h, _ := hostname.Get(context.Background())
 	config := pkgconfigsetup.Datadog()
 	config.SetWithoutSource("serializer_compressor_kind", "none")
-	serializer := serializer.NewSerializer(forwarder, nil, selector.FromConfig(config), pkgconfigsetup.Datadog(), logger, h)
+	serializerSinks := serializer.NewSinks(pkgconfigsetup.Datadog(), logger)
+	serializer := serializer.NewSerializer(forwarder, nil, selector.FromConfig(config), pkgconfigsetup.Datadog(), logger, h, serializerSinks)
 	metricSamplePool := metrics.NewMetricSamplePool(MetricSamplePoolBatchSize, utils.IsTelemetryEnabled(pkgconfigsetup.Datadog()))
 	tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), "timesampler")
 
-	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, tagger, "")
+	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, newContextLimiter(pkgconfigsetup.Datadog()), tagger, "")
 	flushAndSerializeInParallel := NewFlushAndSerializeInParallel(pkgconfigsetup.Datadog())
 	statsdWorker := newTimeSamplerWorker(statsdSampler, DefaultFlushInterval, bufferSize, metricSamplePool, flushAndSerializeInParallel, tagsStore)
 
@@ -65,6 +67,7 @@ func InitAndStartServerlessDemultiplexer(keysPerDomain map[string][]utils.APIKey
 		statsdSampler:               statsdSampler,
 		statsdWorker:                statsdWorker,
 		serializer:                  serializer,
+		serializerSinks:             serializerSinks,
 		metricSamplePool:            metricSamplePool,
 		flushLock:                   &sync.Mutex{},
 		hostTagProvider:             NewHostTagProvider(),
@@ -98,6 +101,7 @@ func (d *ServerlessDemultiplexer) Stop(flush bool) {
 	}
 
 	d.statsdWorker.stop()
+	d.serializerSinks.Close()
 
 	if d.forwarder != nil {
 		d.forwarder.Stop()
@@ -0,0 +1,317 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	openMetricsPath        = "/metrics"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	// openMetricsHostLabel is the label of the series hostname
+	openMetricsHostLabel = "host"
+)
+
+// openMetricsQuantiles are the quantiles of the sketches exposed as summaries
+var openMetricsQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// openMetricsExporter exposes the series and sketches of the last flush of the
+// demultiplexer in the OpenMetrics text format, as they were sent to the serializer.
+type openMetricsExporter struct {
+	log log.Component
+
+	mu       sync.RWMutex
+	series   []*metrics.Serie
+	sketches []*metrics.SketchSeries
+
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+// newOpenMetricsExporter returns the exporter listening on aggregator_openmetrics_endpoint.port,
+// or nil when it is disabled.
+func newOpenMetricsExporter(cfg model.Reader, log log.Component) (*openMetricsExporter, error) {
+	if !cfg.GetBool("aggregator_openmetrics_endpoint.enabled") {
+		return nil, nil
+	}
+
+	addr := net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), cfg.GetString("aggregator_openmetrics_endpoint.port"))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s: %v", addr, err)
+	}
+
+	e := &openMetricsExporter{
+		log:      log,
+		listener: listener,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(openMetricsPath, e.handle)
+	e.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return e, nil
+}
+
+func (e *openMetricsExporter) run() {
+	e.log.Infof("Exposing the flushed metrics in the OpenMetrics format on %s%s", e.listener.Addr(), openMetricsPath)
+	if err := e.httpServer.Serve(e.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		e.log.Errorf("OpenMetrics endpoint stopped: %v", err)
+	}
+}
+
+func (e *openMetricsExporter) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.httpServer.Shutdown(ctx); err != nil {
+		e.log.Warnf("Could not stop the OpenMetrics endpoint: %v", err)
+	}
+}
+
+// update replaces the exposed metrics with the ones recorded during a flush.
+func (e *openMetricsExporter) update(flush *openMetricsFlush) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.series = flush.series
+	e.sketches = flush.sketches
+}
+
+func (e *openMetricsExporter) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	w.Header().Set("Content-Type", openMetricsContentType)
+	if err := writeOpenMetrics(w, e.series, e.sketches); err != nil {
+		e.log.Debugf("Could not write the OpenMetrics payload: %v", err)
+	}
+}
+
+// openMetricsFlush records the series and sketches of a flush while they are appended
+// to the sinks of the serializer.
+type openMetricsFlush struct {
+	mu       sync.Mutex
+	series   []*metrics.Serie
+	sketches []*metrics.SketchSeries
+}
+
+type openMetricsSerieSink struct {
+	flush *openMetricsFlush
+	sink  metrics.SerieSink
+}
+
+// Append implements metrics.SerieSink
+func (s openMetricsSerieSink) Append(serie *metrics.Serie) {
+	s.flush.mu.Lock()
+	s.flush.series = append(s.flush.series, serie)
+	s.flush.mu.Unlock()
+	s.sink.Append(serie)
+}
+
+type openMetricsSketchesSink struct {
+	flush *openMetricsFlush
+	sink  metrics.SketchesSink
+}
+
+// Append implements metrics.SketchesSink
+func (s openMetricsSketchesSink) Append(sketch *metrics.SketchSeries) {
+	s.flush.mu.Lock()
+	s.flush.sketches = append(s.flush.sketches, sketch)
+	s.flush.mu.Unlock()
+	s.sink.Append(sketch)
+}
+
+// openMetricsFamily is the exposition of the metrics sharing a name
+type openMetricsFamily struct {
+	typ   string
+	lines []string
+}
+
+// writeOpenMetrics writes the series and sketches in the OpenMetrics text format: the
+// gauges and rates are exposed as gauges, the counts (which are reset on each flush) as
+// unknown metrics, and the sketches as summaries. Only the last point of each series is
+// exposed, with its timestamp.
+func writeOpenMetrics(w io.Writer, series []*metrics.Serie, sketches []*metrics.SketchSeries) error {
+	families := make(map[string]*openMetricsFamily)
+	family := func(name, typ string) *openMetricsFamily {
+		f, found := families[name]
+		if !found {
+			f = &openMetricsFamily{typ: typ}
+			families[name] = f
+		}
+		return f
+	}
+
+	for _, serie := range series {
+		if len(serie.Points) == 0 {
+			continue
+		}
+		name := openMetricsName(serie.Name)
+		typ := "gauge"
+		if serie.MType == metrics.APICountType {
+			typ = "unknown"
+		}
+		point := serie.Points[len(serie.Points)-1]
+		labels := openMetricsLabels(serie.Host, serie.Tags.UnsafeToReadOnlySliceString())
+		f := family(name, typ)
+		f.lines = append(f.lines, openMetricsLine(name, labels, "", point.Value, point.Ts))
+	}
+
+	config := quantile.Default()
+	for _, sketch := range sketches {
+		if len(sketch.Points) == 0 {
+			continue
+		}
+		name := openMetricsName(sketch.Name)
+		point := sketch.Points[len(sketch.Points)-1]
+		ts := float64(point.Ts)
+		labels := openMetricsLabels(sketch.Host, sketch.Tags.UnsafeToReadOnlySliceString())
+		f := family(name, "summary")
+		for _, q := range openMetricsQuantiles {
+			quantileLabel := `quantile="` + strconv.FormatFloat(q, 'g', -1, 64) + `"`
+			f.lines = append(f.lines, openMetricsLine(name, labels, quantileLabel, point.Sketch.Quantile(config, q), ts))
+		}
+		f.lines = append(f.lines,
+			openMetricsLine(name+"_sum", labels, "", point.Sketch.Basic.Sum, ts),
+			openMetricsLine(name+"_count", labels, "", float64(point.Sketch.Basic.Cnt), ts))
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		f := families[name]
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, f.typ)
+		for _, line := range f.lines {
+			bw.WriteString(line)
+		}
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+func openMetricsLine(name string, labels string, extraLabel string, value float64, ts float64) string {
+	var b strings.Builder
+	b.WriteString(name)
+	if labels != "" || extraLabel != "" {
+		b.WriteByte('{')
+		b.WriteString(labels)
+		if labels != "" && extraLabel != "" {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraLabel)
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(openMetricsValue(value))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(ts, 'f', -1, 64))
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func openMetricsValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// openMetricsLabels converts the host and the tags into sorted labels, the values of the
+// tags sharing a key are joined with commas and the tags without value have an empty value.
+func openMetricsLabels(host string, tags []string) string {
+	values := make(map[string][]string, len(tags)+1)
+	if host != "" {
+		values[openMetricsHostLabel] = []string{host}
+	}
+	for _, tag := range tags {
+		key, value, _ := strings.Cut(tag, ":")
+		key = openMetricsLabelName(key)
+		values[key] = append(values[key], value)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteString(`="`)
+		b.WriteString(openMetricsEscape(strings.Join(values[key], ",")))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// openMetricsName replaces the characters which aren't allowed in the metric names,
+// like the dots of the Datadog metric names, with underscores.
+func openMetricsName(name string) string {
+	return sanitizeOpenMetricsName(name, true)
+}
+
+func openMetricsLabelName(name string) string {
+	return sanitizeOpenMetricsName(name, false)
+}
+
+func sanitizeOpenMetricsName(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9') || (allowColon && c == ':')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func openMetricsEscape(value string) string {
+	return openMetricsEscaper.Replace(value)
+}
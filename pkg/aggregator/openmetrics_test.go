@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestWriteOpenMetricsSeries(t *testing.T) {
+	series := []*metrics.Serie{
+		{
+			Name:   "system.load.1",
+			Points: []metrics.Point{{Ts: 1700000000, Value: 0.5}, {Ts: 1700000015, Value: 0.75}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:web", "role:db"}),
+			Host:   "myhost",
+			MType:  metrics.APIGaugeType,
+		},
+		{
+			Name:   "http.requests",
+			Points: []metrics.Point{{Ts: 1700000010, Value: 12}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"path:/a\"b", "canary"}),
+			MType:  metrics.APICountType,
+		},
+		{
+			Name:  "empty",
+			MType: metrics.APIGaugeType,
+		},
+	}
+
+	var b strings.Builder
+	require.NoError(t, writeOpenMetrics(&b, series, nil))
+	assert.Equal(t, `# TYPE http_requests unknown
+http_requests{canary="",path="/a\"b"} 12 1700000010
+# TYPE system_load_1 gauge
+system_load_1{env="prod",host="myhost",role="web,db"} 0.75 1700000015
+# EOF
+`, b.String())
+}
+
+func TestWriteOpenMetricsSketches(t *testing.T) {
+	var agent quantile.Agent
+	for i := 1; i <= 10; i++ {
+		agent.Insert(float64(i), 1)
+	}
+	sketches := []*metrics.SketchSeries{{
+		Name:   "request.duration",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Points: []metrics.SketchPoint{{Sketch: agent.Finish(), Ts: 1700000000}},
+	}}
+
+	var b strings.Builder
+	require.NoError(t, writeOpenMetrics(&b, nil, sketches))
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	require.Len(t, lines, 8)
+	assert.Equal(t, "# TYPE request_duration summary", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], `request_duration{env="prod",quantile="0.5"} `))
+	assert.True(t, strings.HasPrefix(lines[4], `request_duration{env="prod",quantile="0.99"} `))
+	assert.Equal(t, `request_duration_sum{env="prod"} 55 1700000000`, lines[5])
+	assert.Equal(t, `request_duration_count{env="prod"} 10 1700000000`, lines[6])
+	assert.Equal(t, "# EOF", lines[7])
+}
+
+func TestOpenMetricsName(t *testing.T) {
+	assert.Equal(t, "datadog_agent_running", openMetricsName("datadog.agent.running"))
+	assert.Equal(t, "_xx:yy_z", openMetricsName("9xx:yy-z"))
+	assert.Equal(t, "kube_pod_label_app_kubernetes_io", openMetricsLabelName("kube_pod_label_app.kubernetes:io"))
+}
+
+func TestOpenMetricsExporter(t *testing.T) {
+	e := &openMetricsExporter{}
+
+	// the recording sinks forward the metrics to the serializer sinks
+	flush := &openMetricsFlush{}
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	serie := &metrics.Serie{Name: "foo", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIRateType}
+	openMetricsSerieSink{flush: flush, sink: &series}.Append(serie)
+	openMetricsSketchesSink{flush: flush, sink: &sketches}.Append(&metrics.SketchSeries{Name: "bar"})
+	assert.Equal(t, metrics.Series{serie}, series)
+	assert.Len(t, sketches, 1)
+	e.update(flush)
+
+	recorder := httptest.NewRecorder()
+	e.handle(recorder, httptest.NewRequest(http.MethodGet, openMetricsPath, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, openMetricsContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE foo gauge\nfoo 1 10\n# EOF\n", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	e.handle(recorder, httptest.NewRequest(http.MethodPost, openMetricsPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
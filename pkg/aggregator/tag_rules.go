@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+const (
+	tagRuleMatchTypeWildcard = "wildcard"
+	tagRuleMatchTypeRegex    = "regex"
+
+	// tagRulesCacheSize is the number of metric names whose matching rule is cached,
+	// the cache is cleared when it is full.
+	tagRulesCacheSize = 10000
+)
+
+// TagRuleConfig is the configuration of a rule removing tags from the dogstatsd metrics
+// matching a pattern, loaded from aggregator_tag_rules.
+type TagRuleConfig struct {
+	// Match is the pattern of the metric names, "*" matches any sequence of characters
+	// unless MatchType is "regex"
+	Match     string `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType string `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	// DropTags are the keys of the tags removed from the metrics
+	DropTags []string `mapstructure:"drop_tags" json:"drop_tags" yaml:"drop_tags"`
+	// KeepTags, when set, are the keys of the only tags kept on the metrics
+	KeepTags []string `mapstructure:"keep_tags" json:"keep_tags" yaml:"keep_tags"`
+}
+
+type tagRule struct {
+	regex *regexp.Regexp
+	drop  map[string]struct{}
+	keep  map[string]struct{}
+}
+
+// tagRules removes tags from the metrics before their context is generated, so that the
+// series only differing by the removed tags are aggregated together: counts are summed,
+// distributions are merged into the same sketch, the last value of a gauge is kept.
+//
+// The first rule matching a metric name is applied. tagRules is not thread-safe.
+type tagRules struct {
+	rules []*tagRule
+	cache map[string]*tagRule
+}
+
+// newTagRulesFromConfig returns the tag rules configured in aggregator_tag_rules, or nil
+// when no rule is configured.
+func newTagRulesFromConfig(cfg model.Reader) (*tagRules, error) {
+	if !cfg.IsSet("aggregator_tag_rules") {
+		return nil, nil
+	}
+	var configs []TagRuleConfig
+	if err := structure.UnmarshalKey(cfg, "aggregator_tag_rules", &configs); err != nil {
+		return nil, fmt.Errorf("could not parse aggregator_tag_rules: %v", err)
+	}
+	return newTagRules(configs)
+}
+
+// newTagRules validates and compiles the tag rules, it returns nil when there is no rule.
+func newTagRules(configs []TagRuleConfig) (*tagRules, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	rules := make([]*tagRule, 0, len(configs))
+	for i, config := range configs {
+		if config.Match == "" {
+			return nil, fmt.Errorf("tag rule %d: match is required", i)
+		}
+		if len(config.DropTags) == 0 && len(config.KeepTags) == 0 {
+			return nil, fmt.Errorf("tag rule %d: drop_tags or keep_tags is required", i)
+		}
+
+		pattern := config.Match
+		switch config.MatchType {
+		case "", tagRuleMatchTypeWildcard:
+			pattern = strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+		case tagRuleMatchTypeRegex:
+		default:
+			return nil, fmt.Errorf("tag rule %d: invalid match type, must be `wildcard` or `regex`", i)
+		}
+		regex, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			return nil, fmt.Errorf("tag rule %d: invalid match `%s`: %v", i, config.Match, err)
+		}
+
+		rule := &tagRule{regex: regex}
+		if len(config.DropTags) > 0 {
+			rule.drop = toSet(config.DropTags)
+		}
+		if len(config.KeepTags) > 0 {
+			rule.keep = toSet(config.KeepTags)
+		}
+		rules = append(rules, rule)
+	}
+
+	return &tagRules{
+		rules: rules,
+		cache: make(map[string]*tagRule),
+	}, nil
+}
+
+func toSet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+// match returns the first rule matching the metric name, or nil.
+func (r *tagRules) match(name string) *tagRule {
+	if rule, found := r.cache[name]; found {
+		return rule
+	}
+	var matching *tagRule
+	for _, rule := range r.rules {
+		if rule.regex.MatchString(name) {
+			matching = rule
+			break
+		}
+	}
+	if len(r.cache) >= tagRulesCacheSize {
+		clear(r.cache)
+	}
+	r.cache[name] = matching
+	return matching
+}
+
+// apply removes the tags of the metric from the buffers according to the rule matching its name.
+func (r *tagRules) apply(name string, buffers ...*tagset.HashingTagsAccumulator) {
+	rule := r.match(name)
+	if rule == nil {
+		return
+	}
+	for _, buffer := range buffers {
+		rule.filter(buffer)
+	}
+}
+
+// filter removes the dropped tags and the tags which aren't kept from the buffer, in place.
+func (r *tagRule) filter(buffer *tagset.HashingTagsAccumulator) {
+	kept := 0
+	for i, tag := range buffer.Get() {
+		if r.keeps(tag) {
+			buffer.Swap(kept, i)
+			kept++
+		}
+	}
+	buffer.Truncate(kept)
+}
+
+func (r *tagRule) keeps(tag string) bool {
+	key, _, _ := strings.Cut(tag, ":")
+	if _, found := r.drop[key]; found {
+		return false
+	}
+	if r.keep != nil {
+		_, found := r.keep[key]
+		return found
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestNewTagRules(t *testing.T) {
+	rules, err := newTagRules(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+
+	for name, config := range map[string]TagRuleConfig{
+		"missing match":      {DropTags: []string{"a"}},
+		"missing tags":       {Match: "foo"},
+		"invalid match type": {Match: "foo", MatchType: "glob", DropTags: []string{"a"}},
+		"invalid regex":      {Match: "foo(", MatchType: "regex", DropTags: []string{"a"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTagRules([]TagRuleConfig{config})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTagRulesMatch(t *testing.T) {
+	rules, err := newTagRules([]TagRuleConfig{
+		{Match: "http.*", DropTags: []string{"a"}},
+		{Match: `^db\.(query|rows)$`, MatchType: "regex", DropTags: []string{"b"}},
+		{Match: "*", KeepTags: []string{"c"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, rules.rules[0], rules.match("http.request.duration"))
+	assert.Equal(t, rules.rules[1], rules.match("db.query"))
+	assert.Equal(t, rules.rules[2], rules.match("db.query.count"))
+	assert.Equal(t, rules.rules[2], rules.match("httpd"))
+	// the matching rules are cached
+	assert.Len(t, rules.cache, 4)
+	assert.Equal(t, rules.rules[0], rules.match("http.request.duration"))
+}
+
+func TestTagRulesApply(t *testing.T) {
+	rules, err := newTagRules([]TagRuleConfig{
+		{Match: "drop", DropTags: []string{"request_id", "flag"}},
+		{Match: "keep", KeepTags: []string{"env", "service"}},
+		{Match: "both", DropTags: []string{"env"}, KeepTags: []string{"env", "service"}},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		tags     []string
+		expected []string
+	}{
+		{"drop", []string{"env:prod", "request_id:1", "flag", "service:web"}, []string{"env:prod", "service:web"}},
+		{"keep", []string{"request_id:1", "env:prod", "version:2", "service:web"}, []string{"env:prod", "service:web"}},
+		{"both", []string{"env:prod", "service:web", "version:2"}, []string{"service:web"}},
+		{"other", []string{"env:prod", "request_id:1"}, []string{"env:prod", "request_id:1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			taggerBuffer := tagset.NewHashingTagsAccumulatorWithTags([]string{"pod_name:a", "env:staging"})
+			metricBuffer := tagset.NewHashingTagsAccumulatorWithTags(tc.tags)
+			rules.apply(tc.name, taggerBuffer, metricBuffer)
+
+			assert.ElementsMatch(t, tc.expected, metricBuffer.Get())
+			// the hashes still match the tags
+			assert.ElementsMatch(t, tagset.NewHashingTagsAccumulatorWithTags(metricBuffer.Get()).Hashes(), metricBuffer.Hashes())
+		})
+	}
+}
+
+func testTagRulesAggregation(t *testing.T, store *tags.Store) {
+	configmock.NewFromYAML(t, `
+aggregator_tag_rules:
+  - match: "http.*"
+    drop_tags: ["request_id"]
+`)
+	sampler := testTimeSampler(store)
+
+	for _, requestID := range []string{"1", "2", "3"} {
+		sampler.sample(&metrics.MetricSample{
+			Name:       "http.requests",
+			Value:      1,
+			Mtype:      metrics.CountType,
+			Tags:       []string{"env:prod", "request_id:" + requestID},
+			SampleRate: 1,
+		}, 12345.0)
+		sampler.sample(&metrics.MetricSample{
+			Name:       "http.duration",
+			Value:      10,
+			Mtype:      metrics.DistributionType,
+			Tags:       []string{"env:prod", "request_id:" + requestID},
+			SampleRate: 1,
+		}, 12345.0)
+	}
+
+	series, sketches := flushSerie(sampler, 12360.0)
+	require.Len(t, series, 1)
+	assert.Equal(t, "http.requests", series[0].Name)
+	metrics.AssertCompositeTagsEqual(t, tagset.CompositeTagsFromSlice([]string{"env:prod"}), series[0].Tags)
+	require.Len(t, series[0].Points, 1)
+	assert.Equal(t, 3.0, series[0].Points[0].Value)
+
+	require.Len(t, sketches, 1)
+	assert.Equal(t, "http.duration", sketches[0].Name)
+	metrics.AssertCompositeTagsEqual(t, tagset.CompositeTagsFromSlice([]string{"env:prod"}), sketches[0].Tags)
+	require.Len(t, sketches[0].Points, 1)
+	assert.Equal(t, int64(3), sketches[0].Points[0].Sketch.Basic.Cnt)
+}
+
+func TestTagRulesAggregation(t *testing.T) {
+	testWithTagsStore(t, testTagRulesAggregation)
+}
@@ -50,6 +50,7 @@ func NewLogReporter(hostname string, sourceName, sourceType string, endpoints *c
 		auditor,
 		&diagnostic.NoopMessageReceiver{},
 		nil, // processingRules
+		nil, // metricSender
 		endpoints,
 		dstcontext,
 		&common.NoopStatusProvider{},
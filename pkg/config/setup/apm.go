@@ -137,6 +137,18 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.probabilistic_sampler.sampling_percentage", "DD_APM_PROBABILISTIC_SAMPLER_SAMPLING_PERCENTAGE")
 	config.BindEnv("apm_config.probabilistic_sampler.hash_seed", "DD_APM_PROBABILISTIC_SAMPLER_HASH_SEED")
 	config.BindEnvAndSetDefault("apm_config.error_tracking_standalone.enabled", false, "DD_APM_ERROR_TRACKING_STANDALONE_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.max_buffer_size", "DD_APM_TAIL_SAMPLING_MAX_BUFFER_SIZE")
+	config.BindEnv("apm_config.tail_sampling.policies.errors", "DD_APM_TAIL_SAMPLING_POLICIES_ERRORS")
+	config.BindEnv("apm_config.tail_sampling.policies.latency_threshold", "DD_APM_TAIL_SAMPLING_POLICIES_LATENCY_THRESHOLD")
+	config.BindEnv("apm_config.tail_sampling.policies.span_tags", "DD_APM_TAIL_SAMPLING_POLICIES_SPAN_TAGS")
+	config.BindEnv("apm_config.tail_sampling.policies.rare_resources.enabled", "DD_APM_TAIL_SAMPLING_POLICIES_RARE_RESOURCES_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.policies.rare_resources.cooldown", "DD_APM_TAIL_SAMPLING_POLICIES_RARE_RESOURCES_COOLDOWN")
+	config.BindEnv("apm_config.tail_sampling.policies.rare_resources.cardinality", "DD_APM_TAIL_SAMPLING_POLICIES_RARE_RESOURCES_CARDINALITY")
+	config.BindEnv("apm_config.trace_store.enabled", "DD_APM_TRACE_STORE_ENABLED")
+	config.BindEnv("apm_config.trace_store.retention", "DD_APM_TRACE_STORE_RETENTION")
+	config.BindEnv("apm_config.trace_store.max_traces", "DD_APM_TRACE_STORE_MAX_TRACES")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -150,6 +162,7 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.profiling_additional_endpoints", "DD_APM_PROFILING_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.additional_endpoints", "DD_APM_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.replace_tags", "DD_APM_REPLACE_TAGS")
+	config.BindEnv("apm_config.span_processors", "DD_APM_SPAN_PROCESSORS")
 	config.BindEnv("apm_config.analyzed_spans", "DD_APM_ANALYZED_SPANS")
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")
 	config.BindEnv("apm_config.instrumentation.targets", "DD_APM_INSTRUMENTATION_TARGETS")
@@ -161,6 +174,8 @@ func setupAPM(config pkgconfigmodel.Setup) {
 		return mappings
 	})
 	config.BindEnv("apm_config.receiver_socket", "DD_APM_RECEIVER_SOCKET")
+	config.BindEnv("apm_config.zipkin_receiver.enabled", "DD_APM_ZIPKIN_RECEIVER_ENABLED")
+	config.BindEnv("apm_config.jaeger_receiver.enabled", "DD_APM_JAEGER_RECEIVER_ENABLED")
 	config.BindEnv("apm_config.windows_pipe_name", "DD_APM_WINDOWS_PIPE_NAME")
 	config.BindEnv("apm_config.sync_flushing", "DD_APM_SYNC_FLUSHING")
 	config.BindEnv("apm_config.filter_tags.require", "DD_APM_FILTER_TAGS_REQUIRE")
@@ -226,6 +241,13 @@ func setupAPM(config pkgconfigmodel.Setup) {
 		}
 		return out
 	})
+	config.ParseEnvAsSliceMapString("apm_config.span_processors", func(in string) []map[string]string {
+		var out []map[string]string
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.span_processors" can not be parsed: %v`, err)
+		}
+		return out
+	})
 
 	config.ParseEnvAsMapStringInterface("apm_config.analyzed_spans", func(in string) map[string]interface{} {
 		out, err := parseAnalyzedSpans(in)
@@ -243,6 +265,16 @@ func setupAPM(config pkgconfigmodel.Setup) {
 		}
 		return out
 	})
+
+	config.BindEnv("apm_config.stats_extra_dimensions", "DD_APM_STATS_EXTRA_DIMENSIONS")
+	config.ParseEnvAsStringSlice("apm_config.stats_extra_dimensions", func(in string) []string {
+		var out []string
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.stats_extra_dimensions" can not be parsed: %v`, err)
+		}
+		return out
+	})
+	config.BindEnv("apm_config.stats_extra_dimensions_max_cardinality", "DD_APM_STATS_EXTRA_DIMENSIONS_MAX_CARDINALITY")
 }
 
 func parseKVList(key string) func(string) []string {
@@ -1358,6 +1358,22 @@ func serializer(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("enable_payloads.service_checks", true)
 	config.BindEnvAndSetDefault("enable_payloads.sketches", true)
 	config.BindEnvAndSetDefault("enable_payloads.json_to_v1_intake", true)
+
+	// Serializer: write series, sketches, events and service checks to other outputs than the forwarder
+	config.BindEnvAndSetDefault("serializer_sinks.forward_to_intake", true)
+	config.BindEnvAndSetDefault("serializer_sinks.file.enabled", false)
+	config.BindEnvAndSetDefault("serializer_sinks.file.path", "")
+	config.BindEnvAndSetDefault("serializer_sinks.file.max_size", 100*megaByte)
+	config.BindEnvAndSetDefault("serializer_sinks.file.max_rotated_files", 5)
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.enabled", false)
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.broker", "")
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.topic", "datadog-agent")
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.partition", 0)
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.client_id", "datadog-agent")
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.required_acks", 1)
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.timeout", 10*time.Second)
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.max_batch_size", megaByte)
+	config.BindEnvAndSetDefault("serializer_sinks.kafka.queue_size", 100)
 }
 
 func aggregator(config pkgconfigmodel.Setup) {
@@ -1407,6 +1423,15 @@ func forwarder(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
 
+	// Forwarder retry queue priority classes: each class has its own share of the memory and disk budgets
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.enabled", false)
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.critical.max_mem_ratio", 0.2)
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.critical.max_disk_ratio", 0.2)
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.standard.max_mem_ratio", 0.3)
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.standard.max_disk_ratio", 0.3)
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.bulk.max_mem_ratio", 0.5)
+	config.BindEnvAndSetDefault("forwarder_retry_queue_priority_classes.bulk.max_disk_ratio", 0.5)
+
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_low_prio_buffer_size", 100)
@@ -1436,6 +1461,13 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", defaultStatsdSocket) // Only enabled on unix systems
 	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "")           // Experimental || Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)                 // Notice: 0 means TCP port closed
+	config.BindEnvAndSetDefault("dogstatsd_http_port", 0)                // Notice: 0 means HTTP port closed
+	config.BindEnvAndSetDefault("dogstatsd_http_max_request_size", 4*1024*1024)
+	// Acknowledge the packets of the stream socket to let the clients know when the workers fall behind
+	config.BindEnvAndSetDefault("dogstatsd_stream_flow_control.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_stream_flow_control.high_watermark", 0.8)
+	config.BindEnvAndSetDefault("dogstatsd_stream_flow_control.ack_timeout", 100*time.Millisecond)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
@@ -1451,6 +1483,23 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Limit the number of active contexts per metric name and per origin (container, pod, check), 0 means unlimited.
+	config.BindEnvAndSetDefault("aggregator_context_limits.per_metric", 0)
+	config.BindEnvAndSetDefault("aggregator_context_limits.per_origin", 0)
+	// Handle the new contexts over the limits: "drop" them or "fold" the value of their tag with the most values into "<tag>:overflow".
+	config.BindEnvAndSetDefault("aggregator_context_limits.overflow", "drop")
+	// Expose the series and sketches of the last flush in the OpenMetrics format.
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_openmetrics_endpoint.port", 5015)
+	// Rules removing tags from the dogstatsd metrics, by metric name pattern, before aggregation.
+	config.BindEnv("aggregator_tag_rules")
+	config.ParseEnvAsSlice("aggregator_tag_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"aggregator_tag_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -1474,6 +1523,14 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_no_aggregation_pipeline_batch_size", 2048)
 	// Force the amount of dogstatsd workers (mainly used for benchmarks or some very specific use-case)
 	config.BindEnvAndSetDefault("dogstatsd_workers_count", 0)
+	// Receive Prometheus remote-write requests on an HTTP listener and aggregate their samples as dogstatsd metrics.
+	config.BindEnvAndSetDefault("dogstatsd_remote_write.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_remote_write.port", 8128)
+	config.BindEnvAndSetDefault("dogstatsd_remote_write.max_request_size", 32*1024*1024)
+	// Maximum size of a remote-write request once decompressed, the larger requests are rejected before being decompressed.
+	config.BindEnvAndSetDefault("dogstatsd_remote_write.max_decoded_request_size", 128*1024*1024)
+	// How long the last value of a counter or histogram is kept when the series isn't received anymore.
+	config.BindEnvAndSetDefault("dogstatsd_remote_write.state_expiry", 10*time.Minute)
 
 	// To enable the following feature, GODEBUG must contain `madvdontneed=1`
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.enabled", false)
@@ -1508,6 +1565,15 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnv("histogram_overrides")
+	config.ParseEnvAsSlice("histogram_overrides", func(in string) []interface{} {
+		var overrides []interface{}
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"histogram_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
+	config.BindEnvAndSetDefault("dogstatsd_histogram_to_distribution", []string{})
 }
 
 func logsagent(config pkgconfigmodel.Setup) {
@@ -1547,6 +1613,9 @@ func logsagent(config pkgconfigmodel.Setup) {
 	}
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules")
+	// Receive processing rules through Remote Configuration, they replace the global and per-source
+	// processing rules at runtime without restarting the tailers.
+	config.BindEnvAndSetDefault("logs_config.remote_processing_rules.enabled", false)
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
 	// Tail a container's logs by querying the kubelet's API
@@ -1626,6 +1695,16 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_timeout", 30) // Seconds
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_default_match_threshold", 0.48)
 
+	// Squash the consecutive repetitions of a log into a single log annotated with the number of repetitions.
+	// With the "exact" match, the logs must be identical, with the "shape" match, they only need to have
+	// the same structure (as computed by the auto multiline tokenizer). A group of repetitions spans at most
+	// window seconds.
+	config.BindEnvAndSetDefault("logs_config.squash_repeated_logs.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.squash_repeated_logs.match", "exact")
+	config.BindEnvAndSetDefault("logs_config.squash_repeated_logs.window", 10)
+	config.BindEnvAndSetDefault("logs_config.squash_repeated_logs.shape_max_input_bytes", 200)
+	config.BindEnvAndSetDefault("logs_config.squash_repeated_logs.shape_match_threshold", 0.9)
+
 	// Add a tag to logs that are multiline aggregated
 	config.BindEnvAndSetDefault("logs_config.tag_multi_line_logs", false)
 	// Add a tag to logs that are truncated by the agent
@@ -1635,6 +1714,12 @@ func logsagent(config pkgconfigmodel.Setup) {
 	logsPipelines := min(4, runtime.GOMAXPROCS(0))
 	config.BindEnvAndSetDefault("logs_config.pipelines", logsPipelines)
 
+	// If enabled, the payloads which can't be sent because all the reliable endpoints are unavailable are
+	// stored on disk, up to max_size_bytes, and sent once the endpoints recover. Defaults to <run_path>/spool/logs_config.
+	config.BindEnvAndSetDefault("logs_config.disk_spool.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.disk_spool.path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_spool.max_size_bytes", int64(1024*1024*1024))
+
 	// If true, the agent looks for container logs in the location used by podman, rather
 	// than docker.  This is a temporary configuration parameter to support podman logs until
 	// a more substantial refactor of autodiscovery is made to determine this automatically.
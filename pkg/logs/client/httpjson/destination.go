@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package httpjson implements a destination posting logs to an arbitrary HTTP
+// endpoint, with a JSON body built from a template.
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+var (
+	errClient  = errors.New("client error")
+	errServer  = errors.New("server error")
+	tlmSend    = telemetry.NewCounter("logs_client_httpjson_destination", "send", []string{"endpoint_host", "error"}, "Payloads sent")
+	tlmDropped = telemetry.NewCounter("logs_client_httpjson_destination", "payloads_dropped", []string{"endpoint_host"}, "Number of payloads dropped because of unrecoverable errors")
+)
+
+// templateData is the data the body template is executed on.
+type templateData struct {
+	Logs []client.DecodedLog
+}
+
+// Destination posts the logs of the payloads to an HTTP endpoint.
+type Destination struct {
+	*client.RetryingSender
+
+	url      string
+	host     string
+	headers  map[string]string
+	body     *template.Template
+	client   *http.Client
+	isMRF    bool
+	destMeta *client.DestinationMetadata
+}
+
+// NewDestination returns a new Destination.
+func NewDestination(endpoint config.Endpoint,
+	destinationsContext *client.DestinationsContext,
+	shouldRetry bool,
+	destMeta *client.DestinationMetadata,
+	cfg pkgconfigmodel.Reader) (*Destination, error) {
+
+	body, err := config.ParseBodyTemplate(endpoint.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := backoff.NewExpBackoffPolicy(
+		endpoint.BackoffFactor,
+		endpoint.BackoffBase,
+		endpoint.BackoffMax,
+		endpoint.RecoveryInterval,
+		endpoint.RecoveryReset,
+	)
+
+	d := &Destination{
+		url:     buildURL(endpoint),
+		host:    endpoint.Host,
+		headers: endpoint.Headers,
+		body:    body,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			// reusing core agent HTTP transport to benefit from proxy settings.
+			Transport: httputils.CreateHTTPTransport(cfg),
+		},
+		isMRF:    endpoint.IsMRF,
+		destMeta: destMeta,
+	}
+	d.RetryingSender = client.NewRetryingSender(d.url, d.send, destinationsContext, shouldRetry, policy)
+	return d, nil
+}
+
+// IsMRF indicates that this destination is a Multi-Region Failover destination.
+func (d *Destination) IsMRF() bool {
+	return d.isMRF
+}
+
+// Target is the address of the destination.
+func (d *Destination) Target() string {
+	return d.url
+}
+
+// Metadata returns the metadata of the destination
+func (d *Destination) Metadata() *client.DestinationMetadata {
+	return d.destMeta
+}
+
+func (d *Destination) send(ctx context.Context, payload *message.Payload) (err error) {
+	defer func() {
+		tlmSend.Inc(d.host, errorToTag(err))
+	}()
+
+	body, err := d.buildBody(payload)
+	if err != nil {
+		// the payload can't be converted, sending it again won't help.
+		tlmDropped.Inc(d.host)
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+	for key, value := range d.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return ctx.Err()
+		}
+		// most likely a network or a connect error, the callee should retry.
+		return client.NewRetryableError(err)
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		log.Warnf("failed to post http json payload. code=%d url=%s response=%s", resp.StatusCode, d.url, string(response))
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return client.NewRetryableError(errServer)
+	} else if resp.StatusCode >= http.StatusBadRequest {
+		tlmDropped.Inc(d.host)
+		return errClient
+	}
+	metrics.EncodedBytesSent.Add(int64(len(body)))
+	return nil
+}
+
+// buildBody renders the body template with the logs of the payload.
+func (d *Destination) buildBody(payload *message.Payload) ([]byte, error) {
+	logs, err := client.DecodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	if err := d.body.Execute(&body, templateData{Logs: logs}); err != nil {
+		return nil, fmt.Errorf("could not execute body template: %v", err)
+	}
+	return body.Bytes(), nil
+}
+
+// buildURL builds the url of an endpoint.
+func buildURL(endpoint config.Endpoint) string {
+	scheme := "http"
+	if endpoint.UseSSL() {
+		scheme = "https"
+	}
+	address := endpoint.Host
+	if endpoint.Port != 0 {
+		address = fmt.Sprintf("%v:%v", endpoint.Host, endpoint.Port)
+	}
+	url := url.URL{
+		Scheme: scheme,
+		Host:   address,
+		Path:   endpoint.Path,
+	}
+	return url.String()
+}
+
+func errorToTag(err error) string {
+	if err == nil {
+		return "none"
+	} else if _, ok := err.(*client.RetryableError); ok {
+		return "retryable"
+	}
+	return "non-retryable"
+}
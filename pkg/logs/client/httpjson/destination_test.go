@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package httpjson
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const encodedLogs = `[{"message":"hello","status":"info","timestamp":1700000000000,"hostname":"host","service":"web","ddsource":"nginx","ddtags":"env:prod"}]`
+
+type request struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+func newTestDestination(t *testing.T, statusCode int, bodyTemplate string) (*Destination, chan request) {
+	requests := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{path: r.URL.Path, header: r.Header, body: string(body)}
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	endpoint := config.NewEndpoint("", "", serverURL.Hostname(), port, false)
+	endpoint.Type = config.HTTPJSONEndpointType
+	endpoint.Path = "/ingest"
+	endpoint.Headers = map[string]string{"Authorization": "Bearer token"}
+	endpoint.BodyTemplate = bodyTemplate
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	t.Cleanup(destinationsCtx.Stop)
+
+	destination, err := NewDestination(endpoint, destinationsCtx, false, client.NewNoopDestinationMetadata(), configmock.New(t))
+	require.NoError(t, err)
+	return destination, requests
+}
+
+func TestDestinationPostsDefaultBody(t *testing.T) {
+	destination, requests := newTestDestination(t, http.StatusOK, "")
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	destination.Start(input, output, nil)
+
+	payload := &message.Payload{Encoded: []byte(encodedLogs)}
+	input <- payload
+	assert.Equal(t, payload, <-output)
+
+	req := <-requests
+	assert.Equal(t, "/ingest", req.path)
+	assert.Equal(t, "Bearer token", req.header.Get("Authorization"))
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.JSONEq(t, encodedLogs, req.body)
+}
+
+func TestDestinationPostsTemplatedBody(t *testing.T) {
+	destination, requests := newTestDestination(t, http.StatusOK, `{"entries":[{{range $i, $l := .Logs}}{{if $i}},{{end}}{"line":{{json $l.Message}},"host":{{json $l.Hostname}}}{{end}}]}`)
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	destination.Start(input, output, nil)
+
+	input <- &message.Payload{Encoded: []byte(encodedLogs)}
+	<-output
+
+	req := <-requests
+	assert.JSONEq(t, `{"entries":[{"line":"hello","host":"host"}]}`, req.body)
+}
+
+func TestDestinationDropsPayloadOnClientError(t *testing.T) {
+	destination, requests := newTestDestination(t, http.StatusBadRequest, "")
+
+	err := destination.send(context.Background(), &message.Payload{Encoded: []byte(encodedLogs)})
+	assert.Equal(t, errClient, err)
+	<-requests
+}
+
+func TestDestinationRetriesOnServerError(t *testing.T) {
+	destination, requests := newTestDestination(t, http.StatusServiceUnavailable, "")
+
+	err := destination.send(context.Background(), &message.Payload{Encoded: []byte(encodedLogs)})
+	assert.IsType(t, &client.RetryableError{}, err)
+	<-requests
+}
+
+func TestInvalidBodyTemplate(t *testing.T) {
+	endpoint := config.NewEndpoint("", "", "localhost", 8080, false)
+	endpoint.BodyTemplate = "{{ .Logs"
+	_, err := NewDestination(endpoint, client.NewDestinationsContext(), false, client.NewNoopDestinationMetadata(), configmock.New(t))
+	assert.Error(t, err)
+}
@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package otlp implements a destination exporting logs to an OTLP/gRPC logs endpoint.
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	defaultPort   = 4317
+	exportTimeout = 10 * time.Second
+	scopeName     = "datadog-agent/logs"
+)
+
+var (
+	tlmSend    = telemetry.NewCounter("logs_client_otlp_destination", "send", []string{"endpoint_host", "error"}, "Payloads sent")
+	tlmDropped = telemetry.NewCounter("logs_client_otlp_destination", "payloads_dropped", []string{"endpoint_host"}, "Number of payloads dropped because of unrecoverable errors")
+)
+
+// severities maps the statuses of the logs to OTLP severity numbers.
+var severities = map[string]plog.SeverityNumber{
+	message.StatusEmergency: plog.SeverityNumberFatal4,
+	message.StatusAlert:     plog.SeverityNumberFatal2,
+	message.StatusCritical:  plog.SeverityNumberFatal,
+	message.StatusError:     plog.SeverityNumberError,
+	message.StatusWarning:   plog.SeverityNumberWarn,
+	message.StatusNotice:    plog.SeverityNumberInfo2,
+	message.StatusInfo:      plog.SeverityNumberInfo,
+	message.StatusDebug:     plog.SeverityNumberDebug,
+}
+
+// Destination exports the logs of the payloads to an OTLP/gRPC logs endpoint.
+type Destination struct {
+	*client.RetryingSender
+
+	target   string
+	host     string
+	headers  metadata.MD
+	conn     *grpc.ClientConn
+	client   plogotlp.GRPCClient
+	isMRF    bool
+	destMeta *client.DestinationMetadata
+}
+
+// NewDestination returns a new Destination, the connection is established on the first export.
+func NewDestination(endpoint config.Endpoint,
+	destinationsContext *client.DestinationsContext,
+	shouldRetry bool,
+	destMeta *client.DestinationMetadata) (*Destination, error) {
+
+	port := endpoint.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	target := fmt.Sprintf("%s:%d", endpoint.Host, port)
+
+	creds := insecure.NewCredentials()
+	if endpoint.UseSSL() {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP client for %s: %v", target, err)
+	}
+
+	policy := backoff.NewExpBackoffPolicy(
+		endpoint.BackoffFactor,
+		endpoint.BackoffBase,
+		endpoint.BackoffMax,
+		endpoint.RecoveryInterval,
+		endpoint.RecoveryReset,
+	)
+
+	d := &Destination{
+		target:   target,
+		host:     endpoint.Host,
+		headers:  metadata.New(endpoint.Headers),
+		conn:     conn,
+		client:   plogotlp.NewGRPCClient(conn),
+		isMRF:    endpoint.IsMRF,
+		destMeta: destMeta,
+	}
+	d.RetryingSender = client.NewRetryingSender(target, d.send, destinationsContext, shouldRetry, policy)
+	return d, nil
+}
+
+// IsMRF indicates that this destination is a Multi-Region Failover destination.
+func (d *Destination) IsMRF() bool {
+	return d.isMRF
+}
+
+// Target is the address of the destination.
+func (d *Destination) Target() string {
+	return d.target
+}
+
+// Metadata returns the metadata of the destination
+func (d *Destination) Metadata() *client.DestinationMetadata {
+	return d.destMeta
+}
+
+// Start starts exporting the payloads of the input channel, the connection is
+// closed once the input channel is closed.
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	senderStopped := d.RetryingSender.Start(input, output, isRetrying)
+	stop := make(chan struct{})
+	go func() {
+		<-senderStopped
+		d.conn.Close()
+		stop <- struct{}{}
+	}()
+	return stop
+}
+
+func (d *Destination) send(ctx context.Context, payload *message.Payload) (err error) {
+	defer func() {
+		tlmSend.Inc(d.host, errorToTag(err))
+	}()
+
+	logs, err := client.DecodePayload(payload)
+	if err != nil {
+		// the payload can't be converted, sending it again won't help.
+		tlmDropped.Inc(d.host)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, d.headers), exportTimeout)
+	defer cancel()
+	resp, err := d.client.Export(ctx, plogotlp.NewExportRequestFromLogs(toLogs(logs)))
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return ctx.Err()
+		}
+		if isRetryable(status.Code(err)) {
+			return client.NewRetryableError(err)
+		}
+		tlmDropped.Inc(d.host)
+		return err
+	}
+	if rejected := resp.PartialSuccess().RejectedLogRecords(); rejected > 0 {
+		log.Warnf("%s rejected %d log records: %s", d.target, rejected, resp.PartialSuccess().ErrorMessage())
+	}
+	return nil
+}
+
+// isRetryable returns true for the codes the OTLP specification defines as retryable.
+func isRetryable(code codes.Code) bool {
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+		codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// toLogs converts the logs of a payload to OTLP logs, grouped by host and service.
+func toLogs(logs []client.DecodedLog) plog.Logs {
+	otlpLogs := plog.NewLogs()
+	resources := make(map[[2]string]plog.LogRecordSlice)
+	observed := pcommon.NewTimestampFromTime(time.Now())
+
+	for _, l := range logs {
+		key := [2]string{l.Hostname, l.Service}
+		records, ok := resources[key]
+		if !ok {
+			resourceLogs := otlpLogs.ResourceLogs().AppendEmpty()
+			attrs := resourceLogs.Resource().Attributes()
+			if l.Hostname != "" {
+				attrs.PutStr("host.name", l.Hostname)
+			}
+			if l.Service != "" {
+				attrs.PutStr("service.name", l.Service)
+			}
+			scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+			scopeLogs.Scope().SetName(scopeName)
+			records = scopeLogs.LogRecords()
+			resources[key] = records
+		}
+
+		record := records.AppendEmpty()
+		record.Body().SetStr(l.Message)
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.UnixMilli(l.Timestamp)))
+		record.SetObservedTimestamp(observed)
+		record.SetSeverityText(l.Status)
+		record.SetSeverityNumber(severities[l.Status])
+		if l.Source != "" {
+			record.Attributes().PutStr("datadog.log.source", l.Source)
+		}
+		putTags(record.Attributes(), l.Tags)
+	}
+	return otlpLogs
+}
+
+// putTags adds the tags of a log as attributes, tags without value are set to an
+// empty string and the first value of a tag key wins.
+func putTags(attrs pcommon.Map, tags string) {
+	if tags == "" {
+		return
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		key, value, _ := strings.Cut(tag, ":")
+		if key == "" {
+			continue
+		}
+		if _, exists := attrs.Get(key); exists {
+			continue
+		}
+		attrs.PutStr(key, value)
+	}
+}
+
+func errorToTag(err error) string {
+	if err == nil {
+		return "none"
+	} else if _, ok := err.(*client.RetryableError); ok {
+		return "retryable"
+	}
+	return "non-retryable"
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"google.golang.org/grpc/codes"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+func TestToLogs(t *testing.T) {
+	logs := toLogs([]client.DecodedLog{
+		{Message: "first", Status: "error", Timestamp: 1700000000000, Hostname: "host", Service: "web", Source: "nginx", Tags: "env:prod,team:logs,env:dev,flag"},
+		{Message: "second", Status: "info", Timestamp: 1700000000001, Hostname: "host", Service: "web"},
+		{Message: "third", Status: "warn", Timestamp: 1700000000002, Hostname: "host", Service: "db"},
+	})
+
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+	assert.Equal(t, 3, logs.LogRecordCount())
+
+	web := logs.ResourceLogs().At(0)
+	service, ok := web.Resource().Attributes().Get("service.name")
+	require.True(t, ok)
+	assert.Equal(t, "web", service.Str())
+	host, ok := web.Resource().Attributes().Get("host.name")
+	require.True(t, ok)
+	assert.Equal(t, "host", host.Str())
+
+	records := web.ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, records.Len())
+	first := records.At(0)
+	assert.Equal(t, "first", first.Body().Str())
+	assert.Equal(t, "error", first.SeverityText())
+	assert.Equal(t, plog.SeverityNumberError, first.SeverityNumber())
+	assert.Equal(t, int64(1700000000000), first.Timestamp().AsTime().UnixMilli())
+	assert.Equal(t, map[string]any{
+		"datadog.log.source": "nginx",
+		"env":                "prod",
+		"team":               "logs",
+		"flag":               "",
+	}, first.Attributes().AsRaw())
+
+	db := logs.ResourceLogs().At(1)
+	service, _ = db.Resource().Attributes().Get("service.name")
+	assert.Equal(t, "db", service.Str())
+	assert.Equal(t, plog.SeverityNumberWarn, db.ScopeLogs().At(0).LogRecords().At(0).SeverityNumber())
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, isRetryable(codes.Unavailable))
+	assert.True(t, isRetryable(codes.ResourceExhausted))
+	assert.False(t, isRetryable(codes.InvalidArgument))
+	assert.False(t, isRetryable(codes.Unauthenticated))
+}
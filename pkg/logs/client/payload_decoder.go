@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// zstdDecoder is shared by all the destinations, DecodeAll is safe for concurrent use.
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+
+// DecodedLog is a log of a payload encoded for the Datadog HTTP intake.
+type DecodedLog struct {
+	Message   string `json:"message"`
+	Status    string `json:"status"`
+	Timestamp int64  `json:"timestamp"`
+	Hostname  string `json:"hostname"`
+	Service   string `json:"service"`
+	Source    string `json:"ddsource"`
+	Tags      string `json:"ddtags"`
+}
+
+// DecodePayload decompresses a payload built for the Datadog HTTP intake and returns its logs.
+// It allows the destinations sending logs to other intakes to reuse the processed payloads.
+// The payload is decoded once, the logs are shared by the destinations and must not be modified.
+func DecodePayload(payload *message.Payload) ([]DecodedLog, error) {
+	logs, err := payload.DecodeOnce(func(p *message.Payload) (interface{}, error) {
+		return decodePayload(p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return logs.([]DecodedLog), nil
+}
+
+func decodePayload(payload *message.Payload) ([]DecodedLog, error) {
+	content, err := decompress(payload.Encoded, payload.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress %q payload: %v", payload.Encoding, err)
+	}
+	var logs []DecodedLog
+	if err := json.Unmarshal(content, &logs); err != nil {
+		return nil, fmt.Errorf("could not decode payload: %v", err)
+	}
+	return logs, nil
+}
+
+func decompress(content []byte, encoding string) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch encoding {
+	case "", "identity":
+		return content, nil
+	case "zstd":
+		return zstdDecoder.DecodeAll(content, nil)
+	case "gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(content))
+	case "deflate":
+		reader, err = zlib.NewReader(bytes.NewReader(content))
+	default:
+		return nil, fmt.Errorf("unsupported encoding")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const encodedLogs = `[{"message":"hello","status":"info","timestamp":1700000000000,"hostname":"host","service":"web","ddsource":"nginx","ddtags":"env:prod,team:logs"}]`
+
+func compressWith(t *testing.T, w io.WriteCloser, buf *bytes.Buffer) []byte {
+	_, err := w.Write([]byte(encodedLogs))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecodePayload(t *testing.T) {
+	var gzipBuf, zlibBuf, zstdBuf bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&zstdBuf)
+	require.NoError(t, err)
+
+	payloads := map[string][]byte{
+		"":        []byte(encodedLogs),
+		"gzip":    compressWith(t, gzip.NewWriter(&gzipBuf), &gzipBuf),
+		"deflate": compressWith(t, zlib.NewWriter(&zlibBuf), &zlibBuf),
+		"zstd":    compressWith(t, zstdWriter, &zstdBuf),
+	}
+
+	for encoding, encoded := range payloads {
+		t.Run(encoding, func(t *testing.T) {
+			logs, err := DecodePayload(&message.Payload{Encoded: encoded, Encoding: encoding})
+			require.NoError(t, err)
+			assert.Equal(t, []DecodedLog{{
+				Message:   "hello",
+				Status:    "info",
+				Timestamp: 1700000000000,
+				Hostname:  "host",
+				Service:   "web",
+				Source:    "nginx",
+				Tags:      "env:prod,team:logs",
+			}}, logs)
+		})
+	}
+}
+
+func TestDecodePayloadErrors(t *testing.T) {
+	_, err := DecodePayload(&message.Payload{Encoded: []byte(encodedLogs), Encoding: "br"})
+	assert.Error(t, err)
+
+	_, err = DecodePayload(&message.Payload{Encoded: []byte(encodedLogs), Encoding: "gzip"})
+	assert.Error(t, err)
+
+	_, err = DecodePayload(&message.Payload{Encoded: []byte("not json")})
+	assert.Error(t, err)
+}
+
+func TestDecodePayloadOnce(t *testing.T) {
+	payload := &message.Payload{Encoded: []byte(encodedLogs)}
+	logs, err := DecodePayload(payload)
+	require.NoError(t, err)
+
+	// the destinations sending the payload share its decoding
+	payload.Encoded = []byte("not json")
+	shared, err := DecodePayload(payload)
+	require.NoError(t, err)
+	require.Len(t, shared, 1)
+	assert.Same(t, &logs[0], &shared[0])
+
+	payload = &message.Payload{Encoded: []byte("not json")}
+	_, err = DecodePayload(payload)
+	assert.Error(t, err)
+	_, err = DecodePayload(payload)
+	assert.Error(t, err)
+}
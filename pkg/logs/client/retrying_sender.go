@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// SendFunc sends a payload, it returns a RetryableError when the payload should be sent again.
+type SendFunc func(ctx context.Context, payload *message.Payload) error
+
+// RetryingSender sends the payloads of a destination one at a time, and retries them
+// with a backoff while the send function returns a RetryableError.
+// It implements the Start method of the Destination interface for the destinations
+// which don't need concurrent sends.
+type RetryingSender struct {
+	target              string
+	send                SendFunc
+	destinationsContext *DestinationsContext
+	shouldRetry         bool
+
+	backoff        backoff.Policy
+	nbErrors       int
+	lastRetryError error
+}
+
+// NewRetryingSender returns a new RetryingSender.
+func NewRetryingSender(target string, send SendFunc, destinationsContext *DestinationsContext, shouldRetry bool, policy backoff.Policy) *RetryingSender {
+	return &RetryingSender{
+		target:              target,
+		send:                send,
+		destinationsContext: destinationsContext,
+		shouldRetry:         shouldRetry,
+		backoff:             policy,
+	}
+}
+
+// Start starts sending the payloads of the input channel, the payloads are written
+// to the output channel once sent or dropped.
+func (s *RetryingSender) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go s.run(input, output, stop, isRetrying)
+	return stop
+}
+
+func (s *RetryingSender) run(input chan *message.Payload, output chan *message.Payload, stopChan chan struct{}, isRetrying chan bool) {
+	for p := range input {
+		s.sendAndRetry(p, output, isRetrying)
+	}
+	s.updateRetryState(nil, isRetrying)
+	stopChan <- struct{}{}
+}
+
+func (s *RetryingSender) sendAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	for {
+		backoffDuration := s.backoff.GetBackoffDuration(s.nbErrors)
+		if backoffDuration > 0 {
+			log.Warnf("%s: sleeping %s before retrying due to %d errors", s.target, backoffDuration.String(), s.nbErrors)
+			s.waitForBackoff(time.Now().Add(backoffDuration))
+			metrics.RetryTimeSpent.Add(int64(backoffDuration))
+			metrics.RetryCount.Add(1)
+			metrics.TlmRetryCount.Add(1)
+		}
+
+		ctx := s.destinationsContext.Context()
+		err := s.send(ctx, payload)
+		if err != nil {
+			metrics.DestinationErrors.Add(1)
+			metrics.TlmDestinationErrors.Inc()
+			log.Warnf("Could not send payload to %s: %v", s.target, err)
+		}
+
+		if err == context.Canceled || ctx.Err() == context.Canceled {
+			s.updateRetryState(nil, isRetrying)
+			return
+		}
+
+		if s.shouldRetry && s.updateRetryState(err, isRetrying) {
+			continue
+		}
+
+		metrics.LogsSent.Add(payload.Count())
+		metrics.TlmLogsSent.Add(float64(payload.Count()))
+		output <- payload
+		return
+	}
+}
+
+func (s *RetryingSender) updateRetryState(err error, isRetrying chan bool) bool {
+	if _, ok := err.(*RetryableError); ok {
+		s.nbErrors = s.backoff.IncError(s.nbErrors)
+		if isRetrying != nil && s.lastRetryError == nil {
+			isRetrying <- true
+		}
+		s.lastRetryError = err
+		return true
+	}
+
+	s.nbErrors = s.backoff.DecError(s.nbErrors)
+	if isRetrying != nil && s.lastRetryError != nil {
+		isRetrying <- false
+	}
+	s.lastRetryError = nil
+	return false
+}
+
+func (s *RetryingSender) waitForBackoff(blockedUntil time.Time) {
+	ctx, cancel := context.WithDeadline(s.destinationsContext.Context(), blockedUntil)
+	defer cancel()
+	<-ctx.Done()
+}
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// tapBufferSize is the number of traces buffered by the TapReceiver, the
+// traces are dropped rather than slowing down the pipelines when it is full.
+const tapBufferSize = 1000
+
+// DefaultTapReceiver is the TapReceiver used by the decoders and the processors
+// to report the traces of the messages of the tapped sources.
+var DefaultTapReceiver = NewTapReceiver(tapBufferSize)
+
+// TapReceiver collects the traces of the messages of the tapped sources. A trace
+// contains the content of a message at each stage of the pipeline: the raw frames,
+// the decoder output, the content after the processing rules and the encoded message.
+// Only one client can tap the sources at a time.
+type TapReceiver struct {
+	inputChan chan *message.Message
+	enabled   atomic.Bool
+	// name of the tapped source, all the sources are tapped when empty
+	name atomic.Pointer[string]
+	m    sync.Mutex
+}
+
+// NewTapReceiver returns a new TapReceiver buffering up to bufferSize traces.
+func NewTapReceiver(bufferSize int) *TapReceiver {
+	return &TapReceiver{
+		inputChan: make(chan *message.Message, bufferSize),
+	}
+}
+
+// SetEnabled starts collecting the traces. Returns true if state was successfully changed
+func (r *TapReceiver) SetEnabled(e bool) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.enabled.Load() == e {
+		return false
+	}
+
+	r.enabled.Store(e)
+	if !e {
+		r.name.Store(nil)
+		r.clear()
+	}
+	return true
+}
+
+// clear drops the buffered traces
+func (r *TapReceiver) clear() {
+	for {
+		select {
+		case <-r.inputChan:
+		default:
+			return
+		}
+	}
+}
+
+// IsTapped returns true if the messages of the source with the given name should be traced.
+func (r *TapReceiver) IsTapped(sourceName string) bool {
+	if !r.enabled.Load() {
+		return false
+	}
+	name := r.name.Load()
+	return name == nil || *name == "" || *name == sourceName
+}
+
+// HandleTrace reports the trace of a message, it is dropped when the buffer is full.
+func (r *TapReceiver) HandleTrace(msg *message.Message) {
+	if msg.Tap == nil || !r.enabled.Load() {
+		return
+	}
+	// only keep the metadata, the message continues its way in the pipeline
+	traced := &message.Message{MessageMetadata: msg.MessageMetadata}
+	select {
+	case r.inputChan <- traced:
+	default:
+	}
+}
+
+// Filter writes the traces of the messages matching the filters formatted as a string to the
+// output channel. Only the source with the filters name is tapped, if any.
+func (r *TapReceiver) Filter(filters *Filters, done <-chan struct{}) <-chan string {
+	if filters != nil {
+		r.name.Store(&filters.Name)
+	}
+	out := make(chan string, cap(r.inputChan))
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case msg := <-r.inputChan:
+				if shouldHandleMessage(&messagePair{msg: msg}, filters) {
+					out <- FormatTapTrace(msg)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// FormatTapTrace shows the content of a traced message at each stage of the pipeline.
+func FormatTapTrace(msg *message.Message) string {
+	var b strings.Builder
+	if msg.Origin != nil {
+		fmt.Fprintf(&b, "Integration Name: %s | Type: %s | Service: %s | Source: %s\n",
+			msg.Origin.LogSource.Name,
+			msg.Origin.LogSource.Config.Type,
+			msg.Origin.Service(),
+			msg.Origin.Source())
+	}
+	for _, frame := range msg.Tap.Frames {
+		fmt.Fprintf(&b, "  frame:     %s\n", frame)
+	}
+	fmt.Fprintf(&b, "  decoded:   %s\n", msg.Tap.Decoded)
+	if msg.Tap.Dropped {
+		b.WriteString("  processed: <dropped by a processing rule>\n")
+	} else {
+		fmt.Fprintf(&b, "  processed: %s\n", msg.Tap.Processed)
+		fmt.Fprintf(&b, "  encoded:   %s\n", msg.Tap.Encoded)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
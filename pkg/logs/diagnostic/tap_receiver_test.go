@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTracedMessage(name string, trace *message.TapTrace) *message.Message {
+	msg := newMessage(name, "file", "source", "service")
+	msg.Tap = trace
+	return msg
+}
+
+func TestTapReceiverEnableDisable(t *testing.T) {
+	r := NewTapReceiver(10)
+	assert.False(t, r.IsTapped("app"))
+	assert.True(t, r.SetEnabled(true))
+	assert.False(t, r.SetEnabled(true))
+	assert.True(t, r.IsTapped("app"))
+
+	r.HandleTrace(newTracedMessage("app", &message.TapTrace{Decoded: []byte("a")}))
+	assert.Len(t, r.inputChan, 1)
+
+	assert.True(t, r.SetEnabled(false))
+	// buffered traces are cleared
+	assert.Len(t, r.inputChan, 0)
+	assert.False(t, r.IsTapped("app"))
+
+	// disabled, no traces are buffered
+	r.HandleTrace(newTracedMessage("app", &message.TapTrace{Decoded: []byte("a")}))
+	assert.Len(t, r.inputChan, 0)
+}
+
+func TestTapReceiverFilter(t *testing.T) {
+	r := NewTapReceiver(10)
+	r.SetEnabled(true)
+
+	done := make(chan struct{})
+	defer close(done)
+	traces := r.Filter(&Filters{Name: "app"}, done)
+	assert.True(t, r.IsTapped("app"))
+	assert.False(t, r.IsTapped("other"))
+
+	// messages without trace are ignored
+	r.HandleTrace(newMessage("app", "file", "source", "service"))
+	r.HandleTrace(newTracedMessage("other", &message.TapTrace{Decoded: []byte("other")}))
+	r.HandleTrace(newTracedMessage("app", &message.TapTrace{
+		Frames:    [][]byte{[]byte(`{"log":"hello"}`)},
+		Decoded:   []byte("hello"),
+		Processed: []byte("hello"),
+		Encoded:   []byte(`{"message":"hello"}`),
+	}))
+	r.HandleTrace(newTracedMessage("app", &message.TapTrace{Decoded: []byte("debug"), Dropped: true}))
+
+	trace := <-traces
+	assert.True(t, strings.HasPrefix(trace, "Integration Name: app | Type: file"))
+	assert.Contains(t, trace, "  frame:     {\"log\":\"hello\"}\n")
+	assert.Contains(t, trace, "  decoded:   hello\n")
+	assert.Contains(t, trace, "  processed: hello\n")
+	assert.Contains(t, trace, "  encoded:   {\"message\":\"hello\"}\n")
+
+	trace = <-traces
+	assert.Contains(t, trace, "  decoded:   debug\n")
+	assert.Contains(t, trace, "<dropped by a processing rule>")
+	assert.NotContains(t, trace, "encoded")
+}
+
+func TestTapReceiverDropsWhenFull(t *testing.T) {
+	r := NewTapReceiver(1)
+	r.SetEnabled(true)
+	r.HandleTrace(newTracedMessage("app", &message.TapTrace{Decoded: []byte("a")}))
+	r.HandleTrace(newTracedMessage("app", &message.TapTrace{Decoded: []byte("b")}))
+	require.Len(t, r.inputChan, 1)
+	assert.Equal(t, "a", string((<-r.inputChan).Tap.Decoded))
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package automultilinedetection contains auto multiline detection and aggregation logic.
+package automultilinedetection
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection/tokens"
+)
+
+// ShapeMatcher compares the structure of log messages using the tokens
+// computed by the Tokenizer, two messages having the same shape when
+// they only differ by the values of their variable parts.
+// A ShapeMatcher instance is not thread safe.
+type ShapeMatcher struct {
+	tokenizer      *Tokenizer
+	maxEvalBytes   int
+	matchThreshold float64
+}
+
+// NewShapeMatcher returns a new ShapeMatcher evaluating the first maxEvalBytes
+// bytes of the messages.
+func NewShapeMatcher(maxEvalBytes int, matchThreshold float64) *ShapeMatcher {
+	return &ShapeMatcher{
+		tokenizer:      NewTokenizer(maxEvalBytes),
+		maxEvalBytes:   maxEvalBytes,
+		matchThreshold: matchThreshold,
+	}
+}
+
+// Tokenize returns the tokens representing the shape of the message.
+func (s *ShapeMatcher) Tokenize(content []byte) []tokens.Token {
+	ts, _ := s.tokenizer.tokenize(content[:min(len(content), s.maxEvalBytes)])
+	return ts
+}
+
+// Match returns true if the two token sequences have the same length and
+// match within the threshold of the matcher.
+func (s *ShapeMatcher) Match(seqA []tokens.Token, seqB []tokens.Token) bool {
+	return len(seqA) == len(seqB) && isMatch(seqA, seqB, s.matchThreshold)
+}
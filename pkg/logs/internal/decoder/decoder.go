@@ -12,6 +12,8 @@ import (
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	//nolint:revive // TODO(AML) Fix revive linter
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	automultilinedetection "github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
@@ -51,6 +53,9 @@ type Decoder struct {
 	lineParser  LineParser
 	lineHandler LineHandler
 
+	// squasher collapses the repeated messages sent by the line handler, nil when disabled
+	squasher *RepeatSquasher
+
 	// The decoder holds on to an instace of DetectedPattern which is a thread safe container used to
 	// pass a multiline pattern up from the line handler in order to surface it to the tailer.
 	// The tailer uses this to determine if a pattern should be reused when a file rotates.
@@ -101,7 +106,15 @@ func NewDecoderWithFraming(source *sources.ReplaceableSource, parser parsers.Par
 	outputChan := make(chan *message.Message)
 	detectedPattern := &DetectedPattern{}
 
-	lineHandler := buildLineHandler(source, multiLinePattern, tailerInfo, outputChan, detectedPattern)
+	tap := newDecoderTap(source, diagnostic.DefaultTapReceiver)
+	outputFn := tap.output(func(m *message.Message) { outputChan <- m })
+	var squasher *RepeatSquasher
+	if source.Config().SquashRepeatedLogsEnabled(pkgconfigsetup.Datadog()) {
+		squasher = buildRepeatSquasher(outputFn, tailerInfo)
+		outputFn = squasher.process
+	}
+
+	lineHandler := buildLineHandler(source, multiLinePattern, tailerInfo, outputFn, detectedPattern)
 
 	var lineParser LineParser
 	if parser.SupportsPartialLine() {
@@ -110,13 +123,25 @@ func NewDecoderWithFraming(source *sources.ReplaceableSource, parser parsers.Par
 		lineParser = NewSingleLineParser(lineHandler, parser)
 	}
 
-	framer := framer.NewFramer(lineParser.process, framing, maxMessageSize)
+	framer := framer.NewFramer(tap.process(lineParser.process), framing, maxMessageSize)
 
-	return New(inputChan, outputChan, framer, lineParser, lineHandler, detectedPattern)
+	decoder := New(inputChan, outputChan, framer, lineParser, lineHandler, detectedPattern)
+	decoder.squasher = squasher
+	return decoder
 }
 
-func buildLineHandler(source *sources.ReplaceableSource, multiLinePattern *regexp.Regexp, tailerInfo *status.InfoRegistry, outputChan chan *message.Message, detectedPattern *DetectedPattern) LineHandler {
-	outputFn := func(m *message.Message) { outputChan <- m }
+func buildRepeatSquasher(outputFn func(*message.Message), tailerInfo *status.InfoRegistry) *RepeatSquasher {
+	var matcher *automultilinedetection.ShapeMatcher
+	if pkgconfigsetup.Datadog().GetString("logs_config.squash_repeated_logs.match") == "shape" {
+		matcher = automultilinedetection.NewShapeMatcher(
+			pkgconfigsetup.Datadog().GetInt("logs_config.squash_repeated_logs.shape_max_input_bytes"),
+			pkgconfigsetup.Datadog().GetFloat64("logs_config.squash_repeated_logs.shape_match_threshold"))
+	}
+	window := time.Duration(pkgconfigsetup.Datadog().GetInt("logs_config.squash_repeated_logs.window")) * time.Second
+	return NewRepeatSquasher(outputFn, matcher, config.AggregationTimeout(pkgconfigsetup.Datadog()), window, tailerInfo)
+}
+
+func buildLineHandler(source *sources.ReplaceableSource, multiLinePattern *regexp.Regexp, tailerInfo *status.InfoRegistry, outputFn func(*message.Message), detectedPattern *DetectedPattern) LineHandler {
 	maxContentSize := config.MaxMessageSizeBytes(pkgconfigsetup.Datadog())
 
 	// construct the lineHandler
@@ -222,6 +247,9 @@ func (d *Decoder) run() {
 		// output channel
 		d.lineParser.flush()
 		d.lineHandler.flush()
+		if d.squasher != nil {
+			d.squasher.flush()
+		}
 		close(d.OutputChan)
 	}()
 	for {
@@ -241,6 +269,10 @@ func (d *Decoder) run() {
 		case <-d.lineHandler.flushChan():
 			log.Debug("Flushing line handler because the flush timeout has been reached.")
 			d.lineHandler.flush()
+
+		case <-d.squasher.flushChan():
+			log.Debug("Flushing repeated lines because the flush timeout has been reached.")
+			d.squasher.flush()
 		}
 	}
 }
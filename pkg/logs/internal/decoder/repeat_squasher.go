@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+	"time"
+
+	automultilinedetection "github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection/tokens"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+// RepeatSquasher collapses the consecutive repetitions of a message into the
+// first occurrence, annotated with the number of repetitions and the time of
+// the first and last ones.
+// Two messages are repetitions when their content is identical or, when a
+// ShapeMatcher is given, when they have the same shape.
+// A group of repetitions is sent once a different message is received, once
+// no repetition has been received during the flush timeout or once the group
+// spans the window.
+type RepeatSquasher struct {
+	outputFn     func(*message.Message)
+	matcher      *automultilinedetection.ShapeMatcher
+	flushTimeout time.Duration
+	window       time.Duration
+	flushTimer   *time.Timer
+	squashedInfo *status.CountInfo
+
+	pending       *message.Message
+	pendingTokens []tokens.Token
+	count         int
+	rawDataLen    int
+	firstSeen     time.Time
+	lastSeen      time.Time
+}
+
+// NewRepeatSquasher returns a new RepeatSquasher, messages are compared byte per
+// byte when matcher is nil.
+func NewRepeatSquasher(outputFn func(*message.Message), matcher *automultilinedetection.ShapeMatcher, flushTimeout time.Duration, window time.Duration, tailerInfo *status.InfoRegistry) *RepeatSquasher {
+	squashedInfo := status.NewCountInfo("Repeated lines squashed")
+	tailerInfo.Register(squashedInfo)
+
+	return &RepeatSquasher{
+		outputFn:     outputFn,
+		matcher:      matcher,
+		flushTimeout: flushTimeout,
+		window:       window,
+		squashedInfo: squashedInfo,
+	}
+}
+
+// process squashes the message into the pending one if it is a repetition,
+// otherwise the pending message is sent and replaced by this one.
+func (s *RepeatSquasher) process(msg *message.Message) {
+	s.stopFlushTimerIfNeeded()
+	defer s.startFlushTimerIfNeeded()
+
+	seen := messageTime(msg)
+	var msgTokens []tokens.Token
+	if s.matcher != nil {
+		msgTokens = s.matcher.Tokenize(msg.GetContent())
+	}
+
+	if s.pending != nil && s.isRepetition(msg, msgTokens) && seen.Sub(s.firstSeen) < s.window {
+		s.count++
+		s.rawDataLen += msg.RawDataLen
+		s.lastSeen = seen
+		// keep the position of the latest repetition for the offset of the squashed message
+		s.pending.ParsingExtra.Timestamp = msg.ParsingExtra.Timestamp
+		s.squashedInfo.Add(1)
+		metrics.TlmLogsSquashed.Inc()
+		return
+	}
+
+	s.flush()
+	s.pending = msg
+	s.pendingTokens = msgTokens
+	s.count = 1
+	s.rawDataLen = msg.RawDataLen
+	s.firstSeen = seen
+	s.lastSeen = seen
+}
+
+func (s *RepeatSquasher) isRepetition(msg *message.Message, msgTokens []tokens.Token) bool {
+	if s.matcher == nil {
+		return bytes.Equal(s.pending.GetContent(), msg.GetContent())
+	}
+	return s.matcher.Match(s.pendingTokens, msgTokens)
+}
+
+// flushChan returns the flush timer channel.
+func (s *RepeatSquasher) flushChan() <-chan time.Time {
+	if s != nil && s.flushTimer != nil {
+		return s.flushTimer.C
+	}
+	return nil
+}
+
+// flush sends the pending message.
+func (s *RepeatSquasher) flush() {
+	if s.pending == nil {
+		return
+	}
+	msg := s.pending
+	if s.count > 1 {
+		msg.RawDataLen = s.rawDataLen
+		msg.ParsingExtra.Repeated = &message.RepeatInfo{
+			Count:     s.count,
+			FirstSeen: s.firstSeen,
+			LastSeen:  s.lastSeen,
+		}
+	}
+	s.pending = nil
+	s.pendingTokens = nil
+	s.count = 0
+	s.rawDataLen = 0
+	s.outputFn(msg)
+}
+
+func (s *RepeatSquasher) stopFlushTimerIfNeeded() {
+	if s.flushTimer == nil || s.pending == nil {
+		return
+	}
+	// stop the flush timer, as we now have data
+	if !s.flushTimer.Stop() {
+		<-s.flushTimer.C
+	}
+}
+
+func (s *RepeatSquasher) startFlushTimerIfNeeded() {
+	if s.pending == nil {
+		return
+	}
+	// since there's a pending message, start the flush timer to send it
+	if s.flushTimer == nil {
+		s.flushTimer = time.NewTimer(s.flushTimeout)
+	} else {
+		s.flushTimer.Reset(s.flushTimeout)
+	}
+}
+
+// messageTime returns the time at which the message has been received.
+func messageTime(msg *message.Message) time.Time {
+	if msg.IngestionTimestamp > 0 {
+		return time.Unix(0, msg.IngestionTimestamp)
+	}
+	return time.Now()
+}
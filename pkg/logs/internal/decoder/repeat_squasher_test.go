@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	automultilinedetection "github.com/DataDog/datadog-agent/pkg/logs/internal/decoder/auto_multiline_detection"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+func newRepeatedLine(content string, ts time.Time) *message.Message {
+	msg := message.NewMessage([]byte(content), nil, message.StatusInfo, ts.UnixNano())
+	msg.RawDataLen = len(content) + 1
+	return msg
+}
+
+func newTestRepeatSquasher(matcher *automultilinedetection.ShapeMatcher) (*RepeatSquasher, *[]*message.Message) {
+	var output []*message.Message
+	outputFn := func(m *message.Message) { output = append(output, m) }
+	return NewRepeatSquasher(outputFn, matcher, time.Hour, 10*time.Second, status.NewInfoRegistry()), &output
+}
+
+func TestRepeatSquasherExactMatch(t *testing.T) {
+	squasher, output := newTestRepeatSquasher(nil)
+	start := time.Now()
+
+	squasher.process(newRepeatedLine("connection refused", start))
+	squasher.process(newRepeatedLine("connection refused", start.Add(time.Second)))
+	squasher.process(newRepeatedLine("connection refused", start.Add(2*time.Second)))
+	assert.Empty(t, *output)
+
+	squasher.process(newRepeatedLine("connection established", start.Add(3*time.Second)))
+	require.Len(t, *output, 1)
+	squashed := (*output)[0]
+	assert.Equal(t, "connection refused", string(squashed.GetContent()))
+	assert.Equal(t, 3*len("connection refused\n"), squashed.RawDataLen)
+	require.NotNil(t, squashed.ParsingExtra.Repeated)
+	assert.Equal(t, 3, squashed.ParsingExtra.Repeated.Count)
+	assert.True(t, squashed.ParsingExtra.Repeated.FirstSeen.Equal(time.Unix(0, start.UnixNano())))
+	assert.True(t, squashed.ParsingExtra.Repeated.LastSeen.Equal(time.Unix(0, start.Add(2*time.Second).UnixNano())))
+
+	// a message without repetition is left untouched
+	squasher.flush()
+	require.Len(t, *output, 2)
+	assert.Equal(t, "connection established", string((*output)[1].GetContent()))
+	assert.Nil(t, (*output)[1].ParsingExtra.Repeated)
+}
+
+func TestRepeatSquasherShapeMatch(t *testing.T) {
+	squasher, output := newTestRepeatSquasher(automultilinedetection.NewShapeMatcher(200, 0.9))
+	start := time.Now()
+
+	squasher.process(newRepeatedLine("user 1234 failed to login", start))
+	squasher.process(newRepeatedLine("user 5678 failed to login", start))
+	squasher.process(newRepeatedLine("request served in 12ms", start))
+	squasher.flush()
+
+	require.Len(t, *output, 2)
+	assert.Equal(t, "user 1234 failed to login", string((*output)[0].GetContent()))
+	assert.Equal(t, 2, (*output)[0].ParsingExtra.Repeated.Count)
+	assert.Nil(t, (*output)[1].ParsingExtra.Repeated)
+}
+
+func TestRepeatSquasherWindow(t *testing.T) {
+	squasher, output := newTestRepeatSquasher(nil)
+	start := time.Now()
+
+	squasher.process(newRepeatedLine("disk full", start))
+	squasher.process(newRepeatedLine("disk full", start.Add(5*time.Second)))
+	// the repetition is out of the window of the group, a new group starts
+	squasher.process(newRepeatedLine("disk full", start.Add(11*time.Second)))
+	squasher.flush()
+
+	require.Len(t, *output, 2)
+	assert.Equal(t, 2, (*output)[0].ParsingExtra.Repeated.Count)
+	assert.Nil(t, (*output)[1].ParsingExtra.Repeated)
+}
+
+func TestRepeatSquasherFlushTimer(t *testing.T) {
+	var output []*message.Message
+	squasher := NewRepeatSquasher(func(m *message.Message) { output = append(output, m) }, nil, 10*time.Millisecond, 10*time.Second, status.NewInfoRegistry())
+
+	assert.Nil(t, squasher.flushChan())
+	squasher.process(newRepeatedLine("disk full", time.Now()))
+	squasher.process(newRepeatedLine("disk full", time.Now()))
+
+	<-squasher.flushChan()
+	squasher.flush()
+	require.Len(t, output, 1)
+	assert.Equal(t, 2, output[0].ParsingExtra.Repeated.Count)
+
+	// the timer is restarted for the next message
+	squasher.process(newRepeatedLine("disk full", time.Now()))
+	<-squasher.flushChan()
+	squasher.flush()
+	assert.Len(t, output, 2)
+}
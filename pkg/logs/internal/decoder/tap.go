@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"bytes"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// tapFrame is a raw frame read while the source is tapped.
+type tapFrame struct {
+	content []byte
+	// offset of the frame in the data read by the decoder
+	offset int
+}
+
+// decoderTap records the raw frames of a tapped source and attaches them, with
+// the decoded content, to the messages sent by the decoder.
+// The messages are sent in the order of their frames and their RawDataLen sums
+// the length of their frames, the frames composing a message are the ones read
+// between the end of the previous message and the end of this message.
+type decoderTap struct {
+	source   *sources.ReplaceableSource
+	receiver *diagnostic.TapReceiver
+	frames   []tapFrame
+	// read is the length of the frames read, sent the length of the messages sent
+	read int
+	sent int
+}
+
+func newDecoderTap(source *sources.ReplaceableSource, receiver *diagnostic.TapReceiver) *decoderTap {
+	return &decoderTap{
+		source:   source,
+		receiver: receiver,
+	}
+}
+
+func (t *decoderTap) isTapped() bool {
+	return t.receiver.IsTapped(t.source.UnderlyingSource().Name)
+}
+
+// process wraps the function called with each frame to record the frames.
+func (t *decoderTap) process(process func(*message.Message, int)) func(*message.Message, int) {
+	return func(input *message.Message, rawDataLen int) {
+		if t.isTapped() {
+			t.frames = append(t.frames, tapFrame{
+				content: bytes.Clone(input.GetContent()),
+				offset:  t.read,
+			})
+		}
+		t.read += rawDataLen
+		process(input, rawDataLen)
+	}
+}
+
+// output wraps the function called with each decoded message to attach its trace.
+func (t *decoderTap) output(output func(*message.Message)) func(*message.Message) {
+	return func(msg *message.Message) {
+		start := t.sent
+		t.sent += msg.RawDataLen
+		if !t.isTapped() {
+			t.frames = nil
+			output(msg)
+			return
+		}
+
+		trace := &message.TapTrace{
+			Decoded: bytes.Clone(msg.GetContent()),
+		}
+		for len(t.frames) > 0 && t.frames[0].offset < t.sent {
+			// the frames of a message read before the source was tapped are missing
+			if t.frames[0].offset >= start {
+				trace.Frames = append(trace.Frames, t.frames[0].content)
+			}
+			t.frames = t.frames[1:]
+		}
+		msg.Tap = trace
+		output(msg)
+	}
+}
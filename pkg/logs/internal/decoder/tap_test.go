@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestDecoderTap(t *testing.T) {
+	receiver := diagnostic.NewTapReceiver(10)
+	source := sources.NewReplaceableSource(sources.NewLogSource("app", &config.LogsConfig{}))
+	tap := newDecoderTap(source, receiver)
+
+	// aggregate the frames into one message until a frame doesn't start with a space
+	var outputs []*message.Message
+	var pending *message.Message
+	output := tap.output(func(m *message.Message) { outputs = append(outputs, m) })
+	process := tap.process(func(input *message.Message, rawDataLen int) {
+		content := input.GetContent()
+		if pending != nil && len(content) > 0 && content[0] == ' ' {
+			pending.SetContent(append(pending.GetContent(), content...))
+			pending.RawDataLen += rawDataLen
+			return
+		}
+		if pending != nil {
+			output(pending)
+		}
+		pending = message.NewMessage(content, nil, "", 0)
+		pending.RawDataLen = rawDataLen
+	})
+
+	// not tapped
+	process(NewInput([]byte("first")), 6)
+	process(NewInput([]byte("second")), 7)
+	require.Len(t, outputs, 1)
+	assert.Nil(t, outputs[0].Tap)
+
+	receiver.SetEnabled(true)
+	done := make(chan struct{})
+	defer close(done)
+	receiver.Filter(&diagnostic.Filters{Name: "app"}, done)
+
+	process(NewInput([]byte("third")), 6)
+	process(NewInput([]byte(" continued")), 11)
+	process(NewInput([]byte("fourth")), 7)
+	require.Len(t, outputs, 3)
+	// the "second" frame was read before the source was tapped
+	assert.Empty(t, outputs[1].Tap.Frames)
+	assert.Equal(t, "second", string(outputs[1].Tap.Decoded))
+	assert.Equal(t, [][]byte{[]byte("third"), []byte(" continued")}, outputs[2].Tap.Frames)
+	assert.Equal(t, "third continued", string(outputs[2].Tap.Decoded))
+
+	// only the named source is tapped
+	receiver.Filter(&diagnostic.Filters{Name: "other"}, done)
+	process(NewInput([]byte("fifth")), 6)
+	require.Len(t, outputs, 4)
+	assert.Nil(t, outputs[3].Tap)
+}
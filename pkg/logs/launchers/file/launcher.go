@@ -7,6 +7,7 @@
 package file
 
 import (
+	"os"
 	"regexp"
 	"slices"
 	"time"
@@ -55,6 +56,9 @@ type Launcher struct {
 	scanPeriod             time.Duration
 	flarecontroller        *flareController.FlareController
 	tagger                 tagger.Component
+	// readCompressedFiles are the compressed files read up to their end by scan key,
+	// they aren't tailed again unless they are replaced.
+	readCompressedFiles map[string]os.FileInfo
 }
 
 // NewLauncher returns a new launcher.
@@ -83,6 +87,7 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		scanPeriod:             scanPeriod,
 		flarecontroller:        flarecontroller,
 		tagger:                 tagger,
+		readCompressedFiles:    make(map[string]os.FileInfo),
 	}
 }
 
@@ -172,6 +177,9 @@ func (s *Launcher) scan() {
 		scanKey := file.GetScanKey()
 		tailer, isTailed := s.tailers.Get(scanKey)
 		if isTailed && tailer.IsFinished() {
+			if file.IsCompressed() {
+				s.setCompressedFileRead(file)
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
@@ -216,7 +224,7 @@ func (s *Launcher) scan() {
 	for _, file := range files {
 		scanKey := file.GetScanKey()
 		isTailed := s.tailers.Contains(scanKey)
-		if !isTailed && tailersLen < s.tailingLimit {
+		if !isTailed && tailersLen < s.tailingLimit && !s.isCompressedFileRead(file) {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
 			succeeded := s.startNewTailer(file, config.Beginning)
 			if !succeeded {
@@ -230,6 +238,13 @@ func (s *Launcher) scan() {
 	}
 	log.Debugf("After starting new tailers, there are %d tailers running. Limit is %d.\n", tailersLen, s.tailingLimit)
 
+	// forget the compressed files which aren't to be tailed anymore
+	for scanKey := range s.readCompressedFiles {
+		if !slices.ContainsFunc(files, func(file *tailer.File) bool { return file.GetScanKey() == scanKey }) {
+			delete(s.readCompressedFiles, scanKey)
+		}
+	}
+
 	// Check how many file handles the Agent process has open and log a warning if the process is coming close to the OS file limit
 	fileStats, err := procfilestats.GetProcessFileStats()
 	if err == nil {
@@ -282,7 +297,7 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 			return
 		}
 
-		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) {
+		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) || s.isCompressedFileRead(file) {
 			continue
 		}
 		if tailer, isTailed := s.tailers.Get(file.GetScanKey()); isTailed {
@@ -356,6 +371,31 @@ func (s *Launcher) handleTailingModeChange(tailerID string, currentTailingMode c
 	return currentTailingMode
 }
 
+// setCompressedFileRead records that a compressed file has been read up to its end,
+// its tailer stops at the end of the file since an archive doesn't grow.
+func (s *Launcher) setCompressedFileRead(file *tailer.File) {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return
+	}
+	s.readCompressedFiles[file.GetScanKey()] = info
+}
+
+// isCompressedFileRead returns true if the compressed file has already been read up to
+// its end, and hasn't been replaced since, e.g. by a log rotation reusing its name.
+func (s *Launcher) isCompressedFileRead(file *tailer.File) bool {
+	read, exists := s.readCompressedFiles[file.GetScanKey()]
+	if !exists {
+		return false
+	}
+	info, err := os.Stat(file.Path)
+	if err != nil || !os.SameFile(read, info) || read.Size() != info.Size() || !read.ModTime().Equal(info.ModTime()) {
+		delete(s.readCompressedFiles, file.GetScanKey())
+		return false
+	}
+	return true
+}
+
 // stopTailer stops the tailer
 func (s *Launcher) stopTailer(tailer *tailer.Tailer) {
 	go tailer.Stop()
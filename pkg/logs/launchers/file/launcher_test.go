@@ -8,6 +8,7 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -758,3 +759,53 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
+
+func TestLauncherReadsCompressedFileOnce(t *testing.T) {
+	cfg := configmock.New(t)
+	testDir := t.TempDir()
+	fakeTagger := taggerfxmock.SetupFakeTagger(t)
+
+	sleepDuration := 20 * time.Millisecond
+	fc := flareController.NewFlareController()
+	launcher := NewLauncher(2, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditor.NewRegistry()
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: testDir + "/*.gz"})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.Clear()
+	status.InitStatus(cfg, util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+
+	path := testDir + "/app.log.1.gz"
+	writeArchive := func(content string) {
+		f, err := os.Create(path + ".tmp")
+		assert.Nil(t, err)
+		w := gzip.NewWriter(f)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		assert.Nil(t, f.Close())
+		assert.Nil(t, os.Rename(path+".tmp", path))
+	}
+	writeArchive("hello\n")
+
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	assert.Equal(t, "hello", string((<-outputChan).GetContent()))
+	tailer, _ := launcher.tailers.Get(path)
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+
+	// the finished tailer is stopped and the archive isn't read again
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+
+	// a new archive with the same name is read
+	writeArchive("world\n")
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	assert.Equal(t, "world", string((<-outputChan).GetContent()))
+	launcher.cleanup()
+}
@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/logs/tailers/syslog"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+// A SyslogListener receives syslog messages over UDP, TCP or TCP with TLS and
+// delegates their parsing to syslog tailers, one per TCP connection.
+type SyslogListener struct {
+	pipelineProvider pipeline.Provider
+	source           *sources.LogSource
+	idleTimeout      time.Duration
+	maxFrameSize     int
+	listener         net.Listener
+	tailers          []*syslog.Tailer
+	mu               sync.Mutex
+	stop             chan struct{}
+}
+
+// NewSyslogListener returns an initialized SyslogListener
+func NewSyslogListener(pipelineProvider pipeline.Provider, source *sources.LogSource) *SyslogListener {
+	var idleTimeout time.Duration
+	if source.Config.IdleTimeout != "" {
+		var err error
+		idleTimeout, err = time.ParseDuration(source.Config.IdleTimeout)
+		if err != nil {
+			log.Errorf("Error parsing log's idle_timeout as a duration: %s", err)
+			idleTimeout = 0
+		}
+	}
+
+	return &SyslogListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		idleTimeout:      idleTimeout,
+		maxFrameSize:     config.MaxMessageSizeBytes(pkgconfigsetup.Datadog()),
+		stop:             make(chan struct{}, 1),
+	}
+}
+
+// Start starts listening for syslog messages.
+func (l *SyslogListener) Start() {
+	log.Infof("Starting syslog %s forwarder on port %d", l.protocol(), l.source.Config.Port)
+	var err error
+	if l.protocol() == config.TCPType {
+		err = l.startListener()
+		if err == nil {
+			go l.run()
+		}
+	} else {
+		err = l.startUDPTailer()
+	}
+	if err != nil {
+		log.Errorf("Can't start syslog forwarder on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
+
+// Stop stops accepting new connections and all the active tailers.
+func (l *SyslogListener) Stop() {
+	log.Infof("Stopping syslog forwarder on port %d", l.source.Config.Port)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stop <- struct{}{}
+	if l.listener != nil {
+		l.listener.Close()
+	}
+	stopper := startstop.NewParallelStopper()
+	for _, tailer := range l.tailers {
+		stopper.Add(tailer)
+	}
+	stopper.Stop()
+	l.tailers = nil
+}
+
+func (l *SyslogListener) protocol() string {
+	if l.source.Config.Protocol == "" {
+		return config.UDPType
+	}
+	return l.source.Config.Protocol
+}
+
+// run accepts new TCP connections and create a dedicated tailer for each.
+func (l *SyslogListener) run() {
+	for {
+		select {
+		case <-l.stop:
+			// stop accepting new connections.
+			return
+		default:
+			conn, err := l.listener.Accept()
+			switch {
+			case err != nil && isClosedConnError(err):
+				return
+			case err != nil:
+				// an error occurred, restart the listener.
+				log.Warnf("Can't listen on port %d, restarting a listener: %v", l.source.Config.Port, err)
+				l.listener.Close()
+				if err := l.startListener(); err != nil {
+					log.Errorf("Can't restart listener on port %d: %v", l.source.Config.Port, err)
+					l.source.Status.Error(err)
+					return
+				}
+				l.source.Status.Success()
+			default:
+				l.startTailer(conn)
+				l.source.Status.Success()
+			}
+		}
+	}
+}
+
+// startListener starts a new TCP listener, with TLS when a certificate is configured.
+func (l *SyslogListener) startListener() error {
+	address := fmt.Sprintf(":%d", l.source.Config.Port)
+	if l.source.Config.TLSCertFile == "" {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		l.listener = listener
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.source.Config.TLSCertFile, l.source.Config.TLSKeyFile)
+	if err != nil {
+		return fmt.Errorf("could not load TLS certificate: %v", err)
+	}
+	listener, err := tls.Listen("tcp", address, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return err
+	}
+	l.listener = listener
+	return nil
+}
+
+// startUDPTailer starts the tailer reading the datagrams sent to the port.
+func (l *SyslogListener) startUDPTailer() error {
+	conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tailer := syslog.NewTailer(l.source, conn.(net.Conn), l.pipelineProvider.NextPipelineChan(), 0, l.maxFrameSize)
+	l.tailers = append(l.tailers, tailer)
+	tailer.Start()
+	return nil
+}
+
+// startTailer creates and starts a new tailer that reads from the connection,
+// the tailer is forgotten once the connection is closed.
+func (l *SyslogListener) startTailer(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tailer := syslog.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.idleTimeout, l.maxFrameSize)
+	l.tailers = append(l.tailers, tailer)
+	tailer.Start()
+	go func() {
+		<-tailer.Done()
+		l.removeTailer(tailer)
+	}()
+}
+
+// removeTailer removes a finished tailer from the active ones.
+func (l *SyslogListener) removeTailer(tailer *syslog.Tailer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.Index(l.tailers, tailer); i >= 0 {
+		l.tailers = slices.Delete(l.tailers, i, i+1)
+	}
+}
@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestSyslogTCPShouldReceiveFramedMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort, Protocol: config.TCPType}))
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	multiline := "<14>1 - host app - - - first\nsecond"
+	fmt.Fprintf(conn, "%d %s", len(multiline), multiline)
+	fmt.Fprint(conn, "<12>Oct 11 22:14:15 host app[12]: warning\n")
+
+	var msg *message.Message
+	msg = <-msgChan
+	assert.Equal(t, "first\nsecond", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	msg = <-msgChan
+	assert.Equal(t, "warning", string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+}
+
+func TestSyslogTailerIsRemovedWhenConnectionIsClosed(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort, Protocol: config.TCPType}))
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	fmt.Fprint(conn, "<14>1 - - - - - - hello\n")
+	<-msgChan
+	conn.Close()
+
+	assert.Eventually(t, func() bool {
+		listener.mu.Lock()
+		defer listener.mu.Unlock()
+		return len(listener.tailers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -9,6 +9,7 @@ package message
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
@@ -41,6 +42,11 @@ type Payload struct {
 	Encoding string
 	// The size of the unencoded payload
 	UnencodedSize int
+
+	// the decoding of the payload, shared by the destinations sending it to other intakes
+	decodeOnce sync.Once
+	decoded    interface{}
+	decodeErr  error
 }
 
 func NewPayload(messages []*Message, encoded []byte, encoding string, unencodedSize int) *Payload {
@@ -63,6 +69,15 @@ func (m *Payload) Count() int64 {
 	return int64(len(m.MessageMetas))
 }
 
+// DecodeOnce returns the result of decode on the payload. decode is only called once, so
+// that the destinations sending the same payload share its decoding.
+func (m *Payload) DecodeOnce(decode func(*Payload) (interface{}, error)) (interface{}, error) {
+	m.decodeOnce.Do(func() {
+		m.decoded, m.decodeErr = decode(m)
+	})
+	return m.decoded, m.decodeErr
+}
+
 // Size returns the size of the message.
 func (m *Payload) Size() int64 {
 	var size int64 = 0
@@ -90,10 +105,15 @@ type MessageMetadata struct {
 	RawDataLen int
 	// Tags added on processing
 	ProcessingTags []string
+	// Timestamp promoted from an attribute of the message by a field rule,
+	// used by the encoders instead of the time of the encoding when set.
+	PromotedTimestamp time.Time
 	// Extra information from the parsers
 	ParsingExtra
 	// Extra information for Serverless Logs messages
 	ServerlessExtra
+	// Content of the message at each stage of the pipeline, only set when its source is tapped
+	Tap *TapTrace
 }
 
 // MessageContent contains the message and possibly the tailer internal representation
@@ -201,6 +221,31 @@ func (m *MessageContent) SetEncoded(content []byte) {
 	m.State = StateEncoded
 }
 
+// StructuredAttributes returns the attributes of a structured message.
+// An unstructured message is first converted to a BasicStructuredContent
+// storing its content in the "message" key.
+// It returns nil if the message has already been rendered or if its
+// structured content isn't a BasicStructuredContent.
+func (m *MessageContent) StructuredAttributes() map[string]interface{} {
+	switch m.State {
+	case StateUnstructured:
+		m.structuredContent = &BasicStructuredContent{
+			Data: map[string]interface{}{"message": string(m.content)},
+		}
+		m.content = nil
+		m.State = StateStructured
+	case StateStructured:
+		break
+	default:
+		return nil
+	}
+
+	if basic, ok := m.structuredContent.(*BasicStructuredContent); ok {
+		return basic.Data
+	}
+	return nil
+}
+
 // ParsingExtra ships extra information parsers want to make available
 // to the rest of the pipeline.
 // E.g. Timestamp is used by the docker parsers to transmit a tailing offset.
@@ -211,6 +256,15 @@ type ParsingExtra struct {
 	IsTruncated bool
 	IsMultiLine bool
 	Tags        []string
+	// Set when consecutive repetitions of the message have been squashed into it.
+	Repeated *RepeatInfo
+}
+
+// RepeatInfo describes the repetitions of a message squashed by the decoder.
+type RepeatInfo struct {
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
 }
 
 // ServerlessExtra ships extra information from logs processing in serverless envs.
@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package message
+
+// TapTrace records the content of a message at each stage of the pipeline,
+// it is only set on the messages of the sources being tapped (e.g. by the
+// `tap-logs` command).
+type TapTrace struct {
+	// Frames are the raw frames read by the tailer which compose the message.
+	Frames [][]byte
+	// Decoded is the content sent by the decoder, after the parsing and the
+	// multiline aggregation.
+	Decoded []byte
+	// Processed is the rendered content, after the processing rules and the
+	// SDS scanning.
+	Processed []byte
+	// Encoded is the message encoded in its final format.
+	Encoded []byte
+	// Dropped is true if the message has been dropped by a processing rule.
+	Dropped bool
+}
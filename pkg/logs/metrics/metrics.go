@@ -82,6 +82,19 @@ var (
 	// TlmLogsDiscardedFromSDSBuffer how many messages were dropped when waiting for an SDS configuration because the buffer is full
 	TlmLogsDiscardedFromSDSBuffer = telemetry.NewCounter("logs", "sds__dropped_from_buffer", nil, "Count of messages dropped from the buffer while waiting for an SDS configuration")
 
+	// TlmLogMetricsGenerated counts the metric points generated from logs by the generate_metric processing rules
+	TlmLogMetricsGenerated = telemetry.NewCounter("logs", "log_metrics_generated", []string{"rule"}, "Count of metric points generated from logs")
+	// TlmLogMetricsDropped counts the matching logs for which no metric value could be extracted
+	TlmLogMetricsDropped = telemetry.NewCounter("logs", "log_metrics_dropped", []string{"rule"}, "Count of matching logs without a valid metric value")
+
+	// LogsSampledOut is the total number of logs dropped by the sample and rate_limit processing rules
+	LogsSampledOut = expvar.Int{}
+	// TlmLogsSampledOut counts the logs dropped by the sample and rate_limit processing rules
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out", []string{"rule", "type"}, "Count of logs dropped by the sample and rate_limit processing rules")
+
+	// TlmLogsSquashed counts the repeated logs squashed by the decoders
+	TlmLogsSquashed = telemetry.NewCounter("logs", "squashed", nil, "Count of repeated logs squashed into a previous log")
+
 	// TlmUtilizationRatio is the utilization ratio of a component.
 	// Utilization ratio is calculated as the ratio of time spent in use to the total time.
 	// This metric is internally sampled and exposed as an ewma in order to produce a useable value.
@@ -112,5 +125,6 @@ func init() {
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
 	LogsExpvars.Set("BytesMissed", &BytesMissed)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
 }
@@ -9,6 +9,7 @@ package mock
 import (
 	"context"
 
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
@@ -44,6 +45,10 @@ func (p *mockProvider) StopSDSProcessing() error {
 	return nil
 }
 
+// ReconfigureProcessingRules does nothing
+func (p *mockProvider) ReconfigureProcessingRules(_ config.ProcessingRulesSource, _ *config.ProcessingRulesUpdate) {
+}
+
 func (p *mockProvider) GetOutputChan() chan *message.Message {
 	return nil
 }
@@ -34,6 +34,7 @@ type Pipeline struct {
 // NewPipeline returns a new Pipeline
 func NewPipeline(
 	processingRules []*config.ProcessingRule,
+	metricSender processor.MetricSender,
 	endpoints *config.Endpoints,
 	senderImpl sender.PipelineComponent,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
@@ -58,7 +59,7 @@ func NewPipeline(
 	strategy := getStrategy(strategyInput, senderImpl.In(), flushChan, endpoints, serverlessMeta, senderImpl.PipelineMonitor(), compression)
 
 	inputChan := make(chan *message.Message, pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size"))
-	processor := processor.New(cfg, inputChan, strategyInput, processingRules,
+	processor := processor.New(cfg, inputChan, strategyInput, processingRules, metricSender,
 		encoder, diagnosticMessageReceiver, hostname, senderImpl.PipelineMonitor())
 
 	return &Pipeline{
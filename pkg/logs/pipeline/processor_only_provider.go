@@ -8,6 +8,7 @@ package pipeline
 import (
 	"context"
 	"strconv"
+	"sync"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -25,6 +26,9 @@ type processorOnlyProvider struct {
 	inputChan       chan *message.Message
 	outputChan      chan *message.Message
 	pipelineMonitor *metrics.TelemetryPipelineMonitor
+
+	mu           sync.Mutex
+	rulesUpdates config.ProcessingRulesUpdates
 }
 
 // NewProcessorOnlyProvider is used by the logs check subcommand as the feature does not require the functionalities of the log pipeline other then the processor.
@@ -35,7 +39,7 @@ func NewProcessorOnlyProvider(diagnosticMessageReceiver diagnostic.MessageReceiv
 	inputChan := make(chan *message.Message, chanSize)
 	pipelineID := 0
 	pipelineMonitor := metrics.NewTelemetryPipelineMonitor(strconv.Itoa(pipelineID))
-	processor := processor.New(cfg, inputChan, outputChan, processingRules,
+	processor := processor.New(cfg, inputChan, outputChan, processingRules, nil,
 		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor)
 
 	p := &processorOnlyProvider{
@@ -68,6 +72,18 @@ func (p *processorOnlyProvider) StopSDSProcessing() error {
 	return nil
 }
 
+func (p *processorOnlyProvider) ReconfigureProcessingRules(source config.ProcessingRulesSource, update *config.ProcessingRulesUpdate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	order := processor.RulesReconfigureOrder{
+		Update:       p.rulesUpdates.Set(source, update),
+		ResponseChan: make(chan struct{}),
+	}
+	p.processor.RulesReconfigChan <- order
+	<-order.ResponseChan
+}
+
 func (p *processorOnlyProvider) NextPipelineChan() chan *message.Message {
 	return p.inputChan
 }
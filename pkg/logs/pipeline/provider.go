@@ -7,6 +7,7 @@ package pipeline
 
 import (
 	"context"
+	"sync"
 
 	"github.com/hashicorp/go-multierror"
 	"go.uber.org/atomic"
@@ -22,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	httpsender "github.com/DataDog/datadog-agent/pkg/logs/sender/http"
@@ -52,6 +54,7 @@ type Provider interface {
 	ReconfigureSDSStandardRules(standardRules []byte) (bool, error)
 	ReconfigureSDSAgentConfig(config []byte) (bool, error)
 	StopSDSProcessing() error
+	ReconfigureProcessingRules(source config.ProcessingRulesSource, update *config.ProcessingRulesUpdate)
 	NextPipelineChan() chan *message.Message
 	GetOutputChan() chan *message.Message
 	NextPipelineChanWithMonitor() (chan *message.Message, metrics.PipelineMonitor)
@@ -64,12 +67,16 @@ type provider struct {
 	numberOfPipelines         int
 	diagnosticMessageReceiver diagnostic.MessageReceiver
 	processingRules           []*config.ProcessingRule
+	metricSender              processor.MetricSender
 	endpoints                 *config.Endpoints
 	sender                    sender.PipelineComponent
 
+	// mu protects the pipelines while they are started, stopped or reconfigured
+	mu                   sync.Mutex
 	pipelines            []*Pipeline
 	currentPipelineIndex *atomic.Uint32
 	serverlessMeta       sender.ServerlessMeta
+	rulesUpdates         config.ProcessingRulesUpdates
 
 	hostname    hostnameinterface.Component
 	cfg         pkgconfigmodel.Reader
@@ -82,6 +89,7 @@ func NewProvider(
 	auditor auditor.Auditor,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	processingRules []*config.ProcessingRule,
+	metricSender processor.MetricSender,
 	endpoints *config.Endpoints,
 	destinationsContext *client.DestinationsContext,
 	status statusinterface.Status,
@@ -104,6 +112,7 @@ func NewProvider(
 		numberOfPipelines,
 		diagnosticMessageReceiver,
 		processingRules,
+		metricSender,
 		endpoints,
 		hostname,
 		cfg,
@@ -210,6 +219,7 @@ func newProvider(
 	numberOfPipelines int,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	processingRules []*config.ProcessingRule,
+	metricSender processor.MetricSender,
 	endpoints *config.Endpoints,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
@@ -221,6 +231,7 @@ func newProvider(
 		numberOfPipelines:         numberOfPipelines,
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
 		processingRules:           processingRules,
+		metricSender:              metricSender,
 		endpoints:                 endpoints,
 		sender:                    senderImpl,
 		pipelines:                 []*Pipeline{},
@@ -234,11 +245,15 @@ func newProvider(
 
 // Start initializes the pipelines
 func (p *provider) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.sender.Start()
 
 	for i := 0; i < p.numberOfPipelines; i++ {
 		pipeline := NewPipeline(
 			p.processingRules,
+			p.metricSender,
 			p.endpoints,
 			p.sender,
 			p.diagnosticMessageReceiver,
@@ -255,6 +270,9 @@ func (p *provider) Start() {
 // Stop stops all pipelines in parallel,
 // this call blocks until all pipelines are stopped
 func (p *provider) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	stopper := startstop.NewParallelStopper()
 
 	// close the pipelines
@@ -269,6 +287,9 @@ func (p *provider) Stop() {
 
 // return true if all SDS scanners are active.
 func (p *provider) reconfigureSDS(config []byte, orderType sds.ReconfigureOrderType) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	var responses []chan sds.ReconfigureResponse
 
 	// send a reconfiguration order to every running pipeline
@@ -318,6 +339,32 @@ func (p *provider) ReconfigureSDSAgentConfig(config []byte) (bool, error) {
 	return p.reconfigureSDS(config, sds.AgentConfig)
 }
 
+// ReconfigureProcessingRules replaces the processing rules update of a source and
+// applies the updates of all the sources to every running pipeline, the configured
+// processing rules are restored when no source has an update.
+// The messages already processed aren't affected by the new rules.
+func (p *provider) ReconfigureProcessingRules(source config.ProcessingRulesSource, update *config.ProcessingRulesUpdate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	update = p.rulesUpdates.Set(source, update)
+	var responses []chan struct{}
+
+	for _, pipeline := range p.pipelines {
+		order := processor.RulesReconfigureOrder{
+			Update:       update,
+			ResponseChan: make(chan struct{}),
+		}
+		responses = append(responses, order.ResponseChan)
+		pipeline.processor.RulesReconfigChan <- order
+	}
+
+	for _, response := range responses {
+		<-response
+		close(response)
+	}
+}
+
 // StopSDSProcessing reconfigures the pipeline removing the SDS scanning
 // from the processing steps.
 func (p *provider) StopSDSProcessing() error {
@@ -226,6 +226,7 @@ func TestProviderConfigurations(t *testing.T) {
 				auditor,
 				diagnosticMessageReceiver,
 				nil, // processing rules
+				nil, // metric sender
 				endpoints,
 				destinationsContext,
 				status,
@@ -295,6 +296,7 @@ func TestPipelineChannelDistribution(t *testing.T) {
 				auditor,
 				diagnosticMessageReceiver,
 				nil, // processing rules
+				nil, // metric sender
 				endpoints,
 				destinationsContext,
 				status,
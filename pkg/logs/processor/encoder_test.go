@@ -222,6 +222,27 @@ func TestJsonEncoder(t *testing.T) {
 	assert.NotEmpty(t, log.Timestamp)
 }
 
+func TestEncodersPromotedTimestamp(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	ts := time.Unix(1700000000, 0).UTC()
+
+	msg := newMessage([]byte("message"), source, "")
+	msg.State = message.StateRendered
+	msg.PromotedTimestamp = ts
+	assert.NoError(t, ProtoEncoder.Encode(msg, "unknown"))
+	log := &pb.Log{}
+	assert.NoError(t, log.Unmarshal(msg.GetContent()))
+	assert.Equal(t, ts.UnixNano(), log.Timestamp)
+
+	msg = newMessage([]byte("message"), source, "")
+	msg.State = message.StateRendered
+	msg.PromotedTimestamp = ts
+	assert.NoError(t, JSONEncoder.Encode(msg, "unknown"))
+	payload := &jsonPayload{}
+	assert.NoError(t, json.Unmarshal(msg.GetContent(), payload))
+	assert.Equal(t, ts.UnixNano()/nanoToMillis, payload.Timestamp)
+}
+
 func TestEncoderToValidUTF8(t *testing.T) {
 	// valid utf-8
 	assert.Equal(t, "", toValidUtf8(nil))
@@ -0,0 +1,372 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	messageField = "message"
+
+	defaultKeyValueSeparator = "="
+	defaultFieldSeparator    = " "
+)
+
+// applyFieldRule extracts attributes from the content of the message and/or
+// remaps the attributes of the message according to the given rule.
+// The message is converted to a structured message the first time attributes
+// are extracted from it, its original content being kept in the "message"
+// attribute unless a "message" attribute has been extracted.
+// It returns the content of the message once the rule has been applied.
+func applyFieldRule(rule *config.ProcessingRule, msg *message.Message, content []byte) []byte {
+	var parsed map[string]interface{}
+	switch rule.Type {
+	case config.ParseJSONRule:
+		parsed = parseJSON(content)
+	case config.ParseLogfmt:
+		parsed = parseKeyValue(content, defaultKeyValueSeparator, defaultFieldSeparator)
+	case config.ParseKeyValue:
+		parsed = parseKeyValue(content, orDefault(rule.KeyValueSeparator, defaultKeyValueSeparator), orDefault(rule.FieldSeparator, defaultFieldSeparator))
+	case config.ParseRegex:
+		parsed = parseRegex(rule.Regex, content)
+	case config.RemapFields:
+		// remapping only makes sense on a message which already has attributes
+		if msg.State != message.StateStructured {
+			return content
+		}
+	}
+
+	if rule.Type != config.RemapFields && len(parsed) == 0 {
+		return content
+	}
+
+	// make sure the latest content is stored before the message switches to
+	// its structured representation
+	msg.SetContent(content)
+	attrs := msg.StructuredAttributes()
+	if attrs == nil {
+		return content
+	}
+
+	for key, value := range parsed {
+		attrs[key] = value
+	}
+
+	remapFields(rule, attrs)
+	promoteFields(rule.Promote, msg, attrs)
+	for _, key := range rule.Drop {
+		if key != messageField {
+			delete(attrs, key)
+		}
+	}
+
+	// the "message" attribute must always be a string
+	switch value := attrs[messageField].(type) {
+	case string:
+		return []byte(value)
+	case nil:
+		attrs[messageField] = ""
+		return []byte{}
+	default:
+		str := stringify(value)
+		attrs[messageField] = str
+		return []byte(str)
+	}
+}
+
+// parseJSON returns the attributes of a JSON object, nil if the content
+// isn't a JSON object.
+func parseJSON(content []byte) map[string]interface{} {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(trimmed, &attrs); err != nil {
+		return nil
+	}
+	return attrs
+}
+
+// parseKeyValue extracts the key/value pairs of the content, double-quoted
+// values may contain the field separator and escaped quotes (logfmt).
+// Tokens without a key/value separator are ignored.
+func parseKeyValue(content []byte, kvSeparator, fieldSeparator string) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	rest := string(content)
+
+	for len(rest) > 0 {
+		rest = strings.TrimLeft(rest, fieldSeparator)
+		if rest == "" {
+			break
+		}
+
+		// key
+		idx := strings.Index(rest, kvSeparator)
+		end := strings.Index(rest, fieldSeparator)
+		if idx <= 0 || (end >= 0 && end < idx) {
+			// not a key/value pair, skip the token
+			if end < 0 {
+				break
+			}
+			rest = rest[end:]
+			continue
+		}
+		key := rest[:idx]
+		rest = rest[idx+len(kvSeparator):]
+
+		// value
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest = readQuoted(rest)
+		} else if end = strings.Index(rest, fieldSeparator); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		attrs[key] = value
+	}
+
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// readQuoted reads a double-quoted value at the beginning of s and returns
+// the unquoted value with the remainder of s.
+func readQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	// unterminated quote, use everything up to the end of the content
+	return b.String(), ""
+}
+
+// parseRegex returns the named capture groups of the first match of re.
+func parseRegex(re *regexp.Regexp, content []byte) map[string]interface{} {
+	match := re.FindSubmatch(content)
+	if match == nil {
+		return nil
+	}
+	attrs := make(map[string]interface{})
+	for i, name := range re.SubexpNames() {
+		if name == "" || match[i] == nil {
+			continue
+		}
+		attrs[name] = string(match[i])
+	}
+	return attrs
+}
+
+// remapFields renames and coerces the attributes.
+func remapFields(rule *config.ProcessingRule, attrs map[string]interface{}) {
+	for from, to := range rule.Rename {
+		if value, exists := attrs[from]; exists {
+			delete(attrs, from)
+			attrs[to] = value
+		}
+	}
+
+	for key, kind := range rule.Coerce {
+		value, exists := attrs[key]
+		if !exists {
+			continue
+		}
+		if coerced, ok := coerce(value, kind); ok {
+			attrs[key] = coerced
+		}
+	}
+}
+
+// coerce converts the value to the given kind, it returns false
+// if the conversion isn't possible.
+func coerce(value interface{}, kind string) (interface{}, bool) {
+	switch kind {
+	case config.CoerceString:
+		return stringify(value), true
+	case config.CoerceInt:
+		switch v := value.(type) {
+		case float64:
+			return int64(v), true
+		case bool:
+			if v {
+				return int64(1), true
+			}
+			return int64(0), true
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i, true
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return int64(f), true
+			}
+		}
+	case config.CoerceFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, true
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, true
+			}
+		}
+	case config.CoerceBool:
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case float64:
+			return v != 0, true
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// promoteFields copies the configured attributes to the reserved
+// fields of the message.
+func promoteFields(promote config.FieldPromotion, msg *message.Message, attrs map[string]interface{}) {
+	if value, exists := attrs[promote.Status]; exists && promote.Status != "" {
+		if status := normalizeStatus(stringify(value)); status != "" {
+			msg.Status = status
+		}
+	}
+
+	if value, exists := attrs[promote.Service]; exists && promote.Service != "" && msg.Origin != nil {
+		msg.Origin.SetService(stringify(value))
+	}
+
+	if value, exists := attrs[promote.Timestamp]; exists && promote.Timestamp != "" {
+		if ts, ok := parseTimestamp(value, promote.TimestampLayout); ok {
+			msg.PromotedTimestamp = ts.UTC()
+		}
+	}
+
+	for _, key := range promote.Tags {
+		if value, exists := attrs[key]; exists {
+			msg.ProcessingTags = append(msg.ProcessingTags, key+":"+stringify(value))
+		}
+	}
+}
+
+// annotateRepeated adds the repetitions squashed by the decoder to the
+// attributes of the message.
+func annotateRepeated(msg *message.Message) {
+	attrs := msg.StructuredAttributes()
+	if attrs == nil {
+		return
+	}
+	attrs["repeat_count"] = int64(msg.ParsingExtra.Repeated.Count)
+	attrs["first_seen"] = msg.ParsingExtra.Repeated.FirstSeen.UTC().Format(time.RFC3339Nano)
+	attrs["last_seen"] = msg.ParsingExtra.Repeated.LastSeen.UTC().Format(time.RFC3339Nano)
+}
+
+// normalizeStatus maps the common severity names to a log status,
+// it returns an empty string for an unknown severity.
+func normalizeStatus(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "emerg", "emergency", "fatal", "panic":
+		return message.StatusEmergency
+	case "alert":
+		return message.StatusAlert
+	case "crit", "critical":
+		return message.StatusCritical
+	case "err", "error":
+		return message.StatusError
+	case "warn", "warning":
+		return message.StatusWarning
+	case "notice":
+		return message.StatusNotice
+	case "info", "information", "informational":
+		return message.StatusInfo
+	case "debug", "trace":
+		return message.StatusDebug
+	}
+	return ""
+}
+
+// parseTimestamp parses a timestamp attribute, numbers are considered as
+// epoch in seconds, or in milliseconds when too large to be seconds.
+// Strings are parsed with the given layout, RFC3339 by default.
+func parseTimestamp(value interface{}, layout string) (time.Time, bool) {
+	var epoch float64
+	switch v := value.(type) {
+	case float64:
+		epoch = v
+	case int64:
+		epoch = float64(v)
+	case string:
+		if layout == "" {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				epoch = f
+				break
+			}
+			layout = time.RFC3339Nano
+		}
+		ts, err := time.Parse(layout, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return ts, true
+	default:
+		return time.Time{}, false
+	}
+
+	if epoch <= 0 {
+		return time.Time{}, false
+	}
+	// 1e11 seconds is in year 5138, larger values are milliseconds
+	if epoch > 1e11 {
+		return time.UnixMilli(int64(epoch)), true
+	}
+	sec := int64(epoch)
+	return time.Unix(sec, int64((epoch-float64(sec))*1e9)), true
+}
+
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func renderAttributes(t *testing.T, msg *message.Message) map[string]interface{} {
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	var attrs map[string]interface{}
+	require.NoError(t, json.Unmarshal(rendered, &attrs))
+	return attrs
+}
+
+func TestParseJSONRule(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:   config.ParseJSONRule,
+		Name:   "json",
+		Rename: map[string]string{"msg": "message"},
+		Drop:   []string{"password"},
+		Coerce: map[string]string{"duration": config.CoerceFloat},
+		Promote: config.FieldPromotion{
+			Status:    "level",
+			Service:   "app",
+			Timestamp: "ts",
+			Tags:      []string{"env"},
+		},
+	}
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte(`{"msg":"request done","level":"ERROR","app":"web","ts":1700000000,"env":"prod","duration":"1.5","password":"secret"}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+
+	assert.Equal(t, "request done", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "web", msg.Origin.Service())
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), msg.PromotedTimestamp)
+	assert.Equal(t, []string{"env:prod"}, msg.ProcessingTags)
+
+	attrs := renderAttributes(t, msg)
+	assert.Equal(t, 1.5, attrs["duration"])
+	assert.NotContains(t, attrs, "password")
+	assert.NotContains(t, attrs, "msg")
+
+	// not a JSON object, the message is left untouched
+	msg = newMessage([]byte("plain text"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+	assert.Equal(t, "plain text", string(msg.GetContent()))
+}
+
+func TestParseLogfmtRule(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:    config.ParseLogfmt,
+		Name:    "logfmt",
+		Coerce:  map[string]string{"status_code": config.CoerceInt},
+		Promote: config.FieldPromotion{Status: "level"},
+	}
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte(`level=warn msg="slow \"query\" detected" status_code=500 orphan`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	attrs := renderAttributes(t, msg)
+	assert.Equal(t, `slow "query" detected`, attrs["msg"])
+	assert.Equal(t, float64(500), attrs["status_code"])
+	assert.Equal(t, `level=warn msg="slow \"query\" detected" status_code=500 orphan`, attrs["message"])
+}
+
+func TestParseKeyValueRule(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:              config.ParseKeyValue,
+		Name:              "kv",
+		KeyValueSeparator: ":",
+		FieldSeparator:    ";",
+		Pattern:           "^user",
+		Regex:             regexp.MustCompile("^user"),
+	}
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte("user:john doe;action:login"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	attrs := renderAttributes(t, msg)
+	assert.Equal(t, "john doe", attrs["user"])
+	assert.Equal(t, "login", attrs["action"])
+
+	// not matching the pattern
+	msg = newMessage([]byte("action:login"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+}
+
+func TestParseRegexRule(t *testing.T) {
+	pattern := `^(?P<client>\S+) (?P<method>[A-Z]+) (?P<path>\S+) (?P<message>.*)$`
+	rules := []*config.ProcessingRule{
+		{
+			Type:    config.ParseRegex,
+			Name:    "access",
+			Pattern: pattern,
+			Regex:   regexp.MustCompile(pattern),
+			Promote: config.FieldPromotion{Tags: []string{"method"}},
+		},
+		newProcessingRule(config.MaskSequences, "[masked]", "secret"),
+	}
+	p := &Processor{processingRules: rules}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte("10.0.0.1 GET /index.html served secret page"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+
+	// following rules are applied on the extracted message
+	assert.Equal(t, "served [masked] page", string(msg.GetContent()))
+	assert.Equal(t, []string{"method:GET"}, msg.ProcessingTags)
+	attrs := renderAttributes(t, msg)
+	assert.Equal(t, "10.0.0.1", attrs["client"])
+	assert.Equal(t, "/index.html", attrs["path"])
+}
+
+func TestRemapFieldsRule(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:   config.RemapFields,
+		Name:   "remap",
+		Rename: map[string]string{"SYSLOG_IDENTIFIER": "app"},
+		Drop:   []string{"_PID"},
+	}
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	msg := newStructuredMessage([]byte("hello"), source, "")
+	attrs := msg.StructuredAttributes()
+	attrs["SYSLOG_IDENTIFIER"] = "sshd"
+	attrs["_PID"] = "42"
+
+	assert.True(t, p.applyRedactingRules(msg))
+	rendered := renderAttributes(t, msg)
+	assert.Equal(t, map[string]interface{}{"message": "hello", "app": "sshd"}, rendered)
+
+	// unstructured messages are not converted
+	msg = newMessage([]byte("hello"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, ok := parseTimestamp(float64(1700000000123), "")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1700000000123), ts.UnixMilli())
+
+	ts, ok = parseTimestamp("2024-01-02T03:04:05Z", "")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), ts.UTC())
+
+	ts, ok = parseTimestamp("02/01/2024 03:04", "02/01/2006 15:04")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC), ts.UTC())
+
+	_, ok = parseTimestamp("yesterday", "")
+	assert.False(t, ok)
+}
+
+func TestAnnotateRepeated(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("connection refused"), source, "")
+	first := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	msg.ParsingExtra.Repeated = &message.RepeatInfo{Count: 3, FirstSeen: first, LastSeen: first.Add(2 * time.Second)}
+
+	annotateRepeated(msg)
+
+	assert.Equal(t, "connection refused", string(msg.GetContent()))
+	attrs := renderAttributes(t, msg)
+	assert.Equal(t, "connection refused", attrs["message"])
+	assert.Equal(t, float64(3), attrs["repeat_count"])
+	assert.Equal(t, "2024-03-01T10:00:00Z", attrs["first_seen"])
+	assert.Equal(t, "2024-03-01T10:00:02Z", attrs["last_seen"])
+}
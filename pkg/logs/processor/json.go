@@ -44,6 +44,8 @@ func (j *jsonEncoder) Encode(msg *message.Message, hostname string) error {
 	ts := time.Now().UTC()
 	if !msg.ServerlessExtra.Timestamp.IsZero() {
 		ts = msg.ServerlessExtra.Timestamp
+	} else if !msg.PromotedTimestamp.IsZero() {
+		ts = msg.PromotedTimestamp
 	}
 
 	encoded, err := json.Marshal(jsonPayload{
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// MetricSender submits the metrics generated from logs by the
+// generate_metric processing rules, it is implemented by the
+// aggregator senders.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Gauge(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+}
+
+// generateMetric submits the metric of a generate_metric rule for a log
+// matching the rule pattern.
+func (p *Processor) generateMetric(rule *config.ProcessingRule, msg *message.Message, content []byte) {
+	if p.metricSender == nil {
+		return
+	}
+
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return
+	}
+
+	value := 1.0
+	if rule.Metric.ValueField != "" {
+		var ok bool
+		if value, ok = logMetricValue(rule, msg, match); !ok {
+			metrics.TlmLogMetricsDropped.Inc(rule.Name)
+			return
+		}
+	}
+
+	tags := logMetricTags(rule.Metric.Tags, msg)
+	switch rule.Metric.Type {
+	case config.LogMetricGauge:
+		p.metricSender.Gauge(rule.Metric.Name, value, msg.Hostname, tags)
+	case config.LogMetricDistribution:
+		p.metricSender.Distribution(rule.Metric.Name, value, msg.Hostname, tags)
+	default:
+		p.metricSender.Count(rule.Metric.Name, value, msg.Hostname, tags)
+	}
+	metrics.TlmLogMetricsGenerated.Inc(rule.Name)
+}
+
+// logMetricValue returns the numeric value of the metric, read from the
+// named capture groups of the pattern or from the message attributes.
+func logMetricValue(rule *config.ProcessingRule, msg *message.Message, match [][]byte) (float64, bool) {
+	if idx := rule.Regex.SubexpIndex(rule.Metric.ValueField); idx > 0 && match[idx] != nil {
+		value, err := strconv.ParseFloat(string(match[idx]), 64)
+		return value, err == nil
+	}
+
+	if msg.State != message.StateStructured {
+		return 0, false
+	}
+	attrs := msg.StructuredAttributes()
+	if attrs == nil {
+		return 0, false
+	}
+	switch v := attrs[rule.Metric.ValueField].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case string:
+		value, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return value, err == nil
+	}
+	return 0, false
+}
+
+// logMetricTags returns the tags of the message matching the given tag keys.
+func logMetricTags(keys []string, msg *message.Message) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var tags []string
+	msgTags := msg.Tags()
+	for _, key := range keys {
+		switch key {
+		case "service":
+			if service := msg.Origin.Service(); service != "" {
+				tags = append(tags, "service:"+service)
+				continue
+			}
+		case "source":
+			if source := msg.Origin.Source(); source != "" {
+				tags = append(tags, "source:"+source)
+				continue
+			}
+		}
+		prefix := key + ":"
+		for _, tag := range msgTags {
+			if strings.HasPrefix(tag, prefix) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
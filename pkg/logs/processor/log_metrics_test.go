@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+type submittedMetric struct {
+	kind  string
+	name  string
+	value float64
+	tags  []string
+}
+
+type fakeMetricSender struct {
+	metrics []submittedMetric
+}
+
+func (s *fakeMetricSender) Count(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, submittedMetric{"count", metric, value, tags})
+}
+
+func (s *fakeMetricSender) Gauge(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, submittedMetric{"gauge", metric, value, tags})
+}
+
+func (s *fakeMetricSender) Distribution(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, submittedMetric{"distribution", metric, value, tags})
+}
+
+func TestGenerateMetricCount(t *testing.T) {
+	sender := &fakeMetricSender{}
+	rules := []*config.ProcessingRule{
+		{
+			Type:    config.GenerateMetric,
+			Name:    "errors",
+			Pattern: "ERROR",
+			Regex:   regexp.MustCompile("ERROR"),
+			Metric:  config.LogMetric{Name: "app.errors", Tags: []string{"env", "service"}},
+		},
+		newProcessingRule(config.ExcludeAtMatch, "", "ERROR"),
+	}
+	p := &Processor{processingRules: rules, metricSender: sender}
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "web", Tags: []string{"env:prod", "team:logs"}})
+
+	// the log is counted even if a following rule excludes it
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("ERROR something failed"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("INFO all good"), source, "")))
+
+	assert.Equal(t, []submittedMetric{
+		{"count", "app.errors", 1, []string{"env:prod", "service:web"}},
+	}, sender.metrics)
+}
+
+func TestGenerateMetricValue(t *testing.T) {
+	pattern := `took (?P<duration>[0-9.]+)ms`
+	sender := &fakeMetricSender{}
+	rules := []*config.ProcessingRule{
+		{
+			Type:    config.GenerateMetric,
+			Name:    "latency",
+			Pattern: pattern,
+			Regex:   regexp.MustCompile(pattern),
+			Metric:  config.LogMetric{Name: "app.latency", Type: config.LogMetricDistribution, ValueField: "duration"},
+		},
+		{
+			Type:   config.ParseJSONRule,
+			Name:   "json",
+			Coerce: map[string]string{"size": config.CoerceFloat},
+		},
+		{
+			Type:    config.GenerateMetric,
+			Name:    "size",
+			Pattern: "upload",
+			Regex:   regexp.MustCompile("upload"),
+			Metric:  config.LogMetric{Name: "app.upload_size", Type: config.LogMetricGauge, ValueField: "size"},
+		},
+	}
+	p := &Processor{processingRules: rules, metricSender: sender}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	p.applyRedactingRules(newMessage([]byte("request took 12.5ms"), source, ""))
+	p.applyRedactingRules(newMessage([]byte(`{"message":"upload done","size":"2048"}`), source, ""))
+	// no value to extract
+	p.applyRedactingRules(newMessage([]byte(`{"message":"upload failed"}`), source, ""))
+
+	assert.Equal(t, []submittedMetric{
+		{"distribution", "app.latency", 12.5, nil},
+		{"gauge", "app.upload_size", 2048, nil},
+	}, sender.metrics)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:    config.GenerateMetric,
+		Name:    "errors",
+		Pattern: "ERROR",
+		Regex:   regexp.MustCompile("ERROR"),
+		Metric:  config.LogMetric{Name: "app.errors"},
+	}
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR"), source, "")))
+}
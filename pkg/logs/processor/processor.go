@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"regexp"
+	"slices"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
@@ -32,8 +33,14 @@ type Processor struct {
 	outputChan chan *message.Message // strategy input
 	// ReconfigChan transports rules to use in order to reconfigure
 	// the processing rules of the SDS Scanner.
-	ReconfigChan              chan sds.ReconfigureOrder
+	ReconfigChan chan sds.ReconfigureOrder
+	// RulesReconfigChan transports the processing rules replacing
+	// the configured ones at runtime.
+	RulesReconfigChan         chan RulesReconfigureOrder
 	processingRules           []*config.ProcessingRule
+	configuredRules           []*config.ProcessingRule
+	sourceProcessingRules     map[string][]*config.ProcessingRule
+	metricSender              MetricSender
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
@@ -47,6 +54,13 @@ type Processor struct {
 	utilization     metrics.UtilizationMonitor
 }
 
+// RulesReconfigureOrder is used to replace the processing rules of a
+// Processor at runtime, a nil Update restores the configured rules.
+type RulesReconfigureOrder struct {
+	Update       *config.ProcessingRulesUpdate
+	ResponseChan chan struct{}
+}
+
 type sdsProcessor struct {
 	// buffer stores the messages for the buffering mechanism in case we didn't
 	// receive any SDS configuration & wait_for_configuration == "buffer".
@@ -62,7 +76,7 @@ type sdsProcessor struct {
 
 // New returns an initialized Processor.
 func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule,
-	encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
+	metricSender MetricSender, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
 	pipelineMonitor metrics.PipelineMonitor) *Processor {
 
 	waitForSDSConfig := sds.ShouldBufferUntilSDSConfiguration(cfg)
@@ -72,7 +86,10 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 		inputChan:                 inputChan,
 		outputChan:                outputChan, // strategy input
 		ReconfigChan:              make(chan sds.ReconfigureOrder),
+		RulesReconfigChan:         make(chan RulesReconfigureOrder),
 		processingRules:           processingRules,
+		configuredRules:           processingRules,
+		metricSender:              metricSender,
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
@@ -163,10 +180,32 @@ func (p *Processor) run() {
 			p.mu.Lock()
 			p.applySDSReconfiguration(order)
 			p.mu.Unlock()
+
+		// Processing rules reconfiguration
+		// --------------------------------
+
+		case order := <-p.RulesReconfigChan:
+			p.mu.Lock()
+			p.applyRulesReconfiguration(order)
+			p.mu.Unlock()
 		}
 	}
 }
 
+func (p *Processor) applyRulesReconfiguration(order RulesReconfigureOrder) {
+	p.processingRules = p.configuredRules
+	p.sourceProcessingRules = nil
+	if order.Update != nil {
+		if order.Update.Global != nil {
+			// clip the rules so that appending the source rules never
+			// writes in the array shared with the other processors
+			p.processingRules = slices.Clip(order.Update.Global)
+		}
+		p.sourceProcessingRules = order.Update.Sources
+	}
+	order.ResponseChan <- struct{}{}
+}
+
 func (p *Processor) applySDSReconfiguration(order sds.ReconfigureOrder) {
 	isActive, err := p.sds.scanner.Reconfigure(order)
 	response := sds.ReconfigureResponse{
@@ -229,6 +268,10 @@ func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
 
+	if msg.ParsingExtra.Repeated != nil {
+		annotateRepeated(msg)
+	}
+
 	if toSend := p.applyRedactingRules(msg); toSend {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
@@ -250,13 +293,34 @@ func (p *Processor) processMessage(msg *message.Message) {
 			return
 		}
 
+		if msg.Tap != nil {
+			p.reportTap(msg, rendered)
+		}
+
 		p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
 		p.outputChan <- msg
 		p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+	} else if msg.Tap != nil {
+		p.reportTap(msg, nil)
 	}
 
 }
 
+// reportTap completes the trace of a message of a tapped source and reports it to
+// the tap receiver (e.g. `tap-logs` command), rendered is nil when the message
+// has been dropped by a processing rule.
+func (p *Processor) reportTap(msg *message.Message, rendered []byte) {
+	if rendered == nil {
+		msg.Tap.Dropped = true
+	} else {
+		msg.Tap.Processed = rendered
+		msg.Tap.Encoded = bytes.Clone(msg.GetContent())
+	}
+	diagnostic.DefaultTapReceiver.HandleTrace(msg)
+	// the trace isn't needed anymore, don't keep it in the payload
+	msg.Tap = nil
+}
+
 // applyRedactingRules returns given a message if we should process it or not,
 // it applies the change directly on the Message content.
 func (p *Processor) applyRedactingRules(msg *message.Message) bool {
@@ -265,7 +329,11 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	// Use the internal scrubbing implementation of the Agent
 	// ---------------------------
 
-	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	sourceRules := msg.Origin.LogSource.Config.ProcessingRules
+	if rules, ok := p.sourceProcessingRules[msg.Origin.LogSource.Name]; ok {
+		sourceRules = rules
+	}
+	rules := append(p.processingRules, sourceRules...)
 	for _, rule := range rules {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
@@ -282,6 +350,20 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			if isMatchingLiteralPrefix(rule.Regex, content) {
 				content = rule.Regex.ReplaceAll(content, rule.Placeholder)
 			}
+		case config.ParseJSONRule, config.ParseLogfmt, config.ParseKeyValue, config.RemapFields:
+			// the pattern is optional and restricts the rule to the matching messages
+			if rule.Regex != nil && !rule.Regex.Match(content) {
+				continue
+			}
+			content = applyFieldRule(rule, msg, content)
+		case config.ParseRegex:
+			content = applyFieldRule(rule, msg, content)
+		case config.GenerateMetric:
+			p.generateMetric(rule, msg, content)
+		case config.Sample, config.RateLimit:
+			if isSampledOut(rule, msg, content) {
+				return false
+			}
 		}
 	}
 
@@ -307,6 +307,41 @@ func TestGetHostname(t *testing.T) {
 	assert.Equal(t, "testHostnameFromEnvVar", p.GetHostname(m))
 }
 
+func TestTapTrace(t *testing.T) {
+	pm := metrics.NewNoopPipelineMonitor("")
+	p := &Processor{
+		encoder:                   JSONEncoder,
+		outputChan:                make(chan *message.Message, 1),
+		diagnosticMessageReceiver: &diagnostic.NoopMessageReceiver{},
+		pipelineMonitor:           pm,
+		utilization:               pm.MakeUtilizationMonitor("processor"),
+	}
+
+	receiver := diagnostic.DefaultTapReceiver
+	receiver.SetEnabled(true)
+	defer receiver.SetEnabled(false)
+	done := make(chan struct{})
+	defer close(done)
+	traces := receiver.Filter(&diagnostic.Filters{}, done)
+
+	src := newSource(config.MaskSequences, "[masked]", "secret")
+	msg := newMessage([]byte("a secret message"), &src, "")
+	msg.Tap = &message.TapTrace{Decoded: []byte("a secret message")}
+	p.processMessage(msg)
+	// the trace isn't kept in the message sent
+	assert.Nil(t, (<-p.outputChan).Tap)
+	trace := <-traces
+	assert.Contains(t, trace, "  decoded:   a secret message\n")
+	assert.Contains(t, trace, "  processed: a [masked] message\n")
+	assert.Contains(t, trace, `"message":"a [masked] message"`)
+
+	src = newSource(config.ExcludeAtMatch, "", "secret")
+	msg = newMessage([]byte("a secret message"), &src, "")
+	msg.Tap = &message.TapTrace{Decoded: []byte("a secret message")}
+	p.processMessage(msg)
+	assert.Contains(t, <-traces, "<dropped by a processing rule>")
+}
+
 func TestBuffering(t *testing.T) {
 	assert := assert.New(t)
 
@@ -442,6 +477,55 @@ func TestBuffering(t *testing.T) {
 	messagesDequeue(t, func() bool { return processedMessages.Load() == 4 }, "should continue processing now")
 }
 
+func TestRulesReconfiguration(t *testing.T) {
+	assert := assert.New(t)
+
+	configured := []*config.ProcessingRule{newProcessingRule(config.ExcludeAtMatch, "", "debug")}
+	p := &Processor{processingRules: configured, configuredRules: configured}
+
+	src := newSource(config.ExcludeAtMatch, "", "trace")
+	src.Name = "app"
+	other := newSource(config.ExcludeAtMatch, "", "trace")
+	other.Name = "other"
+
+	reconfigure := func(update *config.ProcessingRulesUpdate) {
+		order := RulesReconfigureOrder{Update: update, ResponseChan: make(chan struct{}, 1)}
+		p.applyRulesReconfiguration(order)
+		<-order.ResponseChan
+	}
+
+	// the configured rules apply
+	assert.False(p.applyRedactingRules(newMessage([]byte("debug message"), &src, "")))
+	assert.False(p.applyRedactingRules(newMessage([]byte("trace message"), &src, "")))
+	assert.True(p.applyRedactingRules(newMessage([]byte("info message"), &src, "")))
+
+	// the global rules and the rules of the "app" source are replaced
+	reconfigure(&config.ProcessingRulesUpdate{
+		Global: []*config.ProcessingRule{newProcessingRule(config.ExcludeAtMatch, "", "info")},
+		Sources: map[string][]*config.ProcessingRule{
+			"app": {newProcessingRule(config.MaskSequences, "[masked]", "secret")},
+		},
+	})
+	assert.True(p.applyRedactingRules(newMessage([]byte("debug message"), &src, "")))
+	assert.True(p.applyRedactingRules(newMessage([]byte("trace message"), &src, "")))
+	assert.False(p.applyRedactingRules(newMessage([]byte("info message"), &src, "")))
+	msg := newMessage([]byte("a secret message"), &src, "")
+	assert.True(p.applyRedactingRules(msg))
+	assert.Equal([]byte("a [masked] message"), msg.GetContent())
+	// the other sources keep their configured rules
+	assert.False(p.applyRedactingRules(newMessage([]byte("trace message"), &other, "")))
+
+	// a nil Global keeps the configured global rules
+	reconfigure(&config.ProcessingRulesUpdate{})
+	assert.False(p.applyRedactingRules(newMessage([]byte("debug message"), &src, "")))
+	assert.True(p.applyRedactingRules(newMessage([]byte("info message"), &src, "")))
+
+	// a nil update restores the configured rules
+	reconfigure(nil)
+	assert.False(p.applyRedactingRules(newMessage([]byte("debug message"), &src, "")))
+	assert.False(p.applyRedactingRules(newMessage([]byte("trace message"), &src, "")))
+}
+
 // messagesDequeue let the other routines being scheduled
 // to give some time for the processor routine to dequeue its messages
 func messagesDequeue(t *testing.T, f func() bool, errorLog string) {
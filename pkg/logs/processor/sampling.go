@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"math/rand"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// isSampledOut returns true if the message matches the pattern of a sample or
+// rate_limit rule and is over the budget of the rule.
+func isSampledOut(rule *config.ProcessingRule, msg *message.Message, content []byte) bool {
+	if !rule.Regex.Match(content) {
+		return false
+	}
+
+	var keep bool
+	switch rule.Type {
+	case config.Sample:
+		keep = rand.Float64() < rule.SampleRate
+	case config.RateLimit:
+		keep = rule.Limiter == nil || rule.Limiter.Allow(rateLimitKey(rule, msg))
+	default:
+		keep = true
+	}
+
+	if !keep {
+		metrics.LogsSampledOut.Add(1)
+		metrics.TlmLogsSampledOut.Inc(rule.Name, rule.Type)
+	}
+	return !keep
+}
+
+// rateLimitKey returns the budget a message is accounted to, the budget of
+// its source by default.
+func rateLimitKey(rule *config.ProcessingRule, msg *message.Message) string {
+	if rule.LimitBy == config.LimitByPattern || msg.Origin == nil || msg.Origin.LogSource == nil {
+		return ""
+	}
+	return msg.Origin.LogSource.Name
+}
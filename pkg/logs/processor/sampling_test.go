@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newRateLimitRule(limitBy string) *config.ProcessingRule {
+	return &config.ProcessingRule{
+		Type:         config.RateLimit,
+		Name:         "stack_traces",
+		Pattern:      "Exception",
+		Regex:        regexp.MustCompile("Exception"),
+		MaxPerSecond: 0.001,
+		Burst:        2,
+		LimitBy:      limitBy,
+		Limiter:      config.NewRateLimiter(0.001, 2),
+	}
+}
+
+func TestRateLimitPerSource(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newRateLimitRule(config.LimitBySource)}}
+	web := sources.NewLogSource("web", &config.LogsConfig{})
+	db := sources.NewLogSource("db", &config.LogsConfig{})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), web, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), web, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), web, "")))
+
+	// the lines not matching the pattern aren't limited
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("request served"), web, "")))
+
+	// the other sources have their own budget
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), db, "")))
+}
+
+func TestRateLimitPerPattern(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newRateLimitRule(config.LimitByPattern)}}
+	web := sources.NewLogSource("web", &config.LogsConfig{})
+	db := sources.NewLogSource("db", &config.LogsConfig{})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), web, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), db, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), web, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("NullPointerException"), db, "")))
+}
+
+func TestSample(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:       config.Sample,
+		Name:       "debug",
+		Pattern:    "DEBUG",
+		Regex:      regexp.MustCompile("DEBUG"),
+		SampleRate: 0.5,
+	}
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		if p.applyRedactingRules(newMessage([]byte("DEBUG cache miss"), source, "")) {
+			kept++
+		}
+		assert.True(t, p.applyRedactingRules(newMessage([]byte("INFO cache hit"), source, "")))
+	}
+	assert.InDelta(t, 500, kept, 100)
+
+	rule.SampleRate = 1
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("DEBUG cache miss"), source, "")))
+}
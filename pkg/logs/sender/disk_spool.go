@@ -0,0 +1,322 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	spoolFileExtension = ".spool"
+	spoolWorkerDirFmt  = "worker_%d"
+	spoolFormatVersion = 1
+
+	// version (1) + encoding length (2) + unencoded size (8) + messages count (4)
+	spoolHeaderSize = 15
+)
+
+var (
+	tlmSpoolPayloadsStored  = telemetry.NewCounter("logs_sender", "spool_payloads_stored", nil, "Payloads stored in the disk spool")
+	tlmSpoolPayloadsDrained = telemetry.NewCounter("logs_sender", "spool_payloads_drained", nil, "Payloads sent from the disk spool")
+	tlmSpoolPayloadsDropped = telemetry.NewCounter("logs_sender", "spool_payloads_dropped", []string{"reason"}, "Payloads dropped from the disk spool")
+	tlmSpoolMessagesDropped = telemetry.NewCounter("logs_sender", "spool_messages_dropped", []string{"reason"}, "Messages dropped from the disk spool")
+	tlmSpoolSizeInBytes     = telemetry.NewGauge("logs_sender", "spool_size_bytes", []string{"worker"}, "Disk space used by the disk spool")
+)
+
+// DiskSpoolConfig configures the disk spool used by the sender workers when
+// all the reliable destinations are unavailable. An empty path disables it.
+type DiskSpoolConfig struct {
+	Path           string
+	MaxSizeInBytes int64
+}
+
+// IsEnabled returns true if the disk spool should be used.
+func (c DiskSpoolConfig) IsEnabled() bool {
+	return c.Path != "" && c.MaxSizeInBytes > 0
+}
+
+// diskSpool is an ordered and size-bounded queue of payloads stored on disk,
+// one file per payload. The payloads are synced to disk before Store returns
+// so their offsets can be committed to the auditor.
+// When the spool is full, the oldest payloads are dropped.
+// A diskSpool is not safe for concurrent use, every sender worker owns its own.
+type diskSpool struct {
+	path               string
+	name               string
+	maxSizeInBytes     int64
+	currentSizeInBytes int64
+	filenames          []string
+	sizes              []int64
+	nextSeq            uint64
+}
+
+// newDiskSpool returns a spool storing its files in the given directory,
+// the payloads left by a previous run are reloaded in order.
+func newDiskSpool(path string, name string, maxSizeInBytes int64) (*diskSpool, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &diskSpool{
+		path:           path,
+		name:           name,
+		maxSizeInBytes: maxSizeInBytes,
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	if len(s.filenames) > 0 {
+		log.Infof("Found %d payloads in the logs disk spool %s, they will be sent once the destinations are available", len(s.filenames), path)
+	}
+	return s, nil
+}
+
+func (s *diskSpool) reload() error {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+
+	// os.ReadDir returns the entries sorted by file name, the file names being
+	// zero-padded sequence numbers, it is also the storage order
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			// leftover of a payload which was being stored when the agent stopped
+			_ = os.Remove(filepath.Join(s.path, entry.Name()))
+			continue
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spoolFileExtension) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if seq, ok := parseSpoolSeq(entry.Name()); ok && seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+		s.filenames = append(s.filenames, filepath.Join(s.path, entry.Name()))
+		s.sizes = append(s.sizes, info.Size())
+		s.currentSizeInBytes += info.Size()
+	}
+	s.updateTelemetry()
+	return nil
+}
+
+// Len returns the number of payloads in the spool.
+func (s *diskSpool) Len() int {
+	return len(s.filenames)
+}
+
+// Store durably writes the payload at the end of the spool.
+func (s *diskSpool) Store(payload *message.Payload) error {
+	data := encodeSpooledPayload(payload)
+	size := int64(len(data))
+	if size > s.maxSizeInBytes {
+		return fmt.Errorf("the payload is too big for the disk spool. Current:%v Maximum:%v", size, s.maxSizeInBytes)
+	}
+	s.makeRoomFor(size)
+
+	filename := filepath.Join(s.path, fmt.Sprintf("%020d%s", s.nextSeq, spoolFileExtension))
+	if err := writeFileSync(filename, data); err != nil {
+		return err
+	}
+	s.nextSeq++
+
+	s.filenames = append(s.filenames, filename)
+	s.sizes = append(s.sizes, size)
+	s.currentSizeInBytes += size
+	tlmSpoolPayloadsStored.Inc()
+	s.updateTelemetry()
+	return nil
+}
+
+// Peek returns the oldest payload of the spool without removing it.
+// Unreadable payloads are dropped.
+func (s *diskSpool) Peek() (*message.Payload, bool) {
+	for len(s.filenames) > 0 {
+		data, err := os.ReadFile(s.filenames[0])
+		if err == nil {
+			var payload *message.Payload
+			if payload, err = decodeSpooledPayload(data); err == nil {
+				return payload, true
+			}
+		}
+		log.Errorf("Dropping unreadable payload %s from the logs disk spool: %v", s.filenames[0], err)
+		tlmSpoolPayloadsDropped.Inc("corrupted")
+		s.removeOldest()
+	}
+	return nil, false
+}
+
+// Remove removes the oldest payload of the spool, once it has been sent.
+func (s *diskSpool) Remove() {
+	if len(s.filenames) == 0 {
+		return
+	}
+	s.removeOldest()
+	tlmSpoolPayloadsDrained.Inc()
+}
+
+func (s *diskSpool) makeRoomFor(size int64) {
+	for len(s.filenames) > 0 && s.currentSizeInBytes+size > s.maxSizeInBytes {
+		filename := s.filenames[0]
+		log.Warnf("Maximum disk space for the logs disk spool is reached. Removing %s", filename)
+		tlmSpoolPayloadsDropped.Inc("full")
+		if data, err := os.ReadFile(filename); err == nil && len(data) >= spoolHeaderSize {
+			tlmSpoolMessagesDropped.Add(float64(binary.BigEndian.Uint32(data[11:15])), "full")
+		}
+		s.removeOldest()
+	}
+}
+
+func (s *diskSpool) removeOldest() {
+	if err := os.Remove(s.filenames[0]); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Can't remove %s from the logs disk spool: %v", s.filenames[0], err)
+	}
+	s.currentSizeInBytes -= s.sizes[0]
+	s.filenames = s.filenames[1:]
+	s.sizes = s.sizes[1:]
+	s.updateTelemetry()
+}
+
+func (s *diskSpool) updateTelemetry() {
+	tlmSpoolSizeInBytes.Set(float64(s.currentSizeInBytes), s.name)
+}
+
+// adoptOrphanSpools moves the payloads spooled by workers which no longer exist
+// (e.g. after a decrease of the number of pipelines) to the spool of the first worker.
+// The adopted payloads are given the sequence numbers following the ones of the first
+// worker, so they are sent after its own payloads.
+func adoptOrphanSpools(path string, workersCount int) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+	target := filepath.Join(path, fmt.Sprintf(spoolWorkerDirFmt, 0))
+	nextSeq := nextSpoolSeq(target)
+	for _, entry := range entries {
+		var idx int
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := fmt.Sscanf(entry.Name(), spoolWorkerDirFmt, &idx); err != nil || idx < workersCount {
+			continue
+		}
+		orphan := filepath.Join(path, entry.Name())
+		files, err := os.ReadDir(orphan)
+		if err != nil {
+			continue
+		}
+		if err := os.MkdirAll(target, 0700); err != nil {
+			log.Warnf("Can't adopt the logs disk spool %s: %v", orphan, err)
+			return
+		}
+		// os.ReadDir returns the files sorted by name, which is their storage order
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), spoolFileExtension) {
+				// including the leftovers of payloads which were being stored
+				_ = os.Remove(filepath.Join(orphan, file.Name()))
+				continue
+			}
+			name := fmt.Sprintf("%020d%s", nextSeq, spoolFileExtension)
+			if err := os.Rename(filepath.Join(orphan, file.Name()), filepath.Join(target, name)); err != nil {
+				log.Warnf("Can't adopt %s from the logs disk spool: %v", file.Name(), err)
+				continue
+			}
+			nextSeq++
+		}
+		_ = os.Remove(orphan)
+	}
+}
+
+// nextSpoolSeq returns the sequence number following the ones of the payloads of a spool directory.
+func nextSpoolSeq(path string) uint64 {
+	var next uint64
+	entries, _ := os.ReadDir(path)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), spoolFileExtension) {
+			continue
+		}
+		if seq, ok := parseSpoolSeq(entry.Name()); ok && seq >= next {
+			next = seq + 1
+		}
+	}
+	return next
+}
+
+// parseSpoolSeq returns the sequence number prefixing a spool file name.
+func parseSpoolSeq(name string) (uint64, bool) {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	seq, err := strconv.ParseUint(name[:end], 10, 64)
+	return seq, err == nil
+}
+
+// encodeSpooledPayload serializes the payload, the message metadata
+// are not kept as the offsets have already been committed.
+func encodeSpooledPayload(payload *message.Payload) []byte {
+	data := make([]byte, spoolHeaderSize, spoolHeaderSize+len(payload.Encoding)+len(payload.Encoded))
+	data[0] = spoolFormatVersion
+	binary.BigEndian.PutUint16(data[1:3], uint16(len(payload.Encoding)))
+	binary.BigEndian.PutUint64(data[3:11], uint64(payload.UnencodedSize))
+	binary.BigEndian.PutUint32(data[11:15], uint32(payload.Count()))
+	data = append(data, payload.Encoding...)
+	return append(data, payload.Encoded...)
+}
+
+func decodeSpooledPayload(data []byte) (*message.Payload, error) {
+	if len(data) < spoolHeaderSize {
+		return nil, errors.New("truncated header")
+	}
+	if data[0] != spoolFormatVersion {
+		return nil, fmt.Errorf("unsupported version %d", data[0])
+	}
+	encodingLen := int(binary.BigEndian.Uint16(data[1:3]))
+	if len(data) < spoolHeaderSize+encodingLen {
+		return nil, errors.New("truncated encoding")
+	}
+	return &message.Payload{
+		Encoding:      string(data[spoolHeaderSize : spoolHeaderSize+encodingLen]),
+		Encoded:       data[spoolHeaderSize+encodingLen:],
+		UnencodedSize: int(binary.BigEndian.Uint64(data[3:11])),
+	}, nil
+}
+
+// writeFileSync writes the file through a temporary file synced to disk
+// before being renamed, so a crash can't leave a partial payload behind.
+func writeFileSync(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newSpoolPayload(content string) *message.Payload {
+	return &message.Payload{
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: len(content) * 2,
+	}
+}
+
+func TestDiskSpoolStoreAndDrainInOrder(t *testing.T) {
+	spool, err := newDiskSpool(t.TempDir(), "0", 1024)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spool.Store(newSpoolPayload(fmt.Sprintf("payload-%d", i))))
+	}
+	assert.Equal(t, 3, spool.Len())
+
+	for i := 0; i < 3; i++ {
+		payload, ok := spool.Peek()
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("payload-%d", i), string(payload.Encoded))
+		assert.Equal(t, "gzip", payload.Encoding)
+		assert.Equal(t, len(payload.Encoded)*2, payload.UnencodedSize)
+		spool.Remove()
+	}
+
+	_, ok := spool.Peek()
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), spool.currentSizeInBytes)
+}
+
+func TestDiskSpoolReload(t *testing.T) {
+	path := t.TempDir()
+	spool, err := newDiskSpool(path, "0", 1024)
+	require.NoError(t, err)
+	require.NoError(t, spool.Store(newSpoolPayload("first")))
+	require.NoError(t, spool.Store(newSpoolPayload("second")))
+
+	// leftover of an interrupted write
+	require.NoError(t, os.WriteFile(filepath.Join(path, "00000000000000000002.spool.tmp"), []byte("partial"), 0600))
+
+	spool, err = newDiskSpool(path, "0", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 2, spool.Len())
+	assert.NoFileExists(t, filepath.Join(path, "00000000000000000002.spool.tmp"))
+
+	// new payloads are stored after the reloaded ones
+	require.NoError(t, spool.Store(newSpoolPayload("third")))
+	for _, expected := range []string{"first", "second", "third"} {
+		payload, ok := spool.Peek()
+		require.True(t, ok)
+		assert.Equal(t, expected, string(payload.Encoded))
+		spool.Remove()
+	}
+}
+
+func TestDiskSpoolDropsOldestWhenFull(t *testing.T) {
+	payloadSize := int64(len(encodeSpooledPayload(newSpoolPayload("payload-0"))))
+	spool, err := newDiskSpool(t.TempDir(), "0", 2*payloadSize)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spool.Store(newSpoolPayload(fmt.Sprintf("payload-%d", i))))
+	}
+	assert.Equal(t, 2, spool.Len())
+	assert.Equal(t, 2*payloadSize, spool.currentSizeInBytes)
+
+	payload, ok := spool.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "payload-1", string(payload.Encoded))
+
+	// a payload larger than the spool is rejected
+	assert.Error(t, spool.Store(newSpoolPayload(string(make([]byte, 3*payloadSize)))))
+	assert.Equal(t, 2, spool.Len())
+}
+
+func TestDiskSpoolDropsCorruptedPayload(t *testing.T) {
+	path := t.TempDir()
+	spool, err := newDiskSpool(path, "0", 1024)
+	require.NoError(t, err)
+	require.NoError(t, spool.Store(newSpoolPayload("corrupted")))
+	require.NoError(t, spool.Store(newSpoolPayload("valid")))
+	require.NoError(t, os.WriteFile(spool.filenames[0], []byte{42}, 0600))
+
+	payload, ok := spool.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "valid", string(payload.Encoded))
+	assert.Equal(t, 1, spool.Len())
+}
+
+func TestAdoptOrphanSpools(t *testing.T) {
+	path := t.TempDir()
+	for i := 0; i < 3; i++ {
+		spool, err := newDiskSpool(filepath.Join(path, fmt.Sprintf(spoolWorkerDirFmt, i)), "0", 1024)
+		require.NoError(t, err)
+		require.NoError(t, spool.Store(newSpoolPayload(fmt.Sprintf("worker-%d-a", i))))
+		if i != 1 {
+			require.NoError(t, spool.Store(newSpoolPayload(fmt.Sprintf("worker-%d-b", i))))
+		}
+	}
+
+	adoptOrphanSpools(path, 2)
+
+	assert.NoDirExists(t, filepath.Join(path, fmt.Sprintf(spoolWorkerDirFmt, 2)))
+	spool, err := newDiskSpool(filepath.Join(path, fmt.Sprintf(spoolWorkerDirFmt, 0)), "0", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 4, spool.Len())
+	require.NoError(t, spool.Store(newSpoolPayload("worker-0-c")))
+
+	var contents []string
+	for spool.Len() > 0 {
+		payload, ok := spool.Peek()
+		require.True(t, ok)
+		contents = append(contents, string(payload.Encoded))
+		spool.Remove()
+	}
+	// the adopted payloads are sent after the ones of the worker, in their order
+	assert.Equal(t, []string{"worker-0-a", "worker-0-b", "worker-2-a", "worker-2-b", "worker-0-c"}, contents)
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/httpjson"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/otlp"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -65,6 +67,10 @@ func NewHTTPSender(
 		queueCount,
 		workersPerQueue,
 		pipelineMonitor,
+		sender.DiskSpoolConfig{
+			Path:           endpoints.DiskSpoolPath,
+			MaxSizeInBytes: endpoints.DiskSpoolMaxSizeBytes,
+		},
 	)
 }
 
@@ -79,23 +85,49 @@ func httpDestinationFactory(
 	minConcurrency int,
 	maxConcurrency int,
 ) sender.DestinationFactory {
+	newDestination := func(endpoint config.Endpoint, shouldRetry bool, destMeta *client.DestinationMetadata) client.Destination {
+		switch endpoint.Type {
+		case config.DatadogEndpointType:
+			if serverlessMeta.IsEnabled() {
+				return http.NewSyncDestination(endpoint, contentyType, destinationsContext, serverlessMeta.SenderDoneChan(), destMeta, cfg)
+			}
+			return http.NewDestination(endpoint, contentyType, destinationsContext, shouldRetry, destMeta, cfg, minConcurrency, maxConcurrency, pipelineMonitor)
+		case config.OTLPEndpointType, config.HTTPJSONEndpointType:
+			if serverlessMeta.IsEnabled() {
+				log.Warnf("%s endpoints are not supported in serverless, ignoring endpoint %s", endpoint.Type, endpoint.Host)
+				return nil
+			}
+			var destination client.Destination
+			var err error
+			if endpoint.Type == config.OTLPEndpointType {
+				destination, err = otlp.NewDestination(endpoint, destinationsContext, shouldRetry, destMeta)
+			} else {
+				destination, err = httpjson.NewDestination(endpoint, destinationsContext, shouldRetry, destMeta, cfg)
+			}
+			if err != nil {
+				log.Errorf("Could not create %s destination for %s, ignoring endpoint: %v", endpoint.Type, endpoint.Host, err)
+				return nil
+			}
+			return destination
+		default:
+			log.Errorf("Unknown endpoint type %q, ignoring endpoint %s", endpoint.Type, endpoint.Host)
+			return nil
+		}
+	}
+
 	return func() *client.Destinations {
 		reliable := []client.Destination{}
 		additionals := []client.Destination{}
 		for i, endpoint := range endpoints.GetReliableEndpoints() {
 			destMeta := client.NewDestinationMetadata(componentName, pipelineMonitor.ID(), "reliable", strconv.Itoa(i))
-			if serverlessMeta.IsEnabled() {
-				reliable = append(reliable, http.NewSyncDestination(endpoint, contentyType, destinationsContext, serverlessMeta.SenderDoneChan(), destMeta, cfg))
-			} else {
-				reliable = append(reliable, http.NewDestination(endpoint, contentyType, destinationsContext, true, destMeta, cfg, minConcurrency, maxConcurrency, pipelineMonitor))
+			if destination := newDestination(endpoint, true, destMeta); destination != nil {
+				reliable = append(reliable, destination)
 			}
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
 			destMeta := client.NewDestinationMetadata(componentName, pipelineMonitor.ID(), "unreliable", strconv.Itoa(i))
-			if serverlessMeta.IsEnabled() {
-				additionals = append(additionals, http.NewSyncDestination(endpoint, contentyType, destinationsContext, serverlessMeta.SenderDoneChan(), destMeta, cfg))
-			} else {
-				additionals = append(additionals, http.NewDestination(endpoint, contentyType, destinationsContext, false, destMeta, cfg, minConcurrency, maxConcurrency, pipelineMonitor))
+			if destination := newDestination(endpoint, false, destMeta); destination != nil {
+				additionals = append(additionals, destination)
 			}
 		}
 		return client.NewDestinations(reliable, additionals)
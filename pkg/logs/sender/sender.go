@@ -6,6 +6,9 @@
 package sender
 
 import (
+	"fmt"
+	"path/filepath"
+	"strconv"
 	"sync"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
@@ -106,6 +109,7 @@ func NewSender(
 	queueCount int,
 	workersPerQueue int,
 	pipelineMonitor metrics.PipelineMonitor,
+	spoolConfig DiskSpoolConfig,
 ) *Sender {
 	var workers []*worker
 	if queueCount <= 0 {
@@ -116,6 +120,11 @@ func NewSender(
 		workersPerQueue = DefaultWorkersPerQueue
 	}
 
+	useSpool := spoolConfig.IsEnabled() && !serverlessMeta.IsEnabled()
+	if useSpool {
+		adoptOrphanSpools(spoolConfig.Path, queueCount*workersPerQueue)
+	}
+
 	queues := make([]chan *message.Payload, queueCount)
 	for idx := range queueCount {
 		// Payloads are large, so the buffer will only hold one per worker
@@ -130,6 +139,9 @@ func NewSender(
 				serverlessMeta,
 				pipelineMonitor,
 			)
+			if useSpool {
+				worker.spool = newWorkerSpool(spoolConfig, len(workers), queueCount*workersPerQueue)
+			}
 			workers = append(workers, worker)
 		}
 	}
@@ -142,6 +154,19 @@ func NewSender(
 	}
 }
 
+// newWorkerSpool returns the disk spool of a worker, the spool space being
+// shared equally between the workers. It returns nil if the spool can't be used.
+func newWorkerSpool(spoolConfig DiskSpoolConfig, workerIdx int, workersCount int) *diskSpool {
+	name := strconv.Itoa(workerIdx)
+	path := filepath.Join(spoolConfig.Path, fmt.Sprintf(spoolWorkerDirFmt, workerIdx))
+	spool, err := newDiskSpool(path, name, spoolConfig.MaxSizeInBytes/int64(workersCount))
+	if err != nil {
+		log.Errorf("Can't use the logs disk spool %s: %v", path, err)
+		return nil
+	}
+	return spool
+}
+
 // In is the input channel of a worker set.
 func (s *Sender) In() chan *message.Payload {
 	idx := s.idx.Inc() % uint32(len(s.queues))
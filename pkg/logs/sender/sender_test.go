@@ -58,6 +58,7 @@ func TestNewSenderWorkerDistribution(t *testing.T) {
 				tc.queuesCount,
 				tc.workersPerQueue,
 				pipelineMonitor,
+				DiskSpoolConfig{},
 			)
 
 			assert.Equal(t, tc.expectedWorkers, len(sender.workers))
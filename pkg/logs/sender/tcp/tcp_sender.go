@@ -45,6 +45,10 @@ func NewTCPSender(
 		queueCount,
 		workersPerQueue,
 		pipelineMonitor,
+		sender.DiskSpoolConfig{
+			Path:           endpoints.DiskSpoolPath,
+			MaxSizeInBytes: endpoints.DiskSpoolMaxSizeBytes,
+		},
 	)
 }
 
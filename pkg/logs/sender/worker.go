@@ -16,8 +16,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// spoolDrainInterval is the interval at which an idle worker tries
+// to send the payloads of its disk spool.
+const spoolDrainInterval = time.Second
+
 var (
 	tlmPayloadsDropped = telemetry.NewCounterWithOpts("logs_sender", "payloads_dropped", []string{"reliable", "destination"}, "Payloads dropped", telemetry.Options{DefaultMetric: true})
 	tlmMessagesDropped = telemetry.NewCounterWithOpts("logs_sender", "messages_dropped", []string{"reliable", "destination"}, "Messages dropped", telemetry.Options{DefaultMetric: true})
@@ -45,6 +50,10 @@ type worker struct {
 
 	pipelineMonitor metrics.PipelineMonitor
 	utilization     metrics.UtilizationMonitor
+
+	// spool stores the payloads on disk while the reliable destinations
+	// are unavailable, nil when disabled
+	spool *diskSpool
 }
 
 func newWorker(
@@ -99,6 +108,15 @@ func (s *worker) run() {
 
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.config, s.destinations.Unreliable, sink, s.bufferSize)
+
+	// the spooled payloads are sent in the background once the destinations recover
+	var drainTick <-chan time.Time
+	if s.spool != nil {
+		drainTicker := time.NewTicker(spoolDrainInterval)
+		defer drainTicker.Stop()
+		drainTick = drainTicker.C
+	}
+
 	continueLoop := true
 	for continueLoop {
 		select {
@@ -108,32 +126,33 @@ func (s *worker) run() {
 			senderDoneWg := &sync.WaitGroup{}
 
 			sent := false
-			for !sent {
-				for _, destSender := range reliableDestinations {
-					if destSender.Send(payload) {
-						if destSender.destination.Metadata().ReportingEnabled {
-							s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
-						}
-						sent = true
-						if s.senderDoneChan != nil {
-							senderDoneWg.Add(1)
-							s.senderDoneChan <- senderDoneWg
-						}
-					}
+			spooled := false
+			for !sent && !spooled {
+				// the payloads are spooled in order, a new payload can only
+				// be sent once the spool has been drained
+				if s.spool == nil || s.drainSpool(reliableDestinations) {
+					sent = s.sendToReliableDestinations(payload, reliableDestinations, senderDoneWg)
 				}
 
 				if !sent {
-					// Throttle the poll loop while waiting for a send to succeed
-					// This will only happen when all reliable destinations
-					// are blocked so logs have no where to go.
-					time.Sleep(100 * time.Millisecond)
+					// All reliable destinations are blocked, store the payload on disk
+					// if possible to not block the pipeline.
+					if s.spool != nil {
+						spooled = s.spoolPayload(payload)
+					}
+					if !spooled {
+						// Throttle the poll loop while waiting for a send to succeed
+						// This will only happen when all reliable destinations
+						// are blocked so logs have no where to go.
+						time.Sleep(100 * time.Millisecond)
+					}
 				}
 			}
 
 			for i, destSender := range reliableDestinations {
 				// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
 				// loss on intermittent failures.
-				if !destSender.lastSendSucceeded {
+				if sent && !destSender.lastSendSucceeded {
 					if !destSender.NonBlockingSend(payload) {
 						tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
 						tlmMessagesDropped.Add(float64(payload.Count()), "true", strconv.Itoa(i))
@@ -164,6 +183,8 @@ func (s *worker) run() {
 				s.flushWg.Done()
 			}
 			s.pipelineMonitor.ReportComponentEgress(payload, "sender")
+		case <-drainTick:
+			s.drainSpool(reliableDestinations)
 		case <-s.done:
 			continueLoop = false
 		}
@@ -180,6 +201,55 @@ func (s *worker) run() {
 	s.finished <- struct{}{}
 }
 
+// sendToReliableDestinations sends the payload to every reliable destination
+// able to receive it, it returns false if none of them could.
+func (s *worker) sendToReliableDestinations(payload *message.Payload, reliableDestinations []*DestinationSender, senderDoneWg *sync.WaitGroup) bool {
+	sent := false
+	for _, destSender := range reliableDestinations {
+		if destSender.Send(payload) {
+			if destSender.destination.Metadata().ReportingEnabled {
+				s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
+			}
+			sent = true
+			if s.senderDoneChan != nil {
+				senderDoneWg.Add(1)
+				s.senderDoneChan <- senderDoneWg
+			}
+		}
+	}
+	return sent
+}
+
+// spoolPayload durably stores the payload in the disk spool, the payload is
+// then sent to the auditor as its offsets can be committed.
+func (s *worker) spoolPayload(payload *message.Payload) bool {
+	if err := s.spool.Store(payload); err != nil {
+		log.Warnf("Can't store the payload in the logs disk spool, the pipeline will be blocked until a destination is available: %v", err)
+		return false
+	}
+	s.outputChan <- payload
+	return true
+}
+
+// drainSpool sends the spooled payloads while the reliable destinations accept
+// them, it returns true once the spool is empty.
+func (s *worker) drainSpool(reliableDestinations []*DestinationSender) bool {
+	for s.spool.Len() > 0 {
+		payload, ok := s.spool.Peek()
+		if !ok {
+			break
+		}
+		senderDoneWg := &sync.WaitGroup{}
+		if !s.sendToReliableDestinations(payload, reliableDestinations, senderDoneWg) {
+			return false
+		}
+		// in serverless, the payload is only removed once the destinations sent it
+		senderDoneWg.Wait()
+		s.spool.Remove()
+	}
+	return true
+}
+
 // Drains the output channel from destinations that don't update the auditor.
 func additionalDestinationsSink(bufferSize int) chan *message.Payload {
 	sink := make(chan *message.Payload, bufferSize)
@@ -345,3 +345,112 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	worker.stop()
 }
+
+// retryingDestination is a destination whose retry state is set by the test.
+type retryingDestination struct {
+	received   chan *message.Payload
+	isRetrying chan bool
+}
+
+func (d *retryingDestination) IsMRF() bool    { return false }
+func (d *retryingDestination) Target() string { return "retrying" }
+func (d *retryingDestination) Metadata() *client.DestinationMetadata {
+	return client.NewNoopDestinationMetadata()
+}
+
+func (d *retryingDestination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) <-chan struct{} {
+	d.isRetrying = isRetrying
+	stopChan := make(chan struct{})
+	go func() {
+		for payload := range input {
+			d.received <- payload
+			output <- payload
+		}
+		close(stopChan)
+	}()
+	return stopChan
+}
+
+func (d *retryingDestination) setRetrying(retrying bool) {
+	// the retry channel is buffered, the first value has been handled
+	// once the last one is accepted
+	for i := 0; i < 3; i++ {
+		d.isRetrying <- retrying
+	}
+}
+
+func TestSenderSpoolsWhenReliableIsRetrying(t *testing.T) {
+	cfg := configmock.New(t)
+	input := make(chan *message.Payload, 1)
+	auditor := &testAuditor{
+		output: make(chan *message.Payload, 10),
+	}
+
+	destination := &retryingDestination{received: make(chan *message.Payload, 10)}
+	destinations := client.NewDestinations([]client.Destination{destination}, nil)
+
+	worker := newWorker(cfg, input, auditor, destinations, 10, NewMockServerlessMeta(false), metrics.NewNoopPipelineMonitor(""))
+	spool, err := newDiskSpool(t.TempDir(), "0", 1024)
+	assert.NoError(t, err)
+	worker.spool = spool
+	worker.start()
+
+	input <- &message.Payload{Encoded: []byte("first")}
+	assert.Equal(t, "first", string((<-destination.received).Encoded))
+	<-auditor.output
+
+	destination.setRetrying(true)
+
+	// the payloads are spooled and committed to the auditor
+	input <- &message.Payload{Encoded: []byte("second")}
+	assert.Equal(t, "second", string((<-auditor.output).Encoded))
+	input <- &message.Payload{Encoded: []byte("third")}
+	assert.Equal(t, "third", string((<-auditor.output).Encoded))
+
+	destination.setRetrying(false)
+
+	// the spool is drained in order once the destination recovers
+	assert.Equal(t, "second", string((<-destination.received).Encoded))
+	assert.Equal(t, "third", string((<-destination.received).Encoded))
+
+	input <- &message.Payload{Encoded: []byte("fourth")}
+	assert.Equal(t, "fourth", string((<-destination.received).Encoded))
+
+	worker.stop()
+}
+
+func TestSenderDrainsSpoolInServerless(t *testing.T) {
+	cfg := configmock.New(t)
+	input := make(chan *message.Payload, 1)
+	auditor := &testAuditor{
+		output: make(chan *message.Payload, 10),
+	}
+
+	destination := &retryingDestination{received: make(chan *message.Payload, 10)}
+	destinations := client.NewDestinations([]client.Destination{destination}, nil)
+
+	serverlessMeta := NewMockServerlessMeta(true)
+	worker := newWorker(cfg, input, auditor, destinations, 10, serverlessMeta, metrics.NewNoopPipelineMonitor(""))
+	spool, err := newDiskSpool(t.TempDir(), "0", 1024)
+	assert.NoError(t, err)
+	assert.NoError(t, spool.Store(&message.Payload{Encoded: []byte("spooled")}))
+	worker.spool = spool
+
+	// the synchronous destinations mark the payloads as sent
+	go func() {
+		for wg := range serverlessMeta.SenderDoneChan() {
+			wg.Done()
+		}
+	}()
+	worker.start()
+
+	serverlessMeta.WaitGroup().Add(1)
+	input <- &message.Payload{Encoded: []byte("new")}
+	assert.Equal(t, "spooled", string((<-destination.received).Encoded))
+	assert.Equal(t, "new", string((<-destination.received).Encoded))
+	serverlessMeta.WaitGroup().Wait()
+	assert.Equal(t, 0, spool.Len())
+
+	worker.stop()
+	close(serverlessMeta.SenderDoneChan())
+}
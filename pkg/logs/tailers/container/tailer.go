@@ -358,7 +358,10 @@ func (t *Tailer) forwardMessages() {
 			tags = append(tags, t.tagProvider.GetTags()...)
 			origin.SetTags(tags)
 			// XXX(remy): is it OK recreating a message here?
-			t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg.ParsingExtra.Repeated = output.ParsingExtra.Repeated
+			msg.Tap = output.Tap
+			t.outputChan <- msg
 		}
 	}
 }
@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Compressed files are archives, typically rotated by logrotate, and are
+// decompressed on the fly. Their offsets are positions in the decompressed
+// content.
+const (
+	gzipExtension = ".gz"
+	zstdExtension = ".zst"
+)
+
+// IsCompressed returns true if the file is a compressed archive.
+func (t *File) IsCompressed() bool {
+	ext := strings.ToLower(filepath.Ext(t.Path))
+	return ext == gzipExtension || ext == zstdExtension
+}
+
+// zstdReadCloser releases the resources of a zstd decoder on Close.
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// setupCompressed sets up the tailer of a compressed file, the decompressed
+// content is skipped up to the offset since compressed streams can't be seeked.
+func (t *Tailer) setupCompressed(offset int64, whence int) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	log.Info("Opening compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+	f, err := filesystem.OpenShared(fullpath)
+	if err != nil {
+		return err
+	}
+
+	var reader io.ReadCloser
+	switch strings.ToLower(filepath.Ext(fullpath)) {
+	case gzipExtension:
+		reader, err = gzip.NewReader(f)
+	case zstdExtension:
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
+		reader = zstdReadCloser{zr}
+	default:
+		err = fmt.Errorf("unsupported compressed file %q", fullpath)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var skipped int64
+	switch whence {
+	case io.SeekEnd:
+		skipped, err = io.Copy(io.Discard, reader)
+	default:
+		skipped, err = io.CopyN(io.Discard, reader, offset)
+		if err == io.EOF {
+			log.Warnf("Offset %d is beyond the decompressed size %d of %s", offset, skipped, fullpath)
+			err = nil
+		}
+	}
+	if err != nil {
+		reader.Close()
+		f.Close()
+		return err
+	}
+
+	t.osFile = f
+	t.compressedReader = reader
+	t.lastReadOffset.Store(skipped)
+	t.decodedOffset.Store(skipped)
+	return nil
+}
+
+// readCompressed reads the next decompressed chunk of a compressed file, it
+// returns io.EOF once the whole archive has been read since it doesn't grow.
+func (t *Tailer) readCompressed() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.compressedReader.Read(inBuf)
+	if err != nil && err != io.EOF {
+		// a corrupted archive won't get better, stop the tailer
+		t.file.Source.Status().Error(err)
+		return 0, log.Error("Unexpected error occurred while decompressing file: ", err)
+	}
+	if n == 0 {
+		if err == io.EOF {
+			log.Info("Read the whole compressed file", t.file.Path, "up to offset", t.lastReadOffset.Load())
+			return 0, io.EOF
+		}
+		return 0, nil
+	}
+	t.lastReadOffset.Add(int64(n))
+	t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+	return n, nil
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+var compressedLines = []string{"hello world\n", "hello again\n", "good bye\n"}
+
+func writeCompressedFile(t *testing.T, path string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.WriteCloser
+	if filepath.Ext(path) == gzipExtension {
+		w = gzip.NewWriter(f)
+	} else {
+		w, err = zstd.NewWriter(f)
+		require.NoError(t, err)
+	}
+	for _, line := range compressedLines {
+		_, err = w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+}
+
+func newCompressedTailer(path string, outputChan chan *message.Message) *Tailer {
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	return NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+	})
+}
+
+func TestIsCompressed(t *testing.T) {
+	assert.True(t, NewFile("/var/log/app.log.1.gz", nil, false).IsCompressed())
+	assert.True(t, NewFile("/var/log/app.log.2.ZST", nil, false).IsCompressed())
+	assert.False(t, NewFile("/var/log/app.log", nil, false).IsCompressed())
+	assert.False(t, NewFile("/var/log/app.log.1", nil, false).IsCompressed())
+}
+
+func TestTailCompressedFile(t *testing.T) {
+	for _, ext := range []string{gzipExtension, zstdExtension} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log.1"+ext)
+			writeCompressedFile(t, path)
+
+			outputChan := make(chan *message.Message, 10)
+			tailer := newCompressedTailer(path, outputChan)
+			// resume after the first line, the offset is in the decompressed content
+			require.NoError(t, tailer.Start(int64(len(compressedLines[0])), io.SeekStart))
+			defer tailer.Stop()
+
+			msg := <-outputChan
+			assert.Equal(t, "hello again", string(msg.GetContent()))
+			assert.Equal(t, len(compressedLines[0])+len(compressedLines[1]), toInt(msg.Origin.Offset))
+
+			msg = <-outputChan
+			assert.Equal(t, "good bye", string(msg.GetContent()))
+			assert.Equal(t, len(compressedLines[0])+len(compressedLines[1])+len(compressedLines[2]), toInt(msg.Origin.Offset))
+		})
+	}
+}
+
+func TestCompressedFileDidRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeCompressedFile(t, path)
+
+	tailer := newCompressedTailer(path, make(chan *message.Message, 10))
+	require.NoError(t, tailer.setupCompressed(0, io.SeekStart))
+	defer tailer.osFile.Close()
+	defer tailer.compressedReader.Close()
+
+	// the decompressed offset can be larger than the archive, this isn't a truncation
+	tailer.lastReadOffset.Store(1 << 20)
+	didRotate, err := tailer.DidRotate()
+	require.NoError(t, err)
+	assert.False(t, didRotate)
+}
+
+func TestTailCompressedFileStopsAtEOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeCompressedFile(t, path)
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newCompressedTailer(path, outputChan)
+	require.NoError(t, tailer.StartFromBeginning())
+
+	for range compressedLines {
+		<-outputChan
+	}
+	// the archive doesn't grow, the tailer finishes once it has been read
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	total := len(compressedLines[0]) + len(compressedLines[1]) + len(compressedLines[2])
+	assert.Equal(t, int64(total), tailer.lastReadOffset.Load())
+	assert.Equal(t, int64(total), tailer.decodedOffset.Load())
+	tailer.Stop()
+}
+
+func TestTailCompressedFileFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeCompressedFile(t, path)
+
+	tailer := newCompressedTailer(path, make(chan *message.Message, 10))
+	require.NoError(t, tailer.Start(0, io.SeekEnd))
+	defer tailer.Stop()
+
+	total := len(compressedLines[0]) + len(compressedLines[1]) + len(compressedLines[2])
+	assert.Equal(t, int64(total), tailer.lastReadOffset.Load())
+	assert.Equal(t, int64(total), tailer.decodedOffset.Load())
+}
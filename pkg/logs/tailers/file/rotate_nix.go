@@ -42,7 +42,8 @@ func (t *Tailer) DidRotate() (bool, error) {
 	fileSize := fi1.Size()
 
 	recreated := !os.SameFile(fi1, fi2)
-	truncated := fileSize < lastReadOffset
+	// the offset of a compressed file is in the decompressed content, only compare plain files sizes
+	truncated := !t.file.IsCompressed() && fileSize < lastReadOffset
 
 	if recreated {
 		log.Debugf("File rotation detected due to recreation, f1: %+v, f2: %+v", fi1, fi2)
@@ -38,7 +38,8 @@ func (t *Tailer) DidRotate() (bool, error) {
 	// polled before the offset.
 	sz := st.Size()
 
-	if sz < offset {
+	// the offset of a compressed file is in the decompressed content, only compare plain files sizes
+	if !t.file.IsCompressed() && sz < offset {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", offset, sz)
 		return true, nil
 	}
@@ -62,6 +62,10 @@ type Tailer struct {
 	// is platform-specific, and not every platform will have a non-nil value here.
 	osFile *os.File
 
+	// compressedReader decompresses osFile when the file is a compressed archive,
+	// it is nil otherwise.
+	compressedReader io.ReadCloser
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -232,7 +236,12 @@ func (t *Tailer) Identifier() string {
 
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.file.IsCompressed() {
+		err = t.setupCompressed(offset, whence)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status().Error(err)
 		return err
@@ -271,7 +280,7 @@ func (t *Tailer) StopAfterFileRotation() {
 		time.Sleep(t.closeTimeout)
 		if newBytesRead := t.bytesRead.Get() - bytesReadAtRotationTime; newBytesRead > 0 {
 			log.Infof("After rotation close timeout (%s), an additional %d bytes were read from file %q", t.closeTimeout, newBytesRead, t.file.Path)
-			if t.osFile != nil {
+			if t.osFile != nil && t.compressedReader == nil {
 				fileStat, err := t.osFile.Stat()
 				if err != nil {
 					log.Warnf("During rotation close, unable to determine total file size for %q, err: %v", t.file.Path, err)
@@ -292,6 +301,9 @@ func (t *Tailer) StopAfterFileRotation() {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer func() {
+		if t.compressedReader != nil {
+			t.compressedReader.Close()
+		}
 		if t.osFile != nil {
 			t.osFile.Close()
 		}
@@ -299,8 +311,13 @@ func (t *Tailer) readForever() {
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
 
+	read := t.read
+	if t.compressedReader != nil {
+		read = t.readCompressed
+	}
+
 	for {
-		n, err := t.read()
+		n, err := read()
 		if err != nil {
 			return
 		}
@@ -369,6 +386,8 @@ func (t *Tailer) forwardMessages() {
 		}
 
 		msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+		msg.ParsingExtra.Repeated = output.ParsingExtra.Repeated
+		msg.Tap = output.Tap
 		// Make the write to the output chan cancellable to be able to stop the tailer
 		// after a file rotation when it is stuck on it.
 		// We don't return directly to keep the same shutdown sequence that in the
@@ -17,12 +17,15 @@ import (
 
 	"github.com/stretchr/testify/suite"
 
+	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
 )
@@ -163,6 +166,39 @@ func (suite *TailerTestSuite) TestTailFromBeginning() {
 	suite.Equal(len(lines[0])+len(lines[1])+len(lines[2]), int(suite.tailer.decodedOffset.Load()))
 }
 
+func (suite *TailerTestSuite) TestTapTrace() {
+	receiver := diagnostic.DefaultTapReceiver
+	receiver.SetEnabled(true)
+	defer receiver.SetEnabled(false)
+	done := make(chan struct{})
+	defer close(done)
+	traces := receiver.Filter(&diagnostic.Filters{}, done)
+
+	hostname, _ := hostnameinterface.NewMock("test-host")
+	processed := make(chan *message.Message, chanSize)
+	p := processor.New(configmock.New(suite.T()), suite.outputChan, processed, nil, nil, processor.JSONEncoder,
+		&diagnostic.NoopMessageReceiver{}, hostname, metrics.NewNoopPipelineMonitor(""))
+	p.Start()
+	defer p.Stop()
+
+	_, err := suite.testFile.WriteString("hello world\n")
+	suite.Nil(err)
+	suite.Nil(suite.tailer.StartFromBeginning())
+
+	// the trace set by the decoder goes through the tailer up to the processor
+	msg := <-processed
+	suite.Contains(string(msg.GetContent()), `"message":"hello world"`)
+	select {
+	case trace := <-traces:
+		suite.Contains(trace, "  frame:     hello world\n")
+		suite.Contains(trace, "  decoded:   hello world\n")
+		suite.Contains(trace, "  processed: hello world\n")
+		suite.Contains(trace, `"message":"hello world"`)
+	case <-time.After(10 * time.Second):
+		suite.Fail("no trace reported")
+	}
+}
+
 func (suite *TailerTestSuite) TestTailFromEnd() {
 	lines := []string{"hello world\n", "hello again\n", "good bye\n"}
 
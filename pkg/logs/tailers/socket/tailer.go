@@ -71,7 +71,10 @@ func (t *Tailer) forwardMessages() {
 		if len(output.GetContent()) > 0 {
 			origin := message.NewOrigin(t.source)
 			origin.SetTags(output.ParsingExtra.Tags)
-			t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg.ParsingExtra.Repeated = output.ParsingExtra.Repeated
+			msg.Tap = output.Tap
+			t.outputChan <- msg
 		}
 	}
 }
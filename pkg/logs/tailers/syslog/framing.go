@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxFrameLengthDigits bounds the MSG-LEN prefix of octet-counted frames.
+const maxFrameLengthDigits = 10
+
+// splitFrames is a bufio.SplitFunc splitting a syslog stream into messages,
+// as described in RFC6587. The framing is detected per message: a message
+// starting with a digit uses octet-counting ("MSG-LEN SP SYSLOG-MSG"),
+// any other message is terminated by a line feed (non-transparent framing).
+func splitFrames(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if data[0] >= '1' && data[0] <= '9' {
+		space := bytes.IndexByte(data[:min(len(data), maxFrameLengthDigits+1)], ' ')
+		if space < 0 {
+			if len(data) > maxFrameLengthDigits || atEOF {
+				return 0, nil, fmt.Errorf("invalid octet-counted frame length %q", data[:min(len(data), maxFrameLengthDigits)])
+			}
+			// request more data
+			return 0, nil, nil
+		}
+		length, err := strconv.Atoi(string(data[:space]))
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid octet-counted frame length %q", data[:space])
+		}
+		end := space + 1 + length
+		if end > len(data) {
+			if atEOF {
+				return 0, nil, fmt.Errorf("truncated octet-counted frame")
+			}
+			// request more data
+			return 0, nil, nil
+		}
+		return end, data[space+1 : end], nil
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	// request more data
+	return 0, nil, nil
+}
+
+// newFrameScanner returns a scanner reading the syslog messages of a stream,
+// messages longer than maxFrameSize stop the scanner with bufio.ErrTooLong.
+func newFrameScanner(stream io.Reader, maxFrameSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, min(maxFrameSize, 64*1024)), maxFrameSize+maxFrameLengthDigits+1)
+	scanner.Split(splitFrames)
+	return scanner
+}
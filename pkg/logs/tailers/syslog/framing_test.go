@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanFrames(stream string, maxFrameSize int) ([]string, error) {
+	scanner := newFrameScanner(strings.NewReader(stream), maxFrameSize)
+	var frames []string
+	for scanner.Scan() {
+		frames = append(frames, scanner.Text())
+	}
+	return frames, scanner.Err()
+}
+
+func TestSplitFrames(t *testing.T) {
+	// octet-counted messages can contain line feeds, the framing can change between messages
+	frames, err := scanFrames("18 <13>1 - - - - - a\n<13>1 - - - - - b\n19 <13>1 - - - - - c\nd<13>last", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<13>1 - - - - - a\n", "<13>1 - - - - - b", "<13>1 - - - - - c\nd", "<13>last"}, frames)
+}
+
+func TestSplitFramesErrors(t *testing.T) {
+	_, err := scanFrames("50 <13>1 - - - - - truncated", 100)
+	assert.Error(t, err)
+
+	_, err = scanFrames("12345678901 <13>1", 100)
+	assert.Error(t, err)
+
+	_, err = scanFrames(strings.Repeat("a", 200)+"\n", 100)
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+}
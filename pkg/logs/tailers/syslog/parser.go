@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// nilValue is the RFC5424 NILVALUE, used for the fields without value.
+const nilValue = "-"
+
+// defaultPriority is the priority given to the messages without one, as a
+// relay would according to RFC3164 (facility user, severity notice).
+const defaultPriority = 13
+
+var (
+	errNoPriority = errors.New("missing priority")
+	utf8BOM       = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// Message is a parsed syslog message.
+type Message struct {
+	Priority int
+	Facility int
+	Severity int
+	// Version is 1 for RFC5424 messages and 0 for RFC3164 messages.
+	Version        int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Msg            []byte
+}
+
+// Parse parses a RFC5424 or RFC3164 syslog message. The format is detected
+// from the header, the fields which can't be parsed in a RFC3164 message are
+// left empty and kept in its content.
+// A message without priority is returned as is with an error.
+func Parse(frame []byte) (Message, error) {
+	frame = bytes.TrimRight(frame, "\r\n\x00")
+
+	priority, rest, err := parsePriority(frame)
+	if err != nil {
+		msg := Message{Msg: frame}
+		setPriority(&msg, defaultPriority)
+		return msg, err
+	}
+
+	var msg Message
+	if version, after, ok := parseVersion(rest); ok {
+		msg, err = parseRFC5424(after)
+		msg.Version = version
+	} else {
+		msg = parseRFC3164(rest, time.Now())
+	}
+	setPriority(&msg, priority)
+	return msg, err
+}
+
+func setPriority(msg *Message, priority int) {
+	msg.Priority = priority
+	msg.Facility = priority / 8
+	msg.Severity = priority % 8
+}
+
+// parsePriority parses the "<PRI>" header of a message.
+func parsePriority(frame []byte) (int, []byte, error) {
+	if len(frame) < 3 || frame[0] != '<' {
+		return 0, frame, errNoPriority
+	}
+	end := bytes.IndexByte(frame[:min(len(frame), 5)], '>')
+	if end < 2 {
+		return 0, frame, errNoPriority
+	}
+	// strconv.Atoi accepts a sign, the priority is made of digits only
+	for _, c := range frame[1:end] {
+		if c < '0' || c > '9' {
+			return 0, frame, fmt.Errorf("invalid priority %q", frame[1:end])
+		}
+	}
+	priority, err := strconv.Atoi(string(frame[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, frame, fmt.Errorf("invalid priority %q", frame[1:end])
+	}
+	return priority, frame[end+1:], nil
+}
+
+// parseVersion parses the version following the priority of RFC5424 messages.
+func parseVersion(rest []byte) (int, []byte, bool) {
+	end := bytes.IndexByte(rest, ' ')
+	if end < 1 || end > 2 {
+		return 0, rest, false
+	}
+	version, err := strconv.Atoi(string(rest[:end]))
+	if err != nil || version < 1 {
+		return 0, rest, false
+	}
+	return version, rest[end+1:], true
+}
+
+// parseRFC5424 parses the header following the version of a RFC5424 message:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(rest []byte) (Message, error) {
+	var msg Message
+	var fields [5]string
+	for i := range fields {
+		var field []byte
+		field, rest = nextField(rest)
+		if field == nil {
+			msg.Msg = rest
+			return msg, errors.New("truncated RFC5424 header")
+		}
+		if string(field) != nilValue {
+			fields[i] = string(field)
+		}
+	}
+	if fields[0] != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return msg, fmt.Errorf("invalid RFC5424 timestamp %q", fields[0])
+		}
+		msg.Timestamp = timestamp
+	}
+	msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	structuredData, rest, err := parseStructuredData(rest)
+	if err != nil {
+		msg.Msg = rest
+		return msg, err
+	}
+	msg.StructuredData = structuredData
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	msg.Msg = bytes.TrimPrefix(rest, utf8BOM)
+	return msg, nil
+}
+
+// nextField returns the field up to the next space and the rest after it, the
+// field is nil when there's no space left.
+func nextField(rest []byte) ([]byte, []byte) {
+	end := bytes.IndexByte(rest, ' ')
+	if end < 0 {
+		return nil, rest
+	}
+	return rest[:end], rest[end+1:]
+}
+
+// parseStructuredData parses the STRUCTURED-DATA of a RFC5424 message, either the
+// NILVALUE or a list of [SD-ID PARAM-NAME="PARAM-VALUE" ...] elements.
+func parseStructuredData(rest []byte) (map[string]map[string]string, []byte, error) {
+	if bytes.HasPrefix(rest, []byte(nilValue)) {
+		return nil, rest[1:], nil
+	}
+	if len(rest) == 0 || rest[0] != '[' {
+		return nil, rest, errors.New("invalid RFC5424 structured data")
+	}
+
+	structuredData := make(map[string]map[string]string)
+	for len(rest) > 0 && rest[0] == '[' {
+		rest = rest[1:]
+		idEnd := bytes.IndexAny(rest, " ]")
+		if idEnd < 1 {
+			return nil, rest, errors.New("invalid RFC5424 structured data element")
+		}
+		params := make(map[string]string)
+		structuredData[string(rest[:idEnd])] = params
+		rest = rest[idEnd:]
+
+		for len(rest) > 0 && rest[0] == ' ' {
+			rest = rest[1:]
+			nameEnd := bytes.Index(rest, []byte(`="`))
+			if nameEnd < 1 {
+				return nil, rest, errors.New("invalid RFC5424 structured data parameter")
+			}
+			name := string(rest[:nameEnd])
+			value, after, err := parseParamValue(rest[nameEnd+2:])
+			if err != nil {
+				return nil, rest, err
+			}
+			params[name] = value
+			rest = after
+		}
+		if len(rest) == 0 || rest[0] != ']' {
+			return nil, rest, errors.New("unterminated RFC5424 structured data element")
+		}
+		rest = rest[1:]
+	}
+	return structuredData, rest, nil
+}
+
+// parseParamValue parses a quoted parameter value, in which '"', '\' and ']' are
+// escaped with a '\'.
+func parseParamValue(rest []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; c {
+		case '\\':
+			if i+1 < len(rest) && (rest[i+1] == '"' || rest[i+1] == '\\' || rest[i+1] == ']') {
+				i++
+				value = append(value, rest[i])
+			} else {
+				value = append(value, c)
+			}
+		case '"':
+			return string(value), rest[i+1:], nil
+		default:
+			value = append(value, c)
+		}
+	}
+	return "", rest, errors.New("unterminated RFC5424 structured data parameter value")
+}
+
+// rfc3164TimestampLayout is the "Mmm dd hh:mm:ss" timestamp of RFC3164 messages.
+const rfc3164TimestampLayout = time.Stamp
+
+// parseRFC3164 parses the content following the priority of a RFC3164
+// message: TIMESTAMP HOSTNAME TAG[PID]: MSG
+// The format isn't strictly followed by senders, so the header is parsed on a
+// best effort basis: the fields which can't be parsed are left in the message.
+func parseRFC3164(rest []byte, now time.Time) Message {
+	var msg Message
+
+	if len(rest) >= len(rfc3164TimestampLayout) {
+		if timestamp, err := time.ParseInLocation(rfc3164TimestampLayout, string(rest[:len(rfc3164TimestampLayout)]), now.Location()); err == nil {
+			// the year isn't part of the timestamp, messages from the end of the
+			// previous year can be received in january
+			timestamp = timestamp.AddDate(now.Year(), 0, 0)
+			if timestamp.After(now.Add(24 * time.Hour)) {
+				timestamp = timestamp.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = timestamp
+			rest = bytes.TrimPrefix(rest[len(rfc3164TimestampLayout):], []byte(" "))
+
+			if hostname, after := nextField(rest); len(hostname) > 0 && !isTag(hostname) {
+				msg.Hostname = string(hostname)
+				rest = after
+			}
+		}
+	}
+
+	if tagEnd := bytes.IndexByte(rest, ':'); tagEnd > 0 && isTag(rest[:tagEnd+1]) {
+		tag := rest[:tagEnd]
+		if pidStart := bytes.IndexByte(tag, '['); pidStart > 0 && tag[len(tag)-1] == ']' {
+			msg.ProcID = string(tag[pidStart+1 : len(tag)-1])
+			tag = tag[:pidStart]
+		}
+		msg.AppName = string(tag)
+		rest = bytes.TrimPrefix(rest[tagEnd+1:], []byte(" "))
+	}
+	msg.Msg = rest
+	return msg
+}
+
+// isTag returns true if the field is a "TAG:" or "TAG[PID]:" prefix.
+func isTag(field []byte) bool {
+	if len(field) < 2 || field[len(field)-1] != ':' {
+		return false
+	}
+	for _, c := range field[:len(field)-1] {
+		if c == ' ' {
+			return false
+		}
+	}
+	return true
+}
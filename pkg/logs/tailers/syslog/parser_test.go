@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	msg, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high\"est\]"] An application event log entry...` + "\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 165, msg.Priority)
+	assert.Equal(t, 20, msg.Facility)
+	assert.Equal(t, 5, msg.Severity)
+	assert.Equal(t, 1, msg.Version)
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), msg.Timestamp.UTC())
+	assert.Equal(t, "mymachine.example.com", msg.Hostname)
+	assert.Equal(t, "evntslog", msg.AppName)
+	assert.Equal(t, "1234", msg.ProcID)
+	assert.Equal(t, "ID47", msg.MsgID)
+	assert.Equal(t, map[string]map[string]string{
+		"exampleSDID@32473":     {"iut": "3", "eventSource": "Application", "eventID": "1011"},
+		"examplePriority@32473": {"class": `high"est]`},
+	}, msg.StructuredData)
+	assert.Equal(t, "An application event log entry...", string(msg.Msg))
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	msg, err := Parse([]byte("<34>1 - - - - - -"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, msg.Facility)
+	assert.Equal(t, 2, msg.Severity)
+	assert.True(t, msg.Timestamp.IsZero())
+	assert.Empty(t, msg.Hostname)
+	assert.Empty(t, msg.AppName)
+	assert.Nil(t, msg.StructuredData)
+	assert.Empty(t, msg.Msg)
+
+	msg, err = Parse([]byte("<34>1 2003-10-11T22:14:15Z host su - ID47 - \xEF\xBB\xBF'su root' failed"))
+	require.NoError(t, err)
+	assert.Equal(t, "su", msg.AppName)
+	assert.Empty(t, msg.ProcID)
+	assert.Equal(t, "'su root' failed", string(msg.Msg))
+}
+
+func TestParseRFC5424Errors(t *testing.T) {
+	for _, frame := range []string{
+		"<34>1 2003-10-11T22:14:15Z host",
+		"<34>1 yesterday host app - - - message",
+		"<34>1 - host app - - [id key=\"value\"",
+		"<34>1 - host app - - [id key=\"value] message",
+	} {
+		_, err := Parse([]byte(frame))
+		assert.Error(t, err, frame)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg, err := Parse([]byte("<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, msg.Facility)
+	assert.Equal(t, 2, msg.Severity)
+	assert.Equal(t, 0, msg.Version)
+	assert.Equal(t, time.October, msg.Timestamp.Month())
+	assert.Equal(t, 11, msg.Timestamp.Day())
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, "su", msg.AppName)
+	assert.Equal(t, "230", msg.ProcID)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.Msg))
+}
+
+func TestParseRFC3164Lenient(t *testing.T) {
+	// no hostname
+	msg, err := Parse([]byte("<13>Feb  5 17:32:18 sshd: Accepted publickey"))
+	require.NoError(t, err)
+	assert.Empty(t, msg.Hostname)
+	assert.Equal(t, "sshd", msg.AppName)
+	assert.Equal(t, "Accepted publickey", string(msg.Msg))
+
+	// no header
+	msg, err = Parse([]byte("<13>Use the BFG! connecting to 10.0.0.1:22"))
+	require.NoError(t, err)
+	assert.True(t, msg.Timestamp.IsZero())
+	assert.Empty(t, msg.AppName)
+	assert.Equal(t, "Use the BFG! connecting to 10.0.0.1:22", string(msg.Msg))
+}
+
+func TestParseRFC3164Year(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 10, 0, time.UTC)
+	msg := parseRFC3164([]byte("Dec 31 23:59:59 host app: message"), now)
+	assert.Equal(t, time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC), msg.Timestamp)
+
+	msg = parseRFC3164([]byte("Jan  1 00:00:05 host app: message"), now)
+	assert.Equal(t, time.Date(2024, time.January, 1, 0, 0, 5, 0, time.UTC), msg.Timestamp)
+}
+
+func TestParseWithoutPriority(t *testing.T) {
+	msg, err := Parse([]byte("just a line\n"))
+	assert.Error(t, err)
+	assert.Equal(t, 13, msg.Priority)
+	assert.Equal(t, 5, msg.Severity)
+	assert.Equal(t, "just a line", string(msg.Msg))
+
+	_, err = Parse([]byte("<999>1 - - - - - -"))
+	assert.Error(t, err)
+}
+
+func TestParseSignedPriority(t *testing.T) {
+	for _, frame := range []string{"<-1>1 - - - - - -", "<-9>message", "<+13>message", "<1-3>message"} {
+		msg, err := Parse([]byte(frame))
+		assert.Error(t, err, frame)
+		assert.Equal(t, 13, msg.Priority, frame)
+		assert.Equal(t, 5, msg.Severity, frame)
+		assert.Equal(t, frame, string(msg.Msg))
+	}
+}
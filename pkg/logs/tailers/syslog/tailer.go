@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a tailer parsing the RFC5424 and RFC3164 syslog
+// messages received on a network connection into structured messages.
+package syslog
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxDatagramSize is the largest UDP payload.
+const maxDatagramSize = 65535
+
+// severityStatusMapping represents the 1:1 mapping between syslog severities and statuses.
+var severityStatusMapping = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// severityStatus returns the status of a syslog severity, the out of range
+// severities get the status of the default priority.
+func severityStatus(severity int) string {
+	if severity < 0 || severity >= len(severityStatusMapping) {
+		return severityStatusMapping[defaultPriority%8]
+	}
+	return severityStatusMapping[severity]
+}
+
+// Tailer reads syslog messages from a connection, either a stream of framed
+// messages (TCP) or a message per datagram (UDP), and sends them as structured
+// messages to its output channel.
+type Tailer struct {
+	source       *sources.LogSource
+	conn         net.Conn
+	outputChan   chan *message.Message
+	idleTimeout  time.Duration
+	maxFrameSize int
+	stopOnce     sync.Once
+	stopping     chan struct{}
+	done         chan struct{}
+}
+
+// NewTailer returns a new Tailer reading from conn, the connection is a stream
+// unless it implements net.PacketConn.
+func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *message.Message, idleTimeout time.Duration, maxFrameSize int) *Tailer {
+	return &Tailer{
+		source:       source,
+		conn:         conn,
+		outputChan:   outputChan,
+		idleTimeout:  idleTimeout,
+		maxFrameSize: maxFrameSize,
+		stopping:     make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start starts reading messages from the connection.
+func (t *Tailer) Start() {
+	go t.readForever()
+}
+
+// Stop closes the connection and waits for the pending message to be sent.
+func (t *Tailer) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stopping)
+		t.conn.Close()
+	})
+	<-t.done
+}
+
+// Done returns a channel closed once the tailer stopped reading, either
+// because it was stopped or because the connection was closed.
+func (t *Tailer) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *Tailer) readForever() {
+	defer func() {
+		t.conn.Close()
+		close(t.done)
+	}()
+
+	var err error
+	if packetConn, ok := t.conn.(net.PacketConn); ok {
+		err = t.readDatagrams(packetConn)
+	} else {
+		err = t.readStream()
+	}
+
+	select {
+	case <-t.stopping:
+		return
+	default:
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Warnf("Couldn't read syslog message from connection: %v", err)
+		t.source.Status.Error(err)
+	}
+}
+
+// readStream reads framed messages from a stream until it is closed.
+func (t *Tailer) readStream() error {
+	scanner := newFrameScanner(deadlineReader{t.conn, t.idleTimeout}, t.maxFrameSize)
+	remoteAddr := t.conn.RemoteAddr()
+	for scanner.Scan() {
+		t.source.RecordBytes(int64(len(scanner.Bytes())))
+		t.forward(scanner.Bytes(), remoteAddr)
+	}
+	return scanner.Err()
+}
+
+// readDatagrams reads a message per datagram until the connection is closed.
+func (t *Tailer) readDatagrams(conn net.PacketConn) error {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, remoteAddr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		t.source.RecordBytes(int64(n))
+		t.forward(buf[:n], remoteAddr)
+	}
+}
+
+// forward parses a message and sends it to the output channel.
+func (t *Tailer) forward(frame []byte, remoteAddr net.Addr) {
+	if len(frame) == 0 {
+		return
+	}
+	msg, err := Parse(frame)
+	if err != nil {
+		log.Debugf("Could not parse syslog message, sending it as is: %v", err)
+	}
+	if len(msg.Msg) > t.maxFrameSize {
+		msg.Msg = msg.Msg[:t.maxFrameSize]
+	}
+
+	origin := message.NewOrigin(t.source)
+	if msg.AppName != "" {
+		// this value is still overridden by the integration config when defined
+		origin.SetService(msg.AppName)
+	}
+	if remoteAddr != nil && pkgconfigsetup.Datadog().GetBool("logs_config.use_sourcehost_tag") {
+		host, _, splitErr := net.SplitHostPort(remoteAddr.String())
+		if splitErr != nil {
+			host = remoteAddr.String()
+		}
+		origin.SetTags([]string{"source_host:" + host})
+	}
+
+	content := &message.BasicStructuredContent{
+		Data: map[string]interface{}{
+			"syslog": attributes(msg),
+		},
+	}
+	content.SetContent(msg.Msg)
+
+	t.outputChan <- message.NewStructuredMessage(content, origin, severityStatus(msg.Severity), time.Now().UnixNano())
+}
+
+// attributes returns the syslog fields of a message, the empty ones are omitted.
+func attributes(msg Message) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"priority": msg.Priority,
+		"facility": msg.Facility,
+		"severity": msg.Severity,
+	}
+	if msg.Version > 0 {
+		attrs["version"] = msg.Version
+	}
+	if !msg.Timestamp.IsZero() {
+		attrs["timestamp"] = msg.Timestamp.Format(time.RFC3339Nano)
+	}
+	for key, value := range map[string]string{
+		"hostname": msg.Hostname,
+		"appname":  msg.AppName,
+		"procid":   msg.ProcID,
+		"msgid":    msg.MsgID,
+	} {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	if len(msg.StructuredData) > 0 {
+		attrs["structured_data"] = msg.StructuredData
+	}
+	return attrs
+}
+
+// deadlineReader resets the read deadline of a connection before each read,
+// so that idle connections are closed.
+type deadlineReader struct {
+	conn        net.Conn
+	idleTimeout time.Duration
+}
+
+func (r deadlineReader) Read(p []byte) (int, error) {
+	if r.idleTimeout > 0 {
+		r.conn.SetReadDeadline(time.Now().Add(r.idleTimeout)) //nolint:errcheck
+	}
+	return r.conn.Read(p)
+}
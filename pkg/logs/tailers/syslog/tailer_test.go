@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestTailerReadsDatagrams(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	outputChan := make(chan *message.Message, 10)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType})
+	tailer := NewTailer(source, conn.(net.Conn), outputChan, 0, 1000)
+	tailer.Start()
+	defer tailer.Stop()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte(`<11>1 2003-10-11T22:14:15Z host app 42 ID47 [origin ip="10.0.0.1"] disk full`))
+	require.NoError(t, err)
+
+	msg := <-outputChan
+	assert.Equal(t, "disk full", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "app", msg.Origin.Service())
+	assert.Equal(t, map[string]interface{}{
+		"message": "disk full",
+		"syslog": map[string]interface{}{
+			"priority":        11,
+			"facility":        1,
+			"severity":        3,
+			"version":         1,
+			"timestamp":       "2003-10-11T22:14:15Z",
+			"hostname":        "host",
+			"appname":         "app",
+			"procid":          "42",
+			"msgid":           "ID47",
+			"structured_data": map[string]map[string]string{"origin": {"ip": "10.0.0.1"}},
+		},
+	}, msg.StructuredAttributes())
+
+	// a message which isn't syslog is sent as is
+	_, err = client.Write([]byte("plain line"))
+	require.NoError(t, err)
+	msg = <-outputChan
+	assert.Equal(t, "plain line", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.GetStatus())
+}
+
+func TestTailerNegativePriority(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	outputChan := make(chan *message.Message, 10)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType})
+	tailer := NewTailer(source, conn.(net.Conn), outputChan, 0, 1000)
+	tailer.Start()
+	defer tailer.Stop()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte("<-1>1 - host app - - - message"))
+	require.NoError(t, err)
+	msg := <-outputChan
+	assert.Equal(t, "<-1>1 - host app - - - message", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.GetStatus())
+}
+
+func TestSeverityStatus(t *testing.T) {
+	assert.Equal(t, message.StatusEmergency, severityStatus(0))
+	assert.Equal(t, message.StatusDebug, severityStatus(7))
+	assert.Equal(t, message.StatusNotice, severityStatus(-1))
+	assert.Equal(t, message.StatusNotice, severityStatus(8))
+}
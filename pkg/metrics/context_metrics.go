@@ -58,7 +58,7 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval, config) // default histogram configuration (no call to `configure`) for now
+			m[contextKey] = newHistogramForMetric(sample.Name, interval, config) // configured by `histogram_overrides` if the name matches
 		case HistorateType:
 			m[contextKey] = NewHistorate(interval, config) // internal histogram has the configuration for now
 		case SetType:
@@ -6,9 +6,13 @@
 package metrics
 
 import (
-	"fmt"
+	"math"
+	"path"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/config/structure"
@@ -29,9 +33,9 @@ func (w weightSamples) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
 
 // Histogram tracks the distribution of samples added over one flush period
 type Histogram struct {
-	aggregates  []string // aggregates configured on this histogram
-	percentiles []int    // percentiles configured on this histogram, each in the 1-100 range
-	interval    int64    // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
+	aggregates  []string  // aggregates configured on this histogram
+	percentiles []float64 // percentiles configured on this histogram, each in the 0-100 range
+	interval    int64     // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
 	samples     weightSamples
 	sum         float64
 	count       int64
@@ -48,25 +52,60 @@ const (
 
 var (
 	defaultAggregates  = []string(nil)
-	defaultPercentiles = []int(nil)
+	defaultPercentiles = []float64(nil)
+	// defaultOverrides is nil until `histogram_overrides` is loaded
+	defaultOverrides = []histogramOverride(nil)
+	// overrideByName caches the entry of `defaultOverrides` matching a metric name, nil
+	// when none does, so that the patterns are only matched once per metric name.
+	overrideByName sync.Map
 )
 
-func parsePercentiles(percentiles []string) []int {
-	res := []int{}
+// histogramOverride replaces the aggregates and the percentiles of the histograms
+// whose name matches a glob pattern.
+type histogramOverride struct {
+	pattern     string
+	aggregates  []string
+	percentiles []float64
+}
+
+// histogramOverrideConfig is an entry of `histogram_overrides`. The global setting is
+// used when `aggregates` or `percentiles` is omitted.
+type histogramOverrideConfig struct {
+	Metric      string   `mapstructure:"metric"`
+	Aggregates  []string `mapstructure:"aggregates"`
+	Percentiles []string `mapstructure:"percentiles"`
+}
+
+// parsePercentiles parses the `histogram_percentiles` setting. The percentiles are rounded
+// to the closest integer, e.g. 0.999 is reported as `.100percentile`, to keep the names of
+// the existing series.
+func parsePercentiles(percentiles []string) []float64 {
+	return parsePercentilesWithDecimals(percentiles, "histogram_percentiles", 0)
+}
+
+// parseOverridePercentiles parses the percentiles of an entry of `histogram_overrides`,
+// they are rounded to 3 decimals, e.g. 0.999 is reported as `.99_9percentile`.
+func parseOverridePercentiles(percentiles []string) []float64 {
+	return parsePercentilesWithDecimals(percentiles, "histogram_overrides", 3)
+}
+
+func parsePercentilesWithDecimals(percentiles []string, setting string, decimals int) []float64 {
+	res := []float64{}
+	scale := math.Pow10(decimals)
 	for _, p := range percentiles {
 		i, err := strconv.ParseFloat(p, 64)
 		if err != nil {
-			log.Errorf("Could not parse '%s' from 'histogram_percentiles' (skipping): %s", p, err)
+			log.Errorf("Could not parse '%s' from '%s' (skipping): %s", p, setting, err)
 			continue
 		}
 		if i < 0 || i > 1 {
-			log.Errorf("histogram_percentiles must be between 0 and 1: skipping %f", i)
+			log.Errorf("%s percentiles must be between 0 and 1: skipping %f", setting, i)
 			continue
 		}
-		// in some cases the '*100' will lower the number resulting in
-		// an int lower by 1 from what is expected (ex: 0.29 would
-		// become 28). As a workaround we add 0.5 before casting.
-		res = append(res, int(i*100+0.5))
+		// in some cases the '*100' will lower the number (ex: 0.29
+		// would become 28.999999999999996). As a workaround the
+		// percentile is rounded.
+		res = append(res, math.Round(i*100*scale)/scale)
 	}
 	return res
 }
@@ -84,9 +123,13 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 			log.Errorf("Could not Unmarshal histogram configuration: %s", err)
 		} else {
 			defaultPercentiles = parsePercentiles(c)
-			sort.Ints(defaultPercentiles)
+			sort.Float64s(defaultPercentiles)
 		}
 	}
+	if defaultOverrides == nil {
+		defaultOverrides = parseOverrides(config)
+		overrideByName.Clear()
+	}
 
 	return &Histogram{
 		interval:    interval,
@@ -95,12 +138,81 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 	}
 }
 
-func (h *Histogram) configure(aggregates []string, percentiles []int) {
+// newHistogramForMetric returns a newly initialized histogram, using the configuration of
+// the first entry of `histogram_overrides` matching the metric name, if any.
+func newHistogramForMetric(name string, interval int64, config pkgconfigmodel.Config) *Histogram {
+	h := NewHistogram(interval, config)
+	if len(defaultOverrides) == 0 {
+		return h
+	}
+
+	cached, ok := overrideByName.Load(name)
+	if !ok {
+		var match *histogramOverride
+		for i := range defaultOverrides {
+			if matched, _ := path.Match(defaultOverrides[i].pattern, name); matched {
+				match = &defaultOverrides[i]
+				break
+			}
+		}
+		cached, _ = overrideByName.LoadOrStore(name, match)
+	}
+	if override := cached.(*histogramOverride); override != nil {
+		h.configure(override.aggregates, override.percentiles)
+	}
+	return h
+}
+
+func parseOverrides(config pkgconfigmodel.Config) []histogramOverride {
+	overrides := []histogramOverride{}
+	if !config.IsSet("histogram_overrides") {
+		return overrides
+	}
+
+	c := []histogramOverrideConfig{}
+	if err := structure.UnmarshalKey(config, "histogram_overrides", &c); err != nil {
+		log.Errorf("Could not Unmarshal histogram overrides configuration: %s", err)
+		return overrides
+	}
+	for _, entry := range c {
+		if _, err := path.Match(entry.Metric, ""); err != nil || entry.Metric == "" {
+			log.Errorf("Invalid metric pattern '%s' in 'histogram_overrides' (skipping)", entry.Metric)
+			continue
+		}
+		override := histogramOverride{
+			pattern:     entry.Metric,
+			aggregates:  defaultAggregates,
+			percentiles: defaultPercentiles,
+		}
+		if entry.Aggregates != nil {
+			override.aggregates = entry.Aggregates
+		}
+		if entry.Percentiles != nil {
+			override.percentiles = parseOverridePercentiles(entry.Percentiles)
+			sort.Float64s(override.percentiles)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides
+}
+
+// configure sets the aggregates and the percentiles of the histogram. The slices may be
+// shared between the histograms, the percentiles are sorted on a copy when needed.
+func (h *Histogram) configure(aggregates []string, percentiles []float64) {
 	h.aggregates = aggregates
-	sort.Ints(percentiles)
+	if !sort.Float64sAreSorted(percentiles) {
+		percentiles = slices.Clone(percentiles)
+		sort.Float64s(percentiles)
+	}
 	h.percentiles = percentiles
 }
 
+// percentileSuffix returns the suffix of a percentile series: `.95percentile` for the 95th
+// percentile and `.99_9percentile` for the 99.9th percentile.
+func percentileSuffix(percentile float64) string {
+	return "." + strings.Replace(strconv.FormatFloat(percentile, 'f', -1, 64), ".", "_", 1) + "percentile"
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func (h *Histogram) addSample(sample *MetricSample, _ float64) {
 	rate := sample.SampleRate
@@ -163,7 +275,7 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 	// Compute percentiles
 	target := make([]int64, 0, len(h.percentiles))
 	for _, percentile := range h.percentiles {
-		target = append(target, (int64(percentile)*h.count-1)/100)
+		target = append(target, int64((percentile*float64(h.count)-1)/100))
 	}
 
 	if len(target) > 0 {
@@ -175,7 +287,7 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 				series = append(series, &Serie{
 					Points:     []Point{{Ts: timestamp, Value: s.value}},
 					MType:      APIGaugeType,
-					NameSuffix: fmt.Sprintf(".%dpercentile", h.percentiles[idx]),
+					NameSuffix: percentileSuffix(h.percentiles[idx]),
 				})
 				idx++
 			}
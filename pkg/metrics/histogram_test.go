@@ -16,11 +16,23 @@ import (
 )
 
 func TestHistogramConf(t *testing.T) {
-	assert.Equal(t, []int{95, 96, 28, 57, 58}, parsePercentiles([]string{"0.95", "0.96", "0.28", "0.57", "0.58"}))
+	assert.Equal(t, []float64{95, 96, 28, 57, 58}, parsePercentiles([]string{"0.95", "0.96", "0.28", "0.57", "0.58"}))
 }
 
 func TestHistogramConfError(t *testing.T) {
-	assert.Equal(t, []int{95, 22}, parsePercentiles([]string{"0.95", "test", "0.12test", "0.22", "200", "-50"}))
+	assert.Equal(t, []float64{95, 22}, parsePercentiles([]string{"0.95", "test", "0.12test", "0.22", "200", "-50"}))
+}
+
+func TestHistogramConfFractional(t *testing.T) {
+	// the global percentiles keep their legacy integer names
+	assert.Equal(t, []float64{50, 100, 100, 29}, parsePercentiles([]string{"0.5", "0.999", "0.9999", "0.29"}))
+	assert.Equal(t, []float64{50, 99.9, 99.99, 29}, parseOverridePercentiles([]string{"0.5", "0.999", "0.9999", "0.29"}))
+}
+
+func TestPercentileSuffix(t *testing.T) {
+	assert.Equal(t, ".95percentile", percentileSuffix(95))
+	assert.Equal(t, ".99_9percentile", percentileSuffix(99.9))
+	assert.Equal(t, ".99_99percentile", percentileSuffix(99.99))
 }
 
 func TestConfigureDefault(t *testing.T) {
@@ -32,7 +44,7 @@ func TestConfigureDefault(t *testing.T) {
 	_, err := hist.flush(60)
 	require.Nil(t, err)
 	assert.Equal(t, []string{"max", "median", "avg", "count"}, hist.aggregates)
-	assert.Equal(t, []int{95}, hist.percentiles)
+	assert.Equal(t, []float64{95}, hist.percentiles)
 }
 
 func TestConfigure(t *testing.T) {
@@ -40,13 +52,90 @@ func TestConfigure(t *testing.T) {
 
 	defaultAggregates = nil
 	defaultPercentiles = nil
+	defaultOverrides = nil
 	aggregates := []string{"max", "min", "test"}
 	mockConfig.SetWithoutSource("histogram_aggregates", aggregates)
 	mockConfig.SetWithoutSource("histogram_percentiles", []string{"0.50", "0.30", "0.98"})
 
 	hist := NewHistogram(10, mockConfig)
 	assert.Equal(t, aggregates, hist.aggregates)
-	assert.Equal(t, []int{30, 50, 98}, hist.percentiles)
+	assert.Equal(t, []float64{30, 50, 98}, hist.percentiles)
+}
+
+func TestConfigureOverrides(t *testing.T) {
+	mockConfig := configmock.New(t)
+
+	defaultAggregates = nil
+	defaultPercentiles = nil
+	defaultOverrides = nil
+	t.Cleanup(func() {
+		defaultAggregates = nil
+		defaultPercentiles = nil
+		defaultOverrides = nil
+	})
+	mockConfig.SetWithoutSource("histogram_aggregates", []string{"max", "avg"})
+	mockConfig.SetWithoutSource("histogram_percentiles", []string{"0.95"})
+	mockConfig.SetWithoutSource("histogram_overrides", []map[string]interface{}{
+		{"metric": "http.request.*", "percentiles": []string{"0.999", "0.5"}},
+		{"metric": "queue.*.size", "aggregates": []string{"count", "avg"}, "percentiles": []string{}},
+		{"metric": "http.*", "aggregates": []string{"min"}},
+		{"metric": "[invalid"},
+	})
+
+	hist := newHistogramForMetric("http.request.duration", 10, mockConfig)
+	assert.Equal(t, []string{"max", "avg"}, hist.aggregates)
+	assert.Equal(t, []float64{50, 99.9}, hist.percentiles)
+
+	hist = newHistogramForMetric("queue.jobs.size", 10, mockConfig)
+	assert.Equal(t, []string{"count", "avg"}, hist.aggregates)
+	assert.Empty(t, hist.percentiles)
+
+	hist = newHistogramForMetric("http.response.size", 10, mockConfig)
+	assert.Equal(t, []string{"min"}, hist.aggregates)
+	assert.Equal(t, []float64{95}, hist.percentiles)
+
+	hist = newHistogramForMetric("db.query.duration", 10, mockConfig)
+	assert.Equal(t, []string{"max", "avg"}, hist.aggregates)
+	assert.Equal(t, []float64{95}, hist.percentiles)
+
+	assert.Len(t, defaultOverrides, 3)
+	// the percentiles shared by the histograms are sorted once
+	assert.Equal(t, []float64{50, 99.9}, defaultOverrides[0].percentiles)
+
+	// the override matching a metric name is cached
+	cached, ok := overrideByName.Load("http.request.duration")
+	require.True(t, ok)
+	assert.Same(t, &defaultOverrides[0], cached.(*histogramOverride))
+	cached, ok = overrideByName.Load("db.query.duration")
+	require.True(t, ok)
+	assert.Nil(t, cached.(*histogramOverride))
+}
+
+func TestConfigureDoesNotModifyPercentiles(t *testing.T) {
+	cfg := setupConfig(t)
+	percentiles := []float64{95, 20, 80}
+	mHistogram := NewHistogram(10, cfg)
+	mHistogram.configure([]string{}, percentiles)
+	assert.Equal(t, []float64{20, 80, 95}, mHistogram.percentiles)
+	assert.Equal(t, []float64{95, 20, 80}, percentiles)
+}
+
+func TestHistogramFractionalPercentiles(t *testing.T) {
+	cfg := setupConfig(t)
+	mHistogram := NewHistogram(10, cfg)
+	mHistogram.configure([]string{}, []float64{99.9, 50})
+
+	for i := 1; i <= 1000; i++ {
+		mHistogram.addSample(&MetricSample{Value: float64(i)}, 50)
+	}
+
+	series, err := mHistogram.flush(60)
+	assert.Nil(t, err)
+	require.Len(t, series, 2)
+	assert.InEpsilon(t, 500, series[0].Points[0].Value, epsilon) // 0.5
+	assert.Equal(t, ".50percentile", series[0].NameSuffix)       // 0.5
+	assert.InEpsilon(t, 999, series[1].Points[0].Value, epsilon) // 0.999
+	assert.Equal(t, ".99_9percentile", series[1].NameSuffix)     // 0.999
 }
 
 func TestDefaultHistogramSampling(t *testing.T) {
@@ -55,6 +144,7 @@ func TestDefaultHistogramSampling(t *testing.T) {
 
 	defaultAggregates = nil
 	defaultPercentiles = nil
+	defaultOverrides = nil
 	mHistogram := NewHistogram(10, cfg)
 
 	// Empty flush
@@ -96,7 +186,7 @@ func TestCustomHistogramSampling(t *testing.T) {
 	// Initialize custom histogram, with an invalid aggregate
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"min", "sum", "invalid"}, []int{})
+	mHistogram.configure([]string{"min", "sum", "invalid"}, []float64{})
 
 	// Empty flush
 	_, err := mHistogram.flush(50)
@@ -142,7 +232,7 @@ func TestHistogramPercentiles(t *testing.T) {
 	// Initialize custom histogram
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "median", "avg", "count", "min"}, []int{95, 80})
+	mHistogram.configure([]string{"max", "median", "avg", "count", "min"}, []float64{95, 80})
 
 	// Empty flush
 	_, err := mHistogram.flush(50)
@@ -191,7 +281,7 @@ func TestHistogramPercentiles(t *testing.T) {
 func TestHistogramSampleRate(t *testing.T) {
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 
 	mHistogram.addSample(&MetricSample{Value: 1}, 50)
 	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
@@ -232,7 +322,7 @@ func TestHistogramSampleRate(t *testing.T) {
 func TestHistogramReset(t *testing.T) {
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 
 	mHistogram.addSample(&MetricSample{Value: 1}, 50)
 	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
@@ -279,7 +369,7 @@ func benchHistogram(b *testing.B, number int, sampleRate float64) {
 	cfg := setupConfig(b)
 	for n := 0; n < b.N; n++ {
 		h := NewHistogram(1, cfg)
-		h.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+		h.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 		m := MetricSample{Value: 21, SampleRate: sampleRate}
 
 		for i := 0; i < number; i++ {
@@ -27,6 +27,7 @@ var validProducts = map[string]struct{}{
 	ProductAPMTracing:                   {},
 	ProductSDSRules:                     {},
 	ProductSDSAgentConfig:               {},
+	ProductLogsProcessingRules:          {},
 	ProductLiveDebugging:                {},
 	ProductContainerAutoscalingSettings: {},
 	ProductContainerAutoscalingValues:   {},
@@ -80,6 +81,8 @@ const (
 	ProductSDSRules = "SDS_RULES_DD"
 	// ProductSDSAgentConfig is the user SDS configurations product.
 	ProductSDSAgentConfig = "SDS_AGENT_CONFIG"
+	// ProductLogsProcessingRules receives the logs processing rules replacing the configured ones
+	ProductLogsProcessingRules = "LOGS_PROCESSING_RULES"
 	// ProductLiveDebugging is the dynamic instrumentation product
 	ProductLiveDebugging = "LIVE_DEBUGGING"
 	// ProductContainerAutoscalingSettings receives definition of container autoscaling
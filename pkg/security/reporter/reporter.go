@@ -58,6 +58,7 @@ func newReporter(hostname string, stopper startstop.Stopper, sourceName, sourceT
 		auditor,
 		&diagnostic.NoopMessageReceiver{},
 		nil,
+		nil,
 		endpoints,
 		context,
 		&seccommon.NoopStatusProvider{},
@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// FileSink writes the records as newline-delimited JSON to a file. The file is rotated
+// when it exceeds its maximum size: `payloads.json` is renamed `payloads.json.1`, the
+// previous `payloads.json.1` is renamed `payloads.json.2` and so on, up to `maxRotatedFiles`.
+// The file is reopened when it is moved by another writer, such as the sink of another
+// serializer or logrotate.
+type FileSink struct {
+	path            string
+	maxSize         int64
+	maxRotatedFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	info   os.FileInfo
+	size   int64
+	closed bool
+}
+
+// NewFileSink creates a new FileSink writing to `path`.
+func NewFileSink(path string, maxSize int64, maxRotatedFiles int) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.New("serializer_sinks.file.path must be set to enable the file sink")
+	}
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid maximum file size %d for the file sink", maxSize)
+	}
+
+	s := &FileSink{
+		path:            path,
+		maxSize:         maxSize,
+		maxRotatedFiles: max(maxRotatedFiles, 0),
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Name returns the name of the sink
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+// Write appends the records to the file, one per line.
+func (s *FileSink) Write(_ string, records [][]byte) error {
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("the file sink is closed")
+	}
+	if err := s.reopenIfMoved(); err != nil {
+		return err
+	}
+	if s.size > 0 && s.size+int64(buf.Len()) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(buf.Bytes())
+	s.size += int64(n)
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	s.file = file
+	s.info = info
+	s.size = info.Size()
+	return nil
+}
+
+// reopenIfMoved opens the file again when it isn't the one written to anymore.
+func (s *FileSink) reopenIfMoved() error {
+	if s.file != nil {
+		info, err := os.Stat(s.path)
+		if err == nil && os.SameFile(info, s.info) {
+			// The file may also be appended to by another writer
+			s.size = info.Size()
+			return nil
+		}
+		_ = s.file.Close()
+		s.file = nil
+	}
+	return s.open()
+}
+
+// rotate closes the current file, shifts the rotated files and opens a new file.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	if s.maxRotatedFiles == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.open()
+	}
+
+	for i := s.maxRotatedFiles - 1; i > 0; i-- {
+		if err := os.Rename(s.rotatedPath(i), s.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.rotatedPath(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.open()
+}
+
+func (s *FileSink) rotatedPath(index int) string {
+	return s.path + "." + strconv.Itoa(index)
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sinks", "payloads.json")
+	s, err := NewFileSink(path, 1024, 2)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(Series, [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)}))
+	require.NoError(t, s.Write(Events, [][]byte{[]byte(`{"c":3}`)}))
+	require.NoError(t, s.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n{\"c\":3}\n", string(content))
+
+	// The file is appended to when the sink is created again
+	s, err = NewFileSink(path, 1024, 2)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(Series, [][]byte{[]byte(`{"d":4}`)}))
+	require.NoError(t, s.Close())
+
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n{\"c\":3}\n{\"d\":4}\n", string(content))
+
+	assert.Error(t, s.Write(Series, [][]byte{[]byte(`{}`)}))
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payloads.json")
+	s, err := NewFileSink(path, 10, 2)
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, record := range []string{`{"a":1}`, `{"b":2}`, `{"c":3}`, `{"d":4}`} {
+		require.NoError(t, s.Write(Series, [][]byte{[]byte(record)}))
+	}
+
+	for file, expected := range map[string]string{
+		path:        "{\"d\":4}\n",
+		path + ".1": "{\"c\":3}\n",
+		path + ".2": "{\"b\":2}\n",
+	} {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileSinkReopenMovedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payloads.json")
+	s, err := NewFileSink(path, 1024, 2)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Write(Series, [][]byte{[]byte(`{"a":1}`)}))
+	require.NoError(t, os.Rename(path, path+".old"))
+	require.NoError(t, s.Write(Series, [][]byte{[]byte(`{"b":2}`)}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"b\":2}\n", string(content))
+}
+
+func TestFileSinkInvalidConfig(t *testing.T) {
+	_, err := NewFileSink("", 10, 2)
+	assert.Error(t, err)
+	_, err = NewFileSink(filepath.Join(t.TempDir(), "payloads.json"), 0, 2)
+	assert.Error(t, err)
+}
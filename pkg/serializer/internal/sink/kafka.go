@@ -0,0 +1,447 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	kafkaProduceAPIKey = 0
+	// kafkaProduceAPIVersion is the first version of the Produce API using record batches (message format v2)
+	kafkaProduceAPIVersion = 3
+	kafkaRecordBatchMagic  = 2
+
+	// kafkaMaxResponseSize protects against invalid response sizes
+	kafkaMaxResponseSize = 1024 * 1024
+
+	// kafkaDefaultQueueSize is the default number of writes waiting to be produced
+	kafkaDefaultQueueSize = 100
+)
+
+var (
+	kafkaCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+	tlmKafkaDropped = telemetry.NewCounter("serializer", "sink_kafka_dropped_records",
+		[]string{"reason"}, "Count of records queued for the kafka sink which could not be produced")
+
+	errKafkaSinkClosed = errors.New("the kafka sink is closed")
+)
+
+// KafkaConfig holds the settings of a KafkaSink
+type KafkaConfig struct {
+	// Broker is the address (host:port) of the broker
+	Broker string
+	// Topic is the topic to which the records are produced
+	Topic string
+	// Partition is the partition of the topic to which the records are produced
+	Partition int32
+	// ClientID identifies the agent in the broker logs and metrics
+	ClientID string
+	// RequiredAcks is the number of acknowledgements the broker must receive before answering:
+	// 0 doesn't wait for any answer, 1 waits for the leader and -1 waits for all the in-sync replicas.
+	RequiredAcks int16
+	// Timeout bounds the connection, the write of the request and the wait for the answer
+	Timeout time.Duration
+	// MaxBatchSize is the maximum size of the records produced in a single request
+	MaxBatchSize int
+	// QueueSize is the maximum number of writes waiting to be produced
+	QueueSize int
+}
+
+// kafkaWrite is a write waiting in the queue of a KafkaSink
+type kafkaWrite struct {
+	payloadType string
+	records     [][]byte
+}
+
+// KafkaSink produces the records to a topic of a broker speaking the Kafka protocol. The
+// payload type is used as the key of the records.
+// The records are produced in the background, so that a slow or unavailable broker doesn't
+// hold the serializer: the writes are queued and dropped when the queue is full.
+// Only the Produce API is implemented: the leader of the partition is not discovered, so
+// `Broker` must be the leader of `Partition`, as is the case with a single-node broker.
+type KafkaSink struct {
+	config KafkaConfig
+
+	// mu protects the queue from the writes after Close
+	mu     sync.Mutex
+	closed bool
+	queue  chan kafkaWrite
+	stop   chan struct{}
+	done   chan struct{}
+
+	// conn and correlationID are only used by the producing goroutine
+	conn          net.Conn
+	correlationID int32
+}
+
+// NewKafkaSink creates a new KafkaSink. The connection to the broker is opened on the first write.
+func NewKafkaSink(config KafkaConfig) (*KafkaSink, error) {
+	if config.Broker == "" {
+		return nil, errors.New("serializer_sinks.kafka.broker must be set to enable the kafka sink")
+	}
+	if config.Topic == "" {
+		return nil, errors.New("serializer_sinks.kafka.topic must be set to enable the kafka sink")
+	}
+	if config.RequiredAcks < -1 || config.RequiredAcks > 1 {
+		return nil, fmt.Errorf("invalid required acks %d for the kafka sink: must be -1, 0 or 1", config.RequiredAcks)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = kafkaDefaultQueueSize
+	}
+	s := &KafkaSink{
+		config: config,
+		queue:  make(chan kafkaWrite, config.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Name returns the name of the sink
+func (s *KafkaSink) Name() string {
+	return "kafka"
+}
+
+// Write queues the records to be produced. An error is returned when the queue is full.
+func (s *KafkaSink) Write(payloadType string, records [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errKafkaSinkClosed
+	}
+	// the slice of records is reused by the caller
+	w := kafkaWrite{payloadType: payloadType, records: append([][]byte(nil), records...)}
+	select {
+	case s.queue <- w:
+		return nil
+	default:
+		return fmt.Errorf("the kafka sink queue is full (%d writes)", cap(s.queue))
+	}
+}
+
+// Close produces the queued records, for at most the timeout, and closes the connection to
+// the broker. The records still queued are dropped.
+func (s *KafkaSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-time.After(s.config.Timeout):
+		close(s.stop)
+		<-s.done
+	}
+	return nil
+}
+
+// run produces the queued records until the queue is closed.
+func (s *KafkaSink) run() {
+	defer close(s.done)
+	defer s.closeConn() //nolint:errcheck
+
+	for w := range s.queue {
+		select {
+		case <-s.stop:
+			tlmKafkaDropped.Add(float64(len(w.records)), "stopped")
+			continue
+		default:
+		}
+		if err := s.write(w.payloadType, w.records); err != nil {
+			log.Errorf("Cannot produce %d %s records to the kafka sink: %v", len(w.records), w.payloadType, err)
+			tlmKafkaDropped.Add(float64(len(w.records)), "error")
+		}
+	}
+}
+
+// write produces the records, in as many requests as required by the maximum batch size.
+func (s *KafkaSink) write(payloadType string, records [][]byte) error {
+	key := []byte(payloadType)
+	for len(records) > 0 {
+		count, size := 0, 0
+		for count < len(records) && (count == 0 || size+len(records[count]) <= s.config.MaxBatchSize) {
+			size += len(records[count])
+			count++
+		}
+		if err := s.produce(key, records[:count]); err != nil {
+			return err
+		}
+		records = records[count:]
+	}
+	return nil
+}
+
+// produce sends a produce request. When the connection was opened by a previous request, the
+// request is sent once more on a new connection if it fails, as the broker may have closed it.
+func (s *KafkaSink) produce(key []byte, values [][]byte) error {
+	s.correlationID++
+	request := encodeProduceRequest(s.config, s.correlationID, encodeRecordBatch(key, values, time.Now()))
+
+	reused := s.conn != nil
+	err := s.roundTrip(request)
+	var brokerErr *kafkaBrokerError
+	if err != nil && reused && !errors.As(err, &brokerErr) {
+		err = s.roundTrip(request)
+	}
+	return err
+}
+
+func (s *KafkaSink) roundTrip(request []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.config.Broker, s.config.Timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if err := s.conn.SetDeadline(time.Now().Add(s.config.Timeout)); err != nil {
+		_ = s.closeConn()
+		return err
+	}
+	if _, err := s.conn.Write(request); err != nil {
+		_ = s.closeConn()
+		return err
+	}
+	if s.config.RequiredAcks == 0 {
+		// The broker doesn't answer
+		return nil
+	}
+
+	response, err := readKafkaResponse(s.conn)
+	if err != nil {
+		_ = s.closeConn()
+		return err
+	}
+	return decodeProduceResponse(response, s.correlationID)
+}
+
+func (s *KafkaSink) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// kafkaBrokerError is returned when the broker rejects the records.
+type kafkaBrokerError struct {
+	topic     string
+	partition int32
+	code      int16
+}
+
+func (e *kafkaBrokerError) Error() string {
+	return fmt.Sprintf("the broker rejected the records of the topic %s, partition %d, with the error code %d", e.topic, e.partition, e.code)
+}
+
+// kafkaEncoder encodes the primitive types of the Kafka protocol
+type kafkaEncoder struct {
+	bytes.Buffer
+}
+
+func (e *kafkaEncoder) int8(v int8) {
+	e.WriteByte(byte(v))
+}
+
+func (e *kafkaEncoder) int16(v int16) {
+	e.Buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (e *kafkaEncoder) int32(v int32) {
+	e.Buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (e *kafkaEncoder) int64(v int64) {
+	e.Buffer.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+}
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.WriteString(v)
+}
+
+// varint encodes a zigzag variable-length integer, as used in the records
+func (e *kafkaEncoder) varint(v int64) {
+	e.Buffer.Write(binary.AppendVarint(nil, v))
+}
+
+// encodeRecordBatch encodes the records in a record batch (message format v2), without compression.
+func encodeRecordBatch(key []byte, values [][]byte, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+
+	var records kafkaEncoder
+	for i, value := range values {
+		var record kafkaEncoder
+		record.int8(0)          // attributes
+		record.varint(0)        // timestamp delta
+		record.varint(int64(i)) // offset delta
+		record.varint(int64(len(key)))
+		record.Write(key)
+		record.varint(int64(len(value)))
+		record.Write(value)
+		record.varint(0) // headers count
+
+		records.varint(int64(record.Len()))
+		records.Write(record.Bytes())
+	}
+
+	// The CRC covers the batch from the attributes to the end
+	var checked kafkaEncoder
+	checked.int16(0)                      // attributes: no compression, no transaction
+	checked.int32(int32(len(values) - 1)) // last offset delta
+	checked.int64(timestamp)              // base timestamp
+	checked.int64(timestamp)              // max timestamp
+	checked.int64(-1)                     // producer id
+	checked.int16(-1)                     // producer epoch
+	checked.int32(-1)                     // base sequence
+	checked.int32(int32(len(values)))     // records count
+	checked.Write(records.Bytes())
+
+	var batch kafkaEncoder
+	batch.int64(0)                                // base offset, assigned by the broker
+	batch.int32(int32(4 + 1 + 4 + checked.Len())) // batch length, from the partition leader epoch
+	batch.int32(-1)                               // partition leader epoch
+	batch.int8(kafkaRecordBatchMagic)             // magic
+	batch.int32(int32(crc32.Checksum(checked.Bytes(), kafkaCRCTable)))
+	batch.Write(checked.Bytes())
+	return batch.Bytes()
+}
+
+// encodeProduceRequest encodes a produce request of a record batch, prefixed by its size.
+func encodeProduceRequest(config KafkaConfig, correlationID int32, recordBatch []byte) []byte {
+	var request kafkaEncoder
+	request.int16(kafkaProduceAPIKey)
+	request.int16(kafkaProduceAPIVersion)
+	request.int32(correlationID)
+	request.string(config.ClientID)
+	request.int16(-1) // transactional id: null
+	request.int16(config.RequiredAcks)
+	request.int32(int32(config.Timeout / time.Millisecond))
+	request.int32(1) // topics count
+	request.string(config.Topic)
+	request.int32(1) // partitions count
+	request.int32(config.Partition)
+	request.int32(int32(len(recordBatch)))
+	request.Write(recordBatch)
+
+	var sized kafkaEncoder
+	sized.int32(int32(request.Len()))
+	sized.Write(request.Bytes())
+	return sized.Bytes()
+}
+
+func readKafkaResponse(r io.Reader) ([]byte, error) {
+	var size int32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 0 || size > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("invalid kafka response size %d", size)
+	}
+	response := make([]byte, size)
+	if _, err := io.ReadFull(r, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// kafkaDecoder decodes the primitive types of the Kafka protocol. The first error is kept
+// and the following reads return zero values.
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) string() string {
+	return string(d.next(int(d.int16())))
+}
+
+// decodeProduceResponse decodes a produce response and returns the first error reported by the broker.
+func decodeProduceResponse(response []byte, correlationID int32) error {
+	d := &kafkaDecoder{buf: response}
+	if id := d.int32(); d.err == nil && id != correlationID {
+		return fmt.Errorf("unexpected kafka correlation id %d, expected %d", id, correlationID)
+	}
+
+	var brokerErr error
+	topics := d.int32()
+	for i := int32(0); i < topics && d.err == nil; i++ {
+		topic := d.string()
+		partitions := d.int32()
+		for j := int32(0); j < partitions && d.err == nil; j++ {
+			partition := d.int32()
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if code != 0 && brokerErr == nil {
+				brokerErr = &kafkaBrokerError{topic: topic, partition: partition, code: code}
+			}
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("invalid kafka produce response: %w", d.err)
+	}
+	return brokerErr
+}
@@ -0,0 +1,279 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package sink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type kafkaRecord struct {
+	topic     string
+	partition int32
+	key       string
+	value     string
+}
+
+// kafkaStandIn is a broker answering the produce requests. It records the records produced
+// and answers with `errorCode`.
+type kafkaStandIn struct {
+	t         *testing.T
+	listener  net.Listener
+	records   chan kafkaRecord
+	errorCode int16
+}
+
+func newKafkaStandIn(t *testing.T, errorCode int16) *kafkaStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &kafkaStandIn{t: t, listener: listener, records: make(chan kafkaRecord, 100), errorCode: errorCode}
+	go b.serve()
+	t.Cleanup(func() { listener.Close() })
+	return b
+}
+
+func (b *kafkaStandIn) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *kafkaStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		request, err := readKafkaResponse(conn)
+		if err != nil {
+			return
+		}
+		d := &kafkaDecoder{buf: request}
+		assert.Equal(b.t, int16(kafkaProduceAPIKey), d.int16())
+		assert.Equal(b.t, int16(kafkaProduceAPIVersion), d.int16())
+		correlationID := d.int32()
+		assert.Equal(b.t, "agent", d.string())
+		assert.Equal(b.t, int16(-1), d.int16()) // transactional id
+		acks := d.int16()
+		d.int32() // timeout
+		require.Equal(b.t, int32(1), d.int32())
+		topic := d.string()
+		require.Equal(b.t, int32(1), d.int32())
+		partition := d.int32()
+		recordBatch := d.next(int(d.int32()))
+		require.NoError(b.t, d.err)
+		b.decodeRecordBatch(topic, partition, recordBatch)
+
+		if acks == 0 {
+			continue
+		}
+		var response kafkaEncoder
+		response.int32(correlationID)
+		response.int32(1)
+		response.string(topic)
+		response.int32(1)
+		response.int32(partition)
+		response.int16(b.errorCode)
+		response.int64(0)
+		response.int64(-1)
+		response.int32(0) // throttle time
+		var sized kafkaEncoder
+		sized.int32(int32(response.Len()))
+		sized.Write(response.Bytes())
+		if _, err := conn.Write(sized.Bytes()); err != nil {
+			return
+		}
+	}
+}
+
+func (b *kafkaStandIn) decodeRecordBatch(topic string, partition int32, recordBatch []byte) {
+	d := &kafkaDecoder{buf: recordBatch}
+	d.int64() // base offset
+	assert.Equal(b.t, int(d.int32()), len(recordBatch)-12)
+	d.int32() // partition leader epoch
+	assert.Equal(b.t, []byte{kafkaRecordBatchMagic}, d.next(1))
+	crc := uint32(d.int32())
+	assert.Equal(b.t, crc32.Checksum(d.buf, kafkaCRCTable), crc)
+	d.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	count := d.int32()
+	require.NoError(b.t, d.err)
+
+	r := bytes.NewReader(d.buf)
+	readBytes := func() string {
+		length, err := binary.ReadVarint(r)
+		require.NoError(b.t, err)
+		value := make([]byte, length)
+		_, err = io.ReadFull(r, value)
+		require.NoError(b.t, err)
+		return string(value)
+	}
+	for i := int32(0); i < count; i++ {
+		_, err := binary.ReadVarint(r) // length
+		require.NoError(b.t, err)
+		_, err = r.ReadByte() // attributes
+		require.NoError(b.t, err)
+		_, err = binary.ReadVarint(r) // timestamp delta
+		require.NoError(b.t, err)
+		offsetDelta, err := binary.ReadVarint(r)
+		require.NoError(b.t, err)
+		assert.Equal(b.t, int64(i), offsetDelta)
+		key := readBytes()
+		value := readBytes()
+		headers, err := binary.ReadVarint(r)
+		require.NoError(b.t, err)
+		assert.Zero(b.t, headers)
+		b.records <- kafkaRecord{topic: topic, partition: partition, key: key, value: value}
+	}
+	assert.Zero(b.t, r.Len())
+}
+
+func (b *kafkaStandIn) receive(count int) []kafkaRecord {
+	var records []kafkaRecord
+	for len(records) < count {
+		select {
+		case record := <-b.records:
+			records = append(records, record)
+		case <-time.After(2 * time.Second):
+			require.FailNow(b.t, "Timeout waiting for the records", "received %v", records)
+		}
+	}
+	return records
+}
+
+func newTestKafkaSink(t *testing.T, broker string, acks int16, maxBatchSize int) *KafkaSink {
+	s, err := NewKafkaSink(KafkaConfig{
+		Broker:       broker,
+		Topic:        "metrics",
+		Partition:    2,
+		ClientID:     "agent",
+		RequiredAcks: acks,
+		Timeout:      time.Second,
+		MaxBatchSize: maxBatchSize,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestKafkaSinkWrite(t *testing.T) {
+	broker := newKafkaStandIn(t, 0)
+	s := newTestKafkaSink(t, broker.listener.Addr().String(), 1, 1024)
+
+	require.NoError(t, s.Write(Series, [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)}))
+	require.NoError(t, s.Write(Events, [][]byte{[]byte(`{"c":3}`)}))
+
+	assert.Equal(t, []kafkaRecord{
+		{topic: "metrics", partition: 2, key: Series, value: `{"a":1}`},
+		{topic: "metrics", partition: 2, key: Series, value: `{"b":2}`},
+		{topic: "metrics", partition: 2, key: Events, value: `{"c":3}`},
+	}, broker.receive(3))
+}
+
+func TestKafkaSinkMaxBatchSize(t *testing.T) {
+	broker := newKafkaStandIn(t, 0)
+	s := newTestKafkaSink(t, broker.listener.Addr().String(), 1, 10)
+
+	require.NoError(t, s.write(Series, [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`), []byte(`{"c":3}`)}))
+	assert.Len(t, broker.receive(3), 3)
+	assert.Equal(t, int32(3), s.correlationID)
+}
+
+func TestKafkaSinkNoAcks(t *testing.T) {
+	broker := newKafkaStandIn(t, 0)
+	s := newTestKafkaSink(t, broker.listener.Addr().String(), 0, 1024)
+
+	require.NoError(t, s.Write(Sketches, [][]byte{[]byte(`{"a":1}`)}))
+	assert.Equal(t, []kafkaRecord{{topic: "metrics", partition: 2, key: Sketches, value: `{"a":1}`}}, broker.receive(1))
+}
+
+func TestKafkaSinkBrokerError(t *testing.T) {
+	// 6: NOT_LEADER_OR_FOLLOWER
+	broker := newKafkaStandIn(t, 6)
+	s := newTestKafkaSink(t, broker.listener.Addr().String(), 1, 1024)
+
+	err := s.write(ServiceChecks, [][]byte{[]byte(`{"a":1}`)})
+	var brokerErr *kafkaBrokerError
+	require.ErrorAs(t, err, &brokerErr)
+	assert.Equal(t, int16(6), brokerErr.code)
+	// The request is not sent again
+	assert.Len(t, broker.receive(1), 1)
+	assert.Empty(t, broker.records)
+}
+
+func TestKafkaSinkReconnect(t *testing.T) {
+	broker := newKafkaStandIn(t, 0)
+	s := newTestKafkaSink(t, broker.listener.Addr().String(), 1, 1024)
+
+	require.NoError(t, s.write(Series, [][]byte{[]byte(`{"a":1}`)}))
+	broker.receive(1)
+
+	// The broker closed the connection
+	s.conn.Close()
+	require.NoError(t, s.write(Series, [][]byte{[]byte(`{"b":2}`)}))
+	assert.Equal(t, []kafkaRecord{{topic: "metrics", partition: 2, key: Series, value: `{"b":2}`}}, broker.receive(1))
+}
+
+func TestKafkaSinkQueue(t *testing.T) {
+	// the broker doesn't accept the connections, so the first write stays in progress
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	s, err := NewKafkaSink(KafkaConfig{
+		Broker:       listener.Addr().String(),
+		Topic:        "metrics",
+		RequiredAcks: 1,
+		Timeout:      500 * time.Millisecond,
+		QueueSize:    1,
+	})
+	require.NoError(t, err)
+
+	records := [][]byte{[]byte(`{"a":1}`)}
+	require.NoError(t, s.Write(Series, records))
+	// the write is queued with a copy of the slice of records
+	records[0] = []byte(`{"b":2}`)
+	assert.Eventually(t, func() bool { return len(s.queue) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, s.Write(Series, records))
+	assert.Error(t, s.Write(Series, records))
+
+	// the queued writes are dropped once the timeout is reached
+	start := time.Now()
+	require.NoError(t, s.Close())
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.ErrorIs(t, s.Write(Series, records), errKafkaSinkClosed)
+	require.NoError(t, s.Close())
+}
+
+func TestKafkaSinkCloseProducesQueuedRecords(t *testing.T) {
+	broker := newKafkaStandIn(t, 0)
+	s := newTestKafkaSink(t, broker.listener.Addr().String(), 1, 1024)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Write(Series, [][]byte{[]byte(`{"a":1}`)}))
+	}
+	require.NoError(t, s.Close())
+	assert.Len(t, broker.receive(10), 10)
+	assert.Nil(t, s.conn)
+}
+
+func TestKafkaSinkInvalidConfig(t *testing.T) {
+	_, err := NewKafkaSink(KafkaConfig{Topic: "metrics"})
+	assert.Error(t, err)
+	_, err = NewKafkaSink(KafkaConfig{Broker: "localhost:9092"})
+	assert.Error(t, err)
+	_, err = NewKafkaSink(KafkaConfig{Broker: "localhost:9092", Topic: "metrics", RequiredAcks: 2})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package sink implements the outputs, other than the forwarder, to which the serializer
+// writes series, sketches, events and service checks.
+package sink
+
+import (
+	"errors"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// Payload types written to the sinks
+const (
+	Series        = "series"
+	Sketches      = "sketches"
+	Events        = "events"
+	ServiceChecks = "service_checks"
+)
+
+// Sink writes records to an output. Each record is a single line JSON document.
+type Sink interface {
+	// Name returns the name of the sink, used in the logs and the telemetry.
+	Name() string
+	// Write writes the records of a payload type, or queues them to be written.
+	Write(payloadType string, records [][]byte) error
+	// Close writes the queued records and releases the resources of the sink.
+	Close() error
+}
+
+// FromConfig returns the sinks enabled in the configuration.
+func FromConfig(cfg model.Reader) ([]Sink, error) {
+	var sinks []Sink
+	var errs []error
+
+	if cfg.GetBool("serializer_sinks.file.enabled") {
+		fileSink, err := NewFileSink(
+			cfg.GetString("serializer_sinks.file.path"),
+			cfg.GetInt64("serializer_sinks.file.max_size"),
+			cfg.GetInt("serializer_sinks.file.max_rotated_files"))
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			sinks = append(sinks, fileSink)
+		}
+	}
+
+	if cfg.GetBool("serializer_sinks.kafka.enabled") {
+		kafkaSink, err := NewKafkaSink(KafkaConfig{
+			Broker:       cfg.GetString("serializer_sinks.kafka.broker"),
+			Topic:        cfg.GetString("serializer_sinks.kafka.topic"),
+			Partition:    cfg.GetInt32("serializer_sinks.kafka.partition"),
+			ClientID:     cfg.GetString("serializer_sinks.kafka.client_id"),
+			RequiredAcks: int16(cfg.GetInt("serializer_sinks.kafka.required_acks")),
+			Timeout:      cfg.GetDuration("serializer_sinks.kafka.timeout"),
+			MaxBatchSize: cfg.GetInt("serializer_sinks.kafka.max_batch_size"),
+			QueueSize:    cfg.GetInt("serializer_sinks.kafka.queue_size"),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			sinks = append(sinks, kafkaSink)
+		}
+	}
+
+	return sinks, errors.Join(errs...)
+}
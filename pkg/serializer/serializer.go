@@ -26,6 +26,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	"github.com/DataDog/datadog-agent/pkg/process/util/api/headers"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sink"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
@@ -97,7 +98,9 @@ type MetricSerializer interface {
 	SendOrchestratorManifests(msgs []types.ProcessMessageBody, hostName, clusterID string) error
 }
 
-// Serializer serializes metrics to the correct format and routes the payloads to the correct endpoint in the Forwarder
+// Serializer serializes metrics to the correct format and routes the payloads to the correct endpoint in the Forwarder.
+// Series, sketches, events and service checks can also be written to sinks, such as files or Kafka topics,
+// in addition to or instead of being sent to the Forwarder.
 type Serializer struct {
 	Forwarder             forwarder.Forwarder
 	orchestratorForwarder orchestratorForwarder.Component
@@ -127,10 +130,14 @@ type Serializer struct {
 	enableSketchProtobufStream    bool
 	hostname                      string
 	logger                        log.Component
+
+	sinks           []sink.Sink
+	forwardToIntake bool
 }
 
-// NewSerializer returns a new Serializer initialized
-func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestratorForwarder.Component, compressor compression.Compressor, config config.Component, logger log.Component, hostName string) *Serializer {
+// NewSerializer returns a new Serializer initialized, writing to the given sinks if any.
+// The sinks can be shared by several serializers, they are closed by their owner.
+func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestratorForwarder.Component, compressor compression.Compressor, config config.Component, logger log.Component, hostName string, sinks *Sinks) *Serializer {
 	streamAvailable := compressor.NewStreamCompressor(&bytes.Buffer{}) != nil
 
 	s := &Serializer{
@@ -158,6 +165,11 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestr
 
 	initExtraHeaders(s)
 
+	if sinks != nil {
+		s.sinks = sinks.sinks
+	}
+	s.forwardToIntake = len(s.sinks) == 0 || config.GetBool("serializer_sinks.forward_to_intake")
+
 	if !s.enableEvents {
 		logger.Warn("event payloads are disabled: all events will be dropped")
 	}
@@ -279,6 +291,11 @@ func (s *Serializer) SendEvents(events event.Events) error {
 		return nil
 	}
 
+	writeToSinks(s, sink.Events, events)
+	if !s.forwardToIntake {
+		return nil
+	}
+
 	var eventPayloads transaction.BytesPayloads
 	var extraHeaders http.Header
 	var err error
@@ -306,6 +323,11 @@ func (s *Serializer) SendServiceChecks(serviceChecks servicecheck.ServiceChecks)
 		return nil
 	}
 
+	writeToSinks(s, sink.ServiceChecks, serviceChecks)
+	if !s.forwardToIntake {
+		return nil
+	}
+
 	serviceChecksSerializer := metricsserializer.ServiceChecks(serviceChecks)
 	var serviceCheckPayloads transaction.BytesPayloads
 	var extraHeaders http.Header
@@ -335,6 +357,13 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 		return nil
 	}
 
+	serieSource, flushSinks := s.withSerieSinks(serieSource)
+	defer flushSinks()
+	if !s.forwardToIntake {
+		drainSerieSource(serieSource)
+		return nil
+	}
+
 	seriesSerializer := metricsserializer.CreateIterableSeries(serieSource)
 	useV1API := !s.config.GetBool("use_v2_api.series")
 
@@ -438,6 +467,14 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 		s.logger.Debug("sketches payloads are disabled: dropping it")
 		return nil
 	}
+
+	sketches, flushSinks := s.withSketchesSinks(sketches)
+	defer flushSinks()
+	if !s.forwardToIntake {
+		drainSketchesSource(sketches)
+		return nil
+	}
+
 	sketchesSerializer := metricsserializer.SketchSeriesList{SketchesSource: sketches}
 	if s.enableSketchProtobufStream {
 		failoverActive, allowlist := s.getFailoverAllowlist()
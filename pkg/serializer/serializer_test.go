@@ -40,7 +40,7 @@ func TestInitExtraHeadersNoopCompression(t *testing.T) {
 	mockConfig.SetWithoutSource("serializer_compressor_kind", "blah")
 
 	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-	s := NewSerializer(nil, nil, compressor, mockConfig, logmock.New(t), "testhost")
+	s := NewSerializer(nil, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 	initExtraHeaders(s)
 
 	expected := make(http.Header)
@@ -78,7 +78,7 @@ func TestInitExtraHeadersWithCompression(t *testing.T) {
 			mockConfig := configmock.New(t)
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(nil, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(nil, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			initExtraHeaders(s)
 
 			expected := make(http.Header)
@@ -268,7 +268,7 @@ func TestSendV1Events(t *testing.T) {
 			f := &forwarder.MockedForwarder{}
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			matcher := createJSONPayloadMatcher(`{"apiKey":"","events":{},"internalHostname"`, s)
 			f.On("SubmitV1Intake", matcher, s.jsonExtraHeadersWithCompression).Return(nil).Times(1)
 
@@ -296,7 +296,7 @@ func TestSendV1EventsCreateMarshalersBySourceType(t *testing.T) {
 			f := &forwarder.MockedForwarder{}
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 
 			events := event.Events{&event.Event{SourceTypeName: "source1"}, &event.Event{SourceTypeName: "source2"}, &event.Event{SourceTypeName: "source3"}}
 			payloadsCountMatcher := func(payloadCount int) interface{} {
@@ -336,7 +336,7 @@ func TestSendV1ServiceChecks(t *testing.T) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			matcher := createJSONPayloadMatcher(`[{"check":"","host_name":"","timestamp":0,"status":0,"message":"","tags":null}]`, s)
 			f.On("SubmitV1CheckRuns", matcher, s.jsonExtraHeadersWithCompression).Return(nil).Times(1)
 
@@ -364,7 +364,7 @@ func TestSendV1Series(t *testing.T) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			matcher := createJSONPayloadMatcher(`{"series":[]}`, s)
 
 			f.On("SubmitV1Series", matcher, s.jsonExtraHeadersWithCompression).Return(nil).Times(1)
@@ -392,7 +392,7 @@ func TestSendSeries(t *testing.T) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			matcher := createProtoscopeMatcher(`1: {
 		1: { 1: {"host"} }
 		5: 3
@@ -423,7 +423,7 @@ func TestSendSketch(t *testing.T) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			matcher := createProtoscopeMatcher(`
 		1: { 1: {"fakename"} 2: {"fakehost"} 8: { 1: { 4: 10 }}}
 		2: {}
@@ -454,7 +454,7 @@ func TestSendMetadata(t *testing.T) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			jsonPayloads, _ := mkPayloads(jsonString, true, s)
 			f.On("SubmitMetadata", jsonPayloads, s.jsonExtraHeadersWithCompression).Return(nil).Times(1)
 
@@ -491,7 +491,7 @@ func TestSendProcessesMetadata(t *testing.T) {
 			mockConfig.SetWithoutSource("serializer_compressor_kind", tc.kind)
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 			payloads, _ := mkPayloads(payload, true, s)
 			f.On("SubmitV1Intake", payloads, s.jsonExtraHeadersWithCompression).Return(nil).Times(1)
 
@@ -533,7 +533,7 @@ func TestSendWithDisabledKind(t *testing.T) {
 			f := &forwarder.MockedForwarder{}
 
 			compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
-			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+			s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", nil)
 
 			jsonPayloads, _ := mkPayloads(jsonString, true, s)
 			payload := &testPayload{}
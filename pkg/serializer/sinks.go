@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package serializer
+
+import (
+	"encoding/json"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/sink"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// sinkBatchSize is the maximum number of records written to the sinks at once
+const sinkBatchSize = 1000
+
+var (
+	tlmSinkRecords = telemetry.NewCounter("serializer", "sink_records",
+		[]string{"sink", "type"}, "Count of records written to the serializer sinks")
+	tlmSinkErrors = telemetry.NewCounter("serializer", "sink_errors",
+		[]string{"sink", "type"}, "Count of errors when writing records to the serializer sinks")
+)
+
+// Sinks are the outputs, other than the forwarder, to which the serializers write series, sketches,
+// events and service checks. They are created once and shared by all the serializers of the process,
+// so that a file isn't written and rotated by several serializers, or a Kafka producer isn't duplicated.
+type Sinks struct {
+	sinks  []sink.Sink
+	logger log.Component
+}
+
+// NewSinks returns the sinks enabled in the configuration, the ones which can't be created are skipped.
+func NewSinks(config config.Component, logger log.Component) *Sinks {
+	sinks, err := sink.FromConfig(config)
+	if err != nil {
+		logger.Errorf("Error when creating the serializer sinks: %v", err)
+	}
+	if len(sinks) > 0 && !config.GetBool("serializer_sinks.forward_to_intake") {
+		logger.Warn("'serializer_sinks.forward_to_intake' is set to false: series, sketches, events and service checks are only written to the serializer sinks")
+	}
+	return &Sinks{sinks: sinks, logger: logger}
+}
+
+// Close closes the sinks, once the records they queued are written.
+// The serializers writing to the sinks should not be used after a call to `Close()`.
+func (s *Sinks) Close() {
+	for _, output := range s.sinks {
+		if err := output.Close(); err != nil {
+			s.logger.Warnf("Error when closing the %s sink of the serializer: %v", output.Name(), err)
+		}
+	}
+}
+
+// sinkRecord is the JSON document written to the sinks for each item
+type sinkRecord struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// sinkWriter batches the records of a payload type and writes them to the sinks.
+type sinkWriter struct {
+	sinks       []sink.Sink
+	payloadType string
+	records     [][]byte
+	logger      log.Component
+}
+
+func (s *Serializer) newSinkWriter(payloadType string) *sinkWriter {
+	return &sinkWriter{
+		sinks:       s.sinks,
+		payloadType: payloadType,
+		logger:      s.logger,
+	}
+}
+
+func (w *sinkWriter) add(payload interface{}) {
+	record, err := json.Marshal(sinkRecord{Type: w.payloadType, Payload: payload})
+	if err != nil {
+		w.logger.Debugf("Cannot serialize a %s record for the sinks: %v", w.payloadType, err)
+		tlmSinkErrors.Inc("all", w.payloadType)
+		return
+	}
+	w.records = append(w.records, record)
+	if len(w.records) >= sinkBatchSize {
+		w.flush()
+	}
+}
+
+func (w *sinkWriter) flush() {
+	if len(w.records) == 0 {
+		return
+	}
+	for _, s := range w.sinks {
+		if err := s.Write(w.payloadType, w.records); err != nil {
+			w.logger.Errorf("Cannot write %d %s records to the %s sink: %v", len(w.records), w.payloadType, s.Name(), err)
+			tlmSinkErrors.Inc(s.Name(), w.payloadType)
+			continue
+		}
+		tlmSinkRecords.Add(float64(len(w.records)), s.Name(), w.payloadType)
+	}
+	w.records = w.records[:0]
+}
+
+// writeToSinks writes a list of items to the sinks.
+func writeToSinks[T any](s *Serializer, payloadType string, items []T) {
+	if len(s.sinks) == 0 {
+		return
+	}
+	w := s.newSinkWriter(payloadType)
+	for _, item := range items {
+		w.add(item)
+	}
+	w.flush()
+}
+
+// sinkSerieSource writes the series to the sinks while they are iterated
+type sinkSerieSource struct {
+	metrics.SerieSource
+	writer *sinkWriter
+}
+
+// withSerieSinks wraps the series so that they are written to the sinks. The returned function
+// writes the series which are still buffered, in case the iteration was interrupted.
+func (s *Serializer) withSerieSinks(serieSource metrics.SerieSource) (metrics.SerieSource, func()) {
+	if len(s.sinks) == 0 {
+		return serieSource, func() {}
+	}
+	source := &sinkSerieSource{SerieSource: serieSource, writer: s.newSinkWriter(sink.Series)}
+	return source, source.writer.flush
+}
+
+func (s *sinkSerieSource) MoveNext() bool {
+	if !s.SerieSource.MoveNext() {
+		s.writer.flush()
+		return false
+	}
+	s.writer.add(s.Current())
+	return true
+}
+
+// sinkSketchesSource writes the sketches to the sinks while they are iterated
+type sinkSketchesSource struct {
+	metrics.SketchesSource
+	writer *sinkWriter
+}
+
+// withSketchesSinks wraps the sketches so that they are written to the sinks. The returned function
+// writes the sketches which are still buffered, in case the iteration was interrupted.
+func (s *Serializer) withSketchesSinks(sketches metrics.SketchesSource) (metrics.SketchesSource, func()) {
+	if len(s.sinks) == 0 {
+		return sketches, func() {}
+	}
+	source := &sinkSketchesSource{SketchesSource: sketches, writer: s.newSinkWriter(sink.Sketches)}
+	return source, source.writer.flush
+}
+
+func (s *sinkSketchesSource) MoveNext() bool {
+	if !s.SketchesSource.MoveNext() {
+		s.writer.flush()
+		return false
+	}
+	s.writer.add(s.Current())
+	return true
+}
+
+// drainSerieSource iterates over all the series, so that they are written to the sinks
+// when they are not sent to the forwarder.
+func drainSerieSource(serieSource metrics.SerieSource) {
+	for serieSource.MoveNext() {
+	}
+}
+
+// drainSketchesSource iterates over all the sketches, so that they are written to the sinks
+// when they are not sent to the forwarder.
+func drainSketchesSource(sketches metrics.SketchesSource) {
+	for sketches.MoveNext() {
+	}
+}
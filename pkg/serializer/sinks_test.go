@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test && zlib && zstd
+
+package serializer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	metricscompressionimpl "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/impl"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/metrics/event"
+	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+)
+
+func newSerializerWithFileSink(t *testing.T, f forwarder.Forwarder, forwardToIntake bool) (*Serializer, string) {
+	path := filepath.Join(t.TempDir(), "payloads.json")
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("serializer_sinks.file.enabled", true)
+	mockConfig.SetWithoutSource("serializer_sinks.file.path", path)
+	mockConfig.SetWithoutSource("serializer_sinks.forward_to_intake", forwardToIntake)
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	sinks := NewSinks(mockConfig, logmock.New(t))
+	s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", sinks)
+	require.Len(t, s.sinks, 1)
+	t.Cleanup(sinks.Close)
+	return s, path
+}
+
+// readSinkRecords returns the payload type and the name of the records written to the file
+func readSinkRecords(t *testing.T, path string) []string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var records []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record struct {
+			Type    string `json:"type"`
+			Payload struct {
+				Metric string `json:"metric"`
+				Title  string `json:"msg_title"`
+				Check  string `json:"check"`
+			} `json:"payload"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record.Type+":"+record.Payload.Metric+record.Payload.Title+record.Payload.Check)
+	}
+	return records
+}
+
+func TestSinksWithForwarder(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	s, path := newSerializerWithFileSink(t, f, true)
+
+	f.On("SubmitSeries", mock.Anything, mock.Anything).Return(nil).Times(1)
+	f.On("SubmitV1Intake", mock.Anything, mock.Anything).Return(nil).Times(1)
+
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{
+		&metrics.Serie{Name: "foo"},
+		&metrics.Serie{Name: "bar"},
+	})))
+	require.NoError(t, s.SendEvents(event.Events{&event.Event{Title: "deploy"}}))
+	f.AssertExpectations(t)
+
+	assert.Equal(t, []string{"series:foo", "series:bar", "events:deploy"}, readSinkRecords(t, path))
+}
+
+func TestSinksWithoutForwarder(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	s, path := newSerializerWithFileSink(t, f, false)
+
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{&metrics.Serie{Name: "foo"}})))
+	require.NoError(t, s.SendSketch(metrics.NewSketchesSourceTestWithSketch()))
+	require.NoError(t, s.SendServiceChecks(servicecheck.ServiceChecks{&servicecheck.ServiceCheck{CheckName: "can_connect"}}))
+
+	f.AssertNotCalled(t, "SubmitSeries")
+	f.AssertNotCalled(t, "SubmitSketchSeries")
+	f.AssertNotCalled(t, "SubmitV1CheckRuns")
+
+	assert.Equal(t, []string{"series:foo", "sketches:fakename", "service_checks:can_connect"}, readSinkRecords(t, path))
+}
+
+func TestSinksSharedBySerializers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payloads.json")
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("serializer_sinks.file.enabled", true)
+	mockConfig.SetWithoutSource("serializer_sinks.file.path", path)
+	mockConfig.SetWithoutSource("serializer_sinks.forward_to_intake", false)
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	sinks := NewSinks(mockConfig, logmock.New(t))
+	f := &forwarder.MockedForwarder{}
+	shared := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", sinks)
+	noAgg := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost", sinks)
+
+	// both serializers write to the same file sink
+	require.NoError(t, shared.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{&metrics.Serie{Name: "foo"}})))
+	require.NoError(t, noAgg.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{&metrics.Serie{Name: "bar"}})))
+	sinks.Close()
+
+	assert.Equal(t, []string{"series:foo", "series:bar"}, readSinkRecords(t, path))
+}
@@ -95,6 +95,7 @@ type Agent struct {
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
 	ProbabilisticSampler  *sampler.ProbabilisticSampler
+	TailSampler           *sampler.TailSampler
 	SamplerMetrics        *sampler.Metrics
 	EventProcessor        *event.Processor
 	TraceWriter           TraceWriter
@@ -121,6 +122,18 @@ type Agent struct {
 	// In takes incoming payloads to be processed by the agent.
 	In chan *api.Payload
 
+	// spanProcessor modifies the tags of the spans according to the span processor rules.
+	// It is nil unless some rules are configured.
+	spanProcessor *spanProcessor
+
+	// traceAssembler buffers the chunks by trace ID for the tail-based sampling. It is nil
+	// unless the tail-based sampling is enabled.
+	traceAssembler *traceAssembler
+
+	// traceStore keeps the last sampled traces for local inspection. It is nil unless the
+	// trace store is enabled.
+	traceStore *traceStore
+
 	// config
 	conf *config.AgentConfig
 
@@ -168,8 +181,20 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		DebugServer:           api.NewDebugServer(conf),
 		Statsd:                statsd,
 		Timing:                timing,
+		spanProcessor:         newSpanProcessor(conf.SpanProcessors),
+		traceStore:            newTraceStore(conf),
 	}
 	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler)
+	if conf.TailSamplingEnabled {
+		log.Infof("Tail-based sampling enabled with a decision wait of %s", conf.TailSamplingDecisionWait)
+		agnt.TailSampler = sampler.NewTailSampler(conf)
+		agnt.SamplerMetrics.Add(agnt.TailSampler)
+		agnt.traceAssembler = newTraceAssembler(conf, statsd, agnt.sampleAssembledTrace)
+	}
+	if agnt.traceStore != nil {
+		agnt.DebugServer.AddRoute(TraceStoreRoute, agnt.traceStore.handler())
+		agnt.DebugServer.AddRoute(TraceStoreRoute+"/", agnt.traceStore.handler())
+	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector, statsd, timing)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
@@ -193,6 +218,9 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.traceAssembler != nil {
+		a.traceAssembler.Start()
+	}
 
 	go a.StatsWriter.Run()
 
@@ -260,6 +288,7 @@ func (a *Agent) loop() {
 		log.Error(err)
 	}
 	for _, stopper := range []interface{ Stop() }{
+		a.traceAssembler, // releases the buffered traces to the TraceWriter
 		a.Concentrator,
 		a.ClientStatsAggregator,
 		a.TraceWriter,
@@ -320,6 +349,9 @@ func (a *Agent) Process(p *api.Payload) {
 
 	a.discardSpans(p)
 
+	// header is the tracer payload of the chunks buffered for the tail-based sampling
+	var header *pb.TracerPayload
+
 	for i := 0; i < len(p.Chunks()); {
 		chunk := p.Chunk(i)
 		if len(chunk.Spans) == 0 {
@@ -376,6 +408,9 @@ func (a *Agent) Process(p *api.Payload) {
 			}
 		}
 		a.Replacer.Replace(chunk.Spans)
+		if a.spanProcessor != nil {
+			a.spanProcessor.process(chunk.Spans)
+		}
 
 		a.setRootSpanTags(root)
 		if !p.ClientComputedTopLevel {
@@ -391,6 +426,16 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		if a.traceAssembler != nil {
+			// The chunk is sampled once the chunks of its trace are assembled
+			if header == nil {
+				header = tracerPayloadHeader(p.TracerPayload)
+			}
+			a.traceAssembler.add(now, &assembledChunk{header: header, ts: ts, pt: pt, size: pt.TraceChunk.Msgsize()})
+			p.RemoveChunk(i)
+			continue
+		}
+
 		keep, numEvents := a.sample(now, ts, pt)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
@@ -416,20 +461,29 @@ func (a *Agent) Process(p *api.Payload) {
 			sampledChunks.TracerPayload = p.TracerPayload.Cut(i)
 			i = 0
 			sampledChunks.TracerPayload.Chunks = newChunksArray(sampledChunks.TracerPayload.Chunks)
-			a.TraceWriter.WriteChunks(sampledChunks)
+			a.writeChunks(now, sampledChunks)
 			sampledChunks = new(writer.SampledChunks)
 		}
 	}
 	sampledChunks.TracerPayload = p.TracerPayload
 	sampledChunks.TracerPayload.Chunks = newChunksArray(p.TracerPayload.Chunks)
 	if sampledChunks.Size > 0 {
-		a.TraceWriter.WriteChunks(sampledChunks)
+		a.writeChunks(now, sampledChunks)
 	}
 	if len(statsInput.Traces) > 0 {
 		a.Concentrator.Add(statsInput)
 	}
 }
 
+// writeChunks sends the sampled chunks to the TraceWriter, and keeps them in the trace store
+// when it is enabled.
+func (a *Agent) writeChunks(now time.Time, sampledChunks *writer.SampledChunks) {
+	if a.traceStore != nil {
+		a.traceStore.add(now, sampledChunks)
+	}
+	a.TraceWriter.WriteChunks(sampledChunks)
+}
+
 func (a *Agent) setPayloadAttributes(p *api.Payload, root *pb.Span, chunk *pb.TraceChunk) {
 	if p.TracerPayload.Hostname == "" {
 		// Older tracers set tracer hostname in the root span.
@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// spanProcessor modifies the tags of the spans according to the configured rules. The rules
+// are applied in order, each rule seeing the modifications of the previous ones.
+type spanProcessor struct {
+	rules []*config.SpanProcessorRule
+}
+
+// newSpanProcessor returns a spanProcessor applying the given rules, or nil if there are none.
+// The rules must have been compiled.
+func newSpanProcessor(rules []*config.SpanProcessorRule) *spanProcessor {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &spanProcessor{rules: rules}
+}
+
+// process applies the rules to the spans they select.
+func (sp *spanProcessor) process(spans []*pb.Span) {
+	for _, rule := range sp.rules {
+		for _, s := range spans {
+			if !ruleSelectsSpan(rule, s) {
+				continue
+			}
+			applySpanProcessorRule(rule, s)
+		}
+	}
+}
+
+// ruleSelectsSpan returns true if the span matches the service, operation and resource patterns of the rule.
+func ruleSelectsSpan(rule *config.SpanProcessorRule, s *pb.Span) bool {
+	if rule.ServiceRe != nil && !rule.ServiceRe.MatchString(s.Service) {
+		return false
+	}
+	if rule.OperationRe != nil && !rule.OperationRe.MatchString(s.Name) {
+		return false
+	}
+	if rule.ResourceRe != nil && !rule.ResourceRe.MatchString(s.Resource) {
+		return false
+	}
+	return true
+}
+
+// ruleSelectsKey returns true if the rule addresses the tag k. The hidden tags are only
+// addressed by their exact key, so that patterns can't alter the tags used by the agent.
+func ruleSelectsKey(rule *config.SpanProcessorRule, k string) bool {
+	if rule.KeyRe == nil {
+		return k == rule.Key
+	}
+	return !strings.HasPrefix(k, "_") && rule.KeyRe.MatchString(k)
+}
+
+func applySpanProcessorRule(rule *config.SpanProcessorRule, s *pb.Span) {
+	switch rule.Action {
+	case config.SpanProcessorAdd:
+		traceutil.SetMeta(s, rule.Key, rule.Value)
+	case config.SpanProcessorDelete:
+		for k := range s.Meta {
+			if ruleSelectsKey(rule, k) {
+				delete(s.Meta, k)
+			}
+		}
+		for k := range s.Metrics {
+			if ruleSelectsKey(rule, k) {
+				delete(s.Metrics, k)
+			}
+		}
+	case config.SpanProcessorRename:
+		if v, ok := s.Meta[rule.Key]; ok {
+			delete(s.Meta, rule.Key)
+			s.Meta[rule.NewKey] = v
+		}
+		if v, ok := s.Metrics[rule.Key]; ok {
+			delete(s.Metrics, rule.Key)
+			s.Metrics[rule.NewKey] = v
+		}
+	case config.SpanProcessorHash:
+		for k, v := range s.Meta {
+			if ruleSelectsKey(rule, k) {
+				s.Meta[k] = hashTagValue(v)
+			}
+		}
+	case config.SpanProcessorToMetrics:
+		for k, v := range s.Meta {
+			if !ruleSelectsKey(rule, k) {
+				continue
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				// only the numeric values can be moved to the metrics
+				continue
+			}
+			delete(s.Meta, k)
+			traceutil.SetMetric(s, k, f)
+		}
+	case config.SpanProcessorToMeta:
+		for k, v := range s.Metrics {
+			if ruleSelectsKey(rule, k) {
+				delete(s.Metrics, k)
+				traceutil.SetMeta(s, k, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+	}
+}
+
+// hashTagValue returns the hex-encoded SHA-256 hash of a tag value.
+func hashTagValue(v string) string {
+	h := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(h[:])
+}
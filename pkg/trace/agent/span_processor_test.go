@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestSpanProcessor(t *testing.T) {
+	newSpan := func() *pb.Span {
+		return &pb.Span{
+			Service:  "web",
+			Name:     "http.request",
+			Resource: "GET /users",
+			Meta: map[string]string{
+				"user.email":   "john@example.com",
+				"user.id":      "42",
+				"customer":     "acme",
+				"_dd.p.dm":     "-1",
+				"http.retries": "3",
+			},
+			Metrics: map[string]float64{
+				"_sampling_priority_v1": 1,
+				"user.score":            0.5,
+				"db.row_count":          10,
+			},
+		}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		rule        *config.SpanProcessorRule
+		wantMeta    map[string]string
+		wantMetrics map[string]float64
+	}{
+		{
+			name: "add",
+			rule: &config.SpanProcessorRule{Action: config.SpanProcessorAdd, Key: "team", Value: "frontend"},
+			wantMeta: map[string]string{
+				"user.email": "john@example.com", "user.id": "42", "customer": "acme", "_dd.p.dm": "-1", "http.retries": "3",
+				"team": "frontend",
+			},
+			wantMetrics: map[string]float64{"_sampling_priority_v1": 1, "user.score": 0.5, "db.row_count": 10},
+		},
+		{
+			name:        "delete",
+			rule:        &config.SpanProcessorRule{Action: config.SpanProcessorDelete, KeyRe: regexp.MustCompile(`^(user\.|_)`)},
+			wantMeta:    map[string]string{"customer": "acme", "_dd.p.dm": "-1", "http.retries": "3"},
+			wantMetrics: map[string]float64{"_sampling_priority_v1": 1, "db.row_count": 10},
+		},
+		{
+			name:        "rename",
+			rule:        &config.SpanProcessorRule{Action: config.SpanProcessorRename, Key: "customer", NewKey: "tenant"},
+			wantMeta:    map[string]string{"user.email": "john@example.com", "user.id": "42", "tenant": "acme", "_dd.p.dm": "-1", "http.retries": "3"},
+			wantMetrics: map[string]float64{"_sampling_priority_v1": 1, "user.score": 0.5, "db.row_count": 10},
+		},
+		{
+			name: "hash",
+			rule: &config.SpanProcessorRule{Action: config.SpanProcessorHash, Key: "user.email"},
+			wantMeta: map[string]string{
+				"user.email": "855f96e983f1f8e8be944692b6f719fd54329826cb62e98015efee8e2e071dd4",
+				"user.id":    "42", "customer": "acme", "_dd.p.dm": "-1", "http.retries": "3",
+			},
+			wantMetrics: map[string]float64{"_sampling_priority_v1": 1, "user.score": 0.5, "db.row_count": 10},
+		},
+		{
+			name:        "to_metrics",
+			rule:        &config.SpanProcessorRule{Action: config.SpanProcessorToMetrics, KeyRe: regexp.MustCompile(`.`)},
+			wantMeta:    map[string]string{"user.email": "john@example.com", "customer": "acme", "_dd.p.dm": "-1"},
+			wantMetrics: map[string]float64{"_sampling_priority_v1": 1, "user.score": 0.5, "db.row_count": 10, "user.id": 42, "http.retries": 3},
+		},
+		{
+			name: "to_meta",
+			rule: &config.SpanProcessorRule{Action: config.SpanProcessorToMeta, KeyRe: regexp.MustCompile(`^user\.`)},
+			wantMeta: map[string]string{
+				"user.email": "john@example.com", "user.id": "42", "customer": "acme", "_dd.p.dm": "-1", "http.retries": "3",
+				"user.score": "0.5",
+			},
+			wantMetrics: map[string]float64{"_sampling_priority_v1": 1, "db.row_count": 10},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSpan()
+			newSpanProcessor([]*config.SpanProcessorRule{tt.rule}).process([]*pb.Span{s})
+			assert.Equal(t, tt.wantMeta, s.Meta)
+			assert.Equal(t, tt.wantMetrics, s.Metrics)
+		})
+	}
+
+	t.Run("predicates", func(t *testing.T) {
+		rules := []*config.SpanProcessorRule{
+			{ServiceRe: regexp.MustCompile(`^web$`), OperationRe: regexp.MustCompile(`^http\.`), Action: config.SpanProcessorAdd, Key: "matched", Value: "1"},
+			{ResourceRe: regexp.MustCompile(`POST`), Action: config.SpanProcessorAdd, Key: "post", Value: "1"},
+			// the rules see the modifications of the previous ones
+			{Action: config.SpanProcessorRename, Key: "matched", NewKey: "selected"},
+		}
+		s1, s2 := newSpan(), newSpan()
+		s2.Service = "db"
+		newSpanProcessor(rules).process([]*pb.Span{s1, s2})
+		assert.Equal(t, "1", s1.Meta["selected"])
+		assert.NotContains(t, s1.Meta, "matched")
+		assert.NotContains(t, s1.Meta, "post")
+		assert.NotContains(t, s2.Meta, "selected")
+	})
+
+	t.Run("no-rules", func(t *testing.T) {
+		assert.Nil(t, newSpanProcessor(nil))
+	})
+}
@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const (
+	// traceAssemblerFlushPeriod is the frequency at which the traces at the end of their decision wait are released.
+	traceAssemblerFlushPeriod = time.Second
+
+	// evictionReasonMemory is the reason of the traces released before the end of their decision
+	// wait to keep the buffer under its memory budget.
+	evictionReasonMemory = "memory"
+	// evictionReasonShutdown is the reason of the traces released when the agent stops.
+	evictionReasonShutdown = "shutdown"
+)
+
+// assembledChunk is a chunk waiting for the sampling decision of its trace.
+type assembledChunk struct {
+	// header holds the fields of the tracer payload of the chunk, without its chunks
+	header *pb.TracerPayload
+	ts     *info.TagStats
+	pt     *traceutil.ProcessedTrace
+	size   int
+}
+
+// assembledTrace holds the chunks of a trace received during its decision wait.
+type assembledTrace struct {
+	traceID  uint64
+	deadline time.Time
+	chunks   []*assembledChunk
+	size     int
+}
+
+// traceAssembler buffers the chunks by trace ID during the decision wait, within a memory
+// budget, and releases the assembled traces to the tail-based sampling. The chunks received
+// after the release of their trace are assembled as a new trace.
+type traceAssembler struct {
+	decisionWait time.Duration
+	maxSize      int
+	release      func(now time.Time, chunks []*assembledChunk)
+	statsd       statsd.ClientInterface
+
+	mu     sync.Mutex
+	traces map[uint64]*list.Element
+	// order holds the traces from the oldest to the most recent one
+	order *list.List
+	size  int
+
+	exit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newTraceAssembler(conf *config.AgentConfig, statsd statsd.ClientInterface, release func(now time.Time, chunks []*assembledChunk)) *traceAssembler {
+	return &traceAssembler{
+		decisionWait: conf.TailSamplingDecisionWait,
+		maxSize:      conf.TailSamplingMaxBufferBytes,
+		release:      release,
+		statsd:       statsd,
+		traces:       make(map[uint64]*list.Element),
+		order:        list.New(),
+		exit:         make(chan struct{}),
+	}
+}
+
+// Start starts releasing the traces at the end of their decision wait.
+func (ta *traceAssembler) Start() {
+	ta.wg.Add(1)
+	go func() {
+		defer ta.wg.Done()
+		ticker := time.NewTicker(traceAssemblerFlushPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				ta.releaseExpired(now)
+				ta.report()
+			case <-ta.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the assembler and releases all the buffered traces.
+func (ta *traceAssembler) Stop() {
+	close(ta.exit)
+	ta.wg.Wait()
+
+	ta.mu.Lock()
+	released := ta.popLocked(ta.order.Len())
+	ta.mu.Unlock()
+	ta.releaseAll(time.Now(), released, evictionReasonShutdown)
+}
+
+// add buffers a chunk with the other chunks of its trace. The oldest traces are released
+// when the buffer exceeds its memory budget.
+func (ta *traceAssembler) add(now time.Time, chunk *assembledChunk) {
+	traceID := chunk.pt.TraceChunk.Spans[0].TraceID
+
+	ta.mu.Lock()
+	e, ok := ta.traces[traceID]
+	if !ok {
+		e = ta.order.PushBack(&assembledTrace{traceID: traceID, deadline: now.Add(ta.decisionWait)})
+		ta.traces[traceID] = e
+	}
+	t := e.Value.(*assembledTrace)
+	t.chunks = append(t.chunks, chunk)
+	t.size += chunk.size
+	ta.size += chunk.size
+
+	var evicted []*assembledTrace
+	for ta.size > ta.maxSize && ta.order.Len() > 0 {
+		evicted = append(evicted, ta.popLocked(1)...)
+	}
+	ta.mu.Unlock()
+
+	ta.releaseAll(now, evicted, evictionReasonMemory)
+}
+
+// releaseExpired releases the traces at the end of their decision wait.
+func (ta *traceAssembler) releaseExpired(now time.Time) {
+	ta.mu.Lock()
+	n := 0
+	for e := ta.order.Front(); e != nil && !now.Before(e.Value.(*assembledTrace).deadline); e = e.Next() {
+		n++
+	}
+	released := ta.popLocked(n)
+	ta.mu.Unlock()
+
+	ta.releaseAll(now, released, "")
+}
+
+// popLocked removes the n oldest traces from the buffer. The lock must be held.
+func (ta *traceAssembler) popLocked(n int) []*assembledTrace {
+	traces := make([]*assembledTrace, 0, n)
+	for ; n > 0; n-- {
+		t := ta.order.Remove(ta.order.Front()).(*assembledTrace)
+		delete(ta.traces, t.traceID)
+		ta.size -= t.size
+		traces = append(traces, t)
+	}
+	return traces
+}
+
+// releaseAll releases the traces. A non-empty reason reports them as evicted before the end of
+// their decision wait.
+func (ta *traceAssembler) releaseAll(now time.Time, traces []*assembledTrace, evictionReason string) {
+	if len(traces) == 0 {
+		return
+	}
+	if evictionReason != "" {
+		log.Debugf("Tail-based sampling: releasing %d traces before the end of their decision wait (%s)", len(traces), evictionReason)
+		_ = ta.statsd.Count("datadog.trace_agent.tail_sampling.evicted", int64(len(traces)), []string{"reason:" + evictionReason}, 1)
+	}
+	for _, t := range traces {
+		ta.release(now, t.chunks)
+	}
+}
+
+func (ta *traceAssembler) report() {
+	ta.mu.Lock()
+	traces, size := ta.order.Len(), ta.size
+	ta.mu.Unlock()
+
+	_ = ta.statsd.Gauge("datadog.trace_agent.tail_sampling.buffered_traces", float64(traces), nil, 1)
+	_ = ta.statsd.Gauge("datadog.trace_agent.tail_sampling.buffered_bytes", float64(size), nil, 1)
+}
+
+// tracerPayloadHeader returns a copy of the fields of a tracer payload, without its chunks.
+func tracerPayloadHeader(tp *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     tp.ContainerID,
+		LanguageName:    tp.LanguageName,
+		LanguageVersion: tp.LanguageVersion,
+		TracerVersion:   tp.TracerVersion,
+		RuntimeID:       tp.RuntimeID,
+		Tags:            tp.Tags,
+		Env:             tp.Env,
+		Hostname:        tp.Hostname,
+		AppVersion:      tp.AppVersion,
+	}
+}
+
+// sampleAssembledTrace applies the tail-based sampling policies to the chunks of an assembled
+// trace. When no policy keeps the trace, each chunk is sampled as if it wasn't assembled.
+func (a *Agent) sampleAssembledTrace(now time.Time, chunks []*assembledChunk) {
+	var spans []*pb.Span
+	for _, c := range chunks {
+		spans = append(spans, c.pt.TraceChunk.Spans...)
+	}
+	policy, tailKeep := a.TailSampler.Sample(now, spans)
+
+	// the chunks are written with the payloads they were received in
+	payloads := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	var order []*pb.TracerPayload
+	for _, c := range chunks {
+		pt := c.pt
+		var keep bool
+		var numEvents int
+		if tailKeep {
+			keep = true
+			pt.TraceChunk.DroppedTrace = false
+			if pt.TraceChunk.Tags == nil {
+				pt.TraceChunk.Tags = make(map[string]string)
+			}
+			pt.TraceChunk.Tags[sampler.TailSamplingPolicyKey] = policy
+			a.SamplerMetrics.RecordMetricsKey(true, sampler.NewMetricsKey(pt.Root.Service, pt.TracerEnv, sampler.NameTail, sampler.PriorityNone))
+			numEvents = len(a.getAnalyzedEvents(pt, c.ts))
+		} else {
+			keep, numEvents = a.sample(now, c.ts, pt)
+		}
+		if !keep && len(pt.TraceChunk.Spans) == 0 {
+			// The entire trace was dropped and no spans were kept.
+			continue
+		}
+
+		sampledChunks, ok := payloads[c.header]
+		if !ok {
+			sampledChunks = &writer.SampledChunks{TracerPayload: tracerPayloadHeader(c.header)}
+			payloads[c.header] = sampledChunks
+			order = append(order, c.header)
+		}
+		if !pt.TraceChunk.DroppedTrace {
+			a.setFirstTraceTags(pt.Root)
+			sampledChunks.SpanCount += int64(len(pt.TraceChunk.Spans))
+		}
+		sampledChunks.EventCount += int64(numEvents)
+		sampledChunks.Size += pt.TraceChunk.Msgsize()
+		sampledChunks.TracerPayload.Chunks = append(sampledChunks.TracerPayload.Chunks, pt.TraceChunk)
+
+		if sampledChunks.Size > writer.MaxPayloadSize {
+			// payload size is getting big; flush what we have so far
+			a.writeChunks(now, sampledChunks)
+			payloads[c.header] = &writer.SampledChunks{TracerPayload: tracerPayloadHeader(c.header)}
+		}
+	}
+	for _, header := range order {
+		if sampledChunks := payloads[header]; sampledChunks.Size > 0 {
+			a.writeChunks(now, sampledChunks)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// releasedTraces records the traces released by a traceAssembler
+type releasedTraces struct {
+	mu     sync.Mutex
+	traces [][]*assembledChunk
+}
+
+func (r *releasedTraces) release(_ time.Time, chunks []*assembledChunk) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.traces = append(r.traces, chunks)
+}
+
+func (r *releasedTraces) traceIDs() []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uint64
+	for _, chunks := range r.traces {
+		ids = append(ids, chunks[0].pt.TraceChunk.Spans[0].TraceID)
+	}
+	return ids
+}
+
+func testAssembledChunk(traceID uint64, size int) *assembledChunk {
+	chunk := testutil.TraceChunkWithSpan(&pb.Span{TraceID: traceID, SpanID: 1})
+	return &assembledChunk{pt: &traceutil.ProcessedTrace{TraceChunk: chunk}, size: size}
+}
+
+func TestTraceAssembler(t *testing.T) {
+	conf := config.New()
+	conf.TailSamplingDecisionWait = 10 * time.Second
+	conf.TailSamplingMaxBufferBytes = 100
+	now := time.Now()
+
+	t.Run("decision-wait", func(t *testing.T) {
+		released := &releasedTraces{}
+		ta := newTraceAssembler(conf, &statsd.NoOpClient{}, released.release)
+
+		ta.add(now, testAssembledChunk(1, 10))
+		ta.add(now.Add(time.Second), testAssembledChunk(2, 10))
+		ta.add(now.Add(2*time.Second), testAssembledChunk(1, 10))
+
+		ta.releaseExpired(now.Add(5 * time.Second))
+		assert.Empty(t, released.traceIDs())
+
+		ta.releaseExpired(now.Add(10 * time.Second))
+		assert.Equal(t, []uint64{1}, released.traceIDs())
+		assert.Len(t, released.traces[0], 2)
+		assert.Equal(t, 10, ta.size)
+
+		// chunks received after the release are assembled as a new trace
+		ta.add(now.Add(10*time.Second), testAssembledChunk(1, 10))
+		ta.releaseExpired(now.Add(15 * time.Second))
+		assert.Equal(t, []uint64{1, 2}, released.traceIDs())
+
+		ta.releaseExpired(now.Add(20 * time.Second))
+		assert.Equal(t, []uint64{1, 2, 1}, released.traceIDs())
+		assert.Equal(t, 0, ta.size)
+		assert.Empty(t, ta.traces)
+	})
+
+	t.Run("memory-budget", func(t *testing.T) {
+		released := &releasedTraces{}
+		ta := newTraceAssembler(conf, &statsd.NoOpClient{}, released.release)
+
+		ta.add(now, testAssembledChunk(1, 40))
+		ta.add(now, testAssembledChunk(2, 40))
+		ta.add(now, testAssembledChunk(1, 10))
+		assert.Empty(t, released.traceIDs())
+
+		// the oldest trace is released early to make room
+		ta.add(now, testAssembledChunk(3, 20))
+		assert.Equal(t, []uint64{1}, released.traceIDs())
+		assert.Equal(t, 60, ta.size)
+
+		// a chunk larger than the budget is released at once
+		ta.add(now, testAssembledChunk(4, 200))
+		assert.Equal(t, []uint64{1, 2, 3, 4}, released.traceIDs())
+		assert.Equal(t, 0, ta.size)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		released := &releasedTraces{}
+		ta := newTraceAssembler(conf, &statsd.NoOpClient{}, released.release)
+		ta.Start()
+
+		ta.add(now, testAssembledChunk(1, 10))
+		ta.add(now, testAssembledChunk(2, 10))
+		ta.Stop()
+		assert.Equal(t, []uint64{1, 2}, released.traceIDs())
+	})
+}
+
+func TestTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSamplingEnabled = true
+	cfg.TailSamplingDecisionWait = 10 * time.Second
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	require.NotNil(t, agnt.traceAssembler)
+
+	now := time.Now()
+	newPayload := func(spans ...*pb.Span) *api.Payload {
+		chunk := testutil.TraceChunkWithSpans(spans)
+		chunk.Priority = int32(sampler.PriorityAutoDrop)
+		return &api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		}
+	}
+	newSpan := func(traceID, spanID, parentID uint64, isError int32) *pb.Span {
+		return &pb.Span{
+			TraceID:  traceID,
+			SpanID:   spanID,
+			ParentID: parentID,
+			Service:  "svc",
+			Name:     "op",
+			Resource: "res",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Error:    isError,
+		}
+	}
+
+	// the error of the trace 1 is in its second chunk
+	agnt.Process(newPayload(newSpan(1, 1, 0, 0)))
+	agnt.Process(newPayload(newSpan(2, 1, 0, 0)))
+	agnt.Process(newPayload(newSpan(1, 2, 1, 1)))
+	assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+
+	agnt.traceAssembler.releaseExpired(time.Now().Add(cfg.TailSamplingDecisionWait))
+
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 2)
+	for _, p := range payloads {
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		chunk := p.TracerPayload.Chunks[0]
+		assert.False(t, chunk.DroppedTrace)
+		assert.Equal(t, sampler.TailPolicyError, chunk.Tags[sampler.TailSamplingPolicyKey])
+		assert.EqualValues(t, 1, chunk.Spans[0].TraceID)
+	}
+
+	// the trace 2 was released too, and dropped by the priority sampler
+	assert.Empty(t, agnt.traceAssembler.traces)
+}
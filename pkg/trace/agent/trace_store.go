@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+const (
+	// TraceStoreRoute is the route of the debug server listing the traces of the local trace store.
+	// The traces are fetched by ID on the TraceStoreRoute + "/<trace ID>" routes.
+	TraceStoreRoute = "/debug/traces"
+
+	// defaultTraceStoreLimit is the default number of traces listed by the TraceStoreRoute.
+	defaultTraceStoreLimit = 100
+)
+
+// TraceSummary describes a trace of the local trace store. The service, name and resource
+// are the ones of its root span.
+type TraceSummary struct {
+	TraceID  uint64 `json:"trace_id"`
+	Service  string `json:"service"`
+	Name     string `json:"name"`
+	Resource string `json:"resource"`
+	Env      string `json:"env"`
+	Start    int64  `json:"start"`
+	Duration int64  `json:"duration"`
+	Error    bool   `json:"error"`
+	Spans    int    `json:"spans"`
+}
+
+// StoredTrace is a trace of the local trace store. Each chunk of the trace is held in the
+// payload it was written in.
+type StoredTrace struct {
+	TraceSummary
+	Payloads []*pb.TracerPayload `json:"payloads"`
+}
+
+// TraceStoreFilter selects the traces listed from the local trace store: the traces with a span
+// of the service and the resource. The empty fields select all the traces.
+type TraceStoreFilter struct {
+	Service  string
+	Resource string
+	// Error selects the traces with an error when true, and without error when false.
+	Error *bool
+	Limit int
+}
+
+// storedTrace holds the chunks of a trace written since it was first seen.
+type storedTrace struct {
+	seen     time.Time
+	payloads []*pb.TracerPayload
+}
+
+// traceStore keeps the last sampled traces in memory, so that they can be inspected locally
+// without a backend. The traces are kept during the retention and the oldest ones are evicted
+// once the store holds maxTraces.
+type traceStore struct {
+	retention time.Duration
+	maxTraces int
+
+	mu     sync.RWMutex
+	traces map[uint64]*storedTrace
+	order  []uint64 // trace IDs by first seen time
+}
+
+// newTraceStore returns the trace store of the agent. It returns nil if the store is disabled.
+func newTraceStore(conf *config.AgentConfig) *traceStore {
+	if !conf.TraceStoreEnabled {
+		return nil
+	}
+	log.Infof("Trace store enabled, keeping up to %d traces during %s", conf.TraceStoreMaxTraces, conf.TraceStoreRetention)
+	return &traceStore{
+		retention: conf.TraceStoreRetention,
+		maxTraces: conf.TraceStoreMaxTraces,
+		traces:    make(map[uint64]*storedTrace),
+	}
+}
+
+// add stores the sampled chunks of a payload sent to the TraceWriter. The chunks of the dropped
+// traces, which only hold the spans kept by single span sampling, are ignored.
+func (s *traceStore) add(now time.Time, pkg *writer.SampledChunks) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chunk := range pkg.TracerPayload.Chunks {
+		if chunk.DroppedTrace || len(chunk.Spans) == 0 {
+			continue
+		}
+		tp := tracerPayloadHeader(pkg.TracerPayload)
+		tp.Chunks = []*pb.TraceChunk{chunk}
+		id := chunk.Spans[0].TraceID
+		if t, ok := s.traces[id]; ok {
+			t.payloads = append(t.payloads, tp)
+			continue
+		}
+		s.traces[id] = &storedTrace{seen: now, payloads: []*pb.TracerPayload{tp}}
+		s.order = append(s.order, id)
+	}
+	s.evictLocked(now)
+}
+
+// evictLocked removes the traces older than the retention, and the oldest traces above maxTraces.
+func (s *traceStore) evictLocked(now time.Time) {
+	var n int
+	for _, id := range s.order {
+		if len(s.order)-n <= s.maxTraces && now.Sub(s.traces[id].seen) < s.retention {
+			break
+		}
+		delete(s.traces, id)
+		n++
+	}
+	if n > 0 {
+		s.order = append(s.order[:0], s.order[n:]...)
+	}
+}
+
+// list returns the summaries of the traces selected by the filter, the most recent first.
+func (s *traceStore) list(now time.Time, f TraceStoreFilter) []TraceSummary {
+	if f.Limit <= 0 {
+		f.Limit = defaultTraceStoreLimit
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summaries := []TraceSummary{}
+	for i := len(s.order) - 1; i >= 0 && len(summaries) < f.Limit; i-- {
+		t := s.traces[s.order[i]]
+		if now.Sub(t.seen) >= s.retention {
+			break
+		}
+		if !t.matches(f) {
+			continue
+		}
+		summaries = append(summaries, t.summary(s.order[i]))
+	}
+	return summaries
+}
+
+// get returns the trace with the given ID.
+func (s *traceStore) get(now time.Time, id uint64) (*StoredTrace, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.traces[id]
+	if !ok || now.Sub(t.seen) >= s.retention {
+		return nil, false
+	}
+	return &StoredTrace{
+		TraceSummary: t.summary(id),
+		Payloads:     append([]*pb.TracerPayload(nil), t.payloads...),
+	}, true
+}
+
+func (t *storedTrace) rangeSpans(f func(span *pb.Span)) {
+	for _, tp := range t.payloads {
+		for _, span := range tp.Chunks[0].Spans {
+			f(span)
+		}
+	}
+}
+
+// matches reports whether the trace has a span of the service and the resource of the filter,
+// and whether it has an error when the filter selects on errors.
+func (t *storedTrace) matches(f TraceStoreFilter) bool {
+	var match, hasError bool
+	t.rangeSpans(func(span *pb.Span) {
+		if (f.Service == "" || span.Service == f.Service) && (f.Resource == "" || span.Resource == f.Resource) {
+			match = true
+		}
+		hasError = hasError || span.Error != 0
+	})
+	return match && (f.Error == nil || *f.Error == hasError)
+}
+
+func (t *storedTrace) summary(id uint64) TraceSummary {
+	var spans []*pb.Span
+	summary := TraceSummary{TraceID: id}
+	t.rangeSpans(func(span *pb.Span) {
+		spans = append(spans, span)
+		summary.Error = summary.Error || span.Error != 0
+	})
+	summary.Spans = len(spans)
+	if root := traceutil.GetRoot(spans); root != nil {
+		summary.Service = root.Service
+		summary.Name = root.Name
+		summary.Resource = root.Resource
+		summary.Start = root.Start
+		summary.Duration = root.Duration
+	}
+	summary.Env = t.payloads[0].Env
+	return summary
+}
+
+// handler serves the traces of the store: the summaries of the traces on the TraceStoreRoute,
+// filtered with the service, resource, error and limit query parameters, and the traces on
+// the TraceStoreRoute + "/<trace ID>" routes.
+func (s *traceStore) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var v interface{}
+		if id := strings.Trim(strings.TrimPrefix(req.URL.Path, TraceStoreRoute), "/"); id != "" {
+			traceID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid trace ID", http.StatusBadRequest)
+				return
+			}
+			t, ok := s.get(time.Now(), traceID)
+			if !ok {
+				http.Error(w, "trace not found", http.StatusNotFound)
+				return
+			}
+			v = t
+		} else {
+			f, err := parseTraceStoreFilter(req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			v = s.list(time.Now(), f)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			log.Errorf("Error encoding the traces of the trace store: %v", err)
+		}
+	})
+}
+
+func parseTraceStoreFilter(req *http.Request) (TraceStoreFilter, error) {
+	q := req.URL.Query()
+	f := TraceStoreFilter{
+		Service:  q.Get("service"),
+		Resource: q.Get("resource"),
+	}
+	if v := q.Get("error"); v != "" {
+		isError, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, errors.New("invalid error parameter")
+		}
+		f.Error = &isError
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return f, errors.New("invalid limit parameter")
+		}
+		f.Limit = limit
+	}
+	return f, nil
+}
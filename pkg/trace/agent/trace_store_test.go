@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+func testStoreChunks(env string, chunks ...*pb.TraceChunk) *writer.SampledChunks {
+	tp := testutil.TracerPayloadWithChunks(chunks)
+	tp.Env = env
+	return &writer.SampledChunks{TracerPayload: tp}
+}
+
+func testStoreSpan(traceID, spanID, parentID uint64, service, resource string, isError int32) *pb.Span {
+	return &pb.Span{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Service:  service,
+		Name:     "op",
+		Resource: resource,
+		Start:    int64(spanID),
+		Duration: 10,
+		Error:    isError,
+	}
+}
+
+func newTestTraceStore(retention time.Duration, maxTraces int) *traceStore {
+	cfg := config.New()
+	cfg.TraceStoreEnabled = true
+	cfg.TraceStoreRetention = retention
+	cfg.TraceStoreMaxTraces = maxTraces
+	return newTraceStore(cfg)
+}
+
+func TestTraceStore(t *testing.T) {
+	assert.Nil(t, newTraceStore(config.New()))
+
+	now := time.Now()
+	s := newTestTraceStore(time.Minute, 10)
+	s.add(now, testStoreChunks("prod",
+		testutil.TraceChunkWithSpans([]*pb.Span{testStoreSpan(1, 1, 0, "web", "GET /", 0)}),
+		testutil.TraceChunkWithSpans([]*pb.Span{testStoreSpan(2, 1, 0, "web", "POST /", 0)}),
+	))
+	// the second chunk of the trace 1, with an error
+	s.add(now, testStoreChunks("prod",
+		testutil.TraceChunkWithSpans([]*pb.Span{testStoreSpan(1, 2, 1, "db", "SELECT", 1)}),
+	))
+	// the chunks of dropped traces are not stored
+	dropped := testutil.TraceChunkWithSpans([]*pb.Span{testStoreSpan(3, 1, 0, "web", "GET /", 0)})
+	dropped.DroppedTrace = true
+	s.add(now, testStoreChunks("prod", dropped))
+
+	summaries := s.list(now, TraceStoreFilter{})
+	require.Len(t, summaries, 2)
+	assert.EqualValues(t, 2, summaries[0].TraceID)
+	assert.Equal(t, TraceSummary{
+		TraceID:  1,
+		Service:  "web",
+		Name:     "op",
+		Resource: "GET /",
+		Env:      "prod",
+		Start:    1,
+		Duration: 10,
+		Error:    true,
+		Spans:    2,
+	}, summaries[1])
+
+	isError, noError := true, false
+	for name, tt := range map[string]struct {
+		filter TraceStoreFilter
+		ids    []uint64
+	}{
+		"service":  {TraceStoreFilter{Service: "db"}, []uint64{1}},
+		"resource": {TraceStoreFilter{Resource: "POST /"}, []uint64{2}},
+		"error":    {TraceStoreFilter{Error: &isError}, []uint64{1}},
+		"no-error": {TraceStoreFilter{Error: &noError}, []uint64{2}},
+		"limit":    {TraceStoreFilter{Limit: 1}, []uint64{2}},
+		"none":     {TraceStoreFilter{Service: "web", Resource: "SELECT"}, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var ids []uint64
+			for _, s := range s.list(now, tt.filter) {
+				ids = append(ids, s.TraceID)
+			}
+			assert.Equal(t, tt.ids, ids)
+		})
+	}
+
+	trace, ok := s.get(now, 1)
+	require.True(t, ok)
+	require.Len(t, trace.Payloads, 2)
+	assert.Equal(t, "prod", trace.Payloads[1].Env)
+	assert.EqualValues(t, 2, trace.Payloads[1].Chunks[0].Spans[0].SpanID)
+	_, ok = s.get(now, 3)
+	assert.False(t, ok)
+}
+
+func TestTraceStoreEviction(t *testing.T) {
+	now := time.Now()
+	s := newTestTraceStore(time.Minute, 2)
+	for id := uint64(1); id <= 3; id++ {
+		s.add(now.Add(time.Duration(id)*time.Second), testStoreChunks("", testutil.TraceChunkWithSpan(testStoreSpan(id, 1, 0, "web", "GET /", 0))))
+	}
+	// the oldest trace is evicted above the maximum number of traces
+	assert.Len(t, s.traces, 2)
+	assert.Equal(t, []uint64{2, 3}, s.order)
+
+	// the traces older than the retention are no longer served, then evicted
+	later := now.Add(time.Minute + 2*time.Second)
+	summaries := s.list(later, TraceStoreFilter{})
+	require.Len(t, summaries, 1)
+	assert.EqualValues(t, 3, summaries[0].TraceID)
+	_, ok := s.get(later, 2)
+	assert.False(t, ok)
+
+	s.add(later, testStoreChunks("", testutil.TraceChunkWithSpan(testStoreSpan(4, 1, 0, "web", "GET /", 0))))
+	assert.Equal(t, []uint64{3, 4}, s.order)
+	assert.Len(t, s.traces, 2)
+}
+
+func TestTraceStoreHandler(t *testing.T) {
+	now := time.Now()
+	s := newTestTraceStore(time.Minute, 10)
+	s.add(now, testStoreChunks("prod",
+		testutil.TraceChunkWithSpans([]*pb.Span{testStoreSpan(1, 1, 0, "web", "GET /", 0), testStoreSpan(1, 2, 1, "db", "SELECT", 1)}),
+		testutil.TraceChunkWithSpans([]*pb.Span{testStoreSpan(2, 1, 0, "web", "POST /", 0)}),
+	))
+	mux := http.NewServeMux()
+	mux.Handle(TraceStoreRoute, s.handler())
+	mux.Handle(TraceStoreRoute+"/", s.handler())
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	rec := get(TraceStoreRoute + "?service=db&error=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var summaries []TraceSummary
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summaries))
+	require.Len(t, summaries, 1)
+	assert.EqualValues(t, 1, summaries[0].TraceID)
+	assert.Equal(t, 2, summaries[0].Spans)
+
+	rec = get(TraceStoreRoute + "/1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var trace StoredTrace
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &trace))
+	assert.EqualValues(t, 1, trace.TraceID)
+	assert.Equal(t, "web", trace.Service)
+	require.Len(t, trace.Payloads, 1)
+	assert.Len(t, trace.Payloads[0].Chunks[0].Spans, 2)
+
+	assert.Equal(t, http.StatusNotFound, get(TraceStoreRoute+"/3").Code)
+	assert.Equal(t, http.StatusBadRequest, get(TraceStoreRoute+"/abc").Code)
+	assert.Equal(t, http.StatusBadRequest, get(TraceStoreRoute+"?error=maybe").Code)
+	assert.Equal(t, http.StatusBadRequest, get(TraceStoreRoute+"?limit=-1").Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, TraceStoreRoute, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestProcessTraceStore(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TraceStoreEnabled = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	require.NotNil(t, agnt.traceStore)
+
+	newPayload := func(traceID uint64, priority sampler.SamplingPriority) *api.Payload {
+		span := testStoreSpan(traceID, 1, 0, "web", "GET /", 0)
+		span.Start = time.Now().Add(-time.Second).UnixNano()
+		chunk := testutil.TraceChunkWithSpan(span)
+		chunk.Priority = int32(priority)
+		return &api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		}
+	}
+	agnt.Process(newPayload(1, sampler.PriorityUserKeep))
+	agnt.Process(newPayload(2, sampler.PriorityUserDrop))
+
+	// only the sampled traces are stored
+	summaries := agnt.traceStore.list(time.Now(), TraceStoreFilter{})
+	require.Len(t, summaries, 1)
+	assert.EqualValues(t, 1, summaries[0].TraceID)
+	assert.Len(t, agnt.TraceWriter.(*mockTraceWriter).payloads, 1)
+}
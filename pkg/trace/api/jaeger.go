@@ -0,0 +1,486 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// jaegerFormat is the name of the Jaeger Thrift format in the receiver stats.
+const jaegerFormat = "jaeger"
+
+// Types of the Thrift binary protocol.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+)
+
+// thriftMaxDepth is the maximum nesting of the skipped Thrift values.
+const thriftMaxDepth = 64
+
+var errThriftInvalid = errors.New("invalid Thrift payload")
+
+// Values of the jaeger.TagType enum.
+const (
+	jaegerTagString = 0
+	jaegerTagDouble = 1
+	jaegerTagBool   = 2
+	jaegerTagLong   = 3
+	jaegerTagBinary = 4
+)
+
+// jaegerTag is a tag of the Jaeger Thrift model.
+type jaegerTag struct {
+	key     string
+	typ     int32
+	str     string
+	double  float64
+	boolean bool
+	long    int64
+	binary  []byte
+}
+
+// jaegerSpan is a span of the Jaeger Thrift model.
+type jaegerSpan struct {
+	traceIDLow   int64
+	traceIDHigh  int64
+	spanID       int64
+	parentSpanID int64
+	// referenceID holds the span ID of the first CHILD_OF reference
+	referenceID   int64
+	operationName string
+	flags         int32
+	startTime     int64 // microseconds since epoch
+	duration      int64 // microseconds
+	tags          []jaegerTag
+}
+
+// jaegerBatch is the jaeger.Batch structure, sent by the Jaeger clients to the collectors.
+// See https://github.com/jaegertracing/jaeger-idl/blob/main/thrift/jaeger.thrift
+type jaegerBatch struct {
+	serviceName string
+	processTags []jaegerTag
+	spans       []*jaegerSpan
+}
+
+// jaegerFlagDebug is the flag of the Jaeger spans forcing the sampling of their trace.
+const jaegerFlagDebug = 2
+
+// decodeJaeger decodes a jaeger.Batch encoded with the Thrift binary protocol.
+func decodeJaeger(req *http.Request) (*pb.TracerPayload, map[uint64]bool, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := copyRequestBody(buf, req); err != nil {
+		return nil, nil, err
+	}
+	r := &thriftReader{b: buf.Bytes()}
+	batch := &jaegerBatch{}
+	if err := r.readStruct(func(id int16, typ byte) error {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			return batch.readProcess(r)
+		case id == 2 && typ == thriftList:
+			return r.readList(thriftStruct, func() error {
+				s, err := readJaegerSpan(r)
+				batch.spans = append(batch.spans, s)
+				return err
+			})
+		}
+		return r.skip(typ, 0)
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	tp := &pb.TracerPayload{}
+	spans := make([]*pb.Span, 0, len(batch.spans))
+	keep := make(map[uint64]bool)
+	for _, js := range batch.spans {
+		span := convertJaegerSpan(batch, js)
+		if span.TraceID == 0 || span.SpanID == 0 {
+			return nil, nil, errors.New("the Jaeger spans must have a trace ID and a span ID")
+		}
+		if js.flags&jaegerFlagDebug != 0 {
+			keep[span.TraceID] = true
+		}
+		spans = append(spans, span)
+	}
+	for _, t := range batch.processTags {
+		if t.key == "hostname" && t.typ == jaegerTagString {
+			tp.Hostname = t.str
+		}
+	}
+	tp.Chunks = traceChunksFromSpans(spans)
+	return tp, keep, nil
+}
+
+// convertJaegerSpan converts a Jaeger span to a Datadog span. The tags of the process are set on the span.
+func convertJaegerSpan(batch *jaegerBatch, js *jaegerSpan) *pb.Span {
+	span := &pb.Span{
+		Service:  batch.serviceName,
+		TraceID:  uint64(js.traceIDLow),
+		SpanID:   uint64(js.spanID),
+		ParentID: uint64(js.parentSpanID),
+		Start:    js.startTime * 1000,
+		Duration: js.duration * 1000,
+		Meta:     make(map[string]string, len(batch.processTags)+len(js.tags)),
+		Metrics:  make(map[string]float64),
+	}
+	if span.ParentID == 0 {
+		span.ParentID = uint64(js.referenceID)
+	}
+	var kind, peerIP string
+	var peerPort int
+	for _, tags := range [][]jaegerTag{batch.processTags, js.tags} {
+		for _, t := range tags {
+			switch t.key {
+			case "hostname":
+				continue
+			case "span.kind":
+				kind = t.str
+				continue
+			case "peer.ipv4":
+				if t.typ == jaegerTagLong {
+					peerIP = net.IPv4(byte(t.long>>24), byte(t.long>>16), byte(t.long>>8), byte(t.long)).String()
+				} else {
+					peerIP = t.str
+				}
+				continue
+			case "peer.ipv6":
+				peerIP = t.str
+				continue
+			case "peer.port":
+				if t.typ == jaegerTagLong {
+					peerPort = int(t.long)
+				} else {
+					peerPort, _ = strconv.Atoi(t.str)
+				}
+				continue
+			}
+			switch t.typ {
+			case jaegerTagString:
+				span.Meta[t.key] = t.str
+			case jaegerTagBool:
+				span.Meta[t.key] = strconv.FormatBool(t.boolean)
+			case jaegerTagDouble:
+				span.Metrics[t.key] = t.double
+			case jaegerTagLong:
+				span.Metrics[t.key] = float64(t.long)
+			case jaegerTagBinary:
+				span.Meta[t.key] = base64.StdEncoding.EncodeToString(t.binary)
+			}
+		}
+	}
+	if span.Meta["otel.status_code"] == "ERROR" {
+		if msg := span.Meta["otel.status_description"]; msg != "" {
+			span.Meta["error"] = msg
+		} else {
+			span.Meta["error"] = "true"
+		}
+	}
+	setSpanIntakeTraceIDHigh(span, uint64(js.traceIDHigh))
+	setSpanIntakePeer(span, "", peerIP, peerPort)
+	setSpanIntakeAttributes(span, jaegerFormat, kind, js.operationName)
+	return span
+}
+
+func (b *jaegerBatch) readProcess(r *thriftReader) error {
+	return r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			b.serviceName, err = r.readString()
+		case id == 2 && typ == thriftList:
+			b.processTags, err = readJaegerTags(r)
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+}
+
+func readJaegerSpan(r *thriftReader) (*jaegerSpan, error) {
+	s := &jaegerSpan{}
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			s.traceIDLow, err = r.readI64()
+		case id == 2 && typ == thriftI64:
+			s.traceIDHigh, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			s.spanID, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			s.parentSpanID, err = r.readI64()
+		case id == 5 && typ == thriftString:
+			s.operationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error { return s.readReference(r) })
+		case id == 7 && typ == thriftI32:
+			s.flags, err = r.readI32()
+		case id == 8 && typ == thriftI64:
+			s.startTime, err = r.readI64()
+		case id == 9 && typ == thriftI64:
+			s.duration, err = r.readI64()
+		case id == 10 && typ == thriftList:
+			s.tags, err = readJaegerTags(r)
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return s, err
+}
+
+// jaegerRefChildOf is the value of the jaeger.SpanRefType enum for the parent references.
+const jaegerRefChildOf = 0
+
+func (s *jaegerSpan) readReference(r *thriftReader) error {
+	var refType int32
+	var spanID int64
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			refType, err = r.readI32()
+		case id == 4 && typ == thriftI64:
+			spanID, err = r.readI64()
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	if err == nil && refType == jaegerRefChildOf && s.referenceID == 0 {
+		s.referenceID = spanID
+	}
+	return err
+}
+
+func readJaegerTags(r *thriftReader) ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(thriftStruct, func() error {
+		var t jaegerTag
+		err := r.readStruct(func(id int16, typ byte) error {
+			var err error
+			switch {
+			case id == 1 && typ == thriftString:
+				t.key, err = r.readString()
+			case id == 2 && typ == thriftI32:
+				t.typ, err = r.readI32()
+			case id == 3 && typ == thriftString:
+				t.str, err = r.readString()
+			case id == 4 && typ == thriftDouble:
+				t.double, err = r.readDouble()
+			case id == 5 && typ == thriftBool:
+				t.boolean, err = r.readBool()
+			case id == 6 && typ == thriftI64:
+				t.long, err = r.readI64()
+			case id == 7 && typ == thriftString:
+				var b string
+				b, err = r.readString()
+				t.binary = []byte(b)
+			default:
+				err = r.skip(typ, 0)
+			}
+			return err
+		})
+		tags = append(tags, t)
+		return err
+	})
+	return tags, err
+}
+
+// thriftReader reads values encoded with the Thrift binary protocol.
+type thriftReader struct {
+	b []byte
+}
+
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errThriftInvalid
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readBool() (bool, error) {
+	b, err := r.readByte()
+	return b != 0, err
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readString() (string, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.next(int(n))
+	return string(b), err
+}
+
+// readStruct calls f with the ID and the type of each field of a struct. f must read or skip the value.
+func (r *thriftReader) readStruct(f func(id int16, typ byte) error) error {
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		if err := f(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readList calls f for each element of a list whose elements have the type elemType.
+func (r *thriftReader) readList(elemType byte, f func() error) error {
+	typ, n, err := r.readListHeader()
+	if err != nil {
+		return err
+	}
+	if typ != elemType {
+		return fmt.Errorf("%w: unexpected list element type %d", errThriftInvalid, typ)
+	}
+	for i := 0; i < n; i++ {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *thriftReader) readListHeader() (typ byte, n int, err error) {
+	if typ, err = r.readByte(); err != nil {
+		return 0, 0, err
+	}
+	size, err := r.readI32()
+	if err != nil {
+		return 0, 0, err
+	}
+	// each element takes at least one byte
+	if size < 0 || int(size) > len(r.b) {
+		return 0, 0, errThriftInvalid
+	}
+	return typ, int(size), nil
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte, depth int) error {
+	if depth > thriftMaxDepth {
+		return fmt.Errorf("%w: too many nested values", errThriftInvalid)
+	}
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readString()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) error { return r.skip(typ, depth+1) })
+	case thriftMap:
+		var kt, vt byte
+		var size int32
+		if kt, err = r.readByte(); err != nil {
+			return err
+		}
+		if vt, err = r.readByte(); err != nil {
+			return err
+		}
+		if size, err = r.readI32(); err != nil {
+			return err
+		}
+		if size < 0 || int(size) > len(r.b) {
+			return errThriftInvalid
+		}
+		for i := 0; i < int(size) && err == nil; i++ {
+			if err = r.skip(kt, depth+1); err == nil {
+				err = r.skip(vt, depth+1)
+			}
+		}
+	case thriftSet, thriftList:
+		var et byte
+		var n int
+		if et, n, err = r.readListHeader(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = r.skip(et, depth+1)
+		}
+	default:
+		err = fmt.Errorf("%w: unknown type %d", errThriftInvalid, typ)
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+)
+
+// thriftWriter encodes values with the Thrift binary protocol.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id) //nolint:errcheck
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) double(id int16, v float64) {
+	w.field(thriftDouble, id)
+	binary.Write(w, binary.BigEndian, math.Float64bits(v)) //nolint:errcheck
+}
+
+func (w *thriftWriter) bool(id int16, v bool) {
+	w.field(thriftBool, id)
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(thriftString, id)
+	binary.Write(w, binary.BigEndian, int32(len(v))) //nolint:errcheck
+	w.WriteString(v)
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, n int) {
+	w.field(thriftList, id)
+	w.WriteByte(elemType)
+	binary.Write(w, binary.BigEndian, int32(n)) //nolint:errcheck
+}
+
+// tags writes a list of jaeger.Tag from values of type string, bool, int64 or float64.
+func (w *thriftWriter) tags(id int16, tags map[string]interface{}) {
+	w.list(id, thriftStruct, len(tags))
+	for k, v := range tags {
+		w.string(1, k)
+		switch v := v.(type) {
+		case string:
+			w.i32(2, jaegerTagString)
+			w.string(3, v)
+		case bool:
+			w.i32(2, jaegerTagBool)
+			w.bool(5, v)
+		case int64:
+			w.i32(2, jaegerTagLong)
+			w.i64(6, v)
+		case float64:
+			w.i32(2, jaegerTagDouble)
+			w.double(4, v)
+		}
+		w.stop()
+	}
+}
+
+func jaegerTestBatch() []byte {
+	var w thriftWriter
+	// process
+	w.field(thriftStruct, 1)
+	w.string(1, "frontend")
+	w.tags(2, map[string]interface{}{"hostname": "host-1", "jaeger.version": "Go-2.30.0"})
+	w.stop()
+
+	w.list(2, thriftStruct, 2)
+	// server span
+	w.i64(1, 0x463a4b2d5f9c3e01)
+	w.i64(2, 0x5af7183fb1d4cf5f)
+	w.i64(3, 0x352bff9a74ca9ad2)
+	w.i64(4, 0)
+	w.string(5, "HTTP GET")
+	w.i32(7, 1)
+	w.i64(8, 1556604172355737)
+	w.i64(9, 1431)
+	w.tags(10, map[string]interface{}{"span.kind": "server", "http.method": "GET", "http.route": "/users", "http.status_code": int64(200)})
+	// logs are ignored
+	w.list(11, thriftStruct, 1)
+	w.i64(1, 1556604172355737)
+	w.tags(2, map[string]interface{}{"event": "start"})
+	w.stop()
+	w.stop()
+
+	// client span, whose parent is set with a reference
+	w.i64(1, 0x463a4b2d5f9c3e01)
+	w.i64(2, 0x5af7183fb1d4cf5f)
+	w.i64(3, 0x6b221d5bc9e6496c)
+	w.i64(4, 0)
+	w.string(5, "query")
+	w.list(6, thriftStruct, 1)
+	w.i32(1, jaegerRefChildOf)
+	w.i64(2, 0x463a4b2d5f9c3e01)
+	w.i64(3, 0x5af7183fb1d4cf5f)
+	w.i64(4, 0x352bff9a74ca9ad2)
+	w.stop()
+	w.i32(7, 3)
+	w.i64(8, 1556604172355800)
+	w.i64(9, 800)
+	w.tags(10, map[string]interface{}{
+		"span.kind":    "client",
+		"peer.ipv4":    int64(0x0a000003),
+		"peer.port":    int64(5432),
+		"peer.service": "postgres",
+		"error":        true,
+		"db.rows":      12.0,
+	})
+	w.stop()
+
+	// seqNo is skipped
+	w.i64(3, 42)
+	w.stop()
+	return w.Bytes()
+}
+
+func TestDecodeJaeger(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/traces", bytes.NewBuffer(jaegerTestBatch()))
+	req.Header.Set("Content-Type", "application/x-thrift")
+	tp, keep, err := decodeJaeger(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "host-1", tp.Hostname)
+	require.Len(t, tp.Chunks, 1)
+	spans := tp.Chunks[0].Spans
+	require.Len(t, spans, 2)
+	server, client := spans[0], spans[1]
+
+	assert.EqualValues(t, uint64(0x463a4b2d5f9c3e01), server.TraceID)
+	assert.Equal(t, "5af7183fb1d4cf5f", server.Meta["_dd.p.tid"])
+	assert.Equal(t, "5af7183fb1d4cf5f", client.Meta["_dd.p.tid"])
+	assert.EqualValues(t, 0, server.ParentID)
+	assert.Equal(t, "frontend", server.Service)
+	assert.Equal(t, "jaeger.server", server.Name)
+	assert.Equal(t, "GET /users", server.Resource)
+	assert.EqualValues(t, 1556604172355737000, server.Start)
+	assert.EqualValues(t, 1431000, server.Duration)
+	assert.Equal(t, "server", server.Meta["span.kind"])
+	assert.Equal(t, "Go-2.30.0", server.Meta["jaeger.version"])
+	assert.NotContains(t, server.Meta, "hostname")
+	assert.EqualValues(t, 200, server.Metrics["http.status_code"])
+	assert.EqualValues(t, 0, server.Error)
+
+	assert.EqualValues(t, 0x352bff9a74ca9ad2, client.ParentID)
+	assert.Equal(t, "jaeger.client", client.Name)
+	assert.Equal(t, "query", client.Resource)
+	assert.Equal(t, "postgres", client.Meta["peer.service"])
+	assert.Equal(t, "10.0.0.3", client.Meta["network.destination.ip"])
+	assert.EqualValues(t, 5432, client.Metrics["network.destination.port"])
+	assert.EqualValues(t, 12, client.Metrics["db.rows"])
+	assert.EqualValues(t, 1, client.Error)
+
+	assert.Equal(t, map[uint64]bool{0x463a4b2d5f9c3e01: true}, keep)
+}
+
+func TestDecodeJaegerInvalid(t *testing.T) {
+	batch := jaegerTestBatch()
+	for name, payload := range map[string][]byte{
+		"truncated":    batch[:len(batch)-10],
+		"unknown-type": {99, 0, 1},
+		"list-size":    {thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff},
+		"string-size":  {thriftString, 0, 9, 0xff, 0xff, 0xff, 0xff},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/traces", bytes.NewBuffer(payload))
+			_, _, err := decodeJaeger(req)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHandleJaeger(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.JaegerReceiverEnabled = true
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/traces", "application/x-thrift", bytes.NewBuffer(jaegerTestBatch()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	p := <-r.out
+	require.Len(t, p.TracerPayload.Chunks, 1)
+	assert.Len(t, p.TracerPayload.Chunks[0].Spans, 2)
+	ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: jaegerFormat, Service: "frontend"})
+	assert.EqualValues(t, 1, ts.TracesReceived.Load())
+	assert.EqualValues(t, 1, ts.PayloadAccepted.Load())
+
+	resp, err = http.Get(server.URL + "/api/traces")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/header"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// tagTraceIDHigh is the meta holding the hex-encoded upper 64 bits of the 128-bit trace IDs,
+// the lower 64 bits are the trace ID of the span.
+const tagTraceIDHigh = "_dd.p.tid"
+
+// spanIntakeDecoder decodes the spans of a request sent in a third-party format, such as Zipkin
+// or Jaeger, and converts them to a Datadog tracer payload. The IDs of the traces flagged as
+// debug by the client are returned in keep.
+type spanIntakeDecoder func(req *http.Request) (tp *pb.TracerPayload, keep map[uint64]bool, err error)
+
+// handleSpanIntake returns a handler accepting spans in a third-party format. The spans are
+// converted with decode and flow through the agent like the spans received from the Datadog
+// tracers. The format is used as the endpoint version of the receiver stats.
+func (r *HTTPReceiver) handleSpanIntake(format string, decode spanIntakeDecoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Header.Get("Sec-Fetch-Site") == "cross-site" {
+			http.Error(w, "cross-site request rejected", http.StatusForbidden)
+			return
+		}
+		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
+		defer req.Body.Close()
+
+		select {
+		case r.recvsem <- struct{}{}:
+		case <-time.After(time.Duration(r.conf.DecoderTimeout) * time.Millisecond):
+			log.Debugf("trace-agent is overwhelmed, a %s payload has been rejected", format)
+			io.Copy(io.Discard, req.Body) //nolint:errcheck
+			w.WriteHeader(http.StatusTooManyRequests)
+			r.spanIntakeTagStats(format, req.Header, "").PayloadRefused.Inc()
+			return
+		}
+		defer func() {
+			<-r.recvsem
+		}()
+
+		start := time.Now()
+		tp, keep, err := decode(req)
+		ts := r.spanIntakeTagStats(format, req.Header, firstSpanService(tp))
+		defer func(err error) {
+			tags := append(ts.AsTags(), fmt.Sprintf("success:%v", err == nil))
+			_ = r.statsd.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), tags, 1)
+		}(err)
+		if err != nil {
+			httpDecodingError(err, []string{"handler:traces", "v:" + format}, w, r.statsd)
+			switch err {
+			case apiutil.ErrLimitedReaderLimitReached:
+				ts.TracesDropped.PayloadTooLarge.Inc()
+			case io.EOF, io.ErrUnexpectedEOF:
+				ts.TracesDropped.EOF.Inc()
+			default:
+				if err, ok := err.(net.Error); ok && err.Timeout() {
+					ts.TracesDropped.Timeout.Inc()
+				} else {
+					ts.TracesDropped.DecodingError.Inc()
+				}
+			}
+			log.Errorf("Cannot decode %s traces payload: %v", format, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		ts.TracesReceived.Add(int64(len(tp.Chunks)))
+		ts.TracesBytes.Add(req.Body.(*apiutil.LimitedReader).Count)
+		ts.PayloadAccepted.Inc()
+		if len(tp.Chunks) == 0 {
+			return
+		}
+		for _, chunk := range tp.Chunks {
+			if keep[chunk.Spans[0].TraceID] {
+				chunk.Priority = int32(sampler.PriorityUserKeep)
+			}
+		}
+
+		tp.ContainerID = r.containerIDProvider.GetContainerID(req.Context(), req.Header)
+		if ctags := getContainerTags(r.conf.ContainerTags, tp.ContainerID); ctags != "" {
+			if tp.Tags == nil {
+				tp.Tags = make(map[string]string)
+			}
+			tp.Tags[tagContainersTags] = ctags
+		}
+		r.out <- &Payload{
+			Source:        ts,
+			TracerPayload: tp,
+		}
+	})
+}
+
+func (r *HTTPReceiver) spanIntakeTagStats(format string, httpHeader http.Header, service string) *info.TagStats {
+	return r.Stats.GetTagStats(info.Tags{
+		Lang:            httpHeader.Get(header.Lang),
+		TracerVersion:   httpHeader.Get(header.TracerVersion),
+		EndpointVersion: format,
+		Service:         service,
+	})
+}
+
+func firstSpanService(tp *pb.TracerPayload) string {
+	if tp == nil || len(tp.Chunks) == 0 || len(tp.Chunks[0].Spans) == 0 {
+		return ""
+	}
+	return tp.Chunks[0].Spans[0].Service
+}
+
+// setSpanIntakeAttributes sets the Datadog conventions of a converted span from its kind, its
+// tags and its third-party name: the span kind, the name, the resource and the error message.
+func setSpanIntakeAttributes(span *pb.Span, format, kind, name string) {
+	kind = strings.ToLower(kind)
+	switch kind {
+	case "server", "client", "producer", "consumer":
+	default:
+		kind = "internal"
+	}
+	traceutil.SetMeta(span, "span.kind", kind)
+	if kind == "client" || kind == "producer" {
+		// client-side spans are not top-level but we still want stats
+		traceutil.SetMeasured(span, true)
+	}
+	span.Name = format + "." + kind
+	if span.Resource = resourceFromTags(span.Meta); span.Resource == "" {
+		span.Resource = name
+	}
+	if span.Resource == "" {
+		span.Resource = span.Name
+	}
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta["deployment.environment"]; env != "" {
+			span.Meta["env"] = env
+		}
+	}
+	if msg, ok := span.Meta["error"]; ok && msg != "false" {
+		span.Error = 1
+		if msg != "" && msg != "true" {
+			traceutil.SetMeta(span, "error.msg", msg)
+		}
+	}
+}
+
+// setSpanIntakePeer sets the Datadog peer tags of a converted span from its remote endpoint.
+func setSpanIntakePeer(span *pb.Span, service, ip string, port int) {
+	if service != "" {
+		traceutil.SetMeta(span, "peer.service", service)
+	}
+	if ip != "" {
+		traceutil.SetMeta(span, "network.destination.ip", ip)
+	}
+	if port != 0 {
+		traceutil.SetMetric(span, "network.destination.port", float64(port))
+	}
+}
+
+// parseSpanIntakeID parses a hex-encoded 64-bit ID.
+func parseSpanIntakeID(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(id, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q", id)
+	}
+	return v, nil
+}
+
+// parseSpanIntakeTraceID parses a hex-encoded 64-bit or 128-bit trace ID into its lower and
+// upper 64 bits.
+func parseSpanIntakeTraceID(id string) (low uint64, high uint64, err error) {
+	if len(id) > 16 {
+		if high, err = parseSpanIntakeID(id[:len(id)-16]); err != nil {
+			return 0, 0, err
+		}
+		id = id[len(id)-16:]
+	}
+	low, err = parseSpanIntakeID(id)
+	return low, high, err
+}
+
+// setSpanIntakeTraceIDHigh sets the upper 64 bits of the 128-bit trace ID of a converted span.
+func setSpanIntakeTraceIDHigh(span *pb.Span, high uint64) {
+	if high != 0 {
+		traceutil.SetMeta(span, tagTraceIDHigh, fmt.Sprintf("%016x", high))
+	}
+}
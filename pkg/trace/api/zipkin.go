@@ -0,0 +1,261 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// zipkinFormat is the name of the Zipkin v2 format in the receiver stats.
+const zipkinFormat = "zipkin"
+
+// zipkinSpan is a span of the Zipkin v2 model.
+// See https://zipkin.io/zipkin-api/#/default/post_spans
+type zipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId"`
+	Name           string            `json:"name"`
+	Kind           string            `json:"kind"`
+	Timestamp      uint64            `json:"timestamp"` // microseconds since epoch
+	Duration       uint64            `json:"duration"`  // microseconds
+	Debug          bool              `json:"debug"`
+	LocalEndpoint  *zipkinEndpoint   `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint   `json:"remoteEndpoint"`
+	Tags           map[string]string `json:"tags"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+// decodeZipkin decodes a list of Zipkin v2 spans, encoded in JSON or in protobuf (proto3).
+func decodeZipkin(req *http.Request) (*pb.TracerPayload, map[uint64]bool, error) {
+	var spans []*zipkinSpan
+	switch getMediaType(req) {
+	case "application/x-protobuf", "application/protobuf":
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if _, err := copyRequestBody(buf, req); err != nil {
+			return nil, nil, err
+		}
+		var err error
+		if spans, err = unmarshalZipkinProto(buf.Bytes()); err != nil {
+			return nil, nil, err
+		}
+	default:
+		if err := json.NewDecoder(req.Body).Decode(&spans); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	converted := make([]*pb.Span, 0, len(spans))
+	keep := make(map[uint64]bool)
+	for _, zs := range spans {
+		if zs == nil {
+			continue
+		}
+		span, err := convertZipkinSpan(zs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if zs.Debug {
+			keep[span.TraceID] = true
+		}
+		converted = append(converted, span)
+	}
+	return &pb.TracerPayload{Chunks: traceChunksFromSpans(converted)}, keep, nil
+}
+
+// convertZipkinSpan converts a Zipkin v2 span to a Datadog span.
+func convertZipkinSpan(zs *zipkinSpan) (*pb.Span, error) {
+	span := &pb.Span{
+		Start:    int64(zs.Timestamp) * 1000,
+		Duration: int64(zs.Duration) * 1000,
+		Meta:     make(map[string]string, len(zs.Tags)+2),
+		Metrics:  make(map[string]float64),
+	}
+	traceID, traceIDHigh, err := parseSpanIntakeTraceID(zs.TraceID)
+	if err != nil {
+		return nil, err
+	}
+	span.TraceID = traceID
+	if span.SpanID, err = parseSpanIntakeID(zs.ID); err != nil {
+		return nil, err
+	}
+	if span.ParentID, err = parseSpanIntakeID(zs.ParentID); err != nil {
+		return nil, err
+	}
+	if span.TraceID == 0 || span.SpanID == 0 {
+		return nil, errors.New("the Zipkin spans must have a trace ID and an ID")
+	}
+	for k, v := range zs.Tags {
+		span.Meta[k] = v
+	}
+	setSpanIntakeTraceIDHigh(span, traceIDHigh)
+	if zs.LocalEndpoint != nil {
+		span.Service = zs.LocalEndpoint.ServiceName
+	}
+	if ep := zs.RemoteEndpoint; ep != nil {
+		ip := ep.IPv4
+		if ip == "" {
+			ip = ep.IPv6
+		}
+		setSpanIntakePeer(span, ep.ServiceName, ip, ep.Port)
+	}
+	setSpanIntakeAttributes(span, zipkinFormat, zs.Kind, zs.Name)
+	return span, nil
+}
+
+// zipkinProtoKinds maps the values of the Span.Kind enum of zipkin.proto to the kinds of the JSON model.
+var zipkinProtoKinds = map[uint64]string{
+	1: "CLIENT",
+	2: "SERVER",
+	3: "PRODUCER",
+	4: "CONSUMER",
+}
+
+// unmarshalZipkinProto decodes a ListOfSpans message of the zipkin.proto definition.
+// See https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto
+func unmarshalZipkinProto(b []byte) ([]*zipkinSpan, error) {
+	var spans []*zipkinSpan
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		s, err := unmarshalZipkinProtoSpan(v)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, s)
+		return nil
+	})
+	return spans, err
+}
+
+func unmarshalZipkinProtoSpan(b []byte) (*zipkinSpan, error) {
+	s := &zipkinSpan{}
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		var err error
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			s.TraceID = hex.EncodeToString(v)
+		case num == 2 && typ == protowire.BytesType:
+			s.ParentID = hex.EncodeToString(v)
+		case num == 3 && typ == protowire.BytesType:
+			s.ID = hex.EncodeToString(v)
+		case num == 4 && typ == protowire.VarintType:
+			s.Kind = zipkinProtoKinds[n]
+		case num == 5 && typ == protowire.BytesType:
+			s.Name = string(v)
+		case num == 6 && typ == protowire.Fixed64Type:
+			s.Timestamp = n
+		case num == 7 && typ == protowire.VarintType:
+			s.Duration = n
+		case num == 8 && typ == protowire.BytesType:
+			s.LocalEndpoint, err = unmarshalZipkinProtoEndpoint(v)
+		case num == 9 && typ == protowire.BytesType:
+			s.RemoteEndpoint, err = unmarshalZipkinProtoEndpoint(v)
+		case num == 11 && typ == protowire.BytesType:
+			if s.Tags == nil {
+				s.Tags = make(map[string]string)
+			}
+			err = unmarshalProtoMapEntry(v, s.Tags)
+		case num == 12 && typ == protowire.VarintType:
+			s.Debug = n != 0
+		}
+		return err
+	})
+	return s, err
+}
+
+func unmarshalZipkinProtoEndpoint(b []byte) (*zipkinEndpoint, error) {
+	ep := &zipkinEndpoint{}
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			ep.ServiceName = string(v)
+		case num == 2 && typ == protowire.BytesType && len(v) == net.IPv4len:
+			ep.IPv4 = net.IP(v).String()
+		case num == 3 && typ == protowire.BytesType && len(v) == net.IPv6len:
+			ep.IPv6 = net.IP(v).String()
+		case num == 4 && typ == protowire.VarintType:
+			ep.Port = int(int32(n))
+		}
+		return nil
+	})
+	return ep, err
+}
+
+// unmarshalProtoMapEntry decodes an entry of a map<string, string> field into m.
+func unmarshalProtoMapEntry(b []byte, m map[string]string) error {
+	var k, v string
+	err := rangeProtoFields(b, func(num protowire.Number, typ protowire.Type, b []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			k = string(b)
+		case 2:
+			v = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m[k] = v
+	return nil
+}
+
+// rangeProtoFields calls f with the fields of a protobuf message. The value of the length-delimited
+// fields is passed in v, and the value of the numeric fields in n. The groups are skipped.
+func rangeProtoFields(b []byte, f func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return fmt.Errorf("invalid protobuf message: %w", protowire.ParseError(l))
+		}
+		b = b[l:]
+		var v []byte
+		var n uint64
+		switch typ {
+		case protowire.VarintType:
+			n, l = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var n32 uint32
+			n32, l = protowire.ConsumeFixed32(b)
+			n = uint64(n32)
+		case protowire.Fixed64Type:
+			n, l = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, l = protowire.ConsumeBytes(b)
+		default:
+			l = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if l < 0 {
+			return fmt.Errorf("invalid protobuf message: %w", protowire.ParseError(l))
+		}
+		b = b[l:]
+		if err := f(num, typ, v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
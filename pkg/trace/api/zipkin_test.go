@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+const zipkinTestJSON = `[
+  {
+    "traceId": "5af7183fb1d4cf5f463a4b2d5f9c3e01",
+    "id": "352bff9a74ca9ad2",
+    "name": "get /users",
+    "kind": "SERVER",
+    "timestamp": 1556604172355737,
+    "duration": 1431,
+    "localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1"},
+    "tags": {"http.method": "GET", "http.route": "/users", "http.status_code": "200"}
+  },
+  {
+    "traceId": "5af7183fb1d4cf5f463a4b2d5f9c3e01",
+    "parentId": "352bff9a74ca9ad2",
+    "id": "6b221d5bc9e6496c",
+    "name": "query",
+    "kind": "CLIENT",
+    "timestamp": 1556604172355800,
+    "duration": 800,
+    "debug": true,
+    "localEndpoint": {"serviceName": "frontend"},
+    "remoteEndpoint": {"serviceName": "postgres", "ipv4": "10.0.0.3", "port": 5432},
+    "tags": {"error": "connection reset"}
+  }
+]`
+
+func assertZipkinTestSpans(t *testing.T, tp *pb.TracerPayload, keep map[uint64]bool) {
+	require.Len(t, tp.Chunks, 1)
+	spans := tp.Chunks[0].Spans
+	require.Len(t, spans, 2)
+	server, client := spans[0], spans[1]
+
+	assert.EqualValues(t, uint64(0x463a4b2d5f9c3e01), server.TraceID)
+	assert.Equal(t, "5af7183fb1d4cf5f", server.Meta["_dd.p.tid"])
+	assert.Equal(t, "5af7183fb1d4cf5f", client.Meta["_dd.p.tid"])
+	assert.EqualValues(t, 0, server.ParentID)
+	assert.Equal(t, "frontend", server.Service)
+	assert.Equal(t, "zipkin.server", server.Name)
+	assert.Equal(t, "GET /users", server.Resource)
+	assert.EqualValues(t, 1556604172355737000, server.Start)
+	assert.EqualValues(t, 1431000, server.Duration)
+	assert.Equal(t, "server", server.Meta["span.kind"])
+	assert.Equal(t, "200", server.Meta["http.status_code"])
+	assert.EqualValues(t, 0, server.Error)
+
+	assert.EqualValues(t, 0x352bff9a74ca9ad2, client.ParentID)
+	assert.Equal(t, "zipkin.client", client.Name)
+	assert.Equal(t, "query", client.Resource)
+	assert.Equal(t, "client", client.Meta["span.kind"])
+	assert.Equal(t, "postgres", client.Meta["peer.service"])
+	assert.Equal(t, "10.0.0.3", client.Meta["network.destination.ip"])
+	assert.EqualValues(t, 5432, client.Metrics["network.destination.port"])
+	assert.EqualValues(t, 1, client.Metrics["_dd.measured"])
+	assert.EqualValues(t, 1, client.Error)
+	assert.Equal(t, "connection reset", client.Meta["error.msg"])
+
+	assert.Equal(t, map[uint64]bool{0x463a4b2d5f9c3e01: true}, keep)
+}
+
+func TestDecodeZipkinJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(zipkinTestJSON))
+	req.Header.Set("Content-Type", "application/json")
+	tp, keep, err := decodeZipkin(req)
+	require.NoError(t, err)
+	assertZipkinTestSpans(t, tp, keep)
+
+	for _, payload := range []string{
+		`{"traceId": "1"}`,
+		`[{"traceId": "zz", "id": "1"}]`,
+		`[{"traceId": "1"}]`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(payload))
+		_, _, err := decodeZipkin(req)
+		assert.Error(t, err, payload)
+	}
+}
+
+func TestDecodeZipkinProto(t *testing.T) {
+	appendBytes := func(b []byte, num protowire.Number, v []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+	appendVarint := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+	appendFixed64 := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, v)
+	}
+	tag := func(k, v string) []byte {
+		return appendBytes(appendBytes(nil, 1, []byte(k)), 2, []byte(v))
+	}
+
+	var server []byte
+	server = appendBytes(server, 1, []byte{0x5a, 0xf7, 0x18, 0x3f, 0xb1, 0xd4, 0xcf, 0x5f, 0x46, 0x3a, 0x4b, 0x2d, 0x5f, 0x9c, 0x3e, 0x01})
+	server = appendBytes(server, 3, []byte{0x35, 0x2b, 0xff, 0x9a, 0x74, 0xca, 0x9a, 0xd2})
+	server = appendVarint(server, 4, 2)
+	server = appendBytes(server, 5, []byte("get /users"))
+	server = appendFixed64(server, 6, 1556604172355737)
+	server = appendVarint(server, 7, 1431)
+	server = appendBytes(server, 8, appendBytes(appendBytes(nil, 1, []byte("frontend")), 2, []byte{192, 168, 99, 1}))
+	server = appendBytes(server, 11, tag("http.method", "GET"))
+	server = appendBytes(server, 11, tag("http.route", "/users"))
+	server = appendBytes(server, 11, tag("http.status_code", "200"))
+	// annotations are ignored
+	server = appendBytes(server, 10, appendBytes(nil, 2, []byte("ws")))
+
+	var client []byte
+	client = appendBytes(client, 1, []byte{0x5a, 0xf7, 0x18, 0x3f, 0xb1, 0xd4, 0xcf, 0x5f, 0x46, 0x3a, 0x4b, 0x2d, 0x5f, 0x9c, 0x3e, 0x01})
+	client = appendBytes(client, 2, []byte{0x35, 0x2b, 0xff, 0x9a, 0x74, 0xca, 0x9a, 0xd2})
+	client = appendBytes(client, 3, []byte{0x6b, 0x22, 0x1d, 0x5b, 0xc9, 0xe6, 0x49, 0x6c})
+	client = appendVarint(client, 4, 1)
+	client = appendBytes(client, 5, []byte("query"))
+	client = appendFixed64(client, 6, 1556604172355800)
+	client = appendVarint(client, 7, 800)
+	client = appendBytes(client, 8, appendBytes(nil, 1, []byte("frontend")))
+	remote := appendBytes(appendBytes(nil, 1, []byte("postgres")), 2, []byte{10, 0, 0, 3})
+	client = appendBytes(client, 9, appendVarint(remote, 4, 5432))
+	client = appendBytes(client, 11, tag("error", "connection reset"))
+	client = appendVarint(client, 12, 1)
+
+	payload := appendBytes(appendBytes(nil, 1, server), 1, client)
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	tp, keep, err := decodeZipkin(req)
+	require.NoError(t, err)
+	assertZipkinTestSpans(t, tp, keep)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBuffer(payload[:len(payload)-3]))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	_, _, err = decodeZipkin(req)
+	assert.Error(t, err)
+}
+
+func TestParseSpanIntakeTraceID(t *testing.T) {
+	low, high, err := parseSpanIntakeTraceID("463a4b2d5f9c3e01")
+	require.NoError(t, err)
+	assert.EqualValues(t, uint64(0x463a4b2d5f9c3e01), low)
+	assert.EqualValues(t, 0, high)
+
+	low, high, err = parseSpanIntakeTraceID("5af7183fb1d4cf5f463a4b2d5f9c3e01")
+	require.NoError(t, err)
+	assert.EqualValues(t, uint64(0x463a4b2d5f9c3e01), low)
+	assert.EqualValues(t, uint64(0x5af7183fb1d4cf5f), high)
+
+	_, _, err = parseSpanIntakeTraceID("5af7183fb1d4cf5z463a4b2d5f9c3e01")
+	assert.Error(t, err)
+}
+
+func TestHandleZipkin(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.ZipkinReceiverEnabled = true
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString(zipkinTestJSON))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	p := <-r.out
+	require.Len(t, p.TracerPayload.Chunks, 1)
+	assert.EqualValues(t, sampler.PriorityUserKeep, p.TracerPayload.Chunks[0].Priority)
+	ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: zipkinFormat, Service: "frontend"})
+	assert.EqualValues(t, 1, ts.TracesReceived.Load())
+
+	resp, err = http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString("} invalid json"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// the endpoint is disabled by default
+	r = newTestReceiverFromConfig(newTestReceiverConfig())
+	rec := httptest.NewRecorder()
+	r.buildMux().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(zipkinTestJSON)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -27,6 +27,9 @@ import (
 // ServiceName specifies the service name used in the operating system.
 const ServiceName = "datadog-trace-agent"
 
+// MaxStatsExtraDimensions is the maximum number of span tags used as extra dimensions of the stats aggregation.
+const MaxStatsExtraDimensions = 10
+
 // ErrMissingAPIKey is returned when the config could not be validated due to missing API key.
 var ErrMissingAPIKey = errors.New("you must specify an API Key, either via a configuration file or the DD_API_KEY env var")
 
@@ -213,6 +216,54 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// Actions of the span processor rules.
+const (
+	// SpanProcessorAdd sets the tag Key to Value.
+	SpanProcessorAdd = "add"
+	// SpanProcessorDelete removes the matching tags.
+	SpanProcessorDelete = "delete"
+	// SpanProcessorRename renames the tag Key to NewKey.
+	SpanProcessorRename = "rename"
+	// SpanProcessorHash replaces the values of the matching tags by their SHA-256 hash.
+	SpanProcessorHash = "hash"
+	// SpanProcessorToMetrics moves the matching tags with a numeric value from the meta to the metrics.
+	SpanProcessorToMetrics = "to_metrics"
+	// SpanProcessorToMeta moves the matching tags from the metrics to the meta.
+	SpanProcessorToMeta = "to_meta"
+)
+
+// SpanProcessorRule specifies a rule modifying the tags of the spans.
+type SpanProcessorRule struct {
+	// Service, Operation and Resource are optional regexp patterns selecting the spans
+	// the rule applies to. An empty pattern matches all the spans.
+	Service   string `mapstructure:"service"`
+	Operation string `mapstructure:"operation"`
+	Resource  string `mapstructure:"resource"`
+
+	// Action specifies what the rule does to the matching tags, it must be one of the
+	// SpanProcessor* actions.
+	Action string `mapstructure:"action"`
+
+	// Key specifies the name of the tag the rule addresses.
+	Key string `mapstructure:"key"`
+
+	// KeyPattern specifies a regexp pattern matching the names of the tags the rule addresses,
+	// for the actions other than add and rename. The tags starting with "_" are never matched.
+	KeyPattern string `mapstructure:"key_pattern"`
+
+	// Value specifies the value set by the add action.
+	Value string `mapstructure:"value"`
+
+	// NewKey specifies the new name of the tag for the rename action.
+	NewKey string `mapstructure:"new_key"`
+
+	// ServiceRe, OperationRe, ResourceRe and KeyRe hold the compiled patterns and are only used internally.
+	ServiceRe   *regexp.Regexp `mapstructure:"-"`
+	OperationRe *regexp.Regexp `mapstructure:"-"`
+	ResourceRe  *regexp.Regexp `mapstructure:"-"`
+	KeyRe       *regexp.Regexp `mapstructure:"-"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -343,6 +394,13 @@ type AgentConfig struct {
 	ComputeStatsBySpanKind bool          // enables/disables the computing of stats based on a span's `span.kind` field
 	PeerTags               []string      // additional tags to use for peer entity stats aggregation
 
+	// StatsExtraDimensions holds the span tags used as additional dimensions of the stats aggregation,
+	// used by Concentrator and ClientStatsAggregator. At most MaxStatsExtraDimensions are allowed.
+	StatsExtraDimensions []string
+	// StatsExtraDimensionsMaxCardinality is the maximum number of distinct values of each extra dimension.
+	// The values seen beyond it are aggregated together.
+	StatsExtraDimensionsMaxCardinality int
+
 	// Sampler configuration
 	ExtraSampleRate float64
 	TargetTPS       float64
@@ -361,9 +419,25 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// Tail-based Sampling configuration
+	TailSamplingEnabled                  bool
+	TailSamplingDecisionWait             time.Duration // time during which the chunks of a trace are assembled before the sampling decision
+	TailSamplingMaxBufferBytes           int           // maximum size of the assembled chunks, the oldest traces are decided early when it is reached
+	TailSamplingErrors                   bool          // keeps the traces containing an error
+	TailSamplingLatencyThreshold         time.Duration // keeps the traces lasting longer than the threshold, disabled when 0
+	TailSamplingSpanTags                 []*TagRegex   // keeps the traces with a span matching one of the tags
+	TailSamplingRareResources            bool          // keeps the traces with a resource not seen during the cooldown period
+	TailSamplingRareResourcesCooldown    time.Duration
+	TailSamplingRareResourcesCardinality int
+
 	// Error Tracking Standalone
 	ErrorTrackingStandalone bool
 
+	// Trace store, keeping the last sampled traces for local inspection
+	TraceStoreEnabled   bool
+	TraceStoreRetention time.Duration // time during which the traces are kept
+	TraceStoreMaxTraces int           // maximum number of traces kept, the oldest ones are evicted first
+
 	// Receiver
 	ReceiverEnabled bool // specifies whether Receiver listeners are enabled. Unless OTLPReceiver is used, this should always be true.
 	ReceiverHost    string
@@ -377,6 +451,9 @@ type AgentConfig struct {
 	MaxConnections  int   // specifies the maximum number of concurrent incoming connections allowed.
 	DecoderTimeout  int   // specifies the maximum time in milliseconds that the decoders will wait for a turn to accept a payload before returning 429
 
+	ZipkinReceiverEnabled bool // enables the intake of Zipkin v2 spans on /api/v2/spans
+	JaegerReceiverEnabled bool // enables the intake of Jaeger Thrift batches on /api/traces
+
 	WindowsPipeName        string
 	PipeBufferSize         int
 	PipeSecurityDescriptor string
@@ -425,6 +502,10 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SpanProcessors is the list of rules modifying the tags of the spans, applied in order
+	// before the computation of the stats and the sampling.
+	SpanProcessors []*SpanProcessorRule
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -565,8 +646,17 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSamplingDecisionWait:             10 * time.Second,
+		TailSamplingMaxBufferBytes:           50 * 1024 * 1024, // 50MB
+		TailSamplingErrors:                   true,
+		TailSamplingRareResourcesCooldown:    5 * time.Minute,
+		TailSamplingRareResourcesCardinality: 1000,
+
 		ErrorTrackingStandalone: false,
 
+		TraceStoreRetention: 5 * time.Minute,
+		TraceStoreMaxTraces: 1000,
+
 		ReceiverEnabled:        true,
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
@@ -618,6 +708,8 @@ func New() *AgentConfig {
 		Features:               make(map[string]struct{}),
 		PeerTagsAggregation:    true,
 		ComputeStatsBySpanKind: true,
+
+		StatsExtraDimensionsMaxCardinality: 100,
 	}
 }
 
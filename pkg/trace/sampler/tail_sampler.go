@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const (
+	// TailSamplingPolicyKey is the chunk tag set to the name of the tail-based sampling policy keeping the trace.
+	TailSamplingPolicyKey = "_dd.tail_sampling.policy"
+
+	// MetricsTailSamplingKept is the metric name for the number of traces kept by each tail-based sampling policy.
+	MetricsTailSamplingKept = "datadog.trace_agent.sampler.tail.kept"
+	// MetricsTailSamplingMisses is the metric name for the number of traces kept by no tail-based sampling policy.
+	MetricsTailSamplingMisses = "datadog.trace_agent.sampler.tail.misses"
+)
+
+// Names of the tail-based sampling policies.
+const (
+	TailPolicyRareResource = "rare_resource"
+	TailPolicyError        = "error"
+	TailPolicyLatency      = "latency"
+	TailPolicySpanTag      = "span_tag"
+)
+
+// tailPolicy decides whether an assembled trace is kept.
+type tailPolicy struct {
+	name string
+	keep func(now time.Time, spans []*pb.Span) bool
+}
+
+// TailSampler applies the tail-based sampling policies to the traces once their chunks
+// have been assembled, so that the decision depends on all the spans of the trace seen
+// by the agent rather than on a single chunk.
+type TailSampler struct {
+	policies []tailPolicy
+
+	mu     sync.Mutex
+	kept   map[string]int64
+	misses int64
+
+	// resources holds the expiration of the resources seen by the rare resource policy
+	resources            map[resourceKey]time.Time
+	resourcesCooldown    time.Duration
+	resourcesCardinality int
+}
+
+type resourceKey struct {
+	service, name, resource string
+}
+
+// NewTailSampler returns a TailSampler applying the policies enabled in the configuration.
+// The rare resource policy runs first, so that the resources of all the traces are recorded.
+func NewTailSampler(conf *config.AgentConfig) *TailSampler {
+	s := &TailSampler{
+		kept:                 make(map[string]int64),
+		resources:            make(map[resourceKey]time.Time),
+		resourcesCooldown:    conf.TailSamplingRareResourcesCooldown,
+		resourcesCardinality: conf.TailSamplingRareResourcesCardinality,
+	}
+	if conf.TailSamplingRareResources {
+		s.policies = append(s.policies, tailPolicy{name: TailPolicyRareResource, keep: s.keepRareResource})
+	}
+	if conf.TailSamplingErrors {
+		s.policies = append(s.policies, tailPolicy{name: TailPolicyError, keep: keepError})
+	}
+	if threshold := conf.TailSamplingLatencyThreshold; threshold > 0 {
+		s.policies = append(s.policies, tailPolicy{name: TailPolicyLatency, keep: func(_ time.Time, spans []*pb.Span) bool {
+			return traceDuration(spans) >= threshold
+		}})
+	}
+	if tags := conf.TailSamplingSpanTags; len(tags) > 0 {
+		s.policies = append(s.policies, tailPolicy{name: TailPolicySpanTag, keep: func(_ time.Time, spans []*pb.Span) bool {
+			return containsSpanTag(spans, tags)
+		}})
+	}
+	return s
+}
+
+// Sample applies the policies to the spans of an assembled trace. It returns the name of
+// the first policy keeping the trace, or false when no policy keeps it.
+func (s *TailSampler) Sample(now time.Time, spans []*pb.Span) (policy string, keep bool) {
+	for _, p := range s.policies {
+		if p.keep(now, spans) {
+			policy, keep = p.name, true
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if keep {
+		s.kept[policy]++
+	} else {
+		s.misses++
+	}
+	return policy, keep
+}
+
+// keepRareResource keeps the traces with a top level or measured span whose resource
+// wasn't seen during the cooldown period.
+func (s *TailSampler) keepRareResource(now time.Time, spans []*pb.Span) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rare := false
+	for _, span := range spans {
+		if !traceutil.HasTopLevel(span) && !traceutil.IsMeasured(span) {
+			continue
+		}
+		key := resourceKey{service: span.Service, name: span.Name, resource: span.Resource}
+		expire, ok := s.resources[key]
+		if ok && now.Before(expire) {
+			continue
+		}
+		if !ok && len(s.resources) >= s.resourcesCardinality && !s.purgeResources(now) {
+			// too many resources are tracked: new resources aren't considered as rare
+			continue
+		}
+		s.resources[key] = now.Add(s.resourcesCooldown)
+		rare = true
+	}
+	return rare
+}
+
+// purgeResources removes the expired resources and reports whether some were removed.
+func (s *TailSampler) purgeResources(now time.Time) bool {
+	n := len(s.resources)
+	for key, expire := range s.resources {
+		if !now.Before(expire) {
+			delete(s.resources, key)
+		}
+	}
+	return len(s.resources) < n
+}
+
+func (s *TailSampler) report(statsd statsd.ClientInterface) {
+	s.mu.Lock()
+	kept, misses := s.kept, s.misses
+	s.kept, s.misses = make(map[string]int64, len(kept)), 0
+	s.mu.Unlock()
+
+	for policy, count := range kept {
+		_ = statsd.Count(MetricsTailSamplingKept, count, []string{"policy:" + policy}, 1)
+	}
+	_ = statsd.Count(MetricsTailSamplingMisses, misses, nil, 1)
+}
+
+func keepError(_ time.Time, spans []*pb.Span) bool {
+	for _, span := range spans {
+		if span.Error != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// traceDuration returns the time between the start of the first span and the end of the last one.
+func traceDuration(spans []*pb.Span) time.Duration {
+	if len(spans) == 0 {
+		return 0
+	}
+	start, end := spans[0].Start, spans[0].Start+spans[0].Duration
+	for _, span := range spans[1:] {
+		start = min(start, span.Start)
+		end = max(end, span.Start+span.Duration)
+	}
+	return time.Duration(end - start)
+}
+
+// containsSpanTag returns true if a span has one of the tags. A tag without value matches
+// all the spans having its key.
+func containsSpanTag(spans []*pb.Span, tags []*config.TagRegex) bool {
+	for _, span := range spans {
+		for _, tag := range tags {
+			v, ok := span.Meta[tag.K]
+			if ok && (tag.V == nil || tag.V.MatchString(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
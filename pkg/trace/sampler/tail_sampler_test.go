@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func tailTestSpans() []*pb.Span {
+	return []*pb.Span{
+		{TraceID: 1, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /users", Start: 100, Duration: 50, Metrics: map[string]float64{"_top_level": 1}},
+		{TraceID: 1, SpanID: 2, ParentID: 1, Service: "db", Name: "query", Resource: "SELECT", Start: 110, Duration: 20, Meta: map[string]string{"user.email": "john@example.com"}},
+	}
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	now := time.Now()
+
+	t.Run("error", func(t *testing.T) {
+		conf := config.New()
+		s := NewTailSampler(conf)
+
+		spans := tailTestSpans()
+		_, keep := s.Sample(now, spans)
+		assert.False(t, keep)
+
+		spans[1].Error = 1
+		policy, keep := s.Sample(now, spans)
+		assert.True(t, keep)
+		assert.Equal(t, TailPolicyError, policy)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		conf := config.New()
+		conf.TailSamplingLatencyThreshold = 60 * time.Nanosecond
+		s := NewTailSampler(conf)
+
+		spans := tailTestSpans()
+		_, keep := s.Sample(now, spans)
+		assert.False(t, keep)
+
+		// the trace lasts from the start of the first span to the end of the last one
+		spans[1].Duration = 60
+		policy, keep := s.Sample(now, spans)
+		assert.True(t, keep)
+		assert.Equal(t, TailPolicyLatency, policy)
+	})
+
+	t.Run("span_tag", func(t *testing.T) {
+		conf := config.New()
+		conf.TailSamplingSpanTags = []*config.TagRegex{
+			{K: "user.email", V: regexp.MustCompile("@datadoghq.com$")},
+			{K: "debug"},
+		}
+		s := NewTailSampler(conf)
+
+		spans := tailTestSpans()
+		_, keep := s.Sample(now, spans)
+		assert.False(t, keep)
+
+		spans[1].Meta["user.email"] = "jane@datadoghq.com"
+		policy, keep := s.Sample(now, spans)
+		assert.True(t, keep)
+		assert.Equal(t, TailPolicySpanTag, policy)
+
+		spans = tailTestSpans()
+		spans[0].Meta = map[string]string{"debug": "false"}
+		_, keep = s.Sample(now, spans)
+		assert.True(t, keep)
+	})
+
+	t.Run("rare_resource", func(t *testing.T) {
+		conf := config.New()
+		conf.TailSamplingErrors = false
+		conf.TailSamplingRareResources = true
+		conf.TailSamplingRareResourcesCooldown = time.Minute
+		conf.TailSamplingRareResourcesCardinality = 2
+		s := NewTailSampler(conf)
+
+		policy, keep := s.Sample(now, tailTestSpans())
+		assert.True(t, keep)
+		assert.Equal(t, TailPolicyRareResource, policy)
+
+		// the resource was seen during the cooldown period
+		_, keep = s.Sample(now.Add(30*time.Second), tailTestSpans())
+		assert.False(t, keep)
+
+		_, keep = s.Sample(now.Add(2*time.Minute), tailTestSpans())
+		assert.True(t, keep)
+
+		// only the top level and measured spans are considered
+		spans := tailTestSpans()
+		spans[1].Resource = "INSERT"
+		_, keep = s.Sample(now.Add(2*time.Minute), spans)
+		assert.False(t, keep)
+	})
+
+	t.Run("rare_resource_cardinality", func(t *testing.T) {
+		conf := config.New()
+		conf.TailSamplingRareResources = true
+		conf.TailSamplingRareResourcesCooldown = time.Minute
+		conf.TailSamplingRareResourcesCardinality = 1
+		s := NewTailSampler(conf)
+
+		spans := tailTestSpans()
+		_, keep := s.Sample(now, spans)
+		assert.True(t, keep)
+
+		// no more resources can be tracked until the first one expires
+		spans[0].Resource = "GET /orders"
+		_, keep = s.Sample(now, spans)
+		assert.False(t, keep)
+
+		_, keep = s.Sample(now.Add(2*time.Minute), spans)
+		assert.True(t, keep)
+		assert.Len(t, s.resources, 1)
+	})
+}
+
+func TestTailSamplerReport(t *testing.T) {
+	conf := config.New()
+	s := NewTailSampler(conf)
+
+	spans := tailTestSpans()
+	s.Sample(time.Now(), spans)
+	spans[0].Error = 1
+	s.Sample(time.Now(), spans)
+	s.Sample(time.Now(), spans)
+
+	assert.Equal(t, map[string]int64{TailPolicyError: 2}, s.kept)
+	assert.EqualValues(t, 1, s.misses)
+}
@@ -53,6 +53,8 @@ type ClientStatsAggregator struct {
 	agentEnv      string
 	agentHostname string
 	agentVersion  string
+	// extraDimensions applies the cardinality limits to the extra dimensions reported by the tracers
+	extraDimensions *extraDimensions
 
 	exit chan struct{}
 	done chan struct{}
@@ -75,6 +77,8 @@ func NewClientStatsAggregator(conf *config.AgentConfig, writer Writer, statsd st
 		exit:          make(chan struct{}),
 		done:          make(chan struct{}),
 		statsd:        statsd,
+
+		extraDimensions: newExtraDimensions(conf.StatsExtraDimensions, conf.StatsExtraDimensionsMaxCardinality, time.Now()),
 	}
 	return c
 }
@@ -115,6 +119,7 @@ func (a *ClientStatsAggregator) flushOnTime(now time.Time) {
 		}
 	}
 	a.oldestTs = flushTs
+	a.extraDimensions.maybeReset(now)
 }
 
 func (a *ClientStatsAggregator) flushAll() {
@@ -157,6 +162,11 @@ func (a *ClientStatsAggregator) add(now time.Time, p *pb.ClientStatsPayload) {
 			a.buckets[ts.Unix()] = b
 		}
 		b.processTags[p.ProcessTagsHash] = p.ProcessTags
+		for _, gs := range clientBucket.Stats {
+			if gs != nil {
+				gs.PeerTags = a.extraDimensions.foldTags(gs.PeerTags)
+			}
+		}
 		b.aggregateStatsBucket(clientBucket, payloadAggKey)
 	}
 }
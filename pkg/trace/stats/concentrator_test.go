@@ -18,6 +18,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/DataDog/sketches-go/ddsketch"
@@ -818,6 +819,47 @@ func TestPeerTags(t *testing.T) {
 	})
 }
 
+func TestExtraDimensions(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	newSpan := func(spanID uint64, tenant string) *pb.Span {
+		return &pb.Span{
+			SpanID:   spanID,
+			Service:  "myservice",
+			Name:     "http.server.request",
+			Resource: "GET /users",
+			Duration: 100,
+			Meta:     map[string]string{"span.kind": "server", "tenant": tenant, "region": "us1"},
+			Metrics:  map[string]float64{"_top_level": 1},
+		}
+	}
+	spans := []*pb.Span{newSpan(1, "a"), newSpan(2, "b"), newSpan(3, "c"), newSpan(4, "a")}
+	testTrace := toProcessedTrace(spans, "none", "", "", "", "")
+	cfg := config.AgentConfig{
+		BucketInterval:                     time.Duration(testBucketInterval),
+		AgentVersion:                       "0.99.0",
+		DefaultEnv:                         "env",
+		Hostname:                           "hostname",
+		StatsExtraDimensions:               []string{"tenant", "http.route"},
+		StatsExtraDimensionsMaxCardinality: 2,
+	}
+	c := NewTestConcentratorWithCfg(now, &cfg)
+	c.addNow(testTrace, infraTags{})
+	stats := c.flushNow(now.UnixNano()+int64(c.spanConcentrator.bufferLen)*testBucketInterval, false)
+
+	hits := make(map[string]uint64)
+	for _, st := range stats.Stats[0].Stats[0].Stats {
+		require.Len(t, st.PeerTags, 1)
+		hits[st.PeerTags[0]] = st.Hits
+	}
+	// the tenant c exceeds the cardinality limit
+	assert.Equal(map[string]uint64{
+		"tenant:a":                              2,
+		"tenant:b":                              1,
+		"tenant:" + ExtraDimensionOverflowValue: 1,
+	}, hits)
+}
+
 // TestComputeStatsThroughSpanKindCheck ensures that we generate stats for spans that have an eligible span.kind.
 func TestComputeStatsThroughSpanKindCheck(t *testing.T) {
 	assert := assert.New(t)
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ExtraDimensionOverflowValue is the value of the extra dimensions whose cardinality exceeds its limit.
+	ExtraDimensionOverflowValue = "_other"
+
+	// extraDimensionsResetPeriod is the period after which the values seen for the extra dimensions are forgotten.
+	extraDimensionsResetPeriod = time.Hour
+)
+
+// extraDimensions computes the user-configured span tags used as additional dimensions of the
+// stats aggregation. The number of distinct values of each dimension is capped: the values seen
+// beyond the limit are folded into ExtraDimensionOverflowValue until the next reset.
+type extraDimensions struct {
+	keys           []string
+	maxCardinality int
+
+	mu        sync.Mutex
+	values    map[string]map[string]struct{}
+	lastReset time.Time
+}
+
+// newExtraDimensions returns the extra dimensions for the given span tags, or nil if there are none.
+func newExtraDimensions(keys []string, maxCardinality int, now time.Time) *extraDimensions {
+	if len(keys) == 0 {
+		return nil
+	}
+	d := &extraDimensions{
+		keys:           keys,
+		maxCardinality: maxCardinality,
+		values:         make(map[string]map[string]struct{}, len(keys)),
+		lastReset:      now,
+	}
+	for _, k := range keys {
+		d.values[k] = make(map[string]struct{})
+	}
+	return d
+}
+
+// fromMeta returns the extra dimensions of a span as "key:value" tags.
+func (d *extraDimensions) fromMeta(meta map[string]string) []string {
+	if d == nil {
+		return nil
+	}
+	var tags []string
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, k := range d.keys {
+		if v, ok := meta[k]; ok && v != "" {
+			tags = append(tags, k+":"+d.foldLocked(k, v))
+		}
+	}
+	return tags
+}
+
+// foldTags applies the cardinality limits to the extra dimensions found in a list of "key:value"
+// tags, such as the peer tags of the stats computed by the tracers.
+func (d *extraDimensions) foldTags(tags []string) []string {
+	if d == nil || len(tags) == 0 {
+		return tags
+	}
+	var folded []string
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, t := range tags {
+		k, v, ok := strings.Cut(t, ":")
+		if !ok {
+			continue
+		}
+		if _, ok := d.values[k]; !ok {
+			continue
+		}
+		if fv := d.foldLocked(k, v); fv != v {
+			if folded == nil {
+				folded = append([]string(nil), tags...)
+			}
+			folded[i] = k + ":" + fv
+		}
+	}
+	if folded == nil {
+		return tags
+	}
+	return folded
+}
+
+// foldLocked records the value of a dimension and returns it, or ExtraDimensionOverflowValue if
+// the dimension reached its cardinality limit. The lock must be held.
+func (d *extraDimensions) foldLocked(key, value string) string {
+	seen := d.values[key]
+	if _, ok := seen[value]; ok {
+		return value
+	}
+	if len(seen) >= d.maxCardinality {
+		return ExtraDimensionOverflowValue
+	}
+	seen[value] = struct{}{}
+	return value
+}
+
+// maybeReset forgets the values seen for the extra dimensions once per extraDimensionsResetPeriod.
+func (d *extraDimensions) maybeReset(now time.Time) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastReset) < extraDimensionsResetPeriod {
+		return
+	}
+	for k := range d.values {
+		d.values[k] = make(map[string]struct{})
+	}
+	d.lastReset = now
+}
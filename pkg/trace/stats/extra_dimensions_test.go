@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtraDimensionsFromMeta(t *testing.T) {
+	now := time.Now()
+	assert.Nil(t, newExtraDimensions(nil, 10, now).fromMeta(map[string]string{"tenant": "a"}))
+
+	d := newExtraDimensions([]string{"tenant", "region"}, 1, now)
+	assert.Equal(t, []string{"tenant:a", "region:us1"}, d.fromMeta(map[string]string{"tenant": "a", "region": "us1", "env": "prod"}))
+	assert.Equal(t, []string{"tenant:a"}, d.fromMeta(map[string]string{"tenant": "a", "region": ""}))
+	assert.Equal(t, []string{"tenant:" + ExtraDimensionOverflowValue}, d.fromMeta(map[string]string{"tenant": "b"}))
+
+	// the values are forgotten after the reset period
+	d.maybeReset(now.Add(time.Minute))
+	assert.Equal(t, []string{"tenant:" + ExtraDimensionOverflowValue}, d.fromMeta(map[string]string{"tenant": "b"}))
+	d.maybeReset(now.Add(extraDimensionsResetPeriod))
+	assert.Equal(t, []string{"tenant:b"}, d.fromMeta(map[string]string{"tenant": "b"}))
+}
+
+func TestExtraDimensionsFoldTags(t *testing.T) {
+	d := newExtraDimensions([]string{"tenant"}, 1, time.Now())
+
+	tags := []string{"peer.service:db", "tenant:a"}
+	assert.Equal(t, tags, d.foldTags(tags))
+
+	tags = []string{"peer.service:db", "tenant:b"}
+	assert.Equal(t, []string{"peer.service:db", "tenant:" + ExtraDimensionOverflowValue}, d.foldTags(tags))
+	// the tags of the tracer are left untouched
+	assert.Equal(t, "tenant:b", tags[1])
+}
@@ -22,6 +22,10 @@ type SpanConcentratorConfig struct {
 	ComputeStatsBySpanKind bool
 	// BucketInterval the size of our pre-aggregation per bucket
 	BucketInterval int64
+	// ExtraDimensions is the list of span tags used as additional aggregation dimensions
+	ExtraDimensions []string
+	// ExtraDimensionsMaxCardinality is the maximum number of distinct values of each extra dimension
+	ExtraDimensionsMaxCardinality int
 }
 
 // StatSpan holds all the required fields from a span needed to calculate stats
@@ -41,6 +45,7 @@ type StatSpan struct {
 	statusCode       uint32
 	isTopLevel       bool
 	matchingPeerTags []string
+	extraDimensions  []string
 	grpcStatusCode   string
 }
 
@@ -90,6 +95,8 @@ type SpanConcentrator struct {
 	// wait such time before flushing the stats.
 	// This only applies to past buckets. Stats buckets in the future are allowed with no restriction.
 	bufferLen int
+	// extraDimensions holds the user-configured aggregation dimensions
+	extraDimensions *extraDimensions
 
 	// mu protects the buckets field
 	mu      sync.Mutex
@@ -103,6 +110,7 @@ func NewSpanConcentrator(cfg *SpanConcentratorConfig, now time.Time) *SpanConcen
 		bsize:                  cfg.BucketInterval,
 		oldestTs:               alignTs(now.UnixNano(), cfg.BucketInterval),
 		bufferLen:              defaultBufferLen,
+		extraDimensions:        newExtraDimensions(cfg.ExtraDimensions, cfg.ExtraDimensionsMaxCardinality, now),
 		mu:                     sync.Mutex{},
 		buckets:                make(map[int64]*RawBucket),
 	}
@@ -154,6 +162,7 @@ func (sc *SpanConcentrator) NewStatSpan(
 		statusCode:       getStatusCode(meta, metrics),
 		isTopLevel:       isTopLevel,
 		matchingPeerTags: matchingPeerTags(meta, peerTags),
+		extraDimensions:  sc.extraDimensions.fromMeta(meta),
 
 		grpcStatusCode: getGRPCStatusCode(meta, metrics),
 	}, true
@@ -208,6 +217,8 @@ func (sc *SpanConcentrator) Flush(now int64, force bool) []*pb.ClientStatsPayloa
 	containerTagsByID := make(map[string][]string)
 	processTagsByHash := make(map[uint64]string)
 
+	sc.extraDimensions.maybeReset(time.Unix(0, now))
+
 	sc.mu.Lock()
 	for ts, srb := range sc.buckets {
 		// Always keep `bufferLen` buckets (default is 2: current + previous one).
@@ -39,6 +39,7 @@ type groupedStats struct {
 	okDistribution  *ddsketch.DDSketch
 	errDistribution *ddsketch.DDSketch
 	peerTags        []string
+	extraDimensions []string
 }
 
 // round a float to an int, uniformly choosing
@@ -76,12 +77,23 @@ func (s *groupedStats) export(a Aggregation) (*pb.ClientGroupedStats, error) {
 		ErrorSummary:   errSummary,
 		Synthetics:     a.Synthetics,
 		SpanKind:       a.SpanKind,
-		PeerTags:       s.peerTags,
+		PeerTags:       s.tags(),
 		IsTraceRoot:    a.IsTraceRoot,
 		GRPCStatusCode: a.GRPCStatusCode,
 	}, nil
 }
 
+// tags returns the peer tags and the extra dimensions of the grouped stats. The extra dimensions
+// are sent along the peer tags, as both become tags of the trace metrics.
+func (s *groupedStats) tags() []string {
+	if len(s.extraDimensions) == 0 {
+		return s.peerTags
+	}
+	tags := make([]string, 0, len(s.peerTags)+len(s.extraDimensions))
+	tags = append(tags, s.peerTags...)
+	return append(tags, s.extraDimensions...)
+}
+
 func newGroupedStats() *groupedStats {
 	okSketch, err := ddsketch.LogCollapsingLowestDenseDDSketch(relativeAccuracy, maxNumBins)
 	if err != nil {
@@ -174,6 +186,7 @@ func (sb *RawBucket) add(s *StatSpan, weight float64, aggr Aggregation) {
 	if gs, ok = sb.data[aggr]; !ok {
 		gs = newGroupedStats()
 		gs.peerTags = s.matchingPeerTags
+		gs.extraDimensions = s.extraDimensions
 		sb.data[aggr] = gs
 	}
 	if s.isTopLevel {